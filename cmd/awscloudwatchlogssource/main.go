@@ -17,11 +17,25 @@ limitations under the License.
 package main
 
 import (
+	"os"
+	"strconv"
+
 	"knative.dev/eventing/pkg/adapter/v2"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/awscloudwatchlogssource"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/sharedmain"
 )
 
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "CLOUDWATCHLOGS_MULTI_TENANT"
+
 func main() {
+	if multiTenant, _ := strconv.ParseBool(os.Getenv(envMultiTenant)); multiTenant {
+		sharedmain.MainWithController(awscloudwatchlogssource.NewMTEnvConfig,
+			awscloudwatchlogssource.NewController, awscloudwatchlogssource.NewMTAdapter)
+		return
+	}
+
 	adapter.Main("awscloudwatchlogssource", awscloudwatchlogssource.NewEnvConfig, awscloudwatchlogssource.NewAdapter)
 }
@@ -17,11 +17,25 @@ limitations under the License.
 package main
 
 import (
+	"os"
+	"strconv"
+
 	"knative.dev/eventing/pkg/adapter/v2"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/awscloudwatchsource"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/sharedmain"
 )
 
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "CLOUDWATCH_MULTI_TENANT"
+
 func main() {
+	if multiTenant, _ := strconv.ParseBool(os.Getenv(envMultiTenant)); multiTenant {
+		sharedmain.MainWithController(awscloudwatchsource.NewMTEnvConfig,
+			awscloudwatchsource.NewController, awscloudwatchsource.NewMTAdapter)
+		return
+	}
+
 	adapter.Main("awscloudwatchsource", awscloudwatchsource.NewEnvConfig, awscloudwatchsource.NewAdapter)
 }
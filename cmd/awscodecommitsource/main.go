@@ -17,11 +17,30 @@ limitations under the License.
 package main
 
 import (
+	"os"
+	"strconv"
+
 	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/signals"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/awscodecommitsource"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/sharedmain"
 )
 
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "CODECOMMIT_MULTI_TENANT"
+
 func main() {
-	adapter.Main("awscodecommitsource", awscodecommitsource.NewEnvConfig, awscodecommitsource.NewAdapter)
+	if multiTenant, _ := strconv.ParseBool(os.Getenv(envMultiTenant)); multiTenant {
+		sharedmain.MainWithController(awscodecommitsource.NewMTEnvConfig,
+			awscodecommitsource.NewController, awscodecommitsource.NewMTAdapter)
+		return
+	}
+
+	// injection provides the Kubernetes clients used to persist the state
+	// of the adapter
+	ctx := adapter.WithInjectorEnabled(signals.NewContext())
+
+	adapter.MainWithContext(ctx, "awscodecommitsource", awscodecommitsource.NewEnvConfig, awscodecommitsource.NewAdapter)
 }
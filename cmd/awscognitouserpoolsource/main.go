@@ -17,11 +17,31 @@ limitations under the License.
 package main
 
 import (
+	"os"
+	"strconv"
+
 	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/signals"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/awscognitouserpoolsource"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/sharedmain"
 )
 
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "COGNITO_MULTI_TENANT"
+
 func main() {
-	adapter.Main("awscognitouserpoolsource", awscognitouserpoolsource.NewEnvConfig, awscognitouserpoolsource.NewAdapter)
+	if multiTenant, _ := strconv.ParseBool(os.Getenv(envMultiTenant)); multiTenant {
+		sharedmain.MainWithController(awscognitouserpoolsource.NewMTEnvConfig,
+			awscognitouserpoolsource.NewController, awscognitouserpoolsource.NewMTAdapter)
+		return
+	}
+
+	// injection provides the Kubernetes clients used to persist the
+	// snapshot of the user pool
+	ctx := adapter.WithInjectorEnabled(signals.NewContext())
+
+	adapter.MainWithContext(ctx, "awscognitouserpoolsource",
+		awscognitouserpoolsource.NewEnvConfig, awscognitouserpoolsource.NewAdapter)
 }
@@ -18,10 +18,15 @@ package main
 
 import (
 	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/signals"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/awsdynamodbsource"
 )
 
 func main() {
-	adapter.Main("awsdynamodbsource", awsdynamodbsource.NewEnvConfig, awsdynamodbsource.NewAdapter)
+	// injection provides the Kubernetes clients used to coordinate the
+	// distribution of shards across replicas
+	ctx := adapter.WithInjectorEnabled(signals.NewContext())
+
+	adapter.MainWithContext(ctx, "awsdynamodbsource", awsdynamodbsource.NewEnvConfig, awsdynamodbsource.NewAdapter)
 }
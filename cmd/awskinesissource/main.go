@@ -18,10 +18,15 @@ package main
 
 import (
 	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/signals"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/awskinesissource"
 )
 
 func main() {
-	adapter.Main("awskinesissource", awskinesissource.NewEnvConfig, awskinesissource.NewAdapter)
+	// injection provides the Kubernetes clients used to coordinate the
+	// distribution of shards across replicas
+	ctx := adapter.WithInjectorEnabled(signals.NewContext())
+
+	adapter.MainWithContext(ctx, "awskinesissource", awskinesissource.NewEnvConfig, awskinesissource.NewAdapter)
 }
@@ -17,16 +17,29 @@ limitations under the License.
 package main
 
 import (
+	"os"
 	"runtime"
+	"strconv"
 
 	"knative.dev/eventing/pkg/adapter/v2"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/awssqssource"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/sharedmain"
 )
 
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "SQS_MULTI_TENANT"
+
 func main() {
 	setMaxProcs(runtime.NumCPU())
 
+	if multiTenant, _ := strconv.ParseBool(os.Getenv(envMultiTenant)); multiTenant {
+		sharedmain.MainWithController(awssqssource.NewMTEnvConfig,
+			awssqssource.NewController, awssqssource.NewMTAdapter)
+		return
+	}
+
 	adapter.Main("awssqssource", awssqssource.NewEnvConfig, awssqssource.NewAdapter)
 }
 
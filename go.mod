@@ -7,7 +7,7 @@ go 1.15
 replace k8s.io/client-go => k8s.io/client-go v0.19.7
 
 require (
-	github.com/aws/aws-sdk-go v1.37.1
+	github.com/aws/aws-sdk-go v1.42.16
 	github.com/cloudevents/sdk-go/v2 v2.2.0
 	github.com/google/go-cmp v0.5.5
 	github.com/google/uuid v1.2.0
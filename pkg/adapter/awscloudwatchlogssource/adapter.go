@@ -85,17 +85,25 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 		logger.Panicf("Unable to parse interval duration: %v", zap.Error(err))
 	}
 
-	logGroup, logStream := ExtractLogDetails(a.Resource)
+	return newAdapter(logger, cloudwatchlogs.New(cfg), ceClient, a, interval)
+}
+
+// newAdapter returns an adapter which polls the log group or log stream
+// identified by the given ARN.
+func newAdapter(logger *zap.SugaredLogger, cwLogsClient cloudwatchlogsiface.CloudWatchLogsAPI,
+	ceClient cloudevents.Client, arn arn.ARN, pollingInterval time.Duration) *adapter {
+
+	logGroup, logStream := ExtractLogDetails(arn.Resource)
 
 	return &adapter{
 		logger: logger,
 
-		cwLogsClient: cloudwatchlogs.New(cfg),
+		cwLogsClient: cwLogsClient,
 		ceClient:     ceClient,
 
-		arn: a,
+		arn: arn,
 
-		pollingInterval: interval,
+		pollingInterval: pollingInterval,
 		logGroup:        logGroup,
 		logStream:       logStream,
 	}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -44,10 +45,22 @@ type envConfig struct {
 
 	Region string `envconfig:"AWS_REGION"`
 
-	Query           string `envconfig:"QUERIES" required:"true"`          // JSON based array of name/query pairs
+	Query           string `envconfig:"QUERIES"`                          // JSON based array of name/query pairs
+	Alarms          string `envconfig:"ALARMS"`                           // JSON based alarm selector
 	PollingInterval string `envconfig:"POLLING_INTERVAL" required:"true"` // free tier is 5m
+
+	SplitDatapoints bool          `envconfig:"SPLIT_DATAPOINTS"`
+	AggregationLag  time.Duration `envconfig:"AGGREGATION_LAG"`
 }
 
+// Granularity to which polling windows are aligned when none of the metric
+// queries defines a period, such as when all queries are math expressions.
+const defaultWindowAlignment = time.Minute
+
+// Maximum number of times metric data is re-queried when CloudWatch reports
+// that the returned data is partial.
+const maxPartialDataRetries = 3
+
 // adapter implements the source's adapter.
 type adapter struct {
 	logger      *zap.SugaredLogger
@@ -58,6 +71,27 @@ type adapter struct {
 
 	metricQueries   []*cloudwatch.MetricDataQuery
 	pollingInterval time.Duration
+
+	metricDiscoveries []*metricDiscovery
+	// queries resolved from metricDiscoveries
+	discoveredQueries []*cloudwatch.MetricDataQuery
+	nextDiscovery     time.Time
+
+	splitDatapoints bool
+	aggregationLag  time.Duration
+	// granularity to which the boundaries of polling windows are aligned
+	windowAlignment time.Duration
+
+	metricsMu sync.Mutex
+	// end of the last polled time window, which is also the start of the
+	// next one
+	metricsWindowEnd time.Time
+
+	alarms *v1alpha1.AWSCloudWatchAlarmSelector
+	// time of the last state transition observed for each watched alarm,
+	// used to avoid emitting the same transition twice
+	alarmsMu         sync.Mutex
+	alarmTransitions map[string]time.Time
 }
 
 // NewEnvConfig returns an accessor for the source's adapter envConfig.
@@ -67,7 +101,6 @@ func NewEnvConfig() pkgadapter.EnvConfigAccessor {
 
 // NewAdapter returns a constructor for the source's adapter.
 func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClient cloudevents.Client) pkgadapter.Adapter {
-	var err error
 	logger := logging.FromContext(ctx)
 
 	eventsource := v1alpha1.AWSCloudWatchSourceName(envAcc.GetNamespace(), envAcc.GetName())
@@ -83,21 +116,60 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 		logger.Panicf("Unable to parse interval duration: %v", zap.Error(err))
 	}
 
-	metricQueries, err := parseQueries(env.Query)
+	a, err := newAdapter(logger, eventsource, cloudwatch.New(cfg), ceClient,
+		interval, env.Query, env.Alarms, env.SplitDatapoints, env.AggregationLag)
 	if err != nil {
-		logger.Panicf("unable to parse metric queries: %v", zap.Error(err))
+		logger.Panicw("Unable to configure adapter", zap.Error(err))
+	}
+
+	return a
+}
+
+// newAdapter returns an adapter which polls the metrics and alarms described
+// by the given JSON representations of metric queries and alarm selector.
+func newAdapter(logger *zap.SugaredLogger, eventsource string,
+	cwClient cloudwatchiface.CloudWatchAPI, ceClient cloudevents.Client,
+	pollingInterval time.Duration, rawQueries, rawAlarms string,
+	splitDatapoints bool, aggregationLag time.Duration) (*adapter, error) {
+
+	var err error
+
+	var metricQueries []*cloudwatch.MetricDataQuery
+	var metricDiscoveries []*metricDiscovery
+	if rawQueries != "" {
+		if metricQueries, err = parseQueries(rawQueries); err != nil {
+			return nil, fmt.Errorf("parsing metric queries: %w", err)
+		}
+		if metricDiscoveries, err = parseMetricDiscoveries(rawQueries); err != nil {
+			return nil, fmt.Errorf("parsing metric discovery queries: %w", err)
+		}
+	}
+
+	var alarms *v1alpha1.AWSCloudWatchAlarmSelector
+	if rawAlarms != "" {
+		if alarms, err = parseAlarmSelector(rawAlarms); err != nil {
+			return nil, fmt.Errorf("parsing alarm selector: %w", err)
+		}
 	}
 
 	return &adapter{
 		logger:      logger,
 		eventsource: eventsource,
 
-		cwClient: cloudwatch.New(cfg),
+		cwClient: cwClient,
 		ceClient: ceClient,
 
-		pollingInterval: interval,
+		pollingInterval: pollingInterval,
 		metricQueries:   metricQueries,
-	}
+		splitDatapoints: splitDatapoints,
+		aggregationLag:  aggregationLag,
+		windowAlignment: windowAlignment(append(metricQueries, discoveryTemplates(metricDiscoveries)...)),
+
+		metricDiscoveries: metricDiscoveries,
+
+		alarms:           alarms,
+		alarmTransitions: make(map[string]time.Time),
+	}, nil
 }
 
 // parseQueries - Take the JSON representation of the query as passed in, and
@@ -156,6 +228,23 @@ func transformQuery(q *v1alpha1.AWSCloudWatchMetricStat) *cloudwatch.MetricStat
 	return &ms
 }
 
+// windowAlignment returns the granularity to which polling windows should be
+// aligned for the given queries, so that datapoints are neither duplicated
+// nor missed at the boundaries of consecutive windows.
+func windowAlignment(queries []*cloudwatch.MetricDataQuery) time.Duration {
+	var maxPeriod int64
+	for _, q := range queries {
+		if q.MetricStat != nil && aws.Int64Value(q.MetricStat.Period) > maxPeriod {
+			maxPeriod = *q.MetricStat.Period
+		}
+	}
+
+	if maxPeriod == 0 {
+		return defaultWindowAlignment
+	}
+	return time.Duration(maxPeriod) * time.Second
+}
+
 // Start implements adapter.Adapter.
 func (a *adapter) Start(ctx context.Context) error {
 	a.logger.Info("Enabling CloudWatch")
@@ -172,30 +261,101 @@ func (a *adapter) Start(ctx context.Context) error {
 			return nil
 
 		case t := <-poll.C:
-			go a.CollectMetrics(priorTime, t)
+			if len(a.metricQueries) > 0 || len(a.metricDiscoveries) > 0 {
+				go a.CollectMetrics(t)
+			}
+			if a.alarms != nil {
+				go a.CollectAlarms(priorTime, t)
+			}
 			priorTime = &t
 		}
 	}
 }
 
-func (a *adapter) CollectMetrics(priorTime *time.Time, currentTime time.Time) {
+// CollectMetrics sends the results of the metric queries for the time window
+// which closed since the last collection.
+func (a *adapter) CollectMetrics(currentTime time.Time) {
 	a.logger.Debug("Firing metrics")
-	startInterval := currentTime.Add(-a.pollingInterval)
 
-	if priorTime != nil {
-		startInterval = *priorTime
+	// collections triggered by consecutive ticks must not interleave,
+	// otherwise their time windows could overlap
+	a.metricsMu.Lock()
+	defer a.metricsMu.Unlock()
+
+	start, end, ok := a.metricsWindow(currentTime)
+	if !ok {
+		a.logger.Debug("No complete time window to collect metrics for")
+		return
 	}
 
+	if len(a.metricDiscoveries) > 0 && !currentTime.Before(a.nextDiscovery) {
+		// on failure, keep querying the previously discovered metrics
+		if queries, err := a.discoverMetrics(); err != nil {
+			a.logger.Errorw("Error discovering metrics", zap.Error(err))
+		} else {
+			a.discoveredQueries = queries
+			a.nextDiscovery = currentTime.Add(discoveryInterval)
+		}
+	}
+
+	// the window is kept for a later collection until metrics have been
+	// discovered at least once, otherwise the data of discovered metrics
+	// would be lost for that window
+	if len(a.metricDiscoveries) > 0 && a.nextDiscovery.IsZero() {
+		a.logger.Debug("Metrics were never discovered, postponing collection")
+		return
+	}
+
+	batches := batchQueries(a.metricQueries, a.discoveredQueries)
+	if len(batches) == 0 {
+		a.logger.Debug("No metric to query, postponing collection")
+		return
+	}
+
+	for _, queries := range batches {
+		var err error
+		if a.splitDatapoints {
+			err = a.collectDatapoints(start, end, queries)
+		} else {
+			err = a.collectMetricResults(start, end, queries)
+		}
+		if err != nil {
+			a.logger.Errorw("Error collecting metrics", zap.Error(err))
+			return
+		}
+	}
+
+	a.metricsWindowEnd = end
+}
+
+// metricsWindow returns the boundaries of the time window to collect metrics
+// for at the given time. The returned boundaries are aligned to the period of
+// the metric queries, and the window starts where the previous one ended. The
+// returned boolean is false if no new window closed since the last collection.
+func (a *adapter) metricsWindow(currentTime time.Time) (start, end time.Time, ok bool) {
+	end = currentTime.Add(-a.aggregationLag).Truncate(a.windowAlignment)
+
+	start = a.metricsWindowEnd
+	if start.IsZero() {
+		start = end.Add(-a.pollingInterval).Truncate(a.windowAlignment)
+	}
+
+	return start, end, end.After(start)
+}
+
+// collectMetricResults sends one event per metric data result returned by
+// the given queries for the given time window.
+func (a *adapter) collectMetricResults(start, end time.Time, queries []*cloudwatch.MetricDataQuery) error {
 	metricInput := cloudwatch.GetMetricDataInput{
-		EndTime:           &currentTime,
-		StartTime:         &startInterval,
-		MetricDataQueries: a.metricQueries,
+		EndTime:           &end,
+		StartTime:         &start,
+		MetricDataQueries: queries,
 	}
 
+	var sendErr error
+
 	err := a.cwClient.GetMetricDataPages(&metricInput, func(output *cloudwatch.GetMetricDataOutput, b bool) bool {
-		err := a.SendMetricEvent(output)
-		if err != nil {
-			a.logger.Errorf("error sending metrics: %v", zap.Error(err))
+		if sendErr = a.SendMetricEvent(output); sendErr != nil {
 			return false
 		}
 
@@ -203,18 +363,112 @@ func (a *adapter) CollectMetrics(priorTime *time.Time, currentTime time.Time) {
 		return !b
 	})
 	if err != nil {
-		a.logger.Errorf("error retrieving metrics: %v", zap.Error(err))
-		return
+		return fmt.Errorf("retrieving metrics: %w", err)
+	}
+	if sendErr != nil {
+		return fmt.Errorf("sending metrics: %w", sendErr)
+	}
+
+	return nil
+}
+
+// collectDatapoints sends one event per datapoint returned by the given
+// queries for the given time window. Queries for which CloudWatch reports
+// partial data are re-run, and only the datapoints which weren't already sent
+// are sent.
+func (a *adapter) collectDatapoints(start, end time.Time, allQueries []*cloudwatch.MetricDataQuery) error {
+	sent := make(map[string]struct{})
+	queries := allQueries
+
+	for attempt := 0; ; attempt++ {
+		metricInput := cloudwatch.GetMetricDataInput{
+			EndTime:           &end,
+			StartTime:         &start,
+			MetricDataQueries: queries,
+			ScanBy:            aws.String(cloudwatch.ScanByTimestampAscending),
+		}
+
+		partial := make(map[string]struct{})
+		var sendErr error
+
+		err := a.cwClient.GetMetricDataPages(&metricInput, func(output *cloudwatch.GetMetricDataOutput, lastPage bool) bool {
+			for _, m := range output.Messages {
+				if sendErr = a.sendMessageEvent(m); sendErr != nil {
+					return false
+				}
+			}
+
+			for _, r := range output.MetricDataResults {
+				if sendErr = a.sendDatapointEvents(r, sent); sendErr != nil {
+					return false
+				}
+
+				// PartialData is expected on all pages but the last one,
+				// which follow-up pages complete
+				if lastPage && aws.StringValue(r.StatusCode) == cloudwatch.StatusCodePartialData {
+					partial[aws.StringValue(r.Id)] = struct{}{}
+				}
+			}
+
+			return !lastPage
+		})
+		if err != nil {
+			return fmt.Errorf("retrieving metrics: %w", err)
+		}
+		if sendErr != nil {
+			return fmt.Errorf("sending metrics: %w", sendErr)
+		}
+
+		if len(partial) == 0 {
+			return nil
+		}
+		if attempt == maxPartialDataRetries {
+			a.logger.Warnf("Metric data still partial after %d attempts, giving up on queries %v",
+				attempt+1, keys(partial))
+			return nil
+		}
+
+		queries = returningOnly(allQueries, partial)
 	}
 }
 
-func (a *adapter) SendMetricEvent(metricOutput *cloudwatch.GetMetricDataOutput) error {
-	// multiple messages or messages and metric data, and insure the CloudEvent
-	// ID is common.
+// returningOnly returns a copy of the given queries where only the queries
+// with the given ids return data. Other queries are preserved since they may
+// be referenced by math expressions.
+func returningOnly(queries []*cloudwatch.MetricDataQuery, ids map[string]struct{}) []*cloudwatch.MetricDataQuery {
+	out := make([]*cloudwatch.MetricDataQuery, len(queries))
+
+	for i, q := range queries {
+		cpy := *q
+		_, returnData := ids[aws.StringValue(q.Id)]
+		cpy.ReturnData = &returnData
+		out[i] = &cpy
+	}
+
+	return out
+}
+
+// keys returns the keys of the given set.
+func keys(set map[string]struct{}) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	return out
+}
 
+// SendMetricEvent sends one event per message and one event per metric data
+// result contained in the given output.
+func (a *adapter) SendMetricEvent(metricOutput *cloudwatch.GetMetricDataOutput) error {
 	for _, v := range metricOutput.Messages {
+		if err := a.sendMessageEvent(v); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range metricOutput.MetricDataResults {
 		event := cloudevents.NewEvent(cloudevents.VersionV1)
-		event.SetType(v1alpha1.AWSEventType(v1alpha1.ServiceCloudWatch, v1alpha1.AWSCloudWatchMessageEventType))
+		event.SetType(v1alpha1.AWSEventType(v1alpha1.ServiceCloudWatch, v1alpha1.AWSCloudWatchMetricEventType))
 		event.SetSource(a.eventsource)
 		err := event.SetData(cloudevents.ApplicationJSON, v)
 
@@ -227,12 +481,65 @@ func (a *adapter) SendMetricEvent(metricOutput *cloudwatch.GetMetricDataOutput)
 		}
 	}
 
-	for _, v := range metricOutput.MetricDataResults {
+	return nil
+}
+
+// sendMessageEvent sends an event containing the given message.
+func (a *adapter) sendMessageEvent(msg *cloudwatch.MessageData) error {
+	event := cloudevents.NewEvent(cloudevents.VersionV1)
+	event.SetType(v1alpha1.AWSEventType(v1alpha1.ServiceCloudWatch, v1alpha1.AWSCloudWatchMessageEventType))
+	event.SetSource(a.eventsource)
+	err := event.SetData(cloudevents.ApplicationJSON, msg)
+
+	if err != nil {
+		return fmt.Errorf("failed to set event data: %w", err)
+	}
+
+	if result := a.ceClient.Send(context.Background(), event); !cloudevents.IsACK(result) {
+		return result
+	}
+
+	return nil
+}
+
+// MetricDatapoint is the payload of an event representing a single datapoint
+// of a metric data result.
+type MetricDatapoint struct {
+	Id        string    `json:"id"`
+	Label     string    `json:"label"`
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// sendDatapointEvents sends one event per datapoint contained in the given
+// metric data result, skipping datapoints which IDs are in the given set of
+// already sent events. The IDs of sent events are added to that set.
+func (a *adapter) sendDatapointEvents(res *cloudwatch.MetricDataResult, sent map[string]struct{}) error {
+	id := aws.StringValue(res.Id)
+
+	for i, ts := range res.Timestamps {
+		if i >= len(res.Values) {
+			break
+		}
+
+		eventID := datapointEventID(id, *ts)
+		if _, ok := sent[eventID]; ok {
+			continue
+		}
+
 		event := cloudevents.NewEvent(cloudevents.VersionV1)
-		event.SetType(v1alpha1.AWSEventType(v1alpha1.ServiceCloudWatch, v1alpha1.AWSCloudWatchMetricEventType))
+		event.SetID(eventID)
+		event.SetType(v1alpha1.AWSEventType(v1alpha1.ServiceCloudWatch, v1alpha1.AWSCloudWatchDatapointEventType))
 		event.SetSource(a.eventsource)
-		err := event.SetData(cloudevents.ApplicationJSON, v)
-
+		event.SetSubject(id)
+		event.SetTime(*ts)
+
+		err := event.SetData(cloudevents.ApplicationJSON, &MetricDatapoint{
+			Id:        id,
+			Label:     aws.StringValue(res.Label),
+			Timestamp: *ts,
+			Value:     aws.Float64Value(res.Values[i]),
+		})
 		if err != nil {
 			return fmt.Errorf("failed to set event data: %w", err)
 		}
@@ -240,7 +547,15 @@ func (a *adapter) SendMetricEvent(metricOutput *cloudwatch.GetMetricDataOutput)
 		if result := a.ceClient.Send(context.Background(), event); !cloudevents.IsACK(result) {
 			return result
 		}
+
+		sent[eventID] = struct{}{}
 	}
 
 	return nil
 }
+
+// datapointEventID returns a deterministic CloudEvent ID for the datapoint of
+// the given query at the given time.
+func datapointEventID(queryID string, ts time.Time) string {
+	return queryID + "/" + ts.UTC().Format(time.RFC3339)
+}
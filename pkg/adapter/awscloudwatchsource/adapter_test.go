@@ -17,6 +17,7 @@ limitations under the License.
 package awscloudwatchsource
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -24,6 +25,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
 	loggingtesting "knative.dev/pkg/logging/testing"
@@ -41,6 +43,12 @@ type mockCloudWatchClient struct {
 
 	Resp cloudwatch.GetMetricDataOutput
 	err  error
+
+	AlarmsResp  cloudwatch.DescribeAlarmsOutput
+	HistoryResp cloudwatch.DescribeAlarmHistoryOutput
+
+	ListMetricsResp cloudwatch.ListMetricsOutput
+	listMetricsErr  error
 }
 
 func (m mockCloudWatchClient) GetMetricDataPages(input *cloudwatch.GetMetricDataInput, fn func(*cloudwatch.GetMetricDataOutput, bool) bool) error {
@@ -49,6 +57,28 @@ func (m mockCloudWatchClient) GetMetricDataPages(input *cloudwatch.GetMetricData
 	return m.err
 }
 
+func (m mockCloudWatchClient) DescribeAlarmsPages(input *cloudwatch.DescribeAlarmsInput, fn func(*cloudwatch.DescribeAlarmsOutput, bool) bool) error {
+	fn(&m.AlarmsResp, true)
+
+	return m.err
+}
+
+func (m mockCloudWatchClient) DescribeAlarmHistoryPages(input *cloudwatch.DescribeAlarmHistoryInput, fn func(*cloudwatch.DescribeAlarmHistoryOutput, bool) bool) error {
+	fn(&m.HistoryResp, true)
+
+	return m.err
+}
+
+func (m mockCloudWatchClient) ListMetricsPages(input *cloudwatch.ListMetricsInput, fn func(*cloudwatch.ListMetricsOutput, bool) bool) error {
+	if m.listMetricsErr != nil {
+		return m.listMetricsErr
+	}
+
+	fn(&m.ListMetricsResp, true)
+
+	return m.err
+}
+
 // TestParseQueries Given a query string, ensure that
 func TestParseQueries(t *testing.T) {
 	const (
@@ -147,6 +177,7 @@ func TestCollectMetrics(t *testing.T) {
 		},
 
 		pollingInterval: pollingInterval,
+		windowAlignment: time.Minute,
 	}
 
 	metricOutput := cloudwatch.GetMetricDataOutput{
@@ -162,7 +193,7 @@ func TestCollectMetrics(t *testing.T) {
 		NextToken: nil,
 	}
 
-	a.CollectMetrics(nil, time.Now())
+	a.CollectMetrics(time.Now())
 
 	events := ceClient.Sent()
 	assert.Len(t, events, 1)
@@ -177,6 +208,44 @@ func TestCollectMetrics(t *testing.T) {
 	assert.EqualValues(t, *metricOutput.MetricDataResults[0], metricRecord)
 }
 
+func TestCollectMetricsWithoutQuery(t *testing.T) {
+	anyName, _ := newMatcher(nil)
+
+	a := &adapter{
+		logger:      loggingtesting.TestLogger(t),
+		eventsource: v1alpha1.AWSCloudWatchSourceName(tNs, tName),
+
+		ceClient: adaptertest.NewTestClient(),
+		cwClient: mockCloudWatchClient{
+			listMetricsErr: errors.New("fake error"),
+		},
+
+		metricDiscoveries: []*metricDiscovery{{
+			name:       "cpu",
+			namespace:  "AWS/EC2",
+			metricName: anyName,
+			period:     60,
+			stat:       "Average",
+		}},
+
+		pollingInterval: time.Minute,
+		windowAlignment: time.Minute,
+	}
+
+	now := time.Date(1970, 1, 1, 12, 0, 30, 0, time.UTC)
+
+	// discovery never succeeded
+	a.CollectMetrics(now)
+	assert.True(t, a.metricsWindowEnd.IsZero(), "Expected the time window to be kept for a later collection")
+
+	// discovery succeeded without any matching metric
+	a.cwClient = mockCloudWatchClient{}
+
+	a.CollectMetrics(now)
+	assert.False(t, a.nextDiscovery.IsZero(), "Expected metrics to be discovered")
+	assert.True(t, a.metricsWindowEnd.IsZero(), "Expected the time window to be kept for a later collection")
+}
+
 func TestSendMetricEvent(t *testing.T) {
 	ceClient := adaptertest.NewTestClient()
 
@@ -257,3 +326,136 @@ func TestSendMessageEvent(t *testing.T) {
 	assert.NoError(t, err)
 	assert.EqualValues(t, *metricOutput.Messages[0], metricRecord)
 }
+
+func TestMetricsWindow(t *testing.T) {
+	a := &adapter{
+		pollingInterval: 2 * time.Minute,
+		aggregationLag:  30 * time.Second,
+		windowAlignment: time.Minute,
+	}
+
+	now := time.Date(1970, 1, 1, 12, 0, 45, 0, time.UTC)
+
+	// first window spans one polling interval
+	start, end, ok := a.metricsWindow(now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(1970, 1, 1, 11, 58, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(1970, 1, 1, 12, 0, 0, 0, time.UTC), end)
+
+	a.metricsWindowEnd = end
+
+	// no new period closed, taking the lag into account
+	_, _, ok = a.metricsWindow(now.Add(40 * time.Second))
+	assert.False(t, ok)
+
+	// next window starts where the previous one ended
+	start, end, ok = a.metricsWindow(now.Add(2 * time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(1970, 1, 1, 12, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(1970, 1, 1, 12, 2, 0, 0, time.UTC), end)
+}
+
+func TestWindowAlignment(t *testing.T) {
+	assert.Equal(t, defaultWindowAlignment, windowAlignment(nil))
+
+	queries := []*cloudwatch.MetricDataQuery{
+		{Id: aws.String("e1"), Expression: aws.String("m1*2")},
+		{Id: aws.String("m1"), MetricStat: &cloudwatch.MetricStat{Period: aws.Int64(60)}},
+		{Id: aws.String("m2"), MetricStat: &cloudwatch.MetricStat{Period: aws.Int64(300)}},
+	}
+	assert.Equal(t, 5*time.Minute, windowAlignment(queries))
+}
+
+// sequenceCloudWatchClient returns the given GetMetricData outputs in
+// sequence, one per call, and records the inputs it received.
+type sequenceCloudWatchClient struct {
+	cloudwatchiface.CloudWatchAPI
+
+	resps  []*cloudwatch.GetMetricDataOutput
+	inputs []*cloudwatch.GetMetricDataInput
+}
+
+func (m *sequenceCloudWatchClient) GetMetricDataPages(input *cloudwatch.GetMetricDataInput, fn func(*cloudwatch.GetMetricDataOutput, bool) bool) error {
+	m.inputs = append(m.inputs, input)
+	fn(m.resps[len(m.inputs)-1], true)
+
+	return nil
+}
+
+func TestCollectDatapoints(t *testing.T) {
+	ceClient := adaptertest.NewTestClient()
+
+	ts1 := time.Date(1970, 1, 1, 12, 0, 0, 0, time.UTC)
+	ts2 := ts1.Add(time.Minute)
+
+	cwClient := &sequenceCloudWatchClient{
+		resps: []*cloudwatch.GetMetricDataOutput{{
+			MetricDataResults: []*cloudwatch.MetricDataResult{{
+				Id:         aws.String("m1"),
+				Label:      aws.String("Duration"),
+				StatusCode: aws.String(cloudwatch.StatusCodeComplete),
+				Timestamps: []*time.Time{&ts1},
+				Values:     []*float64{aws.Float64(1)},
+			}, {
+				Id:         aws.String("e1"),
+				Label:      aws.String("Double"),
+				StatusCode: aws.String(cloudwatch.StatusCodePartialData),
+				Timestamps: []*time.Time{&ts1},
+				Values:     []*float64{aws.Float64(2)},
+			}},
+		}, {
+			MetricDataResults: []*cloudwatch.MetricDataResult{{
+				Id:         aws.String("e1"),
+				Label:      aws.String("Double"),
+				StatusCode: aws.String(cloudwatch.StatusCodeComplete),
+				Timestamps: []*time.Time{&ts1, &ts2},
+				Values:     []*float64{aws.Float64(2), aws.Float64(4)},
+			}},
+		}},
+	}
+
+	a := &adapter{
+		logger:      loggingtesting.TestLogger(t),
+		eventsource: v1alpha1.AWSCloudWatchSourceName(tNs, tName),
+
+		cwClient: cwClient,
+		ceClient: ceClient,
+
+		metricQueries: []*cloudwatch.MetricDataQuery{
+			{Id: aws.String("m1"), MetricStat: &cloudwatch.MetricStat{Period: aws.Int64(60)}},
+			{Id: aws.String("e1"), Expression: aws.String("m1*2")},
+		},
+		pollingInterval: 2 * time.Minute,
+		splitDatapoints: true,
+		windowAlignment: time.Minute,
+	}
+
+	a.CollectMetrics(ts2.Add(90 * time.Second))
+
+	// the partial query was re-run alone, other queries were preserved
+	// without returning data
+	require.Len(t, cwClient.inputs, 2)
+	retried := cwClient.inputs[1].MetricDataQueries
+	require.Len(t, retried, 2)
+	assert.False(t, *retried[0].ReturnData)
+	assert.True(t, *retried[1].ReturnData)
+
+	events := ceClient.Sent()
+	require.Len(t, events, 3)
+
+	expectIDs := []string{"m1/1970-01-01T12:00:00Z", "e1/1970-01-01T12:00:00Z", "e1/1970-01-01T12:01:00Z"}
+	for i, e := range events {
+		assert.Equal(t, "com.amazon.cloudwatch.metrics.datapoint", e.Type())
+		assert.Equal(t, expectIDs[i], e.ID())
+	}
+
+	assert.JSONEq(t, `{"id":"e1","label":"Double","timestamp":"1970-01-01T12:01:00Z","value":4}`,
+		string(events[2].Data()))
+
+	var dp MetricDatapoint
+	require.NoError(t, events[2].DataAs(&dp))
+	assert.Equal(t, MetricDatapoint{Id: "e1", Label: "Double", Timestamp: ts2, Value: 4}, dp)
+	assert.Equal(t, ts2, events[2].Time())
+
+	assert.Equal(t, time.Date(1970, 1, 1, 12, 2, 0, 0, time.UTC), a.metricsWindowEnd)
+}
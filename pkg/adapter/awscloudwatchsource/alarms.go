@@ -0,0 +1,318 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscloudwatchsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+// Maximum number of alarm names accepted by a single DescribeAlarms request.
+const maxAlarmNamesPerRequest = 100
+
+// AlarmStateChange is the payload of an event emitted upon the state
+// transition of a CloudWatch alarm.
+type AlarmStateChange struct {
+	AlarmName     string     `json:"alarmName"`
+	AlarmARN      string     `json:"alarmArn,omitempty"`
+	AlarmType     string     `json:"alarmType,omitempty"`
+	Timestamp     time.Time  `json:"timestamp"`
+	Summary       string     `json:"summary,omitempty"`
+	State         AlarmState `json:"state"`
+	PreviousState AlarmState `json:"previousState"`
+}
+
+// AlarmState describes the state of a CloudWatch alarm at a given time.
+type AlarmState struct {
+	// One of OK, ALARM, INSUFFICIENT_DATA.
+	Value      string          `json:"value"`
+	Reason     string          `json:"reason,omitempty"`
+	ReasonData json.RawMessage `json:"reasonData,omitempty"`
+	// Datapoints which caused the alarm to transition to this state.
+	Datapoints []AlarmDatapoint `json:"evaluatedDatapoints,omitempty"`
+}
+
+// AlarmDatapoint is a datapoint evaluated by a CloudWatch alarm.
+type AlarmDatapoint struct {
+	// Timestamps are reported by CloudWatch in a format which is not
+	// RFC 3339 compliant, so we pass them through untouched.
+	Timestamp   string   `json:"timestamp"`
+	SampleCount *float64 `json:"sampleCount,omitempty"`
+	Value       *float64 `json:"value,omitempty"`
+	Threshold   *float64 `json:"threshold,omitempty"`
+}
+
+// alarmHistoryData is the JSON structure of the HistoryData attribute of
+// StateUpdate alarm history items.
+type alarmHistoryData struct {
+	OldState alarmHistoryState `json:"oldState"`
+	NewState alarmHistoryState `json:"newState"`
+}
+
+type alarmHistoryState struct {
+	StateValue      string          `json:"stateValue"`
+	StateReason     string          `json:"stateReason"`
+	StateReasonData json.RawMessage `json:"stateReasonData"`
+}
+
+type alarmStateReasonData struct {
+	EvaluatedDatapoints []AlarmDatapoint `json:"evaluatedDatapoints"`
+}
+
+// parseAlarmSelector parses the JSON representation of the alarm selector as
+// passed in the environment.
+func parseAlarmSelector(rawSelector string) (*v1alpha1.AWSCloudWatchAlarmSelector, error) {
+	sel := &v1alpha1.AWSCloudWatchAlarmSelector{}
+	if err := json.Unmarshal([]byte(rawSelector), sel); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+// watchedAlarm is an alarm matched by the adapter's alarm selector.
+type watchedAlarm struct {
+	name string
+	arn  string
+}
+
+// CollectAlarms sends an event for each state transition of the watched
+// alarms which occurred since the last collection.
+func (a *adapter) CollectAlarms(priorTime *time.Time, currentTime time.Time) {
+	a.logger.Debug("Collecting alarm state transitions")
+
+	// collections triggered by consecutive ticks must not interleave,
+	// otherwise the same transition could be observed twice
+	a.alarmsMu.Lock()
+	defer a.alarmsMu.Unlock()
+
+	startInterval := currentTime.Add(-a.pollingInterval)
+	if priorTime != nil {
+		startInterval = *priorTime
+	}
+
+	alarms, err := a.listAlarms()
+	if err != nil {
+		a.logger.Errorw("Error listing alarms", zap.Error(err))
+		return
+	}
+
+	observed := make(map[string]struct{}, len(alarms))
+
+	for _, alarm := range alarms {
+		observed[alarm.name] = struct{}{}
+
+		since, seen := a.alarmTransitions[alarm.name]
+		if !seen {
+			since = startInterval
+		}
+
+		last, err := a.sendAlarmTransitions(alarm, since, currentTime)
+		a.alarmTransitions[alarm.name] = last
+		if err != nil {
+			a.logger.Errorw("Error collecting state transitions of alarm "+alarm.name, zap.Error(err))
+		}
+	}
+
+	// forget about alarms which no longer match the selector
+	for name := range a.alarmTransitions {
+		if _, ok := observed[name]; !ok {
+			delete(a.alarmTransitions, name)
+		}
+	}
+}
+
+// listAlarms returns all alarms matched by the adapter's alarm selector.
+func (a *adapter) listAlarms() ([]watchedAlarm, error) {
+	var alarms []watchedAlarm
+
+	collect := func(out *cloudwatch.DescribeAlarmsOutput, lastPage bool) bool {
+		for _, al := range out.MetricAlarms {
+			alarms = append(alarms, watchedAlarm{
+				name: aws.StringValue(al.AlarmName),
+				arn:  aws.StringValue(al.AlarmArn),
+			})
+		}
+		for _, al := range out.CompositeAlarms {
+			alarms = append(alarms, watchedAlarm{
+				name: aws.StringValue(al.AlarmName),
+				arn:  aws.StringValue(al.AlarmArn),
+			})
+		}
+		return !lastPage
+	}
+
+	alarmTypes := aws.StringSlice([]string{
+		cloudwatch.AlarmTypeMetricAlarm,
+		cloudwatch.AlarmTypeCompositeAlarm,
+	})
+
+	if prefix := a.alarms.NamePrefix; prefix != nil {
+		in := &cloudwatch.DescribeAlarmsInput{
+			AlarmNamePrefix: prefix,
+			AlarmTypes:      alarmTypes,
+		}
+		if err := a.cwClient.DescribeAlarmsPages(in, collect); err != nil {
+			return nil, fmt.Errorf("describing alarms with prefix %q: %w", *prefix, err)
+		}
+	}
+
+	for names := a.alarms.Names; len(names) > 0; {
+		n := len(names)
+		if n > maxAlarmNamesPerRequest {
+			n = maxAlarmNamesPerRequest
+		}
+
+		in := &cloudwatch.DescribeAlarmsInput{
+			AlarmNames: aws.StringSlice(names[:n]),
+			AlarmTypes: alarmTypes,
+		}
+		if err := a.cwClient.DescribeAlarmsPages(in, collect); err != nil {
+			return nil, fmt.Errorf("describing alarms by name: %w", err)
+		}
+
+		names = names[n:]
+	}
+
+	return alarms, nil
+}
+
+// sendAlarmTransitions sends an event for each state transition of the given
+// alarm which occurred strictly after 'since', up to 'until'. It returns the
+// time of the last transition that was successfully sent.
+func (a *adapter) sendAlarmTransitions(alarm watchedAlarm, since, until time.Time) (time.Time, error) {
+	last := since
+
+	in := &cloudwatch.DescribeAlarmHistoryInput{
+		AlarmName: &alarm.name,
+		AlarmTypes: aws.StringSlice([]string{
+			cloudwatch.AlarmTypeMetricAlarm,
+			cloudwatch.AlarmTypeCompositeAlarm,
+		}),
+		HistoryItemType: aws.String(cloudwatch.HistoryItemTypeStateUpdate),
+		ScanBy:          aws.String(cloudwatch.ScanByTimestampAscending),
+		StartDate:       &since,
+		EndDate:         &until,
+	}
+
+	var sendErr error
+
+	err := a.cwClient.DescribeAlarmHistoryPages(in, func(out *cloudwatch.DescribeAlarmHistoryOutput, lastPage bool) bool {
+		for _, item := range out.AlarmHistoryItems {
+			ts := aws.TimeValue(item.Timestamp)
+
+			// StartDate is inclusive, and the last transition of the
+			// previous collection was already sent
+			if !ts.After(since) {
+				continue
+			}
+
+			if sendErr = a.sendAlarmEvent(alarm, item); sendErr != nil {
+				return false
+			}
+			last = ts
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return last, fmt.Errorf("describing alarm history: %w", err)
+	}
+
+	return last, sendErr
+}
+
+// sendAlarmEvent sends an event describing the state transition recorded in
+// the given alarm history item.
+func (a *adapter) sendAlarmEvent(alarm watchedAlarm, item *cloudwatch.AlarmHistoryItem) error {
+	data, err := makeAlarmStateChange(alarm, item)
+	if err != nil {
+		return fmt.Errorf("parsing alarm history item: %w", err)
+	}
+
+	event := cloudevents.NewEvent(cloudevents.VersionV1)
+	event.SetID(alarmEventID(alarm, data.Timestamp))
+	event.SetType(v1alpha1.AWSEventType(v1alpha1.ServiceCloudWatch, v1alpha1.AWSCloudWatchAlarmEventType))
+	event.SetSource(a.eventsource)
+	event.SetSubject(data.AlarmName)
+	event.SetTime(data.Timestamp)
+	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		return fmt.Errorf("failed to set event data: %w", err)
+	}
+
+	if result := a.ceClient.Send(context.Background(), event); !cloudevents.IsACK(result) {
+		return result
+	}
+
+	return nil
+}
+
+// makeAlarmStateChange returns the AlarmStateChange represented by the given
+// alarm history item.
+func makeAlarmStateChange(alarm watchedAlarm, item *cloudwatch.AlarmHistoryItem) (*AlarmStateChange, error) {
+	var hist alarmHistoryData
+	if err := json.Unmarshal([]byte(aws.StringValue(item.HistoryData)), &hist); err != nil {
+		return nil, err
+	}
+
+	return &AlarmStateChange{
+		AlarmName:     alarm.name,
+		AlarmARN:      alarm.arn,
+		AlarmType:     aws.StringValue(item.AlarmType),
+		Timestamp:     aws.TimeValue(item.Timestamp),
+		Summary:       aws.StringValue(item.HistorySummary),
+		State:         hist.NewState.toAlarmState(),
+		PreviousState: hist.OldState.toAlarmState(),
+	}, nil
+}
+
+// toAlarmState converts an alarmHistoryState to an AlarmState.
+func (s *alarmHistoryState) toAlarmState() AlarmState {
+	st := AlarmState{
+		Value:      s.StateValue,
+		Reason:     s.StateReason,
+		ReasonData: s.StateReasonData,
+	}
+
+	var reasonData alarmStateReasonData
+	// the reason data is informational, failing to parse it should not
+	// prevent the event from being sent
+	if len(s.StateReasonData) > 0 && json.Unmarshal(s.StateReasonData, &reasonData) == nil {
+		st.Datapoints = reasonData.EvaluatedDatapoints
+	}
+
+	return st
+}
+
+// alarmEventID returns a deterministic CloudEvent ID for the state transition
+// of the given alarm which occurred at the given time.
+func alarmEventID(alarm watchedAlarm, ts time.Time) string {
+	ref := alarm.arn
+	if ref == "" {
+		ref = alarm.name
+	}
+	return ref + "/" + ts.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscloudwatchsource
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+	loggingtesting "knative.dev/pkg/logging/testing"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+func TestParseAlarmSelector(t *testing.T) {
+	sel, err := parseAlarmSelector(`{"names":["alarm1","alarm2"]}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alarm1", "alarm2"}, sel.Names)
+	assert.Nil(t, sel.NamePrefix)
+
+	sel, err = parseAlarmSelector(`{"namePrefix":"prod-"}`)
+	require.NoError(t, err)
+	assert.Empty(t, sel.Names)
+	assert.Equal(t, aws.String("prod-"), sel.NamePrefix)
+}
+
+func TestCollectAlarms(t *testing.T) {
+	const (
+		alarmName = "high-latency"
+		alarmARN  = "arn:aws:cloudwatch:us-west-2:123456789012:alarm:high-latency"
+	)
+
+	const historyData = `{"version":"1.0","oldState":{"stateValue":"OK","stateReason":"Threshold Crossed: ` +
+		`1 datapoint [0.5 (01/01/70 11:55:00)] was not greater than the threshold (1.0)."},` +
+		`"newState":{"stateValue":"ALARM","stateReason":"Threshold Crossed: 1 datapoint [2.0 (01/01/70 11:59:00)] ` +
+		`was greater than the threshold (1.0).","stateReasonData":{"version":"1.0","statistic":"Average",` +
+		`"period":60,"recentDatapoints":[2.0],"threshold":1.0,"evaluatedDatapoints":[` +
+		`{"timestamp":"1970-01-01T11:59:00.000+0000","sampleCount":3.0,"value":2.0}]}}}`
+
+	ts1 := time.Date(1970, 1, 1, 12, 0, 0, 0, time.UTC)
+	ts2 := ts1.Add(30 * time.Second)
+
+	historyItem := func(ts time.Time) *cloudwatch.AlarmHistoryItem {
+		return &cloudwatch.AlarmHistoryItem{
+			AlarmName:       aws.String(alarmName),
+			AlarmType:       aws.String(cloudwatch.AlarmTypeMetricAlarm),
+			HistoryData:     aws.String(historyData),
+			HistoryItemType: aws.String(cloudwatch.HistoryItemTypeStateUpdate),
+			HistorySummary:  aws.String("Alarm updated from OK to ALARM"),
+			Timestamp:       aws.Time(ts),
+		}
+	}
+
+	ceClient := adaptertest.NewTestClient()
+
+	cwClient := mockCloudWatchClient{
+		AlarmsResp: cloudwatch.DescribeAlarmsOutput{
+			MetricAlarms: []*cloudwatch.MetricAlarm{{
+				AlarmName: aws.String(alarmName),
+				AlarmArn:  aws.String(alarmARN),
+			}},
+		},
+		HistoryResp: cloudwatch.DescribeAlarmHistoryOutput{
+			AlarmHistoryItems: []*cloudwatch.AlarmHistoryItem{historyItem(ts1)},
+		},
+	}
+
+	a := &adapter{
+		logger:      loggingtesting.TestLogger(t),
+		eventsource: v1alpha1.AWSCloudWatchSourceName(tNs, tName),
+
+		cwClient: cwClient,
+		ceClient: ceClient,
+
+		pollingInterval: time.Minute,
+
+		alarms:           &v1alpha1.AWSCloudWatchAlarmSelector{Names: []string{alarmName}},
+		alarmTransitions: make(map[string]time.Time),
+	}
+
+	pollTime := ts1.Add(10 * time.Second)
+	a.CollectAlarms(nil, pollTime)
+
+	events := ceClient.Sent()
+	require.Len(t, events, 1)
+
+	assert.Equal(t, "com.amazon.cloudwatch.alarms.state_change", events[0].Type())
+	assert.Equal(t, "io.triggermesh.awscloudwatchsource.test-namespace.test-source", events[0].Source())
+	assert.Equal(t, alarmName, events[0].Subject())
+	assert.Equal(t, alarmARN+"/1970-01-01T12:00:00Z", events[0].ID())
+	assert.Equal(t, ts1, events[0].Time())
+
+	var data AlarmStateChange
+	require.NoError(t, events[0].DataAs(&data))
+
+	assert.Equal(t, alarmName, data.AlarmName)
+	assert.Equal(t, alarmARN, data.AlarmARN)
+	assert.Equal(t, "OK", data.PreviousState.Value)
+	assert.Equal(t, "ALARM", data.State.Value)
+	assert.Contains(t, data.State.Reason, "was greater than the threshold")
+	require.Len(t, data.State.Datapoints, 1)
+	assert.Equal(t, aws.Float64(2.0), data.State.Datapoints[0].Value)
+	assert.Equal(t, "1970-01-01T11:59:00.000+0000", data.State.Datapoints[0].Timestamp)
+
+	// The next poll returns the transition which was already sent along
+	// with a new one. Only the new transition should be sent.
+	cwClient.HistoryResp.AlarmHistoryItems = []*cloudwatch.AlarmHistoryItem{historyItem(ts1), historyItem(ts2)}
+	a.cwClient = cwClient
+
+	a.CollectAlarms(&pollTime, pollTime.Add(time.Minute))
+
+	events = ceClient.Sent()
+	require.Len(t, events, 2)
+	assert.Equal(t, ts2, events[1].Time())
+}
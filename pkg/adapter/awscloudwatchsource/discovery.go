@@ -0,0 +1,318 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscloudwatchsource
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+// Interval at which discovered metrics are resolved again. New metrics take
+// up to 15 minutes to be returned by ListMetrics, so there is little benefit
+// in resolving them more often.
+const discoveryInterval = 15 * time.Minute
+
+// Maximum number of queries accepted by a single GetMetricData request.
+const maxQueriesPerRequest = 500
+
+// Restricts ListMetrics results to metrics which received data recently,
+// so that metrics of terminated resources are eventually dropped.
+const recentlyActive = "PT3H"
+
+// metricDiscovery is a parsed representation of a metric discovery query.
+type metricDiscovery struct {
+	name string
+
+	namespace  string
+	metricName matcher
+	dimensions []dimensionMatcher
+
+	period int64
+	stat   string
+	unit   string
+}
+
+// dimensionMatcher matches the values of a given dimension.
+type dimensionMatcher struct {
+	name  string
+	value matcher
+}
+
+// matcher matches a string, either exactly or against a wildcard pattern.
+// The zero value matches anything.
+type matcher struct {
+	exact   *string
+	pattern *regexp.Regexp
+}
+
+// newMatcher returns a matcher for the given value, which may contain
+// wildcards. A nil value matches anything.
+func newMatcher(val *string) (matcher, error) {
+	if val == nil {
+		return matcher{}, nil
+	}
+
+	if !strings.ContainsAny(*val, "*?") {
+		return matcher{exact: val}, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteByte('^')
+	for _, r := range *val {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteByte('.')
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteByte('$')
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return matcher{}, fmt.Errorf("invalid pattern %q: %w", *val, err)
+	}
+
+	return matcher{pattern: re}, nil
+}
+
+// match returns whether the given value is matched.
+func (m matcher) match(val string) bool {
+	switch {
+	case m.exact != nil:
+		return val == *m.exact
+	case m.pattern != nil:
+		return m.pattern.MatchString(val)
+	default:
+		return true
+	}
+}
+
+// parseMetricDiscoveries takes the JSON representation of the queries as
+// passed in, and returns the metric discoveries it contains.
+func parseMetricDiscoveries(rawQuery string) ([]*metricDiscovery, error) {
+	rawQueries := make([]v1alpha1.AWSCloudWatchMetricQuery, 0)
+
+	err := json.Unmarshal([]byte(rawQuery), &rawQueries)
+	if err != nil {
+		return nil, err
+	}
+
+	var discoveries []*metricDiscovery
+
+	for _, v := range rawQueries {
+		d := v.Discovery
+		if d == nil {
+			continue
+		}
+
+		metricName, err := newMatcher(d.MetricName)
+		if err != nil {
+			return nil, fmt.Errorf("parsing metric name of query %q: %w", v.Name, err)
+		}
+
+		dimensions := make([]dimensionMatcher, len(d.Dimensions))
+		for i, dim := range d.Dimensions {
+			value, err := newMatcher(dim.Value)
+			if err != nil {
+				return nil, fmt.Errorf("parsing value of dimension %q of query %q: %w", dim.Name, v.Name, err)
+			}
+			dimensions[i] = dimensionMatcher{name: dim.Name, value: value}
+		}
+
+		discoveries = append(discoveries, &metricDiscovery{
+			name:       v.Name,
+			namespace:  d.Namespace,
+			metricName: metricName,
+			dimensions: dimensions,
+			period:     d.Period,
+			stat:       d.Stat,
+			unit:       d.Unit,
+		})
+	}
+
+	return discoveries, nil
+}
+
+// discoveryTemplates returns a MetricDataQuery per metric discovery, which
+// carries the discovery's statistics but no concrete metric.
+func discoveryTemplates(discoveries []*metricDiscovery) []*cloudwatch.MetricDataQuery {
+	templates := make([]*cloudwatch.MetricDataQuery, len(discoveries))
+	for i, d := range discoveries {
+		templates[i] = &cloudwatch.MetricDataQuery{
+			Id: aws.String(d.name),
+			MetricStat: &cloudwatch.MetricStat{
+				Period: aws.Int64(d.period),
+				Stat:   aws.String(d.stat),
+			},
+		}
+	}
+	return templates
+}
+
+// discoverMetrics resolves the adapter's metric discoveries into concrete
+// metric data queries.
+func (a *adapter) discoverMetrics() ([]*cloudwatch.MetricDataQuery, error) {
+	var queries []*cloudwatch.MetricDataQuery
+	seen := make(map[string]struct{})
+
+	for _, d := range a.metricDiscoveries {
+		err := a.cwClient.ListMetricsPages(d.listMetricsInput(), func(out *cloudwatch.ListMetricsOutput, lastPage bool) bool {
+			for _, m := range out.Metrics {
+				if !d.match(m) {
+					continue
+				}
+
+				q := d.query(m)
+				if _, ok := seen[*q.Id]; ok {
+					continue
+				}
+				seen[*q.Id] = struct{}{}
+
+				queries = append(queries, q)
+			}
+			return !lastPage
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing metrics for query %q: %w", d.name, err)
+		}
+	}
+
+	sort.Slice(queries, func(i, j int) bool {
+		return *queries[i].Id < *queries[j].Id
+	})
+
+	return queries, nil
+}
+
+// listMetricsInput returns the ListMetrics input matching the discovery as
+// closely as possible. Wildcards are not supported by the API and must be
+// matched on the returned metrics.
+func (d *metricDiscovery) listMetricsInput() *cloudwatch.ListMetricsInput {
+	in := &cloudwatch.ListMetricsInput{
+		Namespace:      &d.namespace,
+		MetricName:     d.metricName.exact,
+		RecentlyActive: aws.String(recentlyActive),
+	}
+
+	for _, dim := range d.dimensions {
+		in.Dimensions = append(in.Dimensions, &cloudwatch.DimensionFilter{
+			Name:  aws.String(dim.name),
+			Value: dim.value.exact,
+		})
+	}
+
+	return in
+}
+
+// match returns whether the given metric is selected by the discovery.
+func (d *metricDiscovery) match(m *cloudwatch.Metric) bool {
+	if !d.metricName.match(aws.StringValue(m.MetricName)) {
+		return false
+	}
+
+	for _, dm := range d.dimensions {
+		var found bool
+		for _, dim := range m.Dimensions {
+			if aws.StringValue(dim.Name) == dm.name && dm.value.match(aws.StringValue(dim.Value)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// query returns a metric data query for the given discovered metric. The ID
+// of the query is deterministic, so that it remains stable across
+// discoveries regardless of the other metrics returned.
+func (d *metricDiscovery) query(m *cloudwatch.Metric) *cloudwatch.MetricDataQuery {
+	dims := make([]string, len(m.Dimensions))
+	for i, dim := range m.Dimensions {
+		dims[i] = aws.StringValue(dim.Name) + "=" + aws.StringValue(dim.Value)
+	}
+	sort.Strings(dims)
+
+	label := aws.StringValue(m.MetricName)
+	if len(dims) > 0 {
+		label += " " + strings.Join(dims, ",")
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(aws.StringValue(m.Namespace) + "\n" + label))
+
+	ms := &cloudwatch.MetricStat{
+		Metric: m,
+		Period: aws.Int64(d.period),
+		Stat:   aws.String(d.stat),
+	}
+	if d.unit != "" {
+		ms.SetUnit(d.unit)
+	}
+
+	return &cloudwatch.MetricDataQuery{
+		Id:         aws.String(fmt.Sprintf("%s_%x", d.name, h.Sum64())),
+		Label:      &label,
+		MetricStat: ms,
+	}
+}
+
+// batchQueries splits the given queries into batches which fit in a single
+// GetMetricData request. Static queries are kept together in the first batch,
+// since math expressions may reference other static queries. The number of
+// static queries is capped by the validation of the source's spec.
+func batchQueries(static, discovered []*cloudwatch.MetricDataQuery) [][]*cloudwatch.MetricDataQuery {
+	var batches [][]*cloudwatch.MetricDataQuery
+
+	batch := static
+	for len(discovered) > 0 {
+		n := maxQueriesPerRequest - len(batch)
+		if n <= 0 {
+			batches = append(batches, batch)
+			batch = nil
+			continue
+		}
+		if n > len(discovered) {
+			n = len(discovered)
+		}
+
+		batch = append(batch[:len(batch):len(batch)], discovered[:n]...)
+		discovered = discovered[n:]
+	}
+
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscloudwatchsource
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	loggingtesting "knative.dev/pkg/logging/testing"
+)
+
+func TestMatcher(t *testing.T) {
+	testCases := []struct {
+		pattern *string
+		value   string
+		expect  bool
+	}{
+		{nil, "anything", true},
+		{aws.String("CPUUtilization"), "CPUUtilization", true},
+		{aws.String("CPUUtilization"), "CPUCreditUsage", false},
+		{aws.String("CPU*"), "CPUCreditUsage", true},
+		{aws.String("CPU*"), "NetworkIn", false},
+		{aws.String("i-?bc"), "i-abc", true},
+		{aws.String("i-?bc"), "i-aabc", false},
+		{aws.String("web.*"), "web.1", true},
+		{aws.String("web.*"), "webx1", false},
+	}
+
+	for _, tc := range testCases {
+		m, err := newMatcher(tc.pattern)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expect, m.match(tc.value), "pattern %v, value %q", aws.StringValue(tc.pattern), tc.value)
+	}
+}
+
+func TestParseMetricDiscoveries(t *testing.T) {
+	const queryStr = `[{"name":"static","expression":"SUM(METRICS())"},` +
+		`{"name":"cpu","discovery":{"namespace":"AWS/EC2","metricName":"CPU*",` +
+		`"dimensions":[{"name":"InstanceId"}],"period":60,"stat":"Average"}}]`
+
+	discoveries, err := parseMetricDiscoveries(queryStr)
+	require.NoError(t, err)
+	require.Len(t, discoveries, 1)
+
+	d := discoveries[0]
+	assert.Equal(t, "cpu", d.name)
+	assert.Equal(t, "AWS/EC2", d.namespace)
+	assert.Equal(t, int64(60), d.period)
+
+	// wildcards are not passed to the API
+	in := d.listMetricsInput()
+	assert.Nil(t, in.MetricName)
+	require.Len(t, in.Dimensions, 1)
+	assert.Equal(t, "InstanceId", *in.Dimensions[0].Name)
+	assert.Nil(t, in.Dimensions[0].Value)
+}
+
+func TestDiscoverMetrics(t *testing.T) {
+	metric := func(name, instanceID string) *cloudwatch.Metric {
+		return &cloudwatch.Metric{
+			Namespace:  aws.String("AWS/EC2"),
+			MetricName: aws.String(name),
+			Dimensions: []*cloudwatch.Dimension{{
+				Name:  aws.String("InstanceId"),
+				Value: aws.String(instanceID),
+			}},
+		}
+	}
+
+	metricName, _ := newMatcher(aws.String("CPU*"))
+	instanceID, _ := newMatcher(aws.String("i-web*"))
+
+	a := &adapter{
+		logger: loggingtesting.TestLogger(t),
+		cwClient: mockCloudWatchClient{
+			ListMetricsResp: cloudwatch.ListMetricsOutput{
+				Metrics: []*cloudwatch.Metric{
+					metric("CPUUtilization", "i-web2"),
+					metric("CPUUtilization", "i-db1"),
+					metric("NetworkIn", "i-web1"),
+					metric("CPUUtilization", "i-web1"),
+				},
+			},
+		},
+		metricDiscoveries: []*metricDiscovery{{
+			name:       "cpu",
+			namespace:  "AWS/EC2",
+			metricName: metricName,
+			dimensions: []dimensionMatcher{{name: "InstanceId", value: instanceID}},
+			period:     60,
+			stat:       "Average",
+		}},
+	}
+
+	queries, err := a.discoverMetrics()
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+
+	labels := []string{*queries[0].Label, *queries[1].Label}
+	assert.ElementsMatch(t, []string{"CPUUtilization InstanceId=i-web1", "CPUUtilization InstanceId=i-web2"}, labels)
+
+	for _, q := range queries {
+		assert.Regexp(t, `^cpu_[0-9a-f]+$`, *q.Id)
+		assert.Equal(t, int64(60), *q.MetricStat.Period)
+		assert.Equal(t, "Average", *q.MetricStat.Stat)
+	}
+
+	// IDs are stable across discoveries
+	again, err := a.discoverMetrics()
+	require.NoError(t, err)
+	assert.Equal(t, queries, again)
+}
+
+func TestBatchQueries(t *testing.T) {
+	makeQueries := func(prefix string, n int) []*cloudwatch.MetricDataQuery {
+		qs := make([]*cloudwatch.MetricDataQuery, n)
+		for i := range qs {
+			qs[i] = &cloudwatch.MetricDataQuery{Id: aws.String(prefix + strconv.Itoa(i))}
+		}
+		return qs
+	}
+
+	static := makeQueries("s", 2)
+	discovered := makeQueries("d", 1100)
+
+	batches := batchQueries(static, discovered)
+	require.Len(t, batches, 3)
+	assert.Len(t, batches[0], 500)
+	assert.Len(t, batches[1], 500)
+	assert.Len(t, batches[2], 102)
+
+	// static queries are kept together in the first batch
+	assert.Equal(t, static, batches[0][:2])
+	assert.Len(t, static, 2)
+
+	assert.Equal(t, [][]*cloudwatch.MetricDataQuery{static}, batchQueries(static, nil))
+	assert.Empty(t, batchQueries(nil, nil))
+}
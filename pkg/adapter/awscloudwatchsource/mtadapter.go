@@ -0,0 +1,134 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscloudwatchsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	k8sclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/logging"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/env"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/poller"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client"
+)
+
+// Polling interval applied when the source doesn't specify one. Same as the
+// value set by the reconciler on single-tenant adapters.
+const defaultPollingInterval = 5 * time.Minute
+
+// NewMTEnvConfig satisfies env.ConfigConstructor.
+// Returns an accessor for the source's multi-tenant adapter envConfig.
+func NewMTEnvConfig() env.ConfigAccessor {
+	return &env.Config{}
+}
+
+// NewMTAdapter returns a constructor for the source's multi-tenant adapter.
+func NewMTAdapter(component string) pkgadapter.AdapterConstructor {
+	return func(ctx context.Context, _ pkgadapter.EnvConfigAccessor, ceClient cloudevents.Client) pkgadapter.Adapter {
+		logger := logging.FromContext(ctx)
+		ns := injection.GetNamespaceScope(ctx)
+		secrCli := k8sclient.Get(ctx).CoreV1().Secrets(ns)
+
+		return poller.New(logger, ceClient,
+			jobBuilder(logger, secrCli),
+			poller.WithCredentials(jobConfig, secrCli, jobCredentials),
+			status.NewPatcher(component, client.Get(ctx).SourcesV1alpha1()),
+		)
+	}
+}
+
+// jobBuilder returns a poller.JobBuilder which polls metrics and alarms using
+// the credentials referenced in each source's spec.
+func jobBuilder(logger *zap.SugaredLogger, secrCli coreclientv1.SecretInterface) poller.JobBuilder {
+	return func(ctx context.Context, src v1alpha1.EventSource, ceClient cloudevents.Client) (pkgadapter.Adapter, error) {
+		typedSrc := src.(*v1alpha1.AWSCloudWatchSource)
+
+		// an invalid spec would cause the poller to crash
+		if err := typedSrc.Validate(ctx); err != nil {
+			return nil, fmt.Errorf("invalid spec: %w", err)
+		}
+
+		sess, err := poller.NewAWSSession(secrCli, &typedSrc.Spec.Credentials,
+			aws.NewConfig().WithRegion(typedSrc.Spec.Region),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// queries and alarm selector are passed to the adapter in the
+		// same JSON representation as the one set by the reconciler on
+		// single-tenant adapters
+		var queries string
+		if qs := typedSrc.Spec.MetricQueries; len(qs) > 0 {
+			q, _ := json.Marshal(qs)
+			queries = string(q)
+		}
+
+		var alarms string
+		if as := typedSrc.Spec.Alarms; as != nil {
+			a, _ := json.Marshal(as)
+			alarms = string(a)
+		}
+
+		pollingInterval := defaultPollingInterval
+		if f := typedSrc.Spec.PollingInterval; f != nil && time.Duration(*f).Nanoseconds() > 0 {
+			pollingInterval = time.Duration(*f)
+		}
+
+		var splitDatapoints bool
+		if split := typedSrc.Spec.SplitDatapoints; split != nil {
+			splitDatapoints = *split
+		}
+
+		var aggregationLag time.Duration
+		if l := typedSrc.Spec.AggregationLag; l != nil && time.Duration(*l).Nanoseconds() > 0 {
+			aggregationLag = time.Duration(*l)
+		}
+
+		logger := logger.With(zap.String("source", src.GetNamespace()+"/"+src.GetName()))
+
+		return newAdapter(logger, src.AsEventSource(), cloudwatch.New(sess), ceClient,
+			pollingInterval, queries, alarms, splitDatapoints, aggregationLag)
+	}
+}
+
+// jobConfig implements poller.ConfigFunc.
+func jobConfig(src v1alpha1.EventSource) interface{} {
+	return src.(*v1alpha1.AWSCloudWatchSource).Spec
+}
+
+// jobCredentials implements poller.CredentialsFunc.
+func jobCredentials(src v1alpha1.EventSource) *v1alpha1.AWSSecurityCredentials {
+	return &src.(*v1alpha1.AWSCloudWatchSource).Spec.Credentials
+}
@@ -20,9 +20,12 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -32,16 +35,14 @@ import (
 	"github.com/aws/aws-sdk-go/service/codecommit/codecommitiface"
 
 	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	k8sclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/logging"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/common"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/store"
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
-)
-
-var (
-	//syncTime       = 10
-	lastCommit     string
-	pullRequestIDs []*string //nolint:unused
+	"github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client"
 )
 
 const (
@@ -54,9 +55,10 @@ const (
 type envConfig struct {
 	pkgadapter.EnvConfig
 
-	ARN           string `envconfig:"ARN" required:"true"`
-	Branch        string `envconfig:"BRANCH" required:"true"`
-	GitEventTypes string `envconfig:"EVENT_TYPES" required:"true"`
+	ARN           string   `envconfig:"ARN" required:"true"`
+	Branch        string   `envconfig:"BRANCH"`
+	Branches      []string `envconfig:"BRANCHES"`
+	GitEventTypes string   `envconfig:"EVENT_TYPES" required:"true"`
 }
 
 // adapter implements the source's adapter.
@@ -66,9 +68,23 @@ type adapter struct {
 	ccClient codecommitiface.CodeCommitAPI
 	ceClient cloudevents.Client
 
-	arn       arn.ARN
-	branch    string
-	gitEvents string
+	arn            arn.ARN
+	branch         string
+	branchPatterns []string
+	gitEvents      string
+
+	// last observed tip commit of each watched branch
+	branchTips map[string]string
+
+	// persistent storage for the state of observed pull requests
+	store store.Store
+	// last observed state of each open pull request, indexed by ID
+	pullRequests map[string]*pullRequestState
+	// time of the last retrieval of each open pull request, indexed by ID
+	pullRequestChecks map[string]time.Time
+
+	// allows overriding the current time in tests
+	now func() time.Time
 }
 
 // NewEnvConfig returns an accessor for the source's adapter envConfig.
@@ -89,15 +105,47 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 		WithMaxRetries(5),
 	))
 
+	// The state is owned by the source object, so it gets garbage
+	// collected together with it.
+	src, err := client.Get(ctx).SourcesV1alpha1().AWSCodeCommitSources(env.Namespace).
+		Get(ctx, env.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Fatalw("Failed to get source object", "error", err)
+	}
+
+	stateStore := store.NewConfigMapStore(
+		k8sclient.Get(ctx).CoreV1().ConfigMaps(env.Namespace),
+		kmeta.ChildName(env.Component+"-"+env.Name, "-state"),
+		store.OwnerReference(src),
+	)
+
+	return newAdapter(logger, codecommit.New(cfg), ceClient, arn,
+		env.Branch, env.Branches, env.GitEventTypes, stateStore)
+}
+
+// newAdapter returns an adapter which polls the given repository for events
+// of the given types.
+func newAdapter(logger *zap.SugaredLogger, ccClient codecommitiface.CodeCommitAPI, ceClient cloudevents.Client,
+	arn arn.ARN, branch string, branchPatterns []string, gitEvents string, stateStore store.Store) *adapter {
+
 	return &adapter{
 		logger: logger,
 
-		ccClient: codecommit.New(cfg),
+		ccClient: ccClient,
 		ceClient: ceClient,
 
-		arn:       arn,
-		branch:    env.Branch,
-		gitEvents: env.GitEventTypes,
+		arn:            arn,
+		branch:         branch,
+		branchPatterns: branchPatterns,
+		gitEvents:      gitEvents,
+
+		branchTips: make(map[string]string),
+
+		store: stateStore,
+
+		pullRequestChecks: make(map[string]time.Time),
+
+		now: time.Now,
 	}
 }
 
@@ -106,43 +154,27 @@ func (a *adapter) Start(ctx context.Context) error {
 	if strings.Contains(a.gitEvents, pushEventType) {
 		a.logger.Info("Push events enabled")
 
-		branchInfo, err := a.ccClient.GetBranch(&codecommit.GetBranchInput{
-			RepositoryName: &a.arn.Resource,
-			BranchName:     &a.branch,
-		})
-		if err != nil {
-			a.logger.Fatalw("Failed to retrieve branch info", "error", err)
+		// record the current tip of each watched branch
+		if err := a.processCommits(); err != nil {
+			return fmt.Errorf("retrieving branch info: %w", err)
 		}
-
-		lastCommit = *branchInfo.Branch.CommitId
 	}
 
 	if strings.Contains(a.gitEvents, prEventType) {
 		a.logger.Info("Pull Request events enabled")
 
-		// get pull request IDs
-		pullRequestsOutput, err := a.ccClient.ListPullRequests(&codecommit.ListPullRequestsInput{
-			RepositoryName: &a.arn.Resource,
-		})
-		if err != nil {
-			a.logger.Fatalw("Failed to retrieve list of pull requests", "error", err)
+		if err := a.loadPullRequests(ctx); err != nil {
+			return fmt.Errorf("retrieving pull requests: %w", err)
 		}
-
-		pullRequestIDs = pullRequestsOutput.PullRequestIds
 	}
 
 	if !strings.Contains(a.gitEvents, pushEventType) && !strings.Contains(a.gitEvents, prEventType) {
-		a.logger.Fatalf("Failed to identify event types in %q. Valid values: (push,pull_request)", a.gitEvents)
-	}
-
-	processedPullRequests, err := a.preparePullRequests()
-	if err != nil {
-		a.logger.Errorw("Failed to process pull requests", "error", err)
+		return fmt.Errorf("failed to identify event types in %q. Valid values: (push,pull_request)", a.gitEvents)
 	}
 
 	backoff := common.NewBackoff()
 
-	err = backoff.Run(ctx.Done(), func(ctx context.Context) (bool, error) {
+	err := backoff.Run(ctx.Done(), func(ctx context.Context) (bool, error) {
 		resetBackoff := false
 
 		if strings.Contains(a.gitEvents, pushEventType) {
@@ -154,23 +186,11 @@ func (a *adapter) Start(ctx context.Context) error {
 		}
 
 		if strings.Contains(a.gitEvents, prEventType) {
-			pullRequests, err := a.preparePullRequests()
+			err := a.processPullRequests(ctx, true)
 			if err != nil {
 				a.logger.Errorw("Failed to process pull requests", "error", err)
 				return resetBackoff, nil
 			}
-
-			pullRequests = removeOldPRs(processedPullRequests, pullRequests)
-
-			for _, pr := range pullRequests {
-				resetBackoff = true
-				err = a.sendEvent(pr)
-				if err != nil {
-					a.logger.Errorw("Failed to send PR event", "error", err)
-					return resetBackoff, nil
-				}
-				processedPullRequests = append(processedPullRequests, pr)
-			}
 		}
 		return resetBackoff, nil
 	})
@@ -178,82 +198,17 @@ func (a *adapter) Start(ctx context.Context) error {
 	return err
 }
 
-func (a *adapter) processCommits() error {
-	branchInfo, err := a.ccClient.GetBranch(&codecommit.GetBranchInput{
-		BranchName:     &a.branch,
-		RepositoryName: &a.arn.Resource,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to get branch info: %w", err)
-	}
-
-	commitOutput, err := a.ccClient.GetCommit(&codecommit.GetCommitInput{
-		CommitId:       branchInfo.Branch.CommitId,
-		RepositoryName: &a.arn.Resource,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to get commit info: %w", err)
-	}
-
-	if *commitOutput.Commit.CommitId == lastCommit {
-		return nil
-	}
-
-	lastCommit = *commitOutput.Commit.CommitId
-
-	err = a.sendEvent(commitOutput.Commit)
-	if err != nil {
-		return fmt.Errorf("failed to send push event: %w", err)
-	}
-
-	return nil
-}
-
-func (a *adapter) preparePullRequests() ([]*codecommit.PullRequest, error) {
-	pullRequests := []*codecommit.PullRequest{}
-
-	input := codecommit.ListPullRequestsInput{
-		RepositoryName: &a.arn.Resource,
-	}
-
-	// Get pull request IDs
-	pullRequestsOutput, err := a.ccClient.ListPullRequests(&input)
-	if err != nil {
-		return pullRequests, fmt.Errorf("failed to list PRs: %w", err)
-	}
-
-	for _, id := range pullRequestsOutput.PullRequestIds {
-		pri := codecommit.GetPullRequestInput{PullRequestId: id}
-
-		prInfo, err := a.ccClient.GetPullRequest(&pri)
-		if err != nil {
-			return pullRequests, fmt.Errorf("failed to get PR info: %w", err)
-		}
-
-		pullRequests = append(pullRequests, prInfo.PullRequest)
-	}
-
-	return pullRequests, nil
-}
-
-// sendEvent sends an event containing data about a git commit or PR
-func (a *adapter) sendEvent(codeCommitEvent interface{}) error {
+// sendEvent sends an event of the given type containing data about a git push
+// or PR.
+func (a *adapter) sendEvent(typ, subject string, data interface{}) error {
 	a.logger.Info("Sending CodeCommit event")
 
 	event := cloudevents.NewEvent(cloudevents.VersionV1)
-	event.SetSubject(a.branch)
+	event.SetType(v1alpha1.AWSEventType(a.arn.Service, typ))
+	event.SetSubject(subject)
 	event.SetSource(a.arn.String())
 
-	switch codeCommitEvent.(type) {
-	case *codecommit.PullRequest:
-		event.SetType(v1alpha1.AWSEventType(a.arn.Service, prEventType))
-	case *codecommit.Commit:
-		event.SetType(v1alpha1.AWSEventType(a.arn.Service, pushEventType))
-	default:
-		return fmt.Errorf("unknown CodeCommit event")
-	}
-
-	err := event.SetData(cloudevents.ApplicationJSON, codeCommitEvent)
+	err := event.SetData(cloudevents.ApplicationJSON, data)
 	if err != nil {
 		return fmt.Errorf("failed to set event data: %w", err)
 	}
@@ -263,25 +218,3 @@ func (a *adapter) sendEvent(codeCommitEvent interface{}) error {
 	}
 	return nil
 }
-
-func removeOldPRs(oldPrs, newPrs []*codecommit.PullRequest) []*codecommit.PullRequest {
-	dct := make(map[string]*codecommit.PullRequest)
-	for _, oldPR := range oldPrs {
-		dct[*oldPR.PullRequestId] = oldPR
-	}
-
-	res := make([]*codecommit.PullRequest, 0)
-
-	for _, newPR := range newPrs {
-		if v, exist := dct[*newPR.PullRequestId]; !exist {
-			res = append(res, newPR)
-			continue
-		} else {
-			if *newPR.PullRequestStatus == *v.PullRequestStatus {
-				continue
-			}
-			res = append(res, newPR)
-		}
-	}
-	return res
-}
@@ -17,49 +17,159 @@ limitations under the License.
 package awscodecommitsource
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/service/codecommit"
 	"github.com/aws/aws-sdk-go/service/codecommit/codecommitiface"
 
+	"k8s.io/client-go/kubernetes/fake"
+
 	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
 	loggingtesting "knative.dev/pkg/logging/testing"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/store"
 )
 
+// mockedClientForPush is a mocked CodeCommit client backed by an in-memory
+// repository.
 type mockedClientForPush struct {
 	codecommitiface.CodeCommitAPI
-	GetBranchResp codecommit.GetBranchOutput
-	GetCommitResp codecommit.GetCommitOutput
-	GetBranchErr  error
-	GetCommitErr  error
+
+	// branch names mapped to their tip commit
+	branches map[string]string
+	// commit IDs mapped to the IDs of their parents
+	commits map[string][]string
+
+	// errors returned by GetBranch, indexed by branch name
+	GetBranchErrs map[string]error
 }
 
+// mockedClientForPR is a mocked CodeCommit client backed by an in-memory set
+// of pull requests.
 type mockedClientForPR struct {
 	codecommitiface.CodeCommitAPI
-	ListPRsResp codecommit.ListPullRequestsOutput
-	GetPRResp   codecommit.GetPullRequestOutput
-	ListPRsErr  error
-	GetPRErr    error
+
+	// pull requests indexed by ID
+	pullRequests map[string]*codecommit.PullRequest
+	// ARNs of approvers indexed by revision ID
+	approvers map[string][]string
+
+	ListPRsErr error
 }
 
 func (m mockedClientForPush) GetBranch(in *codecommit.GetBranchInput) (*codecommit.GetBranchOutput, error) {
-	return &m.GetBranchResp, m.GetBranchErr
+	if err := m.GetBranchErrs[*in.BranchName]; err != nil {
+		return nil, err
+	}
+	return &codecommit.GetBranchOutput{
+		Branch: &codecommit.BranchInfo{
+			BranchName: in.BranchName,
+			CommitId:   aws.String(m.branches[*in.BranchName]),
+		},
+	}, nil
 }
 
-func (m mockedClientForPush) GetCommit(in *codecommit.GetCommitInput) (*codecommit.GetCommitOutput, error) {
-	return &m.GetCommitResp, m.GetCommitErr
+func (m mockedClientForPush) ListBranchesPages(_ *codecommit.ListBranchesInput,
+	fn func(*codecommit.ListBranchesOutput, bool) bool) error {
+
+	var branches []string
+	for b := range m.branches {
+		branches = append(branches, b)
+	}
+	sort.Strings(branches)
+
+	fn(&codecommit.ListBranchesOutput{Branches: aws.StringSlice(branches)}, true)
+	return nil
 }
 
-func (m mockedClientForPR) ListPullRequests(in *codecommit.ListPullRequestsInput) (*codecommit.ListPullRequestsOutput, error) {
-	return &m.ListPRsResp, m.ListPRsErr
+func (m mockedClientForPush) BatchGetCommits(in *codecommit.BatchGetCommitsInput) (*codecommit.BatchGetCommitsOutput, error) {
+	out := &codecommit.BatchGetCommitsOutput{}
+
+	for _, id := range aws.StringValueSlice(in.CommitIds) {
+		parents, ok := m.commits[id]
+		if !ok {
+			out.Errors = append(out.Errors, &codecommit.BatchGetCommitsError{
+				CommitId:     aws.String(id),
+				ErrorMessage: aws.String("commit not found"),
+			})
+			continue
+		}
+
+		out.Commits = append(out.Commits, &codecommit.Commit{
+			CommitId: aws.String(id),
+			Parents:  aws.StringSlice(parents),
+		})
+	}
+
+	return out, nil
+}
+
+func (m mockedClientForPush) GetDifferencesPages(in *codecommit.GetDifferencesInput,
+	fn func(*codecommit.GetDifferencesOutput, bool) bool) error {
+
+	fn(&codecommit.GetDifferencesOutput{
+		Differences: []*codecommit.Difference{{
+			AfterBlob:  &codecommit.BlobMetadata{Path: aws.String(*in.AfterCommitSpecifier + ".txt")},
+			ChangeType: aws.String(codecommit.ChangeTypeEnumA),
+		}},
+	}, true)
+	return nil
+}
+
+// ListPullRequestsPages returns the IDs of the open pull requests, one per
+// page.
+func (m mockedClientForPR) ListPullRequestsPages(_ *codecommit.ListPullRequestsInput,
+	fn func(*codecommit.ListPullRequestsOutput, bool) bool) error {
+
+	if m.ListPRsErr != nil {
+		return m.ListPRsErr
+	}
+
+	var ids []string
+	for id, pr := range m.pullRequests {
+		if *pr.PullRequestStatus == codecommit.PullRequestStatusEnumOpen {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for i, id := range ids {
+		out := &codecommit.ListPullRequestsOutput{PullRequestIds: []*string{aws.String(id)}}
+		if !fn(out, i == len(ids)-1) {
+			break
+		}
+	}
+	return nil
 }
 
 func (m mockedClientForPR) GetPullRequest(in *codecommit.GetPullRequestInput) (*codecommit.GetPullRequestOutput, error) {
-	return &m.GetPRResp, m.GetPRErr
+	return &codecommit.GetPullRequestOutput{
+		PullRequest: m.pullRequests[*in.PullRequestId],
+	}, nil
+}
+
+func (m mockedClientForPR) GetPullRequestApprovalStates(in *codecommit.GetPullRequestApprovalStatesInput,
+) (*codecommit.GetPullRequestApprovalStatesOutput, error) {
+
+	var approvals []*codecommit.Approval
+	for _, user := range m.approvers[*in.RevisionId] {
+		approvals = append(approvals, &codecommit.Approval{
+			ApprovalState: aws.String(codecommit.ApprovalStateApprove),
+			UserArn:       aws.String(user),
+		})
+	}
+
+	return &codecommit.GetPullRequestApprovalStatesOutput{Approvals: approvals}, nil
 }
 
 func TestSendPREvent(t *testing.T) {
@@ -73,11 +183,12 @@ func TestSendPREvent(t *testing.T) {
 	pr := &codecommit.PullRequest{}
 	pr.SetPullRequestId("12345")
 
-	err := a.sendEvent(pr)
+	err := a.sendEvent("pull_request_opened", "12345", pr)
 	assert.NoError(t, err)
 
 	gotEvents := ceClient.Sent()
 	assert.Len(t, gotEvents, 1, "Expected 1 event, got %d", len(gotEvents))
+	assert.Equal(t, "12345", gotEvents[0].Subject())
 
 	var gotData codecommit.PullRequest
 	err = gotEvents[0].DataAs(&gotData)
@@ -93,196 +204,661 @@ func TestSendPushEvent(t *testing.T) {
 		ceClient: ceClient,
 	}
 
-	commit := &codecommit.Commit{}
-	commit.SetCommitId("12345")
+	push := &PushEvent{
+		Branch:  "main",
+		Before:  "12344",
+		After:   "12345",
+		Commits: []*codecommit.Commit{{CommitId: aws.String("12345")}},
+	}
 
-	err := a.sendEvent(commit)
+	err := a.sendEvent("push", "main", push)
 	assert.NoError(t, err)
 
 	gotEvents := ceClient.Sent()
 	assert.Len(t, gotEvents, 1, "Expected 1 event, got %d", len(gotEvents))
+	assert.Equal(t, "main", gotEvents[0].Subject())
 
-	var gotData codecommit.Commit
+	var gotData PushEvent
 	err = gotEvents[0].DataAs(&gotData)
 	assert.NoError(t, err)
-	assert.EqualValues(t, *commit, gotData, "Expected event %q, got %q", *commit, gotData)
+	assert.EqualValues(t, *push, gotData, "Expected event %q, got %q", *push, gotData)
 }
 
 func TestProcessCommits(t *testing.T) {
-	lastCommit = "foo"
-
-	testCases := []struct {
-		GetBranchResp codecommit.GetBranchOutput
-		GetCommitResp codecommit.GetCommitOutput
-		GetBranchErr  error
-		GetCommitErr  error
-		ErrMsg        *string
+	// Repository history used in all test cases:
+	//
+	//   a - b - c - d        (main)
+	//        \
+	//         x              (rewrite)
+	//    \
+	//     f1 - f2            (feature, merged into main as "m" with b)
+	//
+	history := map[string][]string{
+		"a":  nil,
+		"b":  {"a"},
+		"c":  {"b"},
+		"d":  {"c"},
+		"x":  {"b"},
+		"f1": {"a"},
+		"f2": {"f1"},
+		"m":  {"b", "f2"},
+	}
+
+	testCases := map[string]struct {
+		branch   string
+		patterns []string
+
+		prevTips map[string]string
+		curTips  map[string]string
+
+		getBranchErrs map[string]error
+
+		expectErr     string
+		expectPushes  []*PushEvent
+		expectCommits [][]string
+		expectTips    map[string]string
 	}{
-		{
-			GetBranchResp: codecommit.GetBranchOutput{},
-			GetBranchErr:  errors.New("fake get branch error"),
-			GetCommitErr:  nil,
-			ErrMsg:        aws.String("failed to get branch info: fake get branch error"),
+		"Error getting branch": {
+			branch:        "main",
+			prevTips:      map[string]string{"main": "b"},
+			curTips:       map[string]string{"main": "d"},
+			getBranchErrs: map[string]error{"main": errors.New("fake get branch error")},
+			expectErr:     `branch "main": failed to get branch info: fake get branch error`,
+			expectTips:    map[string]string{"main": "b"},
 		},
-		{
-			GetBranchResp: codecommit.GetBranchOutput{
-				Branch: &codecommit.BranchInfo{CommitId: aws.String("123")},
+		"Error getting one of multiple branches": {
+			patterns:      []string{"feature/*"},
+			prevTips:      map[string]string{"feature/one": "b", "feature/two": "a", "feature/gone": "a"},
+			curTips:       map[string]string{"feature/one": "c", "feature/two": "b"},
+			getBranchErrs: map[string]error{"feature/one": errors.New("fake get branch error")},
+			expectErr:     `branch "feature/one": failed to get branch info: fake get branch error`,
+			expectPushes: []*PushEvent{
+				{Branch: "feature/two", Before: "a", After: "b"},
 			},
-			GetBranchErr: nil,
-			GetCommitErr: errors.New("fake get commit error"),
-			ErrMsg:       aws.String("failed to get commit info: fake get commit error"),
+			expectCommits: [][]string{{"b"}},
+			expectTips:    map[string]string{"feature/one": "b", "feature/two": "b"},
+		},
+		"No branch is watched": {
+			curTips:    map[string]string{"main": "d"},
+			prevTips:   map[string]string{},
+			expectTips: map[string]string{},
+		},
+		"First poll only records the branch tip": {
+			branch:     "main",
+			prevTips:   map[string]string{},
+			curTips:    map[string]string{"main": "b"},
+			expectTips: map[string]string{"main": "b"},
 		},
-		{
-			GetBranchResp: codecommit.GetBranchOutput{
-				Branch: &codecommit.BranchInfo{CommitId: aws.String("123")},
+		"Branch is unchanged": {
+			branch:     "main",
+			prevTips:   map[string]string{"main": "b"},
+			curTips:    map[string]string{"main": "b"},
+			expectTips: map[string]string{"main": "b"},
+		},
+		"Multiple commits are pushed": {
+			branch:   "main",
+			prevTips: map[string]string{"main": "b"},
+			curTips:  map[string]string{"main": "d"},
+			expectPushes: []*PushEvent{
+				{Branch: "main", Before: "b", After: "d"},
 			},
-			GetCommitResp: codecommit.GetCommitOutput{
-				Commit: &codecommit.Commit{CommitId: aws.String("foo")},
+			expectCommits: [][]string{{"c", "d"}},
+			expectTips:    map[string]string{"main": "d"},
+		},
+		"Branch is force-pushed": {
+			branch:   "main",
+			prevTips: map[string]string{"main": "d"},
+			curTips:  map[string]string{"main": "x"},
+			expectPushes: []*PushEvent{
+				{Branch: "main", Before: "d", After: "x", Forced: true},
 			},
-			GetBranchErr: nil,
-			GetCommitErr: nil,
-			ErrMsg:       nil,
+			expectCommits: [][]string{{"x"}},
+			expectTips:    map[string]string{"main": "x"},
 		},
-		{
-			GetBranchResp: codecommit.GetBranchOutput{
-				Branch: &codecommit.BranchInfo{CommitId: aws.String("123")},
+		"Branch is merged": {
+			branch:   "main",
+			prevTips: map[string]string{"main": "b"},
+			curTips:  map[string]string{"main": "m"},
+			expectPushes: []*PushEvent{
+				{Branch: "main", Before: "b", After: "m"},
 			},
-			GetCommitResp: codecommit.GetCommitOutput{
-				Commit: &codecommit.Commit{CommitId: aws.String("bar")},
+			expectCommits: [][]string{{"f1", "f2", "m"}},
+			expectTips:    map[string]string{"main": "m"},
+		},
+		"Multiple branches match patterns": {
+			patterns: []string{"feature/*", "release"},
+			prevTips: map[string]string{"feature/one": "b", "feature/gone": "a"},
+			curTips:  map[string]string{"feature/one": "c", "feature/two": "a", "main": "d"},
+			expectPushes: []*PushEvent{
+				{Branch: "feature/one", Before: "b", After: "c"},
 			},
-			GetBranchErr: nil,
-			GetCommitErr: nil,
-			ErrMsg:       nil,
+			expectCommits: [][]string{{"c"}},
+			expectTips:    map[string]string{"feature/one": "c", "feature/two": "a"},
 		},
 	}
 
-	for _, tt := range testCases {
-		ccClient := mockedClientForPush{
-			GetBranchResp: tt.GetBranchResp,
-			GetCommitResp: tt.GetCommitResp,
-			GetBranchErr:  tt.GetBranchErr,
-			GetCommitErr:  tt.GetCommitErr,
-		}
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			ccClient := mockedClientForPush{
+				branches:      tc.curTips,
+				commits:       history,
+				GetBranchErrs: tc.getBranchErrs,
+			}
+
+			ceClient := adaptertest.NewTestClient()
+
+			branchTips := make(map[string]string, len(tc.prevTips))
+			for b, tip := range tc.prevTips {
+				branchTips[b] = tip
+			}
+
+			a := &adapter{
+				logger:   loggingtesting.TestLogger(t),
+				ccClient: ccClient,
+				ceClient: ceClient,
+
+				branch:         tc.branch,
+				branchPatterns: tc.patterns,
+				branchTips:     branchTips,
+			}
+
+			err := a.processCommits()
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			sent := ceClient.Sent()
+			require.Len(t, sent, len(tc.expectPushes))
+
+			for i, expect := range tc.expectPushes {
+				var push PushEvent
+				require.NoError(t, sent[i].DataAs(&push))
+
+				assert.Equal(t, expect.Branch, push.Branch)
+				assert.Equal(t, expect.Before, push.Before)
+				assert.Equal(t, expect.After, push.After)
+				assert.Equal(t, expect.Forced, push.Forced)
+				assert.False(t, push.Truncated)
+
+				var commitIDs []string
+				for _, c := range push.Commits {
+					commitIDs = append(commitIDs, *c.CommitId)
+				}
+				assert.Equal(t, tc.expectCommits[i], commitIDs)
+
+				require.Len(t, push.Files, 1)
+				assert.Equal(t, expect.After+".txt", *push.Files[0].AfterBlob.Path)
+			}
+
+			assert.Equal(t, tc.expectTips, a.branchTips)
+		})
+	}
+}
 
-		a := &adapter{
-			logger:   loggingtesting.TestLogger(t),
-			ccClient: ccClient,
-			ceClient: adaptertest.NewTestClient(),
-		}
+func TestProcessCommitsMergeIntoLongHistory(t *testing.T) {
+	// A branch created from an old commit is merged into main:
+	//
+	//   c0 - ... - c549 - c550 - ... - c599 - m   (main)
+	//                 \                      /
+	//                  f ------------------
+	//
+	history := make(map[string][]string, maxWalkedCommits+100)
+	history["c0"] = nil
+	for i := 1; i < maxWalkedCommits+100; i++ {
+		history[fmt.Sprintf("c%d", i)] = []string{fmt.Sprintf("c%d", i-1)}
+	}
+	history["f"] = []string{"c549"}
+	history["m"] = []string{"c599", "f"}
 
-		err := a.processCommits()
-		if tt.ErrMsg == nil {
-			assert.NoError(t, err)
-		} else {
-			assert.EqualError(t, err, *tt.ErrMsg)
-		}
+	ceClient := adaptertest.NewTestClient()
+
+	a := &adapter{
+		logger: loggingtesting.TestLogger(t),
+		ccClient: mockedClientForPush{
+			branches: map[string]string{"main": "m"},
+			commits:  history,
+		},
+		ceClient: ceClient,
 
-		lastCommit = "foo"
+		branch:     "main",
+		branchTips: map[string]string{"main": "c599"},
 	}
+
+	require.NoError(t, a.processCommits())
+
+	sent := ceClient.Sent()
+	require.Len(t, sent, 1)
+
+	var push PushEvent
+	require.NoError(t, sent[0].DataAs(&push))
+
+	assert.False(t, push.Truncated, "History of the previous tip isn't part of the push")
+	assert.False(t, push.Forced)
+
+	var commitIDs []string
+	for _, c := range push.Commits {
+		commitIDs = append(commitIDs, *c.CommitId)
+	}
+	assert.Equal(t, []string{"f", "m"}, commitIDs)
 }
 
-func TestProcessPullRequest(t *testing.T) {
-	pullRequestIDs = []*string{aws.String("1")}
+func TestProcessPullRequests(t *testing.T) {
+	const (
+		tNs        = "test-ns"
+		tStateName = "test-state"
+	)
+
+	// time of the last activity on all pull requests, and an earlier time
+	// which denotes pull requests that changed since the previous poll
+	var (
+		lastActivity = time.Unix(3600, 0).UTC()
+		prevActivity = time.Unix(0, 0).UTC()
+	)
+
+	newPR := func(id, status, revision, title string, merged bool) *codecommit.PullRequest {
+		return &codecommit.PullRequest{
+			LastActivityDate:  aws.Time(lastActivity),
+			PullRequestId:     aws.String(id),
+			PullRequestStatus: aws.String(status),
+			RevisionId:        aws.String(revision),
+			Title:             aws.String(title),
+			PullRequestTargets: []*codecommit.PullRequestTarget{{
+				MergeMetadata: &codecommit.MergeMetadata{IsMerged: aws.Bool(merged)},
+			}},
+		}
+	}
+
+	const (
+		open   = codecommit.PullRequestStatusEnumOpen
+		closed = codecommit.PullRequestStatusEnumClosed
+	)
+
+	testCases := map[string]struct {
+		prevState map[string]*pullRequestState
+
+		pullRequests map[string]*codecommit.PullRequest
+		approvers    map[string][]string
+
+		listPRsErr error
 
-	testCases := []struct {
-		ListPRsResp codecommit.ListPullRequestsOutput
-		GetPRResp   codecommit.GetPullRequestOutput
-		ListPRsErr  error
-		GetPRErr    error
-		ErrMsg      *string
+		expectErr    string
+		expectEvents []string // "<type> <subject>", sorted
+		expectState  map[string]*pullRequestState
 	}{
-		{
-			ListPRsErr: errors.New("fake list PR error"),
-			GetPRErr:   nil,
-			ErrMsg:     aws.String("failed to list PRs: fake list PR error"),
+		"Error listing PRs": {
+			prevState:  map[string]*pullRequestState{},
+			listPRsErr: errors.New("fake list PR error"),
+			expectErr:  "failed to list PRs: fake list PR error",
 		},
-		{
-			ListPRsResp: codecommit.ListPullRequestsOutput{
-				PullRequestIds: []*string{aws.String("1")},
+		"Nothing changed": {
+			prevState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", LastActivity: lastActivity},
+			},
+			pullRequests: map[string]*codecommit.PullRequest{
+				"1": newPR("1", open, "r1", "t", false),
+			},
+			expectState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", LastActivity: lastActivity},
+			},
+		},
+		"Approval states are not retrieved without activity": {
+			prevState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", Approvers: []string{"u1"}, LastActivity: lastActivity},
+			},
+			pullRequests: map[string]*codecommit.PullRequest{
+				"1": newPR("1", open, "r1", "t", false),
+			},
+			approvers: map[string][]string{
+				"r1": {"u1", "u2"},
+			},
+			expectState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", Approvers: []string{"u1"}, LastActivity: lastActivity},
+			},
+		},
+		"PRs are opened": {
+			prevState: map[string]*pullRequestState{},
+			pullRequests: map[string]*codecommit.PullRequest{
+				"1": newPR("1", open, "r1", "t", false),
+				"2": newPR("2", open, "r2", "t", false),
+			},
+			expectEvents: []string{
+				"com.amazon.codecommit.pull_request_opened 1",
+				"com.amazon.codecommit.pull_request_opened 2",
+			},
+			expectState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", LastActivity: lastActivity},
+				"2": {RevisionID: "r2", Title: "t", LastActivity: lastActivity},
 			},
-			ListPRsErr: nil,
-			GetPRErr:   nil,
-			ErrMsg:     nil,
 		},
-		{
-			ListPRsResp: codecommit.ListPullRequestsOutput{
-				PullRequestIds: []*string{aws.String("2")},
+		"PR is updated with a new revision": {
+			prevState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", Approvers: []string{"u1"}, LastActivity: prevActivity},
+			},
+			pullRequests: map[string]*codecommit.PullRequest{
+				"1": newPR("1", open, "r2", "t", false),
+			},
+			expectEvents: []string{
+				"com.amazon.codecommit.pull_request_updated 1",
+			},
+			expectState: map[string]*pullRequestState{
+				"1": {RevisionID: "r2", Title: "t", LastActivity: lastActivity},
+			},
+		},
+		"PR title is edited": {
+			prevState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", LastActivity: prevActivity},
+			},
+			pullRequests: map[string]*codecommit.PullRequest{
+				"1": newPR("1", open, "r1", "new t", false),
+			},
+			expectEvents: []string{
+				"com.amazon.codecommit.pull_request_updated 1",
+			},
+			expectState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "new t", LastActivity: lastActivity},
+			},
+		},
+		"PR is approved": {
+			prevState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", Approvers: []string{"u1"}, LastActivity: prevActivity},
+			},
+			pullRequests: map[string]*codecommit.PullRequest{
+				"1": newPR("1", open, "r1", "t", false),
+			},
+			approvers: map[string][]string{
+				"r1": {"u1", "u2"},
 			},
-			GetPRResp: codecommit.GetPullRequestOutput{
-				PullRequest: &codecommit.PullRequest{},
+			expectEvents: []string{
+				"com.amazon.codecommit.pull_request_approved 1",
+			},
+			expectState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", Approvers: []string{"u1", "u2"}, LastActivity: lastActivity},
 			},
-			ListPRsErr: nil,
-			GetPRErr:   errors.New("fake get PR error"),
-			ErrMsg:     aws.String("failed to get PR info: fake get PR error"),
 		},
-		{
-			ListPRsResp: codecommit.ListPullRequestsOutput{
-				PullRequestIds: []*string{aws.String("2")},
+		"PR approval is revoked": {
+			prevState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", Approvers: []string{"u1"}, LastActivity: prevActivity},
+			},
+			pullRequests: map[string]*codecommit.PullRequest{
+				"1": newPR("1", open, "r1", "t", false),
 			},
-			GetPRResp: codecommit.GetPullRequestOutput{
-				PullRequest: &codecommit.PullRequest{},
+			expectState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", LastActivity: lastActivity},
 			},
-			ListPRsErr: nil,
-			GetPRErr:   nil,
-			ErrMsg:     nil,
 		},
-		{
-			ListPRsResp: codecommit.ListPullRequestsOutput{
-				PullRequestIds: []*string{aws.String("2")},
+		"PRs are merged and closed": {
+			prevState: map[string]*pullRequestState{
+				"1": {RevisionID: "r1", Title: "t", LastActivity: prevActivity},
+				"2": {RevisionID: "r2", Title: "t", LastActivity: prevActivity},
+			},
+			pullRequests: map[string]*codecommit.PullRequest{
+				"1": newPR("1", closed, "r1", "t", true),
+				"2": newPR("2", closed, "r2", "t", false),
 			},
-			GetPRResp: codecommit.GetPullRequestOutput{
-				PullRequest: &codecommit.PullRequest{},
+			expectEvents: []string{
+				"com.amazon.codecommit.pull_request_closed 2",
+				"com.amazon.codecommit.pull_request_merged 1",
 			},
-			ListPRsErr: nil,
-			GetPRErr:   nil,
-			ErrMsg:     nil,
+			expectState: map[string]*pullRequestState{},
 		},
 	}
 
-	for _, tt := range testCases {
-		ccClient := mockedClientForPR{
-			ListPRsResp: tt.ListPRsResp,
-			GetPRResp:   tt.GetPRResp,
-			ListPRsErr:  tt.ListPRsErr,
-			GetPRErr:    tt.GetPRErr,
-		}
-		ceClient := adaptertest.NewTestClient()
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
 
-		a := &adapter{
-			logger:   loggingtesting.TestLogger(t),
-			ccClient: ccClient,
-			ceClient: ceClient,
-		}
+			ccClient := mockedClientForPR{
+				pullRequests: tc.pullRequests,
+				approvers:    tc.approvers,
+				ListPRsErr:   tc.listPRsErr,
+			}
+
+			ceClient := adaptertest.NewTestClient()
+
+			cmCli := fake.NewSimpleClientset().CoreV1().ConfigMaps(tNs)
+
+			a := &adapter{
+				logger:   loggingtesting.TestLogger(t),
+				ccClient: ccClient,
+				ceClient: ceClient,
+
+				arn: arn.ARN{Service: "codecommit", Resource: "test-repo"},
+
+				store:        store.NewConfigMapStore(cmCli, tStateName, nil),
+				pullRequests: tc.prevState,
+
+				pullRequestChecks: make(map[string]time.Time),
+				now:               time.Now,
+			}
+
+			err := a.processPullRequests(ctx, true)
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var events []string
+			for _, e := range ceClient.Sent() {
+				events = append(events, e.Type()+" "+e.Subject())
+			}
+			sort.Strings(events)
+
+			assert.Equal(t, tc.expectEvents, events)
+			assert.Equal(t, tc.expectState, a.pullRequests)
+
+			// the state must be restored by a new adapter instance
+
+			if tc.expectEvents == nil {
+				return
+			}
+
+			a.pullRequests = nil
+			ceClient.Reset()
+
+			err = a.loadPullRequests(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectState, a.pullRequests)
+			assert.Empty(t, ceClient.Sent())
+		})
+	}
+}
 
-		_, err := a.preparePullRequests()
-		if tt.ErrMsg == nil {
-			assert.NoError(t, err)
-		} else {
-			assert.EqualError(t, err, *tt.ErrMsg)
+func TestLoadPullRequestsWithoutState(t *testing.T) {
+	ccClient := mockedClientForPR{
+		pullRequests: map[string]*codecommit.PullRequest{
+			"1": {
+				PullRequestId:     aws.String("1"),
+				PullRequestStatus: aws.String(codecommit.PullRequestStatusEnumOpen),
+				RevisionId:        aws.String("r1"),
+				Title:             aws.String("t"),
+			},
+		},
+	}
+
+	ceClient := adaptertest.NewTestClient()
+
+	a := &adapter{
+		logger:   loggingtesting.TestLogger(t),
+		ccClient: ccClient,
+		ceClient: ceClient,
+
+		store: store.NewConfigMapStore(fake.NewSimpleClientset().CoreV1().ConfigMaps("test-ns"), "test-state", nil),
+
+		pullRequestChecks: make(map[string]time.Time),
+		now:               time.Now,
+	}
+
+	err := a.loadPullRequests(context.Background())
+	require.NoError(t, err)
+
+	// PRs that are open when the adapter first starts are not reported
+	assert.Empty(t, ceClient.Sent())
+
+	expectState := map[string]*pullRequestState{
+		"1": {RevisionID: "r1", Title: "t"},
+	}
+	assert.Equal(t, expectState, a.pullRequests)
+}
+
+func TestProcessPullRequestsCheckInterval(t *testing.T) {
+	now := time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	newPR := func(id, revision string, lastActivity time.Time) *codecommit.PullRequest {
+		return &codecommit.PullRequest{
+			LastActivityDate:  aws.Time(lastActivity),
+			PullRequestId:     aws.String(id),
+			PullRequestStatus: aws.String(codecommit.PullRequestStatusEnumOpen),
+			RevisionId:        aws.String(revision),
+			Title:             aws.String("t"),
 		}
+	}
+
+	idleSince := now.Add(-10 * time.Hour)
+	activeSince := now.Add(-time.Minute)
+
+	ccClient := mockedClientForPR{
+		pullRequests: map[string]*codecommit.PullRequest{
+			"idle":   newPR("idle", "r1", idleSince),
+			"active": newPR("active", "r1", activeSince),
+		},
+	}
+
+	ceClient := adaptertest.NewTestClient()
+
+	a := &adapter{
+		logger:   loggingtesting.TestLogger(t),
+		ccClient: ccClient,
+		ceClient: ceClient,
+
+		arn: arn.ARN{Service: "codecommit", Resource: "test-repo"},
+
+		store: store.NewConfigMapStore(fake.NewSimpleClientset().CoreV1().ConfigMaps("test-ns"), "test-state", nil),
+		pullRequests: map[string]*pullRequestState{
+			"idle":   {RevisionID: "r1", Title: "t", LastActivity: idleSince},
+			"active": {RevisionID: "r1", Title: "t", LastActivity: activeSince},
+		},
 
-		pullRequestIDs = []*string{aws.String("1")}
+		pullRequestChecks: make(map[string]time.Time),
+		now:               func() time.Time { return now },
 	}
+
+	sentEvents := func() []string {
+		var events []string
+		for _, e := range ceClient.Sent() {
+			events = append(events, e.Type()+" "+e.Subject())
+		}
+		sort.Strings(events)
+		ceClient.Reset()
+		return events
+	}
+
+	// all pull requests are checked after the adapter starts
+
+	require.NoError(t, a.processPullRequests(context.Background(), true))
+	assert.Empty(t, sentEvents())
+	assert.Equal(t, map[string]time.Time{"idle": now, "active": now}, a.pullRequestChecks)
+
+	// both pull requests get updated, and a new one gets opened
+
+	now = now.Add(time.Minute)
+
+	ccClient.pullRequests["idle"] = newPR("idle", "r2", now)
+	ccClient.pullRequests["active"] = newPR("active", "r2", now)
+	ccClient.pullRequests["new"] = newPR("new", "r1", now)
+
+	require.NoError(t, a.processPullRequests(context.Background(), true))
+	assert.Equal(t, []string{
+		"com.amazon.codecommit.pull_request_opened new",
+		"com.amazon.codecommit.pull_request_updated active",
+	}, sentEvents(), "Expected the idle pull request to be checked later")
+
+	// the idle pull request is checked once its check interval elapsed
+
+	now = now.Add(maxPullRequestCheckInterval)
+
+	require.NoError(t, a.processPullRequests(context.Background(), true))
+	assert.Equal(t, []string{
+		"com.amazon.codecommit.pull_request_updated idle",
+	}, sentEvents())
 }
 
-func TestRemoveOldPRs(t *testing.T) {
-	oldPRs := []*codecommit.PullRequest{
-		{PullRequestId: aws.String("1"), PullRequestStatus: aws.String("CREATED")},
-		{PullRequestId: aws.String("2"), PullRequestStatus: aws.String("CREATED")},
+func TestProcessPullRequestsSendFailure(t *testing.T) {
+	ctx := context.Background()
+
+	lastActivity := time.Unix(3600, 0).UTC()
+
+	ccClient := mockedClientForPR{
+		pullRequests: map[string]*codecommit.PullRequest{
+			"1": {
+				LastActivityDate:  aws.Time(lastActivity),
+				PullRequestId:     aws.String("1"),
+				PullRequestStatus: aws.String(codecommit.PullRequestStatusEnumOpen),
+				RevisionId:        aws.String("r1"),
+				Title:             aws.String("t"),
+			},
+		},
+		approvers: map[string][]string{
+			"r1": {"u1"},
+		},
 	}
-	newPRs := []*codecommit.PullRequest{
-		{PullRequestId: aws.String("1"), PullRequestStatus: aws.String("CREATED")},
-		{PullRequestId: aws.String("2"), PullRequestStatus: aws.String("CLOSED")},
-		{PullRequestId: aws.String("3"), PullRequestStatus: aws.String("CREATED")},
+
+	ceClient := adaptertest.NewTestClient()
+	// the "opened" event is sent, the "approved" event fails
+	ceClient.Send_AppendResult(nil)
+	ceClient.Send_AppendResult(errors.New("fake send error"))
+
+	stateStore := store.NewConfigMapStore(fake.NewSimpleClientset().CoreV1().ConfigMaps("test-ns"), "test-state", nil)
+
+	newAdapter := func() *adapter {
+		return &adapter{
+			logger:   loggingtesting.TestLogger(t),
+			ccClient: ccClient,
+			ceClient: ceClient,
+
+			arn: arn.ARN{Service: "codecommit", Resource: "test-repo"},
+
+			store:        stateStore,
+			pullRequests: map[string]*pullRequestState{},
+
+			pullRequestChecks: make(map[string]time.Time),
+			now:               time.Now,
+		}
 	}
 
-	expectedPRs := []*codecommit.PullRequest{
-		{PullRequestId: aws.String("2"), PullRequestStatus: aws.String("CLOSED")},
-		{PullRequestId: aws.String("3"), PullRequestStatus: aws.String("CREATED")},
+	a := newAdapter()
+
+	err := a.processPullRequests(ctx, true)
+	require.Error(t, err)
+	require.Len(t, ceClient.Sent(), 2)
+	ceClient.Reset()
+
+	// the state which was persisted after the first event is restored
+	// by a new adapter instance
+
+	a = newAdapter()
+	a.pullRequests = nil
+	require.NoError(t, a.loadPullRequests(ctx))
+
+	expectState := map[string]*pullRequestState{
+		"1": {RevisionID: "r1", Title: "t"},
 	}
+	assert.Equal(t, expectState, a.pullRequests)
+
+	// only the event which failed is sent again
+
+	require.NoError(t, a.processPullRequests(ctx, true))
 
-	prs := removeOldPRs(oldPRs, newPRs)
-	t.Log(prs)
-	assert.Equal(t, 2, len(prs))
-	assert.Equal(t, expectedPRs, prs)
+	events := ceClient.Sent()
+	require.Len(t, events, 1)
+	assert.Equal(t, "com.amazon.codecommit.pull_request_approved", events[0].Type())
+
+	expectState = map[string]*pullRequestState{
+		"1": {RevisionID: "r1", Title: "t", Approvers: []string{"u1"}, LastActivity: lastActivity},
+	}
+	assert.Equal(t, expectState, a.pullRequests)
 }
@@ -0,0 +1,35 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscodecommitsource
+
+import (
+	"context"
+
+	"k8s.io/client-go/tools/cache"
+
+	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/poller"
+	informerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/informers/sources/v1alpha1/awscodecommitsource"
+)
+
+// NewController returns a constructor for the event source's Reconciler.
+func NewController(component string) pkgadapter.ControllerConstructor {
+	return poller.NewController(component, func(ctx context.Context) cache.SharedIndexInformer {
+		return informerv1alpha1.Get(ctx).Informer()
+	})
+}
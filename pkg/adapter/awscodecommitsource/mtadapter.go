@@ -0,0 +1,113 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscodecommitsource
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/codecommit"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	k8sclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/logging"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/env"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/poller"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/store"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client"
+)
+
+// NewMTEnvConfig satisfies env.ConfigConstructor.
+// Returns an accessor for the source's multi-tenant adapter envConfig.
+func NewMTEnvConfig() env.ConfigAccessor {
+	return &env.Config{}
+}
+
+// NewMTAdapter returns a constructor for the source's multi-tenant adapter.
+func NewMTAdapter(component string) pkgadapter.AdapterConstructor {
+	return func(ctx context.Context, _ pkgadapter.EnvConfigAccessor, ceClient cloudevents.Client) pkgadapter.Adapter {
+		logger := logging.FromContext(ctx)
+		ns := injection.GetNamespaceScope(ctx)
+		kubeCli := k8sclient.Get(ctx)
+		secrCli := kubeCli.CoreV1().Secrets(ns)
+
+		return poller.New(logger, ceClient,
+			jobBuilder(logger, kubeCli, ns, component),
+			poller.WithCredentials(jobConfig, secrCli, jobCredentials),
+			status.NewPatcher(component, client.Get(ctx).SourcesV1alpha1()),
+		)
+	}
+}
+
+// jobBuilder returns a poller.JobBuilder which polls the repository
+// referenced in each source's spec.
+//
+// Sources which retrieve events via EventBridge are never handled by
+// multi-tenant adapters.
+func jobBuilder(logger *zap.SugaredLogger, kubeCli kubernetes.Interface, ns, component string) poller.JobBuilder {
+	secrCli := kubeCli.CoreV1().Secrets(ns)
+	cmCli := kubeCli.CoreV1().ConfigMaps(ns)
+
+	return func(ctx context.Context, src v1alpha1.EventSource, ceClient cloudevents.Client) (pkgadapter.Adapter, error) {
+		typedSrc := src.(*v1alpha1.AWSCodeCommitSource)
+
+		srcARN := arn.ARN(typedSrc.Spec.ARN)
+
+		sess, err := poller.NewAWSSession(secrCli, &typedSrc.Spec.Credentials,
+			aws.NewConfig().WithRegion(srcARN.Region).WithMaxRetries(5),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// reuse the ConfigMap of the single-tenant adapter, if any, to
+		// avoid reporting known pull requests again
+		stateStore := store.NewConfigMapStore(cmCli,
+			kmeta.ChildName(component+"-"+src.GetName(), "-state"),
+			store.OwnerReference(typedSrc),
+		)
+
+		logger := logger.With(zap.String("source", src.GetNamespace()+"/"+src.GetName()))
+
+		return newAdapter(logger, codecommit.New(sess), ceClient, srcARN,
+			typedSrc.Spec.Branch, typedSrc.Spec.Branches, strings.Join(typedSrc.Spec.EventTypes, ","),
+			stateStore), nil
+	}
+}
+
+// jobConfig implements poller.ConfigFunc.
+func jobConfig(src v1alpha1.EventSource) interface{} {
+	return src.(*v1alpha1.AWSCodeCommitSource).Spec
+}
+
+// jobCredentials implements poller.CredentialsFunc.
+func jobCredentials(src v1alpha1.EventSource) *v1alpha1.AWSSecurityCredentials {
+	return &src.(*v1alpha1.AWSCodeCommitSource).Spec.Credentials
+}
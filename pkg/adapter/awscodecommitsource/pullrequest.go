@@ -0,0 +1,353 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscodecommitsource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codecommit"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+// stateKeyPullRequests is the key under which the state of the observed pull
+// requests is persisted.
+const stateKeyPullRequests = "pullRequests"
+
+// Open pull requests which have been idle for a while are unlikely to change
+// soon, so their details are retrieved at an interval which grows with their
+// idle time, up to a maximum. Pull requests with recent activity are checked
+// at every poll.
+const (
+	pullRequestCheckIdleRatio   = 10
+	maxPullRequestCheckInterval = 30 * time.Minute
+)
+
+// pullRequestState is the state of an open pull request, as observed during a
+// poll.
+type pullRequestState struct {
+	RevisionID string `json:"revisionId"`
+	Title      string `json:"title"`
+	// ARNs of the users who approved the current revision, sorted.
+	Approvers []string `json:"approvers,omitempty"`
+	// Time of the last activity on the pull request, in UTC.
+	LastActivity time.Time `json:"lastActivity"`
+}
+
+// loadPullRequests restores the state of the pull requests persisted by a
+// previous instance of the adapter. When no state was persisted, the open
+// pull requests are recorded without sending any event, so they serve as a
+// reference for subsequent polls.
+func (a *adapter) loadPullRequests(ctx context.Context) error {
+	found, err := a.store.Load(ctx, stateKeyPullRequests, &a.pullRequests)
+	if err != nil {
+		return fmt.Errorf("failed to load state of pull requests: %w", err)
+	}
+	if found && a.pullRequests != nil {
+		return nil
+	}
+
+	a.pullRequests = make(map[string]*pullRequestState)
+
+	return a.processPullRequests(ctx, false)
+}
+
+// processPullRequests sends an event for each change observed on the
+// repository's pull requests since the previous poll, if notify is true, and
+// persists the resulting state.
+//
+// The CodeCommit API only lists the IDs of open pull requests, so opened and
+// closed pull requests are detected at every poll, but the details of pull
+// requests which are still open are only retrieved when they are due for a
+// check (see pullRequestCheckDue). Their approval states are only retrieved
+// when some activity occurred since the previous check.
+func (a *adapter) processPullRequests(ctx context.Context, notify bool) (retErr error) {
+	// the initial state is persisted even if empty
+	changed := !notify
+
+	defer func() {
+		if !changed {
+			return
+		}
+		if err := a.savePullRequests(ctx); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+
+	now := a.now()
+
+	ids, err := a.listOpenPullRequests()
+	if err != nil {
+		return fmt.Errorf("failed to list PRs: %w", err)
+	}
+
+	open := make(map[string]struct{}, len(ids))
+
+	for _, id := range ids {
+		open[id] = struct{}{}
+
+		prev := a.pullRequests[id]
+
+		if prev != nil && !a.pullRequestCheckDue(id, prev, now) {
+			continue
+		}
+
+		pr, err := a.getPullRequest(id)
+		if err != nil {
+			return fmt.Errorf("failed to get PR info: %w", err)
+		}
+		a.pullRequestChecks[id] = now
+
+		lastActivity := aws.TimeValue(pr.LastActivityDate).UTC()
+
+		// approvals are recorded as activities on the pull request,
+		// same as any other change
+		if prev != nil && prev.RevisionID == aws.StringValue(pr.RevisionId) &&
+			prev.LastActivity.Equal(lastActivity) {
+			continue
+		}
+
+		approvers, err := a.pullRequestApprovers(pr)
+		if err != nil {
+			return fmt.Errorf("failed to get PR approval states: %w", err)
+		}
+
+		curr := &pullRequestState{
+			RevisionID:   aws.StringValue(pr.RevisionId),
+			Title:        aws.StringValue(pr.Title),
+			Approvers:    approvers,
+			LastActivity: lastActivity,
+		}
+
+		if reflect.DeepEqual(prev, curr) {
+			continue
+		}
+
+		if notify {
+			for _, typ := range pullRequestChanges(prev, curr) {
+				if err := a.sendEvent(typ, id, pr); err != nil {
+					return fmt.Errorf("failed to send PR event: %w", err)
+				}
+
+				// events which were sent must not be sent
+				// again if a subsequent one fails
+				prev = pullRequestStateAfter(prev, curr, typ)
+				a.pullRequests[id] = prev
+				if err := a.savePullRequests(ctx); err != nil {
+					return err
+				}
+			}
+		}
+
+		a.pullRequests[id] = curr
+		changed = true
+	}
+
+	// report pull requests which were closed since the previous poll
+	for id := range a.pullRequests {
+		if _, isOpen := open[id]; isOpen {
+			continue
+		}
+
+		pr, err := a.getPullRequest(id)
+		if err != nil {
+			return fmt.Errorf("failed to get PR info: %w", err)
+		}
+
+		// the pull request was possibly opened again after it got listed
+		if aws.StringValue(pr.PullRequestStatus) == codecommit.PullRequestStatusEnumOpen {
+			continue
+		}
+
+		typ := v1alpha1.AWSCodeCommitPullRequestClosedEventType
+		if isMerged(pr) {
+			typ = v1alpha1.AWSCodeCommitPullRequestMergedEventType
+		}
+
+		if notify {
+			if err := a.sendEvent(typ, id, pr); err != nil {
+				return fmt.Errorf("failed to send PR event: %w", err)
+			}
+		}
+
+		delete(a.pullRequests, id)
+		delete(a.pullRequestChecks, id)
+		changed = true
+
+		if notify {
+			if err := a.savePullRequests(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// savePullRequests persists the state of the observed pull requests.
+func (a *adapter) savePullRequests(ctx context.Context) error {
+	if err := a.store.Save(ctx, stateKeyPullRequests, a.pullRequests); err != nil {
+		return fmt.Errorf("failed to persist state of pull requests: %w", err)
+	}
+	return nil
+}
+
+// pullRequestCheckDue returns whether the details of the given open pull
+// request should be retrieved at the given time. Pull requests are checked at
+// an interval proportional to the time they had been idle for at their last
+// check, and are always checked after the adapter starts.
+func (a *adapter) pullRequestCheckDue(id string, st *pullRequestState, now time.Time) bool {
+	lastCheck, checked := a.pullRequestChecks[id]
+	if !checked {
+		return true
+	}
+
+	interval := lastCheck.Sub(st.LastActivity) / pullRequestCheckIdleRatio
+	if interval > maxPullRequestCheckInterval {
+		interval = maxPullRequestCheckInterval
+	}
+
+	return now.Sub(lastCheck) >= interval
+}
+
+// listOpenPullRequests returns the IDs of all open pull requests.
+func (a *adapter) listOpenPullRequests() ([]string, error) {
+	var ids []string
+
+	in := &codecommit.ListPullRequestsInput{
+		RepositoryName:    &a.arn.Resource,
+		PullRequestStatus: aws.String(codecommit.PullRequestStatusEnumOpen),
+	}
+
+	err := a.ccClient.ListPullRequestsPages(in, func(out *codecommit.ListPullRequestsOutput, lastPage bool) bool {
+		ids = append(ids, aws.StringValueSlice(out.PullRequestIds)...)
+		return !lastPage
+	})
+
+	return ids, err
+}
+
+// getPullRequest returns the pull request with the given ID.
+func (a *adapter) getPullRequest(id string) (*codecommit.PullRequest, error) {
+	out, err := a.ccClient.GetPullRequest(&codecommit.GetPullRequestInput{
+		PullRequestId: &id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.PullRequest, nil
+}
+
+// pullRequestApprovers returns the sorted ARNs of the users who approved the
+// current revision of the given pull request.
+func (a *adapter) pullRequestApprovers(pr *codecommit.PullRequest) ([]string, error) {
+	out, err := a.ccClient.GetPullRequestApprovalStates(&codecommit.GetPullRequestApprovalStatesInput{
+		PullRequestId: pr.PullRequestId,
+		RevisionId:    pr.RevisionId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var approvers []string
+	for _, appr := range out.Approvals {
+		if aws.StringValue(appr.ApprovalState) == codecommit.ApprovalStateApprove {
+			approvers = append(approvers, aws.StringValue(appr.UserArn))
+		}
+	}
+	sort.Strings(approvers)
+
+	return approvers, nil
+}
+
+// pullRequestChanges returns the types of the events describing the changes
+// between two states of an open pull request. A nil previous state denotes a
+// newly opened pull request.
+func pullRequestChanges(prev, curr *pullRequestState) []string {
+	var types []string
+
+	switch {
+	case prev == nil:
+		types = append(types, v1alpha1.AWSCodeCommitPullRequestOpenedEventType)
+		prev = &pullRequestState{}
+	case prev.RevisionID != curr.RevisionID || prev.Title != curr.Title:
+		types = append(types, v1alpha1.AWSCodeCommitPullRequestUpdatedEventType)
+	}
+
+	// approvals apply to a single revision
+	prevApprovers := prev.Approvers
+	if prev.RevisionID != curr.RevisionID {
+		prevApprovers = nil
+	}
+
+	if hasNewElements(prevApprovers, curr.Approvers) {
+		types = append(types, v1alpha1.AWSCodeCommitPullRequestApprovedEventType)
+	}
+
+	return types
+}
+
+// pullRequestStateAfter returns the state of an open pull request once the
+// event of the given type, which describes part of its changes from prev to
+// curr, was sent. The returned state excludes the changes described by other
+// events, as well as the time of the last activity, so that these changes are
+// still observed at the next check.
+func pullRequestStateAfter(prev, curr *pullRequestState, typ string) *pullRequestState {
+	if prev == nil {
+		prev = &pullRequestState{}
+	}
+
+	next := *curr
+	next.LastActivity = prev.LastActivity
+
+	if typ != v1alpha1.AWSCodeCommitPullRequestApprovedEventType {
+		// approvals apply to a single revision
+		next.Approvers = prev.Approvers
+		if prev.RevisionID != curr.RevisionID {
+			next.Approvers = nil
+		}
+	}
+
+	return &next
+}
+
+// hasNewElements returns whether curr contains elements which are absent from
+// prev. Both slices must be sorted.
+func hasNewElements(prev, curr []string) bool {
+	for _, elem := range curr {
+		if i := sort.SearchStrings(prev, elem); i == len(prev) || prev[i] != elem {
+			return true
+		}
+	}
+	return false
+}
+
+// isMerged returns whether the given pull request was merged.
+func isMerged(pr *codecommit.PullRequest) bool {
+	for _, tgt := range pr.PullRequestTargets {
+		if tgt.MergeMetadata != nil && aws.BoolValue(tgt.MergeMetadata.IsMerged) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,324 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscodecommitsource
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/codecommit"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// maxWalkedCommits is the maximum number of commits traversed while walking
+// the history of a branch between two polls.
+const maxWalkedCommits = 500
+
+// maxBatchGetCommits is the maximum number of commit IDs accepted by the
+// BatchGetCommits API.
+const maxBatchGetCommits = 100
+
+// PushEvent describes the updates of a Git branch observed between two polls.
+type PushEvent struct {
+	// Name of the updated branch.
+	Branch string
+	// Tip of the branch before and after the update.
+	Before string
+	After  string
+	// Whether the update was not a fast-forward, e.g. after a force-push.
+	// Always false when Truncated is true, since the previous tip may
+	// simply be further in the history of the branch.
+	Forced bool
+	// Whether the list of commits is incomplete because the number of
+	// pushed commits exceeded the maximum number of commits we traverse.
+	Truncated bool
+	// Commits pushed to the branch, from the oldest to the most recent.
+	Commits []*codecommit.Commit
+	// Files changed between the Before and After commits.
+	Files []*codecommit.Difference
+}
+
+// processCommits sends a push event for each watched branch whose tip changed
+// since the previous poll. Branches are processed independently of each other,
+// so that a failure on one branch doesn't prevent events from being sent for
+// the others.
+func (a *adapter) processCommits() error {
+	branches, err := a.watchedBranches()
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	current := make(map[string]struct{}, len(branches))
+
+	var errs []error
+
+	for _, branch := range branches {
+		current[branch] = struct{}{}
+
+		if err := a.processBranch(branch); err != nil {
+			errs = append(errs, fmt.Errorf("branch %q: %w", branch, err))
+		}
+	}
+
+	// forget about deleted branches
+	for branch := range a.branchTips {
+		if _, exists := current[branch]; !exists {
+			delete(a.branchTips, branch)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// processBranch sends a push event if the tip of the given branch changed
+// since the previous poll. The recorded tip of the branch is updated only once
+// the event was sent, so that a failed attempt is retried at the next poll.
+func (a *adapter) processBranch(branch string) error {
+	branchInfo, err := a.ccClient.GetBranch(&codecommit.GetBranchInput{
+		BranchName:     &branch,
+		RepositoryName: &a.arn.Resource,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get branch info: %w", err)
+	}
+
+	tip := aws.StringValue(branchInfo.Branch.CommitId)
+
+	prevTip, seen := a.branchTips[branch]
+	if !seen {
+		// the first observation of a branch only serves as a
+		// reference for subsequent polls
+		a.branchTips[branch] = tip
+		return nil
+	}
+
+	if tip == prevTip {
+		return nil
+	}
+
+	push, err := a.makePushEvent(branch, prevTip, tip)
+	if err != nil {
+		return fmt.Errorf("failed to determine pushed commits: %w", err)
+	}
+
+	if err := a.sendEvent(pushEventType, branch, push); err != nil {
+		return fmt.Errorf("failed to send push event: %w", err)
+	}
+
+	a.branchTips[branch] = tip
+
+	return nil
+}
+
+// watchedBranches returns the names of the branches observed by the adapter.
+// The repository's branches are listed only when glob patterns were provided.
+func (a *adapter) watchedBranches() ([]string, error) {
+	if len(a.branchPatterns) == 0 {
+		if a.branch == "" {
+			return nil, nil
+		}
+		return []string{a.branch}, nil
+	}
+
+	var branches []string
+
+	err := a.ccClient.ListBranchesPages(&codecommit.ListBranchesInput{
+		RepositoryName: &a.arn.Resource,
+	}, func(out *codecommit.ListBranchesOutput, lastPage bool) bool {
+		for _, b := range aws.StringValueSlice(out.Branches) {
+			if a.isWatchedBranch(b) {
+				branches = append(branches, b)
+			}
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return branches, nil
+}
+
+// isWatchedBranch returns whether the branch with the given name is observed by
+// the adapter.
+func (a *adapter) isWatchedBranch(branch string) bool {
+	if branch == a.branch {
+		return true
+	}
+
+	for _, pattern := range a.branchPatterns {
+		if match, _ := path.Match(pattern, branch); match {
+			return true
+		}
+	}
+
+	return false
+}
+
+// makePushEvent returns a PushEvent describing the update of the given branch
+// from the commit "before" to the commit "after".
+func (a *adapter) makePushEvent(branch, before, after string) (*PushEvent, error) {
+	commits, reachedBefore, complete, err := a.walkCommits(after, before, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unless the history between both tips is linear, the walk may have
+	// traversed commits which were already ancestors of the previous tip,
+	// such as the history of merged branches, or the common history of
+	// a rewritten branch. The new tip's history is then walked again
+	// without traversing those commits, which would otherwise count
+	// towards the limit of traversed commits.
+	if !reachedBefore || hasMergeCommit(commits) {
+		// the previous tip may have become unreachable after the
+		// branch was rewritten, in which case we simply report all
+		// traversed commits
+		prevCommits, _, _, err := a.walkCommits(before, "", nil)
+		if err != nil {
+			a.logger.Warnw("Failed to walk the history of the previous branch tip", "error", err)
+		} else {
+			known := make(map[string]struct{}, len(prevCommits))
+			for _, c := range prevCommits {
+				known[aws.StringValue(c.CommitId)] = struct{}{}
+			}
+
+			commits, reachedBefore, complete, err = a.walkCommits(after, before, known)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	push := &PushEvent{
+		Branch: branch,
+		Before: before,
+		After:  after,
+		// the previous tip is not an ancestor of the new tip, so the
+		// branch was rewritten
+		Forced:    !reachedBefore && complete,
+		Truncated: !complete,
+	}
+
+	// commits are returned by walkCommits from the most recent to the
+	// oldest
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	push.Commits = commits
+
+	files, err := a.changedFiles(before, after)
+	if err != nil {
+		return nil, err
+	}
+	push.Files = files
+
+	return push, nil
+}
+
+// walkCommits walks the history of the commit "from", breadth-first, and
+// returns the commits it traverses, excluding the commit "stop" and the
+// commits contained in "known", whose history isn't traversed either.
+//
+// The returned booleans indicate whether the commit "stop" was reached, and
+// whether the walk completed before reaching the limit of traversed commits.
+func (a *adapter) walkCommits(from, stop string,
+	known map[string]struct{}) (commits []*codecommit.Commit, reachedStop, complete bool, err error) {
+
+	if _, ok := known[from]; ok {
+		return nil, false, true, nil
+	}
+
+	visited := map[string]struct{}{from: {}}
+	frontier := []string{from}
+
+	for len(frontier) > 0 {
+		if len(commits) >= maxWalkedCommits {
+			return commits, reachedStop, false, nil
+		}
+
+		batch := frontier
+		if len(batch) > maxBatchGetCommits {
+			batch = batch[:maxBatchGetCommits]
+		}
+		frontier = frontier[len(batch):]
+
+		out, err := a.ccClient.BatchGetCommits(&codecommit.BatchGetCommitsInput{
+			CommitIds:      aws.StringSlice(batch),
+			RepositoryName: &a.arn.Resource,
+		})
+		if err != nil {
+			return nil, false, false, fmt.Errorf("failed to get commits: %w", err)
+		}
+		if len(out.Errors) > 0 {
+			e := out.Errors[0]
+			return nil, false, false, fmt.Errorf("failed to get commit %s: %s",
+				aws.StringValue(e.CommitId), aws.StringValue(e.ErrorMessage))
+		}
+
+		for _, c := range out.Commits {
+			commits = append(commits, c)
+
+			for _, parent := range aws.StringValueSlice(c.Parents) {
+				if parent == stop {
+					reachedStop = true
+					continue
+				}
+				if _, ok := known[parent]; ok {
+					continue
+				}
+				if _, ok := visited[parent]; ok {
+					continue
+				}
+				visited[parent] = struct{}{}
+				frontier = append(frontier, parent)
+			}
+		}
+	}
+
+	return commits, reachedStop, true, nil
+}
+
+// changedFiles returns the differences between the commits "before" and "after".
+func (a *adapter) changedFiles(before, after string) ([]*codecommit.Difference, error) {
+	var diffs []*codecommit.Difference
+
+	err := a.ccClient.GetDifferencesPages(&codecommit.GetDifferencesInput{
+		BeforeCommitSpecifier: &before,
+		AfterCommitSpecifier:  &after,
+		RepositoryName:        &a.arn.Resource,
+	}, func(out *codecommit.GetDifferencesOutput, lastPage bool) bool {
+		diffs = append(diffs, out.Differences...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get differences: %w", err)
+	}
+
+	return diffs, nil
+}
+
+// hasMergeCommit returns whether any of the given commits has multiple parents.
+func hasMergeCommit(commits []*codecommit.Commit) bool {
+	for _, c := range commits {
+		if len(c.Parents) > 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -19,6 +19,7 @@ package awscognitoidentitysource
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -47,6 +48,16 @@ type envConfig struct {
 	ARN string `envconfig:"ARN" required:"true"`
 }
 
+// Maximum page sizes accepted by the Cognito APIs.
+const (
+	maxIdentitiesPageSize = 60
+	maxRecordsPageSize    = 1024
+)
+
+// maxDatasetsPageSize is the maximum number of datasets per identity, which
+// allows listing them in a single page.
+const maxDatasetsPageSize = 20
+
 // adapter implements the source's adapter.
 type adapter struct {
 	logger *zap.SugaredLogger
@@ -57,6 +68,21 @@ type adapter struct {
 
 	arn            arn.ARN
 	identityPoolID string
+
+	// last observed state of each dataset
+	datasets map[datasetKey]*datasetState
+}
+
+// datasetKey uniquely identifies a dataset within an identity pool.
+type datasetKey struct {
+	identityID  string
+	datasetName string
+}
+
+// datasetState is the state of a dataset, as observed during a poll.
+type datasetState struct {
+	lastModifiedDate time.Time
+	syncCount        int64
 }
 
 // NewEnvConfig returns an accessor for the source's adapter envConfig.
@@ -86,6 +112,8 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 
 		arn:            arn,
 		identityPoolID: common.MustParseCognitoIdentityResource(arn.Resource),
+
+		datasets: make(map[datasetKey]*datasetState),
 	}
 }
 
@@ -93,44 +121,121 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 func (a *adapter) Start(ctx context.Context) error {
 	a.logger.Infof("Listening to AWS Cognito stream for Identity: %s", a.identityPoolID)
 
+	// The current state of each dataset must be recorded before any event
+	// gets sent. A failure to do so is retried at the next poll, like any
+	// other API error.
+	initialized := false
+
 	backoff := common.NewBackoff()
 
 	err := backoff.Run(ctx.Done(), func(ctx context.Context) (bool, error) {
-		resetBackoff := false
-		identities, err := a.getIdentities()
-		if err != nil {
-			a.logger.Error(err)
+		if !initialized {
+			if _, err := a.processDatasets(false); err != nil {
+				a.logger.Errorw("Failed to retrieve datasets", "error", err)
+				return false, nil
+			}
+			initialized = true
+			return false, nil
 		}
 
-		datasets, err := a.getDatasets(identities)
+		changed, err := a.processDatasets(true)
 		if err != nil {
-			a.logger.Error(err)
+			a.logger.Errorw("Failed to process datasets", "error", err)
+		}
+		return changed, nil
+	})
+
+	return err
+}
+
+// processDatasets sends an event with the records that changed in each
+// dataset of the identity pool since the previous poll, if notify is true.
+// It returns whether any dataset changed.
+//
+// Records are only listed for datasets whose modification date differs from
+// the one observed during the previous poll, starting after the last observed
+// sync count of the dataset.
+func (a *adapter) processDatasets(notify bool) (bool, error) {
+	identities, err := a.getIdentities()
+	if err != nil {
+		return false, fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	datasets, err := a.getDatasets(identities)
+	if err != nil {
+		return false, fmt.Errorf("failed to list datasets: %w", err)
+	}
+
+	changed := false
+	current := make(map[datasetKey]struct{}, len(datasets))
+
+	for _, dataset := range datasets {
+		key := datasetKey{
+			identityID:  aws.StringValue(dataset.IdentityId),
+			datasetName: aws.StringValue(dataset.DatasetName),
 		}
+		current[key] = struct{}{}
 
-		for _, dataset := range datasets {
-			resetBackoff = true
-			records, err := a.getRecords(dataset)
+		lastModified := aws.TimeValue(dataset.LastModifiedDate)
+
+		prev, seen := a.datasets[key]
+		if seen && prev.lastModifiedDate.Equal(lastModified) {
+			continue
+		}
+
+		// the first observation of the identity pool only serves as a
+		// reference for subsequent polls
+		if !notify {
+			syncCount, err := a.getSyncCount(dataset)
 			if err != nil {
-				a.logger.Error(err)
-				continue
+				return changed, fmt.Errorf("failed to get sync count of dataset %q: %w", key.datasetName, err)
 			}
 
-			err = a.sendCognitoEvent(dataset, records)
-			if err != nil {
-				a.logger.Errorf("SendCloudEvent failed: %v", err)
+			a.datasets[key] = &datasetState{
+				lastModifiedDate: lastModified,
+				syncCount:        syncCount,
 			}
+			continue
 		}
-		return resetBackoff, nil
-	})
 
-	return err
+		var lastSyncCount int64
+		if seen {
+			lastSyncCount = prev.syncCount
+		}
+
+		records, syncCount, err := a.getRecords(dataset, lastSyncCount)
+		if err != nil {
+			return changed, fmt.Errorf("failed to list records of dataset %q: %w", key.datasetName, err)
+		}
+
+		if len(records) > 0 {
+			changed = true
+			if err := a.sendCognitoEvent(dataset, records, syncCount); err != nil {
+				return changed, fmt.Errorf("failed to send event: %w", err)
+			}
+		}
+
+		a.datasets[key] = &datasetState{
+			lastModifiedDate: lastModified,
+			syncCount:        syncCount,
+		}
+	}
+
+	// forget about deleted datasets
+	for key := range a.datasets {
+		if _, exists := current[key]; !exists {
+			delete(a.datasets, key)
+		}
+	}
+
+	return changed, nil
 }
 
 func (a *adapter) getIdentities() ([]*cognitoidentity.IdentityDescription, error) {
 	identities := []*cognitoidentity.IdentityDescription{}
 
 	listIdentitiesInput := cognitoidentity.ListIdentitiesInput{
-		MaxResults:     aws.Int64(1),
+		MaxResults:     aws.Int64(maxIdentitiesPageSize),
 		IdentityPoolId: &a.identityPoolID,
 	}
 
@@ -158,6 +263,7 @@ func (a *adapter) getDatasets(identities []*cognitoidentity.IdentityDescription)
 		listDatasetsInput := cognitosync.ListDatasetsInput{
 			IdentityPoolId: &a.identityPoolID,
 			IdentityId:     identity.IdentityId,
+			MaxResults:     aws.Int64(maxDatasetsPageSize),
 		}
 
 		for {
@@ -178,33 +284,59 @@ func (a *adapter) getDatasets(identities []*cognitoidentity.IdentityDescription)
 	return datasets, nil
 }
 
-func (a *adapter) getRecords(dataset *cognitosync.Dataset) ([]*cognitosync.Record, error) {
+// getRecords returns the records of the given dataset which were modified
+// after the given sync count, together with the current sync count of the
+// dataset.
+func (a *adapter) getRecords(dataset *cognitosync.Dataset, lastSyncCount int64) ([]*cognitosync.Record, int64, error) {
 	records := []*cognitosync.Record{}
 
 	input := cognitosync.ListRecordsInput{
 		DatasetName:    dataset.DatasetName,
 		IdentityId:     dataset.IdentityId,
 		IdentityPoolId: &a.identityPoolID,
+		LastSyncCount:  &lastSyncCount,
+		MaxResults:     aws.Int64(maxRecordsPageSize),
 	}
 
 	for {
 		recordsOutput, err := a.cgnSyncClient.ListRecords(&input)
 		if err != nil {
-			return records, err
+			return records, 0, err
+		}
+
+		// the dataset was re-created, all its records are new
+		if aws.BoolValue(recordsOutput.DatasetDeletedAfterRequestedSyncCount) && *input.LastSyncCount != 0 {
+			records = records[:0]
+			input.LastSyncCount = aws.Int64(0)
+			input.NextToken = nil
+			continue
 		}
 
 		records = append(records, recordsOutput.Records...)
 
 		input.NextToken = recordsOutput.NextToken
 		if recordsOutput.NextToken == nil {
-			break
+			return records, aws.Int64Value(recordsOutput.DatasetSyncCount), nil
 		}
 	}
+}
+
+// getSyncCount returns the current sync count of the given dataset.
+func (a *adapter) getSyncCount(dataset *cognitosync.Dataset) (int64, error) {
+	recordsOutput, err := a.cgnSyncClient.ListRecords(&cognitosync.ListRecordsInput{
+		DatasetName:    dataset.DatasetName,
+		IdentityId:     dataset.IdentityId,
+		IdentityPoolId: &a.identityPoolID,
+		MaxResults:     aws.Int64(1),
+	})
+	if err != nil {
+		return 0, err
+	}
 
-	return records, nil
+	return aws.Int64Value(recordsOutput.DatasetSyncCount), nil
 }
 
-func (a *adapter) sendCognitoEvent(dataset *cognitosync.Dataset, records []*cognitosync.Record) error {
+func (a *adapter) sendCognitoEvent(dataset *cognitosync.Dataset, records []*cognitosync.Record, syncCount int64) error {
 	a.logger.Info("Processing Dataset: ", *dataset.DatasetName)
 
 	data := &CognitoIdentitySyncEvent{
@@ -225,7 +357,11 @@ func (a *adapter) sendCognitoEvent(dataset *cognitosync.Dataset, records []*cogn
 	event.SetType(v1alpha1.AWSEventType(a.arn.Service, v1alpha1.AWSCognitoIdentityGenericEventType))
 	event.SetSubject(*dataset.DatasetName)
 	event.SetSource(a.arn.String())
-	event.SetID(*dataset.IdentityId)
+	// a dataset's sync count is incremented with every change
+	event.SetID(fmt.Sprintf("%s/%s/%d", *dataset.IdentityId, *dataset.DatasetName, syncCount))
+	if dataset.LastModifiedDate != nil {
+		event.SetTime(*dataset.LastModifiedDate)
+	}
 	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
 		return fmt.Errorf("failed to set event data: %w", err)
 	}
@@ -19,8 +19,10 @@ package awscognitoidentitysource
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cognitoidentity"
@@ -58,6 +60,158 @@ func (m mockedCognitoSyncClient) ListRecords(in *cognitosync.ListRecordsInput) (
 	return &m.listRecordsOutput, m.listRecordsOutputError
 }
 
+// mockedCognitoSyncStore is a mocked Cognito Sync client backed by an
+// in-memory set of datasets.
+type mockedCognitoSyncStore struct {
+	cognitosynciface.CognitoSyncAPI
+
+	// datasets indexed by identity ID
+	datasets map[string][]*cognitosync.Dataset
+	// records indexed by dataset name
+	records map[string][]*cognitosync.Record
+
+	// sync counts requested in calls to ListRecords
+	lastSyncCounts *[]int64
+}
+
+func (m mockedCognitoSyncStore) ListDatasets(in *cognitosync.ListDatasetsInput) (*cognitosync.ListDatasetsOutput, error) {
+	return &cognitosync.ListDatasetsOutput{Datasets: m.datasets[*in.IdentityId]}, nil
+}
+
+func (m mockedCognitoSyncStore) ListRecords(in *cognitosync.ListRecordsInput) (*cognitosync.ListRecordsOutput, error) {
+	lastSyncCount := aws.Int64Value(in.LastSyncCount)
+	*m.lastSyncCounts = append(*m.lastSyncCounts, lastSyncCount)
+
+	var syncCount int64
+	var records []*cognitosync.Record
+
+	for _, r := range m.records[*in.DatasetName] {
+		if *r.SyncCount > syncCount {
+			syncCount = *r.SyncCount
+		}
+		if *r.SyncCount > lastSyncCount {
+			records = append(records, r)
+		}
+	}
+
+	return &cognitosync.ListRecordsOutput{
+		Records:          records,
+		DatasetSyncCount: &syncCount,
+	}, nil
+}
+
+func TestProcessDatasets(t *testing.T) {
+	t0 := time.Unix(0, 0).UTC()
+	t1 := t0.Add(time.Minute)
+
+	identityClient := mockedCognitoIdentityClient{
+		listIdentitiesOutput: cognitoidentity.ListIdentitiesOutput{
+			Identities: []*cognitoidentity.IdentityDescription{{
+				IdentityId: aws.String("id1"),
+			}},
+		},
+	}
+
+	dataset := func(name string, lastModified time.Time) *cognitosync.Dataset {
+		return &cognitosync.Dataset{
+			IdentityId:       aws.String("id1"),
+			DatasetName:      aws.String(name),
+			LastModifiedDate: &lastModified,
+		}
+	}
+
+	record := func(key string, syncCount int64) *cognitosync.Record {
+		return &cognitosync.Record{
+			Key:       aws.String(key),
+			SyncCount: &syncCount,
+		}
+	}
+
+	var lastSyncCounts []int64
+
+	syncClient := mockedCognitoSyncStore{
+		datasets: map[string][]*cognitosync.Dataset{
+			"id1": {dataset("ds1", t0), dataset("ds2", t0)},
+		},
+		records: map[string][]*cognitosync.Record{
+			"ds1": {record("a", 1), record("b", 2)},
+			"ds2": {record("c", 1)},
+		},
+		lastSyncCounts: &lastSyncCounts,
+	}
+
+	ceClient := adaptertest.NewTestClient()
+
+	a := &adapter{
+		logger:            loggingtesting.TestLogger(t),
+		cgnIdentityClient: identityClient,
+		cgnSyncClient:     syncClient,
+		ceClient:          ceClient,
+
+		identityPoolID: "fooPool",
+
+		datasets: make(map[datasetKey]*datasetState),
+	}
+
+	// first poll only records the state of datasets
+
+	changed, err := a.processDatasets(false)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, ceClient.Sent())
+
+	// unmodified datasets are skipped
+
+	lastSyncCounts = nil
+
+	changed, err = a.processDatasets(true)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, ceClient.Sent())
+	assert.Empty(t, lastSyncCounts, "Expected records to be listed only for modified datasets")
+
+	// only modified records are sent
+
+	syncClient.datasets["id1"] = []*cognitosync.Dataset{dataset("ds1", t1), dataset("ds2", t0), dataset("ds3", t1)}
+	syncClient.records["ds1"] = []*cognitosync.Record{record("a", 3), record("b", 2)}
+	syncClient.records["ds3"] = []*cognitosync.Record{record("d", 1)}
+
+	changed, err = a.processDatasets(true)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []int64{2, 0}, lastSyncCounts)
+
+	sent := ceClient.Sent()
+	require.Len(t, sent, 2)
+
+	expectRecords := map[string][]string{
+		"id1/ds1/3": {"a"},
+		"id1/ds3/1": {"d"},
+	}
+
+	for _, e := range sent {
+		var data CognitoIdentitySyncEvent
+		require.NoError(t, e.DataAs(&data))
+
+		var keys []string
+		for _, r := range data.DatasetRecords {
+			keys = append(keys, *r.Key)
+		}
+		assert.Equal(t, expectRecords[e.ID()], keys, "Unexpected records for event %s", e.ID())
+		assert.Equal(t, t1, e.Time().UTC())
+	}
+
+	assert.Equal(t, &datasetState{lastModifiedDate: t1, syncCount: 3}, a.datasets[datasetKey{"id1", "ds1"}])
+
+	// deleted datasets are forgotten
+
+	syncClient.datasets["id1"] = []*cognitosync.Dataset{dataset("ds1", t1)}
+
+	_, err = a.processDatasets(true)
+	require.NoError(t, err)
+	assert.Len(t, a.datasets, 1)
+}
+
 func TestGetIdentities(t *testing.T) {
 	a := &adapter{
 		logger: loggingtesting.TestLogger(t),
@@ -126,20 +280,22 @@ func TestGetRecords(t *testing.T) {
 		listRecordsOutputError: errors.New("fake ListRecords error"),
 	}
 
-	records, err := a.getRecords(&dataset)
+	records, _, err := a.getRecords(&dataset, 0)
 	assert.Error(t, err)
 	assert.Equal(t, 0, len(records))
 
 	a.cgnSyncClient = mockedCognitoSyncClient{
 		listRecordsOutput: cognitosync.ListRecordsOutput{
-			Records: []*cognitosync.Record{{}, {}},
+			Records:          []*cognitosync.Record{{}, {}},
+			DatasetSyncCount: aws.Int64(2),
 		},
 		listRecordsOutputError: nil,
 	}
 
-	records, err = a.getRecords(&dataset)
+	records, syncCount, err := a.getRecords(&dataset, 0)
 	assert.NoError(t, err)
 	assert.Equal(t, 2, len(records))
+	assert.Equal(t, int64(2), syncCount)
 }
 
 func TestSendCognitoEvent(t *testing.T) {
@@ -157,11 +313,12 @@ func TestSendCognitoEvent(t *testing.T) {
 	}
 	records := []*cognitosync.Record{}
 
-	err := a.sendCognitoEvent(&dataset, records)
+	err := a.sendCognitoEvent(&dataset, records, 3)
 	assert.NoError(t, err)
 
 	gotEvents := ceClient.Sent()
 	assert.Len(t, gotEvents, 1, "Expected 1 event, got %d", len(gotEvents))
+	assert.Equal(t, "3234234/foo/3", gotEvents[0].ID())
 
 	wantData := `{"CreationDate":null,"DataStorage":null,"DatasetName":"foo","IdentityID":"3234234","LastModifiedBy":null,"LastModifiedDate":null,"NumRecords":null,"EventType":"SyncTrigger","Region":"","IdentityPoolID":"fooPool","DatasetRecords":[]}`
 	gotData := string(gotEvents[0].Data())
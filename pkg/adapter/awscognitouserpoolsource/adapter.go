@@ -19,10 +19,10 @@ package awscognitouserpoolsource
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"go.uber.org/zap"
-	"k8s.io/apimachinery/pkg/util/uuid"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 
@@ -33,11 +33,15 @@ import (
 	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
 
 	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	k8sclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/logging"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/common"
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/health"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/store"
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client"
 )
 
 // envConfig is a set parameters sourced from the environment for the source's
@@ -45,7 +49,12 @@ import (
 type envConfig struct {
 	pkgadapter.EnvConfig
 
-	ARN string `envconfig:"ARN" required:"true"`
+	ARN         string `envconfig:"ARN" required:"true"`
+	TrackGroups bool   `envconfig:"TRACK_GROUP_MEMBERSHIP"`
+
+	// Secret shared with the relay of Cognito Lambda triggers. Trigger
+	// payloads are only ingested when this value is set.
+	TriggersSharedSecret string `envconfig:"COGNITO_TRIGGERS_SHARED_SECRET"`
 }
 
 // adapter implements the source's adapter.
@@ -55,8 +64,17 @@ type adapter struct {
 	cgnIdentityClient cognitoidentityprovideriface.CognitoIdentityProviderAPI
 	ceClient          cloudevents.Client
 
-	arn        arn.ARN
-	userPoolID string
+	arn         arn.ARN
+	userPoolID  string
+	trackGroups bool
+
+	// persistent storage for the snapshot of the user pool
+	store store.Store
+	// last snapshot of the user pool
+	snapshot userPoolSnapshot
+
+	// handler of Cognito Lambda trigger payloads, if enabled
+	triggers *triggersHandler
 }
 
 // NewEnvConfig returns an accessor for the source's adapter envConfig.
@@ -77,20 +95,71 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 		WithMaxRetries(5),
 	))
 
+	// The snapshot is owned by the source object, so it gets garbage
+	// collected together with it.
+	src, err := client.Get(ctx).SourcesV1alpha1().AWSCognitoUserPoolSources(env.Namespace).
+		Get(ctx, env.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Fatalw("Failed to get source object", "error", err)
+	}
+
+	stateStore := store.NewConfigMapStore(
+		k8sclient.Get(ctx).CoreV1().ConfigMaps(env.Namespace),
+		kmeta.ChildName(env.Component+"-"+env.Name, "-state"),
+		store.OwnerReference(src),
+	)
+
+	a := newAdapter(logger, cognitoidentityprovider.New(cfg), ceClient, arn,
+		common.MustParseCognitoUserPoolResource(arn.Resource), env.TrackGroups, stateStore)
+
+	if env.TriggersSharedSecret != "" {
+		a.triggers = &triggersHandler{
+			logger:       logger,
+			sharedSecret: []byte(env.TriggersSharedSecret),
+			userPoolID:   a.userPoolID,
+			send:         a.sendCognitoEvent,
+		}
+	}
+
+	return a
+}
+
+// newAdapter returns an adapter which polls the users of the given user pool.
+func newAdapter(logger *zap.SugaredLogger, cli cognitoidentityprovideriface.CognitoIdentityProviderAPI,
+	ceClient cloudevents.Client, arn arn.ARN, userPoolID string, trackGroups bool, stateStore store.Store) *adapter {
+
 	return &adapter{
 		logger: logger,
 
-		cgnIdentityClient: cognitoidentityprovider.New(cfg),
+		cgnIdentityClient: cli,
 		ceClient:          ceClient,
 
-		arn:        arn,
-		userPoolID: common.MustParseCognitoUserPoolResource(arn.Resource),
+		arn:         arn,
+		userPoolID:  userPoolID,
+		trackGroups: trackGroups,
+
+		store: stateStore,
 	}
 }
 
 // Start implements adapter.Adapter.
 func (a *adapter) Start(ctx context.Context) error {
-	go health.Start(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The adapter of a source which ingests Lambda triggers runs as a
+	// Knative Service, which exposes a single port. The health endpoint
+	// is served by the triggers handler in that case.
+	triggersErrCh := make(chan error, 1)
+	if a.triggers != nil {
+		go func() {
+			triggersErrCh <- runTriggersHandler(ctx, a.triggers)
+			cancel()
+		}()
+	} else {
+		close(triggersErrCh)
+		go health.Start(ctx)
+	}
 
 	if err := validatePool(a.cgnIdentityClient, a.userPoolID); err != nil {
 		return fmt.Errorf("validating user pool: %w", err)
@@ -100,75 +169,45 @@ func (a *adapter) Start(ctx context.Context) error {
 
 	a.logger.Infof("Listening to AWS Cognito User Pool: %s", a.userPoolID)
 
-	var latestTimestamp time.Time
+	err := a.pollUsers(ctx)
 
-	backoff := common.NewBackoff()
+	// polling may stop on its own, in which case the triggers handler
+	// must be stopped too
+	cancel()
 
-	err := backoff.Run(ctx.Done(), func(ctx context.Context) (bool, error) {
-		resetBackoff := false
-		users, err := a.listUsers()
-		if err != nil {
-			a.logger.Errorf("Cognito ListUsers failed: %v", err)
-			return resetBackoff, err
-		}
-
-		users, latestTimestamp = filterByTimestamp(users, latestTimestamp)
-
-		for _, user := range users {
-			// we have new users - reset backoff duration
-			resetBackoff = true
-			err := a.sendCognitoEvent(user)
-			if err != nil {
-				a.logger.Errorf("Failed to send cloudevent: %v", err)
-			}
-		}
-		return resetBackoff, nil
-	})
+	if err := <-triggersErrCh; err != nil {
+		return fmt.Errorf("running Lambda triggers handler: %w", err)
+	}
 
 	return err
 }
 
-func (a *adapter) listUsers() ([]*cognitoidentityprovider.UserType, error) {
-	input := &cognitoidentityprovider.ListUsersInput{
-		UserPoolId: &a.userPoolID,
-	}
-	output, err := a.cgnIdentityClient.ListUsers(input)
-	if err != nil {
-		return nil, err
+// pollUsers records the current state of the user pool, then polls it for
+// changes until the given context is cancelled.
+func (a *adapter) pollUsers(ctx context.Context) error {
+	if err := a.loadSnapshot(ctx); err != nil {
+		return fmt.Errorf("recording users: %w", err)
 	}
-	if output == nil {
-		return nil, fmt.Errorf("cognito ListUsers response is nil")
-	}
-	return output.Users, nil
-}
 
-func filterByTimestamp(users []*cognitoidentityprovider.UserType, latestTimestamp time.Time) ([]*cognitoidentityprovider.UserType, time.Time) {
-	var newUsers []*cognitoidentityprovider.UserType
-	newLatestTimestamp := latestTimestamp
-	for _, user := range users {
-		// Get latest modification timestamp from users list
-		// and store it in temporary variable
-		if user.UserLastModifiedDate.After(newLatestTimestamp) {
-			newLatestTimestamp = *user.UserLastModifiedDate
-		}
-		// latest.isZero() true in first iteration - do not send already existing users.
-		// Also, do not send user object if it was not modified after our latest timestamp mark.
-		// (we use "not after" because "before" will be always false for the last created user)
-		if latestTimestamp.IsZero() || !user.UserLastModifiedDate.After(latestTimestamp) {
-			continue
+	backoff := common.NewBackoff()
+
+	return backoff.Run(ctx.Done(), func(ctx context.Context) (bool, error) {
+		changed, err := a.processUsers(ctx, true)
+		if err != nil {
+			a.logger.Errorw("Failed to process users", "error", err)
 		}
-		newUsers = append(newUsers, user)
-	}
-	return newUsers, newLatestTimestamp
+		return changed, nil
+	})
 }
 
-func (a *adapter) sendCognitoEvent(user *cognitoidentityprovider.UserType) error {
+// sendCognitoEvent sends an event of the given type about the given user.
+func (a *adapter) sendCognitoEvent(typ, username, id string, data interface{}) error {
 	event := cloudevents.NewEvent(cloudevents.VersionV1)
-	event.SetSubject(a.userPoolID)
+	event.SetSubject(username)
 	event.SetSource(a.arn.String())
-	event.SetID(string(uuid.NewUUID()))
-	event.SetType(v1alpha1.AWSEventType(a.arn.Service, v1alpha1.AWSCognitoUserPoolGenericEventType))
-	if err := event.SetData(cloudevents.ApplicationJSON, user); err != nil {
+	event.SetID(id)
+	event.SetType(v1alpha1.AWSEventType(a.arn.Service, typ))
+	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
 		return fmt.Errorf("failed to set event data: %w", err)
 	}
 
@@ -18,26 +18,78 @@ package awscognitouserpoolsource
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
 	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider/cognitoidentityprovideriface"
 
+	"k8s.io/client-go/kubernetes/fake"
+
 	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
 	loggingtesting "knative.dev/pkg/logging/testing"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/store"
 )
 
+// mockedCognitoUserPoolClient is a mocked Cognito User Pools client backed by
+// an in-memory set of users.
 type mockedCognitoUserPoolClient struct {
 	cognitoidentityprovideriface.CognitoIdentityProviderAPI
-	listUsersOutput cognitoidentityprovider.ListUsersOutput
+
+	users    []*cognitoidentityprovider.UserType
+	usersErr error
+	// usernames indexed by group name
+	groups map[string][]string
+}
+
+// ListUsersPages returns the users of the user pool, one per page.
+func (m mockedCognitoUserPoolClient) ListUsersPages(_ *cognitoidentityprovider.ListUsersInput,
+	fn func(*cognitoidentityprovider.ListUsersOutput, bool) bool) error {
+
+	if m.usersErr != nil {
+		return m.usersErr
+	}
+
+	for i, u := range m.users {
+		out := &cognitoidentityprovider.ListUsersOutput{Users: []*cognitoidentityprovider.UserType{u}}
+		if !fn(out, i == len(m.users)-1) {
+			break
+		}
+	}
+	return nil
 }
 
-func (m mockedCognitoUserPoolClient) ListUsers(in *cognitoidentityprovider.ListUsersInput) (*cognitoidentityprovider.ListUsersOutput, error) {
-	return &m.listUsersOutput, nil
+func (m mockedCognitoUserPoolClient) ListGroupsPages(_ *cognitoidentityprovider.ListGroupsInput,
+	fn func(*cognitoidentityprovider.ListGroupsOutput, bool) bool) error {
+
+	out := &cognitoidentityprovider.ListGroupsOutput{}
+	for g := range m.groups {
+		out.Groups = append(out.Groups, &cognitoidentityprovider.GroupType{GroupName: aws.String(g)})
+	}
+	fn(out, true)
+	return nil
+}
+
+func (m mockedCognitoUserPoolClient) ListUsersInGroupPages(in *cognitoidentityprovider.ListUsersInGroupInput,
+	fn func(*cognitoidentityprovider.ListUsersInGroupOutput, bool) bool) error {
+
+	out := &cognitoidentityprovider.ListUsersInGroupOutput{}
+	for _, u := range m.groups[*in.GroupName] {
+		out.Users = append(out.Users, &cognitoidentityprovider.UserType{Username: aws.String(u)})
+	}
+	fn(out, true)
+	return nil
 }
 
 func (mockedCognitoUserPoolClient) DescribeUserPool(*cognitoidentityprovider.DescribeUserPoolInput) (*cognitoidentityprovider.DescribeUserPoolOutput, error) {
@@ -45,66 +97,28 @@ func (mockedCognitoUserPoolClient) DescribeUserPool(*cognitoidentityprovider.Des
 }
 
 func TestListUsers(t *testing.T) {
-	user := cognitoidentityprovider.UserType{
-		UserLastModifiedDate: aws.Time(time.Now()),
-	}
+	user1 := cognitoidentityprovider.UserType{Username: aws.String("user1")}
+	user2 := cognitoidentityprovider.UserType{Username: aws.String("user2")}
 
 	a := &adapter{
 		userPoolID: "userpool/fooPool",
 		logger:     loggingtesting.TestLogger(t),
 		ceClient:   adaptertest.NewTestClient(),
 		cgnIdentityClient: mockedCognitoUserPoolClient{
-			listUsersOutput: cognitoidentityprovider.ListUsersOutput{
-				Users: []*cognitoidentityprovider.UserType{
-					&user,
-				},
-			},
+			users: []*cognitoidentityprovider.UserType{&user1, &user2},
 		},
 	}
 
 	users, err := a.listUsers()
 	assert.NoError(t, err)
-	assert.Equal(t, []*cognitoidentityprovider.UserType{&user}, users)
-}
-
-func TestFilterByTimestamp(t *testing.T) {
-	now := time.Unix(0, 0)
-
-	// existing user
-	user1 := cognitoidentityprovider.UserType{
-		UserLastModifiedDate: &now,
-	}
-
-	// we must get 0 new users
-	users, timestamp := filterByTimestamp([]*cognitoidentityprovider.UserType{
-		&user1,
-	}, now)
-	assert.Len(t, users, 0)
-
-	// new user
-	user2 := cognitoidentityprovider.UserType{
-		UserLastModifiedDate: aws.Time(time.Unix(0, 1)),
-	}
-
-	// we must get user2 only
-	users, timestamp = filterByTimestamp([]*cognitoidentityprovider.UserType{
-		&user1,
-		&user2,
-	}, timestamp)
-	assert.Equal(t, []*cognitoidentityprovider.UserType{&user2}, users)
-
-	// repeat filtering, we must ignore user2
-	users, _ = filterByTimestamp([]*cognitoidentityprovider.UserType{
-		&user1,
-		&user2,
-	}, timestamp)
-	assert.Len(t, users, 0)
+	assert.Equal(t, []*cognitoidentityprovider.UserType{&user1, &user2}, users)
 }
 
 func TestSendCognitoEvent(t *testing.T) {
 	ceClient := adaptertest.NewTestClient()
 
 	user := cognitoidentityprovider.UserType{
+		Username:             aws.String("user1"),
 		UserLastModifiedDate: aws.Time(time.Now().UTC()),
 	}
 
@@ -115,11 +129,13 @@ func TestSendCognitoEvent(t *testing.T) {
 		cgnIdentityClient: mockedCognitoUserPoolClient{},
 	}
 
-	err := a.sendCognitoEvent(&user)
+	err := a.sendCognitoEvent("user_created", "user1", "user1/1/user_created", &user)
 	assert.NoError(t, err)
 
 	events := ceClient.Sent()
 	assert.Len(t, events, 1)
+	assert.Equal(t, "user1", events[0].Subject())
+	assert.Equal(t, "user1/1/user_created", events[0].ID())
 
 	var gotUser cognitoidentityprovider.UserType
 	err = events[0].DataAs(&gotUser)
@@ -128,6 +144,253 @@ func TestSendCognitoEvent(t *testing.T) {
 	assert.Equal(t, user, gotUser)
 }
 
+func TestProcessUsers(t *testing.T) {
+	newUser := func(name, status string, enabled bool, email string) *cognitoidentityprovider.UserType {
+		return &cognitoidentityprovider.UserType{
+			Username:   aws.String(name),
+			UserStatus: aws.String(status),
+			Enabled:    aws.Bool(enabled),
+			Attributes: []*cognitoidentityprovider.AttributeType{{
+				Name:  aws.String("email"),
+				Value: aws.String(email),
+			}},
+		}
+	}
+
+	const (
+		unconfirmed = cognitoidentityprovider.UserStatusTypeUnconfirmed
+		confirmed   = cognitoidentityprovider.UserStatusTypeConfirmed
+	)
+
+	ctx := context.Background()
+
+	cli := &mockedCognitoUserPoolClient{
+		users: []*cognitoidentityprovider.UserType{
+			newUser("user1", confirmed, true, "user1@example.com"),
+			newUser("user2", unconfirmed, true, "user2@example.com"),
+		},
+		groups: map[string][]string{
+			"admins": {"user1"},
+		},
+	}
+
+	ceClient := adaptertest.NewTestClient()
+
+	cmCli := fake.NewSimpleClientset().CoreV1().ConfigMaps("test-ns")
+
+	newAdapter := func() *adapter {
+		return &adapter{
+			logger:            loggingtesting.TestLogger(t),
+			cgnIdentityClient: cli,
+			ceClient:          ceClient,
+
+			arn:         arn.ARN{Service: "cognito-idp"},
+			userPoolID:  "fooPool",
+			trackGroups: true,
+
+			store: store.NewConfigMapStore(cmCli, "test-state", nil),
+		}
+	}
+
+	sentEvents := func() []string {
+		var events []string
+		for _, e := range ceClient.Sent() {
+			events = append(events, e.ID()+" "+e.Type())
+		}
+		sort.Strings(events)
+		ceClient.Reset()
+		return events
+	}
+
+	a := newAdapter()
+
+	// first poll only records the snapshot
+
+	err := a.loadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, sentEvents())
+
+	// nothing changed
+
+	changed, err := a.processUsers(ctx, true)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, sentEvents())
+
+	// users are changed, created and deleted
+
+	cli.users = []*cognitoidentityprovider.UserType{
+		newUser("user1", confirmed, false, "new-user1@example.com"),
+		newUser("user2", confirmed, true, "user2@example.com"),
+		newUser("user3", unconfirmed, true, "user3@example.com"),
+	}
+	cli.groups = map[string][]string{
+		"admins": {"user2"},
+		"users":  {"user1", "user3"},
+	}
+
+	changed, err = a.processUsers(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	expectEvents := []string{
+		"user1/2/user_added_to_group/users com.amazon.cognito-idp.user_added_to_group",
+		"user1/2/user_disabled com.amazon.cognito-idp.user_disabled",
+		"user1/2/user_removed_from_group/admins com.amazon.cognito-idp.user_removed_from_group",
+		"user1/2/user_updated com.amazon.cognito-idp.user_updated",
+		"user2/2/user_added_to_group/admins com.amazon.cognito-idp.user_added_to_group",
+		"user2/2/user_confirmed com.amazon.cognito-idp.user_confirmed",
+		"user3/1/user_added_to_group/users com.amazon.cognito-idp.user_added_to_group",
+		"user3/1/user_created com.amazon.cognito-idp.user_created",
+	}
+	assert.Equal(t, expectEvents, sentEvents())
+
+	cli.users = cli.users[1:]
+
+	changed, err = a.processUsers(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	expectEvents = []string{
+		"user1/3/user_deleted com.amazon.cognito-idp.user_deleted",
+	}
+	assert.Equal(t, expectEvents, sentEvents())
+
+	// the snapshot is restored by a new adapter instance
+
+	snapshot := a.snapshot
+
+	a = newAdapter()
+
+	err = a.loadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, a.snapshot)
+
+	changed, err = a.processUsers(ctx, true)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, sentEvents())
+}
+
+func TestTriggersHandler(t *testing.T) {
+	const secret = "s3cr3t"
+
+	const preSignUpPayload = `{"version":"1","triggerSource":"PreSignUp_SignUp","region":"us-east-1",` +
+		`"userPoolId":"fooPool","userName":"user1","request":{},"response":{}}`
+
+	testCases := map[string]struct {
+		method  string
+		auth    string
+		payload string
+
+		expectCode  int
+		expectEvent string
+	}{
+		"Valid payload": {
+			method:      http.MethodPost,
+			auth:        "Bearer " + secret,
+			payload:     preSignUpPayload,
+			expectCode:  http.StatusOK,
+			expectEvent: "com.amazon.cognito-idp.pre_sign_up",
+		},
+		"Token generation": {
+			method: http.MethodPost,
+			auth:   "Bearer " + secret,
+			payload: `{"triggerSource":"TokenGeneration_RefreshTokens",` +
+				`"userPoolId":"fooPool","userName":"user1"}`,
+			expectCode:  http.StatusOK,
+			expectEvent: "com.amazon.cognito-idp.pre_token_generation",
+		},
+		"Unsupported method": {
+			method:     http.MethodGet,
+			auth:       "Bearer " + secret,
+			expectCode: http.StatusMethodNotAllowed,
+		},
+		"Missing shared secret": {
+			method:     http.MethodPost,
+			payload:    preSignUpPayload,
+			expectCode: http.StatusUnauthorized,
+		},
+		"Wrong shared secret": {
+			method:     http.MethodPost,
+			auth:       "Bearer not" + secret,
+			payload:    preSignUpPayload,
+			expectCode: http.StatusUnauthorized,
+		},
+		"Malformed payload": {
+			method:     http.MethodPost,
+			auth:       "Bearer " + secret,
+			payload:    `{"triggerSource":`,
+			expectCode: http.StatusBadRequest,
+		},
+		"Other user pool": {
+			method: http.MethodPost,
+			auth:   "Bearer " + secret,
+			payload: `{"triggerSource":"PreSignUp_SignUp",` +
+				`"userPoolId":"barPool","userName":"user1"}`,
+			expectCode: http.StatusForbidden,
+		},
+		"Oversized payload": {
+			method:     http.MethodPost,
+			auth:       "Bearer " + secret,
+			payload:    `{"userName":"` + strings.Repeat("a", maxTriggerPayloadSize) + `"}`,
+			expectCode: http.StatusRequestEntityTooLarge,
+		},
+		"Unsupported trigger source": {
+			method: http.MethodPost,
+			auth:   "Bearer " + secret,
+			payload: `{"triggerSource":"Unknown_Trigger",` +
+				`"userPoolId":"fooPool","userName":"user1"}`,
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			ceClient := adaptertest.NewTestClient()
+
+			a := &adapter{
+				arn:      arn.ARN{Service: cognitoidentityprovider.ServiceName},
+				logger:   loggingtesting.TestLogger(t),
+				ceClient: ceClient,
+			}
+
+			h := &triggersHandler{
+				logger:       a.logger,
+				sharedSecret: []byte(secret),
+				userPoolID:   "fooPool",
+				send:         a.sendCognitoEvent,
+			}
+
+			req := httptest.NewRequest(tc.method, "/", strings.NewReader(tc.payload))
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+
+			events := ceClient.Sent()
+
+			if tc.expectEvent == "" {
+				assert.Empty(t, events)
+				return
+			}
+
+			require.Len(t, events, 1)
+			assert.Equal(t, tc.expectEvent, events[0].Type())
+			assert.Equal(t, "user1", events[0].Subject())
+			assert.JSONEq(t, tc.payload, string(events[0].Data()))
+
+			// the payload is returned to the relay unchanged
+			assert.JSONEq(t, tc.payload, rec.Body.String())
+		})
+	}
+}
+
 func TestStart(t *testing.T) {
 	const testTimeout = 2 * time.Second
 
@@ -136,6 +399,8 @@ func TestStart(t *testing.T) {
 		logger:            loggingtesting.TestLogger(t),
 		ceClient:          adaptertest.NewTestClient(),
 		cgnIdentityClient: mockedCognitoUserPoolClient{},
+
+		store: store.NewConfigMapStore(fake.NewSimpleClientset().CoreV1().ConfigMaps("test-ns"), "test-state", nil),
 	}
 
 	// create a context that will be done after testTimeout
@@ -164,3 +429,41 @@ func TestStart(t *testing.T) {
 		assert.NoError(t, err, "Receiver returned an error")
 	}
 }
+
+func TestStartPollingFailureWithTriggers(t *testing.T) {
+	const testTimeout = 2 * time.Second
+
+	a := &adapter{
+		userPoolID: "userpool/fooPool",
+		logger:     loggingtesting.TestLogger(t),
+		ceClient:   adaptertest.NewTestClient(),
+		cgnIdentityClient: mockedCognitoUserPoolClient{
+			usersErr: errors.New("fake error"),
+		},
+
+		triggers: &triggersHandler{
+			logger:       loggingtesting.TestLogger(t),
+			sharedSecret: []byte("s3cr3t"),
+			userPoolID:   "fooPool",
+		},
+
+		store: store.NewConfigMapStore(fake.NewSimpleClientset().CoreV1().ConfigMaps("test-ns"), "test-state", nil),
+	}
+
+	testCtx, testCancel := context.WithTimeout(context.Background(), testTimeout)
+	defer testCancel()
+
+	errCh := make(chan error)
+
+	go func() {
+		errCh <- a.Start(context.Background())
+	}()
+
+	// the adapter must exit on its own, without being sent a stop signal
+	select {
+	case <-testCtx.Done():
+		t.Errorf("Test timed out after %v", testTimeout)
+	case err := <-errCh:
+		assert.Error(t, err, "Expected the adapter to return the polling error")
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscognitouserpoolsource
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"knative.dev/pkg/logging"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/health"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/router"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	serverPort                uint16 = 8080
+	serverShutdownGracePeriod        = time.Second * 10
+
+	triggersPath = "/"
+	healthPath   = "/health"
+
+	// Maximum size of a trigger payload. Cognito trigger payloads are a
+	// few KiB large, this only protects the adapter from oversized
+	// requests.
+	maxTriggerPayloadSize = 1024 * 1024
+)
+
+// Event types of Cognito Lambda triggers, indexed by the prefix of their
+// trigger source, e.g. "PreSignUp" for "PreSignUp_AdminCreateUser".
+// https://docs.aws.amazon.com/cognito/latest/developerguide/cognito-user-identity-pools-working-with-aws-lambda-triggers.html#cognito-user-identity-pools-working-with-aws-lambda-trigger-sources
+var triggerEventTypes = map[string]string{
+	"PreSignUp":                   v1alpha1.AWSCognitoUserPoolPreSignUpEventType,
+	"PostConfirmation":            v1alpha1.AWSCognitoUserPoolPostConfirmationEventType,
+	"PreAuthentication":           v1alpha1.AWSCognitoUserPoolPreAuthenticationEventType,
+	"PostAuthentication":          v1alpha1.AWSCognitoUserPoolPostAuthenticationEventType,
+	"CustomMessage":               v1alpha1.AWSCognitoUserPoolCustomMessageEventType,
+	"TokenGeneration":             v1alpha1.AWSCognitoUserPoolPreTokenGenerationEventType,
+	"UserMigration":               v1alpha1.AWSCognitoUserPoolUserMigrationEventType,
+	"DefineAuthChallenge":         v1alpha1.AWSCognitoUserPoolDefineAuthChallengeEventType,
+	"CreateAuthChallenge":         v1alpha1.AWSCognitoUserPoolCreateAuthChallengeEventType,
+	"VerifyAuthChallengeResponse": v1alpha1.AWSCognitoUserPoolVerifyAuthChallengeResponseEventType,
+}
+
+// triggerEvent contains the attributes of a Cognito Lambda trigger payload
+// which are relevant to the event source.
+// https://docs.aws.amazon.com/cognito/latest/developerguide/cognito-user-identity-pools-working-with-aws-lambda-triggers.html#cognito-user-pools-lambda-trigger-event-parameter-shared
+type triggerEvent struct {
+	TriggerSource string `json:"triggerSource"`
+	UserPoolID    string `json:"userPoolId"`
+	UserName      string `json:"userName"`
+}
+
+// triggersHandler converts Cognito Lambda trigger payloads forwarded by a
+// relay function to CloudEvents.
+type triggersHandler struct {
+	logger *zap.SugaredLogger
+
+	sharedSecret []byte
+	userPoolID   string
+
+	send func(typ, username, id string, data interface{}) error
+}
+
+// Check that triggersHandler implements http.Handler.
+var _ http.Handler = (*triggersHandler)(nil)
+
+// ServeHTTP implements http.Handler.
+// Responds with the original payload, which the relay function can return to
+// Cognito as is.
+func (h *triggersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Unsupported method "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Invalid or missing shared secret", http.StatusUnauthorized)
+		return
+	}
+
+	if r.ContentLength > maxTriggerPayloadSize {
+		http.Error(w, "Request body exceeds the maximum payload size", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// the Content-Length header is optional, the size of the body must
+	// also be enforced while reading it
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxTriggerPayloadSize))
+	if err != nil {
+		handleError("Failed to read request body", err, http.StatusBadRequest, h.logger, w)
+		return
+	}
+
+	trigger := &triggerEvent{}
+	if err := json.Unmarshal(body, trigger); err != nil {
+		handleError("Failed to parse trigger payload", err, http.StatusBadRequest, h.logger, w)
+		return
+	}
+
+	if trigger.UserPoolID != h.userPoolID {
+		http.Error(w, "Trigger payload does not belong to user pool "+h.userPoolID, http.StatusForbidden)
+		return
+	}
+
+	typ, ok := triggerEventType(trigger.TriggerSource)
+	if !ok {
+		http.Error(w, "Unsupported trigger source "+trigger.TriggerSource, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.send(typ, trigger.UserName, uuid.New().String(), json.RawMessage(body)); err != nil {
+		handleError("Failed to send CloudEvent", err, http.StatusInternalServerError, h.logger, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// isAuthorized returns whether the given request carries the shared secret as
+// a bearer token.
+func (h *triggersHandler) isAuthorized(r *http.Request) bool {
+	const bearerPrefix = "Bearer "
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, bearerPrefix) {
+		return false
+	}
+
+	token := []byte(strings.TrimPrefix(auth, bearerPrefix))
+	return subtle.ConstantTimeCompare(token, h.sharedSecret) == 1
+}
+
+// triggerEventType returns the event type matching the given trigger source.
+func triggerEventType(triggerSource string) (string, bool) {
+	prefix := strings.SplitN(triggerSource, "_", 2)[0]
+	typ, ok := triggerEventTypes[prefix]
+	return typ, ok
+}
+
+// handleError logs the given error and writes it to the given ResponseWriter.
+func handleError(msg string, err error, httpCode int, logger *zap.SugaredLogger, w http.ResponseWriter) {
+	logger.Errorw(msg, zap.Error(err))
+	http.Error(w, fmt.Sprint(msg, ": ", err), httpCode)
+}
+
+// runTriggersHandler serves the given triggers handler over HTTP, together with
+// the health endpoint, until ctx gets cancelled.
+func runTriggersHandler(ctx context.Context, h *triggersHandler) error {
+	r := &router.Router{}
+	r.RegisterPath(triggersPath, h)
+	r.RegisterPath(healthPath, health.Handler())
+
+	s := &http.Server{
+		Addr:    fmt.Sprint(":", serverPort),
+		Handler: r,
+	}
+
+	logging.FromContext(ctx).Info("Starting HTTP handler for Lambda triggers")
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- s.ListenAndServe()
+	}()
+
+	handleServerError := func(err error) error {
+		if err != http.ErrServerClosed {
+			return fmt.Errorf("during server runtime: %w", err)
+		}
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		logging.FromContext(ctx).Info("HTTP handler for Lambda triggers is shutting down")
+
+		ctx, cancel := context.WithTimeout(context.Background(), serverShutdownGracePeriod)
+		defer cancel()
+
+		if err := s.Shutdown(ctx); err != nil {
+			return fmt.Errorf("during server shutdown: %w", err)
+		}
+
+		return handleServerError(<-errCh)
+
+	case err := <-errCh:
+		return handleServerError(err)
+	}
+}
@@ -0,0 +1,368 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscognitouserpoolsource
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+// maxPageSize is the maximum page size accepted by the listing operations of
+// the Cognito User Pools API.
+const maxPageSize = 60
+
+// stateKeyUsers is the key under which the snapshot of the user pool is
+// persisted. The snapshot of a large user pool exceeds the size of a single
+// ConfigMap, and is therefore split by the store across multiple ConfigMaps.
+const stateKeyUsers = "users"
+
+// userPoolSnapshot is a snapshot of the users of a user pool.
+type userPoolSnapshot struct {
+	// Users indexed by username.
+	Users map[string]*userSnapshot `json:"users"`
+	// Whether group memberships were recorded.
+	Groups bool `json:"groups,omitempty"`
+}
+
+// userSnapshot is a snapshot of a single user.
+type userSnapshot struct {
+	Status  string `json:"status"`
+	Enabled bool   `json:"enabled"`
+	// Digest of the user's attributes.
+	Attributes string `json:"attributes"`
+	// Names of the groups the user belongs to, sorted.
+	Groups []string `json:"groups,omitempty"`
+	// Incremented with every change of the user, to provide stable and
+	// unique event IDs.
+	Version int64 `json:"version"`
+}
+
+// GroupMembership is the payload of the events describing a change of the
+// groups a user belongs to.
+type GroupMembership struct {
+	Username  string
+	GroupName string
+}
+
+// loadSnapshot restores the snapshot persisted by a previous instance of the
+// adapter. When no snapshot was persisted, a snapshot of the current users is
+// recorded without sending any event, so it serves as a reference for
+// subsequent polls.
+func (a *adapter) loadSnapshot(ctx context.Context) error {
+	found, err := a.store.Load(ctx, stateKeyUsers, &a.snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot of users: %w", err)
+	}
+	if found && a.snapshot.Users != nil {
+		return nil
+	}
+
+	a.snapshot = userPoolSnapshot{
+		Users: make(map[string]*userSnapshot),
+	}
+
+	_, err = a.processUsers(ctx, false)
+	return err
+}
+
+// processUsers compares the current users of the user pool with the last
+// snapshot, and sends an event for each observed change, if notify is true.
+// It persists the resulting snapshot and returns whether any change was
+// observed.
+func (a *adapter) processUsers(ctx context.Context, notify bool) (bool, error) {
+	users, err := a.listUsers()
+	if err != nil {
+		return false, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	var groups map[string][]string
+	if a.trackGroups {
+		if groups, err = a.listGroupMemberships(); err != nil {
+			return false, fmt.Errorf("failed to list group memberships: %w", err)
+		}
+	}
+
+	// group memberships are compared only if they were recorded already
+	notifyGroups := notify && a.snapshot.Groups
+
+	changed := false
+	current := make(map[string]struct{}, len(users))
+
+	for _, user := range users {
+		username := aws.StringValue(user.Username)
+		current[username] = struct{}{}
+
+		curr := &userSnapshot{
+			Status:     aws.StringValue(user.UserStatus),
+			Enabled:    aws.BoolValue(user.Enabled),
+			Attributes: attributesDigest(user.Attributes),
+			Groups:     groups[username],
+		}
+
+		prev := a.snapshot.Users[username]
+		if prev != nil {
+			curr.Version = prev.Version
+			if reflect.DeepEqual(prev, curr) {
+				continue
+			}
+		}
+		curr.Version++
+
+		if notify {
+			for _, typ := range userChanges(prev, curr) {
+				id := eventID(username, curr.Version, typ)
+				if err := a.sendCognitoEvent(typ, username, id, user); err != nil {
+					return changed, fmt.Errorf("failed to send event: %w", err)
+				}
+			}
+		}
+
+		if notifyGroups {
+			var prevGroups []string
+			if prev != nil {
+				prevGroups = prev.Groups
+			}
+
+			added, removed := groupChanges(prevGroups, curr.Groups)
+
+			if err := a.sendGroupMembershipEvents(v1alpha1.AWSCognitoUserPoolUserAddedToGroupEventType,
+				username, curr.Version, added); err != nil {
+
+				return changed, err
+			}
+			if err := a.sendGroupMembershipEvents(v1alpha1.AWSCognitoUserPoolUserRemovedFromGroupEventType,
+				username, curr.Version, removed); err != nil {
+
+				return changed, err
+			}
+		}
+
+		a.snapshot.Users[username] = curr
+		changed = true
+	}
+
+	for username, prev := range a.snapshot.Users {
+		if _, exists := current[username]; exists {
+			continue
+		}
+
+		if notify {
+			user := &cognitoidentityprovider.UserType{Username: aws.String(username)}
+			typ := v1alpha1.AWSCognitoUserPoolUserDeletedEventType
+
+			id := eventID(username, prev.Version+1, typ)
+			if err := a.sendCognitoEvent(typ, username, id, user); err != nil {
+				return changed, fmt.Errorf("failed to send event: %w", err)
+			}
+		}
+
+		delete(a.snapshot.Users, username)
+		changed = true
+	}
+
+	if a.trackGroups != a.snapshot.Groups {
+		a.snapshot.Groups = a.trackGroups
+		changed = true
+	}
+
+	// the initial snapshot is persisted even if empty
+	if changed || !notify {
+		if err := a.store.Save(ctx, stateKeyUsers, &a.snapshot); err != nil {
+			return changed, fmt.Errorf("failed to persist snapshot of users: %w", err)
+		}
+	}
+
+	return changed, nil
+}
+
+// sendGroupMembershipEvents sends an event of the given type for each of the
+// given groups.
+func (a *adapter) sendGroupMembershipEvents(typ, username string, version int64, groupNames []string) error {
+	for _, g := range groupNames {
+		data := &GroupMembership{Username: username, GroupName: g}
+		id := eventID(username, version, typ) + "/" + g
+
+		if err := a.sendCognitoEvent(typ, username, id, data); err != nil {
+			return fmt.Errorf("failed to send event: %w", err)
+		}
+	}
+	return nil
+}
+
+// eventID returns the ID of the event of the given type which describes a
+// change of the given version of a user.
+func eventID(username string, version int64, typ string) string {
+	return username + "/" + strconv.FormatInt(version, 10) + "/" + typ
+}
+
+// listUsers returns all the users of the user pool.
+func (a *adapter) listUsers() ([]*cognitoidentityprovider.UserType, error) {
+	var users []*cognitoidentityprovider.UserType
+
+	in := &cognitoidentityprovider.ListUsersInput{
+		UserPoolId: &a.userPoolID,
+		Limit:      aws.Int64(maxPageSize),
+	}
+
+	err := a.cgnIdentityClient.ListUsersPages(in, func(out *cognitoidentityprovider.ListUsersOutput, lastPage bool) bool {
+		users = append(users, out.Users...)
+		return !lastPage
+	})
+
+	return users, err
+}
+
+// listGroupMemberships returns the sorted names of the groups each user of
+// the user pool belongs to, indexed by username.
+func (a *adapter) listGroupMemberships() (map[string][]string, error) {
+	var groupNames []string
+
+	listGroupsIn := &cognitoidentityprovider.ListGroupsInput{
+		UserPoolId: &a.userPoolID,
+		Limit:      aws.Int64(maxPageSize),
+	}
+
+	err := a.cgnIdentityClient.ListGroupsPages(listGroupsIn,
+		func(out *cognitoidentityprovider.ListGroupsOutput, lastPage bool) bool {
+			for _, g := range out.Groups {
+				groupNames = append(groupNames, aws.StringValue(g.GroupName))
+			}
+			return !lastPage
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing groups: %w", err)
+	}
+
+	sort.Strings(groupNames)
+
+	memberships := make(map[string][]string)
+
+	for _, groupName := range groupNames {
+		listUsersIn := &cognitoidentityprovider.ListUsersInGroupInput{
+			UserPoolId: &a.userPoolID,
+			GroupName:  aws.String(groupName),
+			Limit:      aws.Int64(maxPageSize),
+		}
+
+		err := a.cgnIdentityClient.ListUsersInGroupPages(listUsersIn,
+			func(out *cognitoidentityprovider.ListUsersInGroupOutput, lastPage bool) bool {
+				for _, u := range out.Users {
+					username := aws.StringValue(u.Username)
+					memberships[username] = append(memberships[username], groupName)
+				}
+				return !lastPage
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("listing users in group %q: %w", groupName, err)
+		}
+	}
+
+	return memberships, nil
+}
+
+// userChanges returns the types of the events describing the changes between
+// two snapshots of a user. A nil previous snapshot denotes a new user.
+func userChanges(prev, curr *userSnapshot) []string {
+	if prev == nil {
+		return []string{v1alpha1.AWSCognitoUserPoolUserCreatedEventType}
+	}
+
+	var types []string
+
+	statusChanged := prev.Status != curr.Status
+
+	if statusChanged && isConfirmation(prev.Status, curr.Status) {
+		types = append(types, v1alpha1.AWSCognitoUserPoolUserConfirmedEventType)
+		statusChanged = false
+	}
+
+	if statusChanged || prev.Attributes != curr.Attributes {
+		types = append(types, v1alpha1.AWSCognitoUserPoolUserUpdatedEventType)
+	}
+
+	if prev.Enabled != curr.Enabled {
+		if curr.Enabled {
+			types = append(types, v1alpha1.AWSCognitoUserPoolUserEnabledEventType)
+		} else {
+			types = append(types, v1alpha1.AWSCognitoUserPoolUserDisabledEventType)
+		}
+	}
+
+	return types
+}
+
+// isConfirmation returns whether the transition between the given user
+// statuses denotes the confirmation of a user account, either by the user
+// itself or after an administrator created it.
+func isConfirmation(prevStatus, currStatus string) bool {
+	return currStatus == cognitoidentityprovider.UserStatusTypeConfirmed &&
+		(prevStatus == cognitoidentityprovider.UserStatusTypeUnconfirmed ||
+			prevStatus == cognitoidentityprovider.UserStatusTypeForceChangePassword)
+}
+
+// groupChanges returns the names of the groups a user was added to and
+// removed from. Both lists must be sorted.
+func groupChanges(prev, curr []string) (added, removed []string) {
+	for _, g := range curr {
+		if !containsString(prev, g) {
+			added = append(added, g)
+		}
+	}
+	for _, g := range prev {
+		if !containsString(curr, g) {
+			removed = append(removed, g)
+		}
+	}
+
+	return added, removed
+}
+
+// containsString returns whether the given sorted list contains the given
+// string.
+func containsString(sorted []string, s string) bool {
+	i := sort.SearchStrings(sorted, s)
+	return i < len(sorted) && sorted[i] == s
+}
+
+// attributesDigest returns a digest of the given user attributes, which is
+// independent from their order.
+func attributesDigest(attrs []*cognitoidentityprovider.AttributeType) string {
+	kv := make([]string, len(attrs))
+	for i, attr := range attrs {
+		kv[i] = aws.StringValue(attr.Name) + "=" + aws.StringValue(attr.Value)
+	}
+	sort.Strings(kv)
+
+	h := fnv.New64a()
+	for _, s := range kv {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+
+	return strconv.FormatUint(h.Sum64(), 16)
+}
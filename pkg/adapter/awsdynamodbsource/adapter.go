@@ -35,11 +35,17 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
 	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	k8sclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/logging"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/common"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/sharding"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/store"
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client"
 )
 
 const (
@@ -60,6 +66,10 @@ type envConfig struct {
 	pkgadapter.EnvConfig
 
 	ARN string `envconfig:"ARN" required:"true"`
+
+	// Distribute the stream's shards across replicas of the adapter.
+	CoordinateShards bool   `envconfig:"COORDINATE_SHARDS"`
+	PodName          string `envconfig:"POD_NAME"`
 }
 
 // adapter implements the source's adapter.
@@ -72,7 +82,10 @@ type adapter struct {
 
 	arn arn.ARN
 
-	// tracker for running records processors
+	// coordinator of shards ownership between replicas, if enabled
+	shards *sharding.Coordinator
+
+	// tracker for running records processors, indexed by shard ID
 	processors sync.Map
 	wg         sync.WaitGroup
 
@@ -97,7 +110,7 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 		WithRegion(arn.Region),
 	))
 
-	return &adapter{
+	a := &adapter{
 		logger: logger,
 
 		dyndbClient:    dynamodb.New(cfg),
@@ -106,6 +119,24 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 
 		arn: arn,
 	}
+
+	if env.CoordinateShards {
+		// Leases are owned by the source object, so they get garbage
+		// collected together with it.
+		src, err := client.Get(ctx).SourcesV1alpha1().AWSDynamoDBSources(env.Namespace).
+			Get(ctx, env.Name, metav1.GetOptions{})
+		if err != nil {
+			logger.Fatalw("Failed to get source object", zap.Error(err))
+		}
+
+		a.shards = sharding.NewCoordinator(logger,
+			k8sclient.Get(ctx).CoordinationV1().Leases(env.Namespace),
+			store.OwnerReference(src),
+			env.Component+"-"+env.Name, env.PodName, sharding.DefaultLeaseDuration,
+		)
+	}
+
+	return a
 }
 
 // Start implements adapter.Adapter.
@@ -144,6 +175,12 @@ loop:
 	a.logger.Info("Waiting for termination of records processors")
 	a.wg.Wait()
 
+	if a.shards != nil {
+		if err := a.shards.Leave(context.Background()); err != nil {
+			return fmt.Errorf("leaving group of replicas: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -172,10 +209,13 @@ func (a *adapter) getLatestStreamARN(ctx context.Context) (*string, error) {
 	return table.Table.LatestStreamArn, nil
 }
 
-// recheckStream ensures a records processor is running for each of the stream's shards.
+// recheckStream ensures a records processor is running for each of the
+// stream's shards, or for each shard owned by the current replica when shards
+// are distributed across replicas.
 func (a *adapter) recheckStream(ctx context.Context, streamARN *string) error {
 	a.logger.Debug("Checking stream for new shards")
 
+	var shardIDs []*string
 	var lastEvaluatedShardID *string
 
 	for {
@@ -204,7 +244,7 @@ func (a *adapter) recheckStream(ctx context.Context, streamARN *string) error {
 		}
 
 		for _, s := range stream.StreamDescription.Shards {
-			a.ensureRecordsProcessor(ctx, streamARN, s.ShardId)
+			shardIDs = append(shardIDs, s.ShardId)
 		}
 
 		lastEvaluatedShardID = stream.StreamDescription.LastEvaluatedShardId
@@ -216,12 +256,46 @@ func (a *adapter) recheckStream(ctx context.Context, streamARN *string) error {
 		}
 	}
 
+	if a.shards == nil {
+		for _, shardID := range shardIDs {
+			a.ensureRecordsProcessor(ctx, streamARN, shardID)
+		}
+		return nil
+	}
+
+	ids := make([]string, len(shardIDs))
+	for i, id := range shardIDs {
+		ids[i] = *id
+	}
+
+	asgmt, err := a.shards.Sync(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("synchronizing shards ownership: %w", err)
+	}
+
+	for _, shardID := range asgmt.Revoked {
+		a.stopRecordsProcessor(shardID)
+	}
+
+	owned := make(map[string]struct{}, len(asgmt.Owned))
+	for _, shardID := range asgmt.Owned {
+		owned[shardID] = struct{}{}
+	}
+	for _, shardID := range shardIDs {
+		if _, isOwned := owned[*shardID]; isOwned {
+			a.ensureRecordsProcessor(ctx, streamARN, shardID)
+		}
+	}
+
 	return nil
 }
 
 // ensureRecordsProcessor ensures a records processor is running for the given shard.
 func (a *adapter) ensureRecordsProcessor(ctx context.Context, streamARN *string, shardID *string) {
-	if _, running := a.processors.LoadOrStore(*shardID, struct{}{}); running {
+	ctx, cancel := context.WithCancel(ctx)
+
+	if _, running := a.processors.LoadOrStore(*shardID, cancel); running {
+		cancel()
 		a.logger.Debug("Record processor already running for shard ID ", *shardID)
 		return
 	}
@@ -229,8 +303,10 @@ func (a *adapter) ensureRecordsProcessor(ctx context.Context, streamARN *string,
 	a.wg.Add(1)
 
 	go func() {
-		defer a.processors.Delete(*shardID)
 		defer a.wg.Done()
+		defer a.releaseShard(*shardID)
+		defer a.processors.Delete(*shardID)
+		defer cancel()
 
 		a.logger.Info("Starting records processor for shard ID ", *shardID)
 
@@ -243,13 +319,59 @@ func (a *adapter) ensureRecordsProcessor(ctx context.Context, streamARN *string,
 	}()
 }
 
+// stopRecordsProcessor stops the records processor of the given shard, if it
+// is running.
+func (a *adapter) stopRecordsProcessor(shardID string) {
+	if cancel, running := a.processors.Load(shardID); running {
+		a.logger.Info("Stopping records processor for shard ID ", shardID)
+		cancel.(context.CancelFunc)()
+	}
+}
+
+// releaseShard hands the given shard over to other replicas, if shards are
+// distributed across replicas.
+func (a *adapter) releaseShard(shardID string) {
+	if a.shards == nil {
+		return
+	}
+
+	if err := a.shards.Release(context.Background(), shardID); err != nil {
+		a.logger.Errorw("Failed to release shard ID "+shardID, zap.Error(err))
+	}
+}
+
 // runRecordsProcessor runs a records processor for the given shard.
 func (a *adapter) runRecordsProcessor(ctx context.Context, streamARN *string, shardID *string) error {
-	si, err := a.dyndbStrClient.GetShardIteratorWithContext(ctx, &dynamodbstreams.GetShardIteratorInput{
+	siInput := &dynamodbstreams.GetShardIteratorInput{
 		StreamArn:         streamARN,
 		ShardId:           shardID,
 		ShardIteratorType: aws.String(dynamodbstreams.ShardIteratorTypeLatest),
-	})
+	}
+
+	// records created before that time were already sent by the first
+	// owner of the shard
+	var skipBefore time.Time
+
+	// resume after the last record processed by the previous owner of the
+	// shard, if any
+	if a.shards != nil {
+		switch seq := a.shards.LastCheckpoint(*shardID); {
+		case seq != "":
+			siInput.ShardIteratorType = aws.String(dynamodbstreams.ShardIteratorTypeAfterSequenceNumber)
+			siInput.SequenceNumber = &seq
+		case a.shards.WasHandedOver(*shardID):
+			// the previous owner of the shard may have stopped
+			// before sending the first records it read, which
+			// were created after it started reading the shard.
+			// DynamoDB Streams can't start reading a shard at a
+			// given time, so older records are skipped instead.
+			siInput.ShardIteratorType = aws.String(dynamodbstreams.ShardIteratorTypeTrimHorizon)
+			// creation times are rounded down to the second
+			skipBefore = a.shards.StartedAt(*shardID).Truncate(time.Second)
+		}
+	}
+
+	si, err := a.dyndbStrClient.GetShardIteratorWithContext(ctx, siInput)
 	if err != nil {
 		return fmt.Errorf("getting shard iterator for shard ID %s: %w", *shardID, err)
 	}
@@ -287,10 +409,18 @@ loop:
 			}
 
 			for _, r := range r.Records {
-				a.logger.Debug("Processing record ID: " + *r.EventID)
+				if createdBefore(r, skipBefore) {
+					a.logger.Debug("Skipping record ID " + *r.EventID + " created before the shard was first read")
+				} else {
+					a.logger.Debug("Processing record ID: " + *r.EventID)
+
+					if err := a.sendDynamoDBEvent(r); err != nil {
+						return fmt.Errorf("sending CloudEvent: %w", err)
+					}
+				}
 
-				if err := a.sendDynamoDBEvent(r); err != nil {
-					return fmt.Errorf("sending CloudEvent: %w", err)
+				if a.shards != nil && r.Dynamodb != nil && r.Dynamodb.SequenceNumber != nil {
+					a.shards.SetCheckpoint(*shardID, *r.Dynamodb.SequenceNumber)
 				}
 			}
 
@@ -301,6 +431,9 @@ loop:
 			// average every 4 hours.
 			if currentShardIter == nil {
 				a.logger.Info("Shard ID ", *shardID, " got sealed")
+				if a.shards != nil {
+					a.shards.SetCompleted(*shardID)
+				}
 				break loop
 			}
 
@@ -329,6 +462,15 @@ func (a *adapter) sendDynamoDBEvent(r *dynamodbstreams.Record) error {
 	return nil
 }
 
+// createdBefore returns whether the given Record was created before the given
+// time. Records without creation time are considered recent.
+func createdBefore(r *dynamodbstreams.Record, t time.Time) bool {
+	if r.Dynamodb == nil || r.Dynamodb.ApproximateCreationDateTime == nil {
+		return false
+	}
+	return r.Dynamodb.ApproximateCreationDateTime.Before(t)
+}
+
 // asEventSubject returns an event subject corresponding to the given record.
 func asEventSubject(r *dynamodbstreams.Record) string {
 	if r == nil || r.Dynamodb == nil || r.Dynamodb.Keys == nil {
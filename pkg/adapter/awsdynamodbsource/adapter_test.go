@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
@@ -33,8 +34,13 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
 	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
 	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
 	loggingtesting "knative.dev/pkg/logging/testing"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/sharding"
 )
 
 const (
@@ -294,3 +300,147 @@ func makeMockRecords(shardIdx, iteratorIdx int) []*dynamodbstreams.Record {
 		},
 	}}
 }
+
+// mockedShardReader is a DynamoDB Streams client which serves the records of
+// a single shard.
+type mockedShardReader struct {
+	dynamodbstreamsiface.DynamoDBStreamsAPI
+
+	gotShardIteratorInput *dynamodbstreams.GetShardIteratorInput
+	records               []*dynamodbstreams.Record
+}
+
+func (m *mockedShardReader) GetShardIteratorWithContext(_ context.Context,
+	in *dynamodbstreams.GetShardIteratorInput, _ ...request.Option) (*dynamodbstreams.GetShardIteratorOutput, error) {
+
+	m.gotShardIteratorInput = in
+	return &dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String("shardIterator")}, nil
+}
+
+// GetRecordsWithContext returns all records at once, then signals that the
+// shard is sealed by returning a nil NextShardIterator.
+func (m *mockedShardReader) GetRecordsWithContext(context.Context,
+	*dynamodbstreams.GetRecordsInput, ...request.Option) (*dynamodbstreams.GetRecordsOutput, error) {
+
+	return &dynamodbstreams.GetRecordsOutput{Records: m.records}, nil
+}
+
+func TestRunRecordsProcessorCheckpoint(t *testing.T) {
+	const shardID = tShardIDPrefix + "000"
+
+	ctx := context.Background()
+
+	coord := sharding.NewCoordinator(loggingtesting.TestLogger(t),
+		fake.NewSimpleClientset().CoordinationV1().Leases("test"),
+		&metav1.OwnerReference{APIVersion: "test/v1", Kind: "Test", Name: "test"},
+		"test", "replica-0", sharding.DefaultLeaseDuration,
+	)
+
+	asgmt, err := coord.Sync(ctx, []string{shardID})
+	require.NoError(t, err)
+	require.Equal(t, []string{shardID}, asgmt.Owned)
+
+	coord.SetCheckpoint(shardID, "41")
+
+	strClient := &mockedShardReader{
+		records: []*dynamodbstreams.Record{
+			newShardRecord("42", time.Now()),
+			newShardRecord("43", time.Now()),
+		},
+	}
+	ceClient := adaptertest.NewTestClient()
+
+	a := &adapter{
+		logger:         loggingtesting.TestLogger(t),
+		dyndbStrClient: strClient,
+		ceClient:       ceClient,
+		arn:            arn.ARN{Resource: tTableArnResource},
+		shards:         coord,
+	}
+
+	err = a.runRecordsProcessor(ctx, aws.String("streamARN"), aws.String(shardID))
+	require.NoError(t, err)
+
+	gotInput := strClient.gotShardIteratorInput
+	assert.Equal(t, dynamodbstreams.ShardIteratorTypeAfterSequenceNumber, *gotInput.ShardIteratorType,
+		"Expected processor to resume after last checkpoint")
+	assert.Equal(t, "41", *gotInput.SequenceNumber)
+
+	assert.Len(t, ceClient.Sent(), 2)
+	assert.Equal(t, "43", coord.LastCheckpoint(shardID))
+
+	require.NoError(t, coord.Release(ctx, shardID))
+
+	asgmt, err = coord.Sync(ctx, []string{shardID})
+	require.NoError(t, err)
+	assert.Empty(t, asgmt.Owned, "Expected sealed shard to never be acquired again")
+}
+
+func TestRunRecordsProcessorHandedOver(t *testing.T) {
+	const shardID = tShardIDPrefix + "000"
+
+	ctx := context.Background()
+
+	leaseCli := fake.NewSimpleClientset().CoordinationV1().Leases("test")
+	owner := &metav1.OwnerReference{APIVersion: "test/v1", Kind: "Test", Name: "test"}
+
+	prevCoord := sharding.NewCoordinator(loggingtesting.TestLogger(t), leaseCli, owner,
+		"test", "replica-0", sharding.DefaultLeaseDuration)
+
+	_, err := prevCoord.Sync(ctx, []string{shardID})
+	require.NoError(t, err)
+	startedAt := prevCoord.StartedAt(shardID)
+	require.NoError(t, prevCoord.Release(ctx, shardID))
+	require.NoError(t, prevCoord.Leave(ctx))
+
+	coord := sharding.NewCoordinator(loggingtesting.TestLogger(t), leaseCli, owner,
+		"test", "replica-1", sharding.DefaultLeaseDuration)
+
+	asgmt, err := coord.Sync(ctx, []string{shardID})
+	require.NoError(t, err)
+	require.Equal(t, []string{shardID}, asgmt.Owned)
+
+	strClient := &mockedShardReader{
+		records: []*dynamodbstreams.Record{
+			newShardRecord("41", startedAt.Add(-time.Hour)),
+			newShardRecord("42", startedAt.Add(time.Second)),
+		},
+	}
+	ceClient := adaptertest.NewTestClient()
+
+	a := &adapter{
+		logger:         loggingtesting.TestLogger(t),
+		dyndbStrClient: strClient,
+		ceClient:       ceClient,
+		arn:            arn.ARN{Resource: tTableArnResource},
+		shards:         coord,
+	}
+
+	err = a.runRecordsProcessor(ctx, aws.String("streamARN"), aws.String(shardID))
+	require.NoError(t, err)
+
+	gotInput := strClient.gotShardIteratorInput
+	assert.Equal(t, dynamodbstreams.ShardIteratorTypeTrimHorizon, *gotInput.ShardIteratorType)
+	assert.Nil(t, gotInput.SequenceNumber)
+
+	// records created before the shard was first read were already sent
+	// by the previous owner
+	events := ceClient.Sent()
+	require.Len(t, events, 1)
+	assert.Equal(t, "42", events[0].ID())
+
+	assert.Equal(t, "42", coord.LastCheckpoint(shardID))
+}
+
+// newShardRecord returns a record with the given sequence number and
+// creation time. The sequence number is also used as the event ID.
+func newShardRecord(seq string, created time.Time) *dynamodbstreams.Record {
+	return &dynamodbstreams.Record{
+		EventID:   aws.String(seq),
+		EventName: aws.String(dynamodbstreams.OperationTypeInsert),
+		Dynamodb: &dynamodbstreams.StreamRecord{
+			SequenceNumber:              aws.String(seq),
+			ApproximateCreationDateTime: aws.Time(created),
+		},
+	}
+}
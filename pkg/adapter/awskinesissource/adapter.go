@@ -19,6 +19,7 @@ package awskinesissource
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"go.uber.org/zap"
 
@@ -32,11 +33,17 @@ import (
 	"github.com/aws/aws-sdk-go/service/kinesis"
 	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	k8sclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/logging"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/common"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/sharding"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/store"
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client"
 )
 
 // envConfig is a set parameters sourced from the environment for the source's
@@ -45,6 +52,10 @@ type envConfig struct {
 	pkgadapter.EnvConfig
 
 	ARN string `envconfig:"ARN" required:"true"`
+
+	// Distribute the stream's shards across replicas of the adapter.
+	CoordinateShards bool   `envconfig:"COORDINATE_SHARDS"`
+	PodName          string `envconfig:"POD_NAME"`
 }
 
 // adapter implements the source's adapter.
@@ -56,6 +67,13 @@ type adapter struct {
 
 	arn    arn.ARN
 	stream string
+
+	// coordinator of shards ownership between replicas, if enabled
+	shards *sharding.Coordinator
+
+	// tracker for running shard readers, indexed by shard ID
+	readers sync.Map
+	wg      sync.WaitGroup
 }
 
 // NewEnvConfig returns an accessor for the source's adapter envConfig.
@@ -76,7 +94,7 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 		WithMaxRetries(5),
 	))
 
-	return &adapter{
+	a := &adapter{
 		logger: logger,
 
 		knsClient: kinesis.New(cfg),
@@ -85,10 +103,32 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 		arn:    arn,
 		stream: common.MustParseKinesisResource(arn.Resource),
 	}
+
+	if env.CoordinateShards {
+		// Leases are owned by the source object, so they get garbage
+		// collected together with it.
+		src, err := client.Get(ctx).SourcesV1alpha1().AWSKinesisSources(env.Namespace).
+			Get(ctx, env.Name, metav1.GetOptions{})
+		if err != nil {
+			logger.Fatalw("Failed to get source object", zap.Error(err))
+		}
+
+		a.shards = sharding.NewCoordinator(logger,
+			k8sclient.Get(ctx).CoordinationV1().Leases(env.Namespace),
+			store.OwnerReference(src),
+			env.Component+"-"+env.Name, env.PodName, sharding.DefaultLeaseDuration,
+		)
+	}
+
+	return a
 }
 
 // Start implements adapter.Adapter.
 func (a *adapter) Start(ctx context.Context) error {
+	if a.shards != nil {
+		return a.runShardReaders(ctx)
+	}
+
 	// Get info about a particular stream
 	myStream, err := a.knsClient.DescribeStream(&kinesis.DescribeStreamInput{
 		StreamName: &a.stream,
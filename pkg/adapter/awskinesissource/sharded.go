@@ -0,0 +1,233 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awskinesissource
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+)
+
+const (
+	// Interval at which the ownership of shards is synchronized between
+	// replicas. Must remain well below sharding.DefaultLeaseDuration.
+	shardsSyncPeriod = 15 * time.Second
+	// Interval between two GetRecords requests on a shard which returned
+	// no record. Kinesis allows up to 5 such requests per second per shard.
+	getRecordsPeriod = 1 * time.Second
+)
+
+// runShardReaders reads records from the shards owned by the current replica
+// until the given context is cancelled.
+func (a *adapter) runShardReaders(ctx context.Context) error {
+	a.logger.Info("Distributing shards of stream " + a.stream + " across replicas")
+
+	t := time.NewTimer(0)
+	defer t.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-t.C:
+			if err := a.syncShards(ctx); err != nil {
+				a.logger.Errorw("Failed to synchronize shards", zap.Error(err))
+			}
+			t.Reset(shardsSyncPeriod)
+		}
+	}
+
+	a.logger.Info("Waiting for termination of shard readers")
+	a.wg.Wait()
+
+	if err := a.shards.Leave(context.Background()); err != nil {
+		return fmt.Errorf("leaving group of replicas: %w", err)
+	}
+
+	return nil
+}
+
+// syncShards ensures a reader is running for each shard owned by the current
+// replica, and stops the readers of shards which were revoked.
+func (a *adapter) syncShards(ctx context.Context) error {
+	shardIDs, err := a.listShards(ctx)
+	if err != nil {
+		return fmt.Errorf("listing shards: %w", err)
+	}
+
+	asgmt, err := a.shards.Sync(ctx, shardIDs)
+	if err != nil {
+		return fmt.Errorf("synchronizing shards ownership: %w", err)
+	}
+
+	for _, shardID := range asgmt.Revoked {
+		a.stopShardReader(shardID)
+	}
+	for _, shardID := range asgmt.Owned {
+		a.ensureShardReader(ctx, shardID)
+	}
+
+	return nil
+}
+
+// listShards returns the IDs of all the stream's shards.
+func (a *adapter) listShards(ctx context.Context) ([]string, error) {
+	var shardIDs []string
+	var exclusiveStartShardID *string
+
+	for {
+		stream, err := a.knsClient.DescribeStreamWithContext(ctx, &kinesis.DescribeStreamInput{
+			StreamName:            &a.stream,
+			ExclusiveStartShardId: exclusiveStartShardID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("describing stream: %w", err)
+		}
+
+		shards := stream.StreamDescription.Shards
+		for _, s := range shards {
+			shardIDs = append(shardIDs, *s.ShardId)
+		}
+
+		if !aws.BoolValue(stream.StreamDescription.HasMoreShards) || len(shards) == 0 {
+			break
+		}
+		exclusiveStartShardID = shards[len(shards)-1].ShardId
+	}
+
+	return shardIDs, nil
+}
+
+// ensureShardReader ensures a reader is running for the given shard.
+func (a *adapter) ensureShardReader(ctx context.Context, shardID string) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	if _, running := a.readers.LoadOrStore(shardID, cancel); running {
+		cancel()
+		return
+	}
+
+	a.wg.Add(1)
+
+	go func() {
+		defer a.wg.Done()
+		defer a.releaseShard(shardID)
+		defer a.readers.Delete(shardID)
+		defer cancel()
+
+		a.logger.Info("Starting reader for shard ID ", shardID)
+
+		if err := a.readShard(ctx, shardID); err != nil {
+			a.logger.Errorw("Reader for shard ID "+shardID+" returned with error", zap.Error(err))
+			return
+		}
+
+		a.logger.Info("Reader for shard ID " + shardID + " has stopped")
+	}()
+}
+
+// stopShardReader stops the reader of the given shard, if it is running.
+func (a *adapter) stopShardReader(shardID string) {
+	if cancel, running := a.readers.Load(shardID); running {
+		a.logger.Info("Stopping reader for shard ID ", shardID)
+		cancel.(context.CancelFunc)()
+	}
+}
+
+// releaseShard hands the given shard over to other replicas.
+func (a *adapter) releaseShard(shardID string) {
+	if err := a.shards.Release(context.Background(), shardID); err != nil {
+		a.logger.Errorw("Failed to release shard ID "+shardID, zap.Error(err))
+	}
+}
+
+// readShard sends the records of the given shard as CloudEvents, starting
+// after the last checkpoint of the shard if there is one, or at the time the
+// shard was first read by any replica if it was handed over.
+func (a *adapter) readShard(ctx context.Context, shardID string) error {
+	siInput := &kinesis.GetShardIteratorInput{
+		StreamName:        &a.stream,
+		ShardId:           &shardID,
+		ShardIteratorType: aws.String(kinesis.ShardIteratorTypeLatest),
+	}
+
+	switch seq := a.shards.LastCheckpoint(shardID); {
+	case seq != "":
+		siInput.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber)
+		siInput.StartingSequenceNumber = &seq
+	case a.shards.WasHandedOver(shardID):
+		// the previous owner of the shard may have stopped before
+		// sending the first records it read, which arrived after it
+		// started reading the shard
+		siInput.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeAtTimestamp)
+		siInput.Timestamp = aws.Time(a.shards.StartedAt(shardID))
+	}
+
+	si, err := a.knsClient.GetShardIteratorWithContext(ctx, siInput)
+	if err != nil {
+		return fmt.Errorf("getting shard iterator: %w", err)
+	}
+
+	shardIterator := si.ShardIterator
+
+	t := time.NewTimer(0)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+
+		records, err := a.knsClient.GetRecordsWithContext(ctx, &kinesis.GetRecordsInput{
+			ShardIterator: shardIterator,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("getting records: %w", err)
+		}
+
+		for _, r := range records.Records {
+			if err := a.sendKinesisRecord(r); err != nil {
+				return fmt.Errorf("sending CloudEvent: %w", err)
+			}
+			a.shards.SetCheckpoint(shardID, *r.SequenceNumber)
+		}
+
+		// the shard was closed after a resharding operation and has no
+		// more record to return
+		if shardIterator = records.NextShardIterator; shardIterator == nil {
+			a.shards.SetCompleted(shardID)
+			return nil
+		}
+
+		delay := getRecordsPeriod
+		if len(records.Records) > 0 {
+			delay = 0
+		}
+		t.Reset(delay)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awskinesissource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+	loggingtesting "knative.dev/pkg/logging/testing"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/sharding"
+)
+
+type mockedShardReader struct {
+	kinesisiface.KinesisAPI
+
+	gotShardIteratorInput *kinesis.GetShardIteratorInput
+	records               []*kinesis.Record
+}
+
+func (m *mockedShardReader) GetShardIteratorWithContext(_ aws.Context, in *kinesis.GetShardIteratorInput,
+	_ ...request.Option) (*kinesis.GetShardIteratorOutput, error) {
+
+	m.gotShardIteratorInput = in
+	return &kinesis.GetShardIteratorOutput{ShardIterator: aws.String("shardIterator")}, nil
+}
+
+// GetRecordsWithContext returns all records at once, then signals that the
+// shard is closed by returning a nil NextShardIterator.
+func (m *mockedShardReader) GetRecordsWithContext(aws.Context, *kinesis.GetRecordsInput,
+	...request.Option) (*kinesis.GetRecordsOutput, error) {
+
+	return &kinesis.GetRecordsOutput{Records: m.records}, nil
+}
+
+func TestReadShard(t *testing.T) {
+	const shardID = "shardId-000000000000"
+
+	ctx := context.Background()
+
+	coord := sharding.NewCoordinator(loggingtesting.TestLogger(t),
+		fake.NewSimpleClientset().CoordinationV1().Leases("test"),
+		&metav1.OwnerReference{APIVersion: "test/v1", Kind: "Test", Name: "test"},
+		"test", "replica-0", sharding.DefaultLeaseDuration,
+	)
+
+	asgmt, err := coord.Sync(ctx, []string{shardID})
+	require.NoError(t, err)
+	require.Equal(t, []string{shardID}, asgmt.Owned)
+
+	coord.SetCheckpoint(shardID, "41")
+
+	knsClient := &mockedShardReader{
+		records: []*kinesis.Record{
+			{SequenceNumber: aws.String("42"), PartitionKey: aws.String("key"), Data: []byte("foo")},
+			{SequenceNumber: aws.String("43"), PartitionKey: aws.String("key"), Data: []byte("bar")},
+		},
+	}
+	ceClient := adaptertest.NewTestClient()
+
+	a := &adapter{
+		logger:    loggingtesting.TestLogger(t),
+		knsClient: knsClient,
+		ceClient:  ceClient,
+		stream:    "fooStream",
+		shards:    coord,
+	}
+
+	err = a.readShard(ctx, shardID)
+	require.NoError(t, err)
+
+	gotInput := knsClient.gotShardIteratorInput
+	assert.Equal(t, kinesis.ShardIteratorTypeAfterSequenceNumber, *gotInput.ShardIteratorType,
+		"Expected reader to resume after last checkpoint")
+	assert.Equal(t, "41", *gotInput.StartingSequenceNumber)
+
+	assert.Len(t, ceClient.Sent(), 2)
+	assert.Equal(t, "43", coord.LastCheckpoint(shardID))
+
+	require.NoError(t, coord.Release(ctx, shardID))
+
+	asgmt, err = coord.Sync(ctx, []string{shardID})
+	require.NoError(t, err)
+	assert.Empty(t, asgmt.Owned, "Expected closed shard to never be acquired again")
+}
+
+func TestReadShardHandedOver(t *testing.T) {
+	const shardID = "shardId-000000000000"
+
+	ctx := context.Background()
+
+	leaseCli := fake.NewSimpleClientset().CoordinationV1().Leases("test")
+	owner := &metav1.OwnerReference{APIVersion: "test/v1", Kind: "Test", Name: "test"}
+
+	prevCoord := sharding.NewCoordinator(loggingtesting.TestLogger(t), leaseCli, owner,
+		"test", "replica-0", sharding.DefaultLeaseDuration)
+
+	_, err := prevCoord.Sync(ctx, []string{shardID})
+	require.NoError(t, err)
+	startedAt := prevCoord.StartedAt(shardID)
+	require.NoError(t, prevCoord.Release(ctx, shardID))
+	require.NoError(t, prevCoord.Leave(ctx))
+
+	coord := sharding.NewCoordinator(loggingtesting.TestLogger(t), leaseCli, owner,
+		"test", "replica-1", sharding.DefaultLeaseDuration)
+
+	asgmt, err := coord.Sync(ctx, []string{shardID})
+	require.NoError(t, err)
+	require.Equal(t, []string{shardID}, asgmt.Owned)
+
+	knsClient := &mockedShardReader{}
+
+	a := &adapter{
+		logger:    loggingtesting.TestLogger(t),
+		knsClient: knsClient,
+		ceClient:  adaptertest.NewTestClient(),
+		stream:    "fooStream",
+		shards:    coord,
+	}
+
+	err = a.readShard(ctx, shardID)
+	require.NoError(t, err)
+
+	gotInput := knsClient.gotShardIteratorInput
+	assert.Equal(t, kinesis.ShardIteratorTypeAtTimestamp, *gotInput.ShardIteratorType,
+		"Expected reader of a shard handed over without checkpoint to start where the previous owner started")
+	require.NotNil(t, gotInput.Timestamp)
+	assert.True(t, startedAt.Equal(*gotInput.Timestamp),
+		"Expected start time %s, got %s", startedAt, *gotInput.Timestamp)
+	assert.Nil(t, gotInput.StartingSequenceNumber)
+}
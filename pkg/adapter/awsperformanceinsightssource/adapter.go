@@ -18,6 +18,11 @@ package awsperformanceinsightssource
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -28,7 +33,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/pi"
-	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/pi/piiface"
 
 	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
 	"knative.dev/pkg/logging"
@@ -37,8 +42,6 @@ import (
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
 )
 
-const serviceType = "RDS"
-
 // envConfig is a set parameters sourced from the environment for the source's
 // adapter.
 type envConfig struct {
@@ -48,28 +51,54 @@ type envConfig struct {
 
 	PollingInterval string `envconfig:"POLLING_INTERVAL" required:"true"`
 
-	Metrics []string `envconfig:"PI_METRICS" required:"true"`
+	// Granularity of the datapoints, in seconds.
+	Period int64 `envconfig:"PI_PERIOD" required:"true"`
+
+	Metrics []string `envconfig:"PI_METRICS"`
+
+	// JSON representation of the structured metric queries.
+	MetricQueries string `envconfig:"PI_METRIC_QUERIES"`
+
+	// JSON representation of the top dimension keys queries.
+	TopDimensions string `envconfig:"PI_TOP_DIMENSIONS"`
+
+	// JSON representation of the database instances resolved by the
+	// reconciler from the ARN.
+	Instances string `envconfig:"PI_INSTANCES" required:"true"`
 }
 
 // adapter implements the source's adapter.
 type adapter struct {
 	logger *zap.SugaredLogger
 
-	pIClient *pi.PI
+	pIClient piiface.PIAPI
 	ceClient cloudevents.Client
 
 	arn             arn.ARN
 	pollingInterval time.Duration
+	period          time.Duration
 	metricQueries   []*pi.MetricQuery
-	resourceID      string
+	topDimensions   []*pi.DescribeDimensionKeysInput
+	instances       []v1alpha1.AWSPerformanceInsightsInstance
+
+	// end of the last time window for which metrics were sent, per
+	// database instance
+	metricsMu        sync.Mutex
+	metricsWindowEnd map[string]time.Time
 }
 
 // event represents the structured event data to be sent as the payload of the Cloudevent
 type event struct {
-	Metric string  `json:"metric"`
-	Value  float64 `json:"value"`
+	Metric     string            `json:"metric"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Value      float64           `json:"value"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
 }
 
+// Maximum number of metric queries accepted by a single GetResourceMetrics
+// request.
+const maxMetricQueriesPerRequest = 15
+
 // NewEnvConfig returns an accessor for the source's adapter envConfig.
 func NewEnvConfig() pkgadapter.EnvConfigAccessor {
 	return &envConfig{}
@@ -77,7 +106,6 @@ func NewEnvConfig() pkgadapter.EnvConfigAccessor {
 
 // NewAdapter returns a constructor for the source's adapter.
 func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClient cloudevents.Client) pkgadapter.Adapter {
-	var err error
 	logger := logging.FromContext(ctx)
 
 	env := envAcc.(*envConfig)
@@ -93,38 +121,92 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 		logger.Panicf("Unable to parse interval duration: %v", zap.Error(err))
 	}
 
-	var mql []*pi.MetricQuery
+	adapter, err := newAdapter(logger, pi.New(cfg), ceClient, a, interval,
+		time.Duration(env.Period)*time.Second, env.Metrics, env.MetricQueries, env.TopDimensions, env.Instances)
+	if err != nil {
+		logger.Panicw("Unable to create adapter", zap.Error(err))
+	}
 
-	for _, m := range env.Metrics {
-		mq := &pi.MetricQuery{Metric: aws.String(m)}
-		mql = append(mql, mq)
+	return adapter
+}
+
+// newAdapter returns an adapter which polls the database instances
+// represented by the given JSON string.
+func newAdapter(logger *zap.SugaredLogger, piClient piiface.PIAPI, ceClient cloudevents.Client,
+	arn arn.ARN, pollingInterval, period time.Duration,
+	metrics []string, rawQueries, rawTopDimensions, rawInstances string) (*adapter, error) {
+
+	mql, err := parseMetricQueries(metrics, rawQueries)
+	if err != nil {
+		return nil, fmt.Errorf("parsing metric queries: %w", err)
 	}
 
-	r := rds.New(cfg)
-	var resourceID string
-	dbi, err := r.DescribeDBInstances(&rds.DescribeDBInstancesInput{})
+	tdl, err := parseTopDimensions(rawTopDimensions)
 	if err != nil {
-		logger.Panicf("Unable describe DB instances: %v", zap.Error(err))
+		return nil, fmt.Errorf("parsing top dimensions queries: %w", err)
 	}
 
-	for _, instance := range dbi.DBInstances {
-		if *instance.DBInstanceArn == a.String() {
-			resourceID = *instance.DbiResourceId
-		}
+	instances, err := parseInstances(rawInstances)
+	if err != nil {
+		return nil, fmt.Errorf("parsing database instances: %w", err)
 	}
 
 	return &adapter{
 		logger: logger,
 
-		pIClient: pi.New(cfg),
+		pIClient: piClient,
 		ceClient: ceClient,
 
-		arn: a,
+		arn: arn,
 
-		pollingInterval: interval,
+		pollingInterval: pollingInterval,
+		period:          period,
 		metricQueries:   mql,
-		resourceID:      resourceID,
+		topDimensions:   tdl,
+		instances:       instances,
+
+		metricsWindowEnd: make(map[string]time.Time, len(instances)),
+	}, nil
+}
+
+// parseMetricQueries returns the metric queries represented by the given
+// metric names and JSON representation of structured metric queries.
+func parseMetricQueries(metrics []string, rawQueries string) ([]*pi.MetricQuery, error) {
+	var mql []*pi.MetricQuery
+
+	for _, m := range metrics {
+		mq := &pi.MetricQuery{Metric: aws.String(m)}
+		mql = append(mql, mq)
+	}
+
+	if rawQueries == "" {
+		return mql, nil
+	}
+
+	queries := make([]v1alpha1.AWSPerformanceInsightsMetricQuery, 0)
+	if err := json.Unmarshal([]byte(rawQueries), &queries); err != nil {
+		return nil, err
+	}
+
+	for _, q := range queries {
+		mql = append(mql, &pi.MetricQuery{
+			Metric:  aws.String(q.Metric),
+			GroupBy: toDimensionGroup(q.GroupBy),
+			Filter:  toFilter(q.Filter),
+		})
+	}
+
+	return mql, nil
+}
+
+// parseInstances takes the JSON representation of the database instances as
+// passed in the environment, and returns them as a list.
+func parseInstances(rawInstances string) ([]v1alpha1.AWSPerformanceInsightsInstance, error) {
+	instances := make([]v1alpha1.AWSPerformanceInsightsInstance, 0)
+	if err := json.Unmarshal([]byte(rawInstances), &instances); err != nil {
+		return nil, err
 	}
+	return instances, nil
 }
 
 // Start implements adapter.Adapter.
@@ -136,61 +218,168 @@ func (a *adapter) Start(ctx context.Context) error {
 	defer poll.Stop()
 
 	// Wake up every pollingInterval, and retrieve the logs
-	var priorTime time.Time
-	priorTime = time.Now()
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 
 		case t := <-poll.C:
-			go a.PollMetrics(priorTime, t)
-			priorTime = t
+			go a.PollMetrics(t)
+		}
+	}
+}
+
+// PollMetrics sends the metrics of every database instance for the time
+// window which closed since the last poll.
+func (a *adapter) PollMetrics(currentTime time.Time) {
+	// polls triggered by consecutive ticks must not interleave, otherwise
+	// their time windows could overlap
+	a.metricsMu.Lock()
+	defer a.metricsMu.Unlock()
+
+	for _, inst := range a.instances {
+		start, end, ok := a.metricsWindow(inst, currentTime)
+		if !ok {
+			a.logger.Debug("No complete time window to poll metrics for instance " + inst.ARN)
+			continue
+		}
+
+		if err := a.pollInstanceMetrics(inst, start, end); err != nil {
+			// the window is polled again in its entirety on the next
+			// tick, events which were already sent carry the same ID
+			a.logger.Errorw("Error polling metrics of instance "+inst.ARN, zap.Error(err))
+			continue
 		}
+		a.pollInstanceTopDimensions(inst, start, end)
+
+		a.metricsWindowEnd[inst.ResourceID] = end
 	}
 }
 
-func (a *adapter) PollMetrics(priorTime time.Time, currentTime time.Time) {
-	rmi := &pi.GetResourceMetricsInput{
-		EndTime:       aws.Time(time.Now()),
-		StartTime:     aws.Time(priorTime),
-		Identifier:    aws.String(a.resourceID),
-		MetricQueries: a.metricQueries,
-		ServiceType:   aws.String(serviceType),
+// metricsWindow returns the boundaries of the time window to poll metrics for
+// the given instance at the given time. The returned boundaries are aligned to
+// the period of the datapoints, and the window starts where the previous one
+// ended. The returned boolean is false if no new window closed since the last
+// poll.
+func (a *adapter) metricsWindow(inst v1alpha1.AWSPerformanceInsightsInstance,
+	currentTime time.Time) (start, end time.Time, ok bool) {
+
+	end = currentTime.Truncate(a.period)
+
+	start = a.metricsWindowEnd[inst.ResourceID]
+	if start.IsZero() {
+		start = end.Add(-a.pollingInterval).Truncate(a.period)
 	}
 
-	rm, err := a.pIClient.GetResourceMetrics(rmi)
+	return start, end, end.After(start)
+}
+
+// pollInstanceMetrics sends the metrics of the given instance for the given
+// time window.
+func (a *adapter) pollInstanceMetrics(inst v1alpha1.AWSPerformanceInsightsInstance, start, end time.Time) error {
+	for queries := a.metricQueries; len(queries) > 0; {
+		n := len(queries)
+		if n > maxMetricQueriesPerRequest {
+			n = maxMetricQueriesPerRequest
+		}
 
-	if err != nil {
-		a.logger.Errorf("retrieving resource metrics: %v", err)
-		return
-	}
-
-	for _, d := range rm.MetricList {
-		for _, metric := range d.DataPoints {
-			if metric.Value != nil {
-				e := &event{
-					Metric: *d.Key.Metric,
-					Value:  *metric.Value,
-				}
-
-				event := cloudevents.NewEvent(cloudevents.VersionV1)
-				event.SetType(v1alpha1.AWSPerformanceInsightsGenericEventType)
-				event.SetSource(a.arn.String())
-				event.SetExtension("pimetric", d.Key.Metric)
-				ceer := event.SetData(cloudevents.ApplicationJSON, e)
-				if ceer != nil {
-					a.logger.Errorf("failed to set event data: %v", err)
-					return
-				}
-
-				if result := a.ceClient.Send(context.Background(), event); !cloudevents.IsACK(result) {
-					a.logger.Errorf("failed to send event data: %v", err)
-					return
-				}
-
-				a.logger.Debug("Sent Cloudevent Sucessfully")
+		rmi := &pi.GetResourceMetricsInput{
+			StartTime:       aws.Time(start),
+			EndTime:         aws.Time(end),
+			PeriodInSeconds: aws.Int64(int64(a.period / time.Second)),
+			Identifier:      aws.String(inst.ResourceID),
+			MetricQueries:   queries[:n],
+			ServiceType:     aws.String(inst.ServiceType),
+		}
+
+		for {
+			rm, err := a.pIClient.GetResourceMetrics(rmi)
+			if err != nil {
+				return fmt.Errorf("retrieving resource metrics: %w", err)
+			}
+
+			if err := a.sendMetricEvents(inst, rm.MetricList); err != nil {
+				return fmt.Errorf("sending metrics: %w", err)
+			}
+
+			if rm.NextToken == nil {
+				break
+			}
+			rmi.NextToken = rm.NextToken
+		}
+
+		queries = queries[n:]
+	}
+
+	return nil
+}
+
+// sendMetricEvents sends an event per datapoint of the given metrics.
+func (a *adapter) sendMetricEvents(inst v1alpha1.AWSPerformanceInsightsInstance, metrics []*pi.MetricKeyDataPoints) error {
+	for _, d := range metrics {
+		metric := aws.StringValue(d.Key.Metric)
+		dims := aws.StringValueMap(d.Key.Dimensions)
+
+		for _, dp := range d.DataPoints {
+			if dp.Value == nil {
+				continue
+			}
+
+			e := &event{
+				Metric:     metric,
+				Timestamp:  aws.TimeValue(dp.Timestamp),
+				Value:      *dp.Value,
+				Dimensions: dims,
+			}
+
+			event := cloudevents.NewEvent(cloudevents.VersionV1)
+			event.SetID(metricEventID(inst, metric, dims, e.Timestamp))
+			event.SetType(v1alpha1.AWSEventType(a.arn.Service, v1alpha1.AWSPerformanceInsightsMetricEventType))
+			event.SetSource(a.arn.String())
+			event.SetSubject(inst.ARN)
+			event.SetTime(e.Timestamp)
+			event.SetExtension("pimetric", metric)
+			setDimensionExtensions(&event, dims)
+			if err := event.SetData(cloudevents.ApplicationJSON, e); err != nil {
+				return fmt.Errorf("failed to set event data: %w", err)
+			}
+
+			if result := a.ceClient.Send(context.Background(), event); !cloudevents.IsACK(result) {
+				return fmt.Errorf("failed to send event: %w", result)
 			}
+
+			a.logger.Debug("Sent Cloudevent Sucessfully")
 		}
 	}
+
+	return nil
+}
+
+// metricEventID returns a deterministic CloudEvent ID for the datapoint of
+// the given instance, metric and dimension key at the given time.
+func metricEventID(inst v1alpha1.AWSPerformanceInsightsInstance, metric string,
+	dims map[string]string, ts time.Time) string {
+
+	id := inst.ResourceID + "/" + metric
+	if len(dims) > 0 {
+		id += "/" + dimensionsHash(dims)
+	}
+	return id + "/" + ts.UTC().Format(time.RFC3339)
+}
+
+// dimensionsHash returns a short hash of the given dimension key. Dimension
+// values such as SQL statements are too long to be included in IDs verbatim.
+func dimensionsHash(dims map[string]string) string {
+	names := make([]string, 0, len(dims))
+	for n := range dims {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	h := fnv.New64a()
+	for _, n := range names {
+		_, _ = h.Write([]byte(n + "=" + dims[n] + "\n"))
+	}
+
+	return fmt.Sprintf("%x", h.Sum64())
 }
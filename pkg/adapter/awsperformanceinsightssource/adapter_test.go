@@ -0,0 +1,320 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsperformanceinsightssource
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/pi"
+	"github.com/aws/aws-sdk-go/service/pi/piiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+	loggingtesting "knative.dev/pkg/logging/testing"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+var (
+	tARN = arn.ARN{
+		Partition: "aws",
+		Service:   "rds",
+		Region:    "us-west-2",
+		AccountID: "123456789012",
+		Resource:  "cluster:triggermeshtest",
+	}
+
+	tInstance = v1alpha1.AWSPerformanceInsightsInstance{
+		ARN:         "arn:aws:rds:us-west-2:123456789012:db:triggermeshtest",
+		ResourceID:  "db-0123456789ABCDEFGHIJKLMNOP",
+		ServiceType: pi.ServiceTypeRds,
+	}
+)
+
+type mockPIClient struct {
+	piiface.PIAPI
+
+	metricsResp       pi.GetResourceMetricsOutput
+	dimensionKeysResp pi.DescribeDimensionKeysOutput
+
+	metricsInputs       []*pi.GetResourceMetricsInput
+	dimensionKeysInputs []*pi.DescribeDimensionKeysInput
+}
+
+func (m *mockPIClient) GetResourceMetrics(in *pi.GetResourceMetricsInput) (*pi.GetResourceMetricsOutput, error) {
+	m.metricsInputs = append(m.metricsInputs, in)
+	return &m.metricsResp, nil
+}
+
+func (m *mockPIClient) DescribeDimensionKeys(in *pi.DescribeDimensionKeysInput) (*pi.DescribeDimensionKeysOutput, error) {
+	m.dimensionKeysInputs = append(m.dimensionKeysInputs, in)
+	return &m.dimensionKeysResp, nil
+}
+
+func TestParseMetricQueries(t *testing.T) {
+	const queriesStr = `[{"metric":"db.load.avg","groupBy":{"group":"db.sql","dimensions":["db.sql.id"],"limit":5},` +
+		`"filter":{"db.user.name":"admin"}},{"metric":"db.sampledload.avg"}]`
+
+	queries, err := parseMetricQueries([]string{"os.cpuUtilization.user.avg"}, queriesStr)
+	require.NoError(t, err)
+
+	expect := []*pi.MetricQuery{{
+		Metric: aws.String("os.cpuUtilization.user.avg"),
+	}, {
+		Metric: aws.String("db.load.avg"),
+		GroupBy: &pi.DimensionGroup{
+			Group:      aws.String("db.sql"),
+			Dimensions: aws.StringSlice([]string{"db.sql.id"}),
+			Limit:      aws.Int64(5),
+		},
+		Filter: aws.StringMap(map[string]string{"db.user.name": "admin"}),
+	}, {
+		Metric: aws.String("db.sampledload.avg"),
+	}}
+
+	assert.Equal(t, expect, queries)
+}
+
+func TestParseTopDimensions(t *testing.T) {
+	const queriesStr = `[{"metric":"db.load.avg","groupBy":{"group":"db.wait_event","limit":10},` +
+		`"partitionBy":{"group":"db.sql"}}]`
+
+	inputs, err := parseTopDimensions(queriesStr)
+	require.NoError(t, err)
+
+	expect := []*pi.DescribeDimensionKeysInput{{
+		Metric: aws.String("db.load.avg"),
+		GroupBy: &pi.DimensionGroup{
+			Group: aws.String("db.wait_event"),
+			Limit: aws.Int64(10),
+		},
+		PartitionBy: &pi.DimensionGroup{
+			Group: aws.String("db.sql"),
+		},
+	}}
+
+	assert.Equal(t, expect, inputs)
+
+	inputs, err = parseTopDimensions("")
+	assert.NoError(t, err)
+	assert.Empty(t, inputs)
+}
+
+func TestPollMetricsGroupedByDimension(t *testing.T) {
+	ceClient := adaptertest.NewTestClient()
+
+	mqs := make([]*pi.MetricQuery, maxMetricQueriesPerRequest+1)
+	for i := range mqs {
+		mqs[i] = &pi.MetricQuery{Metric: aws.String("db.load.avg")}
+	}
+
+	piClient := &mockPIClient{
+		metricsResp: pi.GetResourceMetricsOutput{
+			MetricList: []*pi.MetricKeyDataPoints{{
+				Key: &pi.ResponseResourceMetricKey{
+					Metric: aws.String("db.load.avg"),
+					Dimensions: aws.StringMap(map[string]string{
+						"db.sql.id":        "ABCDEF",
+						"db.sql.statement": "SELECT 1",
+					}),
+				},
+				DataPoints: []*pi.DataPoint{{
+					Timestamp: aws.Time(time.Unix(0, 0)),
+					Value:     aws.Float64(1.5),
+				}},
+			}},
+		},
+	}
+
+	a := &adapter{
+		logger:           loggingtesting.TestLogger(t),
+		pIClient:         piClient,
+		ceClient:         ceClient,
+		arn:              tARN,
+		pollingInterval:  time.Minute,
+		period:           time.Minute,
+		metricQueries:    mqs,
+		instances:        []v1alpha1.AWSPerformanceInsightsInstance{tInstance},
+		metricsWindowEnd: make(map[string]time.Time),
+	}
+
+	now := time.Date(2021, 1, 1, 12, 0, 30, 0, time.UTC)
+	a.PollMetrics(now)
+
+	require.Len(t, piClient.metricsInputs, 2, "Queries weren't split into batches")
+	assert.Len(t, piClient.metricsInputs[0].MetricQueries, maxMetricQueriesPerRequest)
+	assert.Len(t, piClient.metricsInputs[1].MetricQueries, 1)
+
+	in := piClient.metricsInputs[0]
+	assert.Equal(t, tInstance.ResourceID, *in.Identifier)
+	assert.Equal(t, time.Date(2021, 1, 1, 11, 59, 0, 0, time.UTC), *in.StartTime)
+	assert.Equal(t, time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC), *in.EndTime)
+	assert.Equal(t, int64(60), *in.PeriodInSeconds)
+
+	events := ceClient.Sent()
+	require.Len(t, events, 2)
+
+	e := events[0]
+	assert.Equal(t, "com.amazon.rds.pi.metric", e.Type())
+	assert.Equal(t, tARN.String(), e.Source())
+	assert.Equal(t, tInstance.ARN, e.Subject())
+	assert.Equal(t, time.Unix(0, 0).UTC(), e.Time().UTC())
+	assert.Equal(t, metricEventID(tInstance, "db.load.avg", map[string]string{
+		"db.sql.id":        "ABCDEF",
+		"db.sql.statement": "SELECT 1",
+	}, time.Unix(0, 0)), e.ID())
+	assert.Equal(t, events[0].ID(), events[1].ID(), "Identical datapoints should have the same ID")
+	assert.Equal(t, "db.load.avg", e.Extensions()["pimetric"])
+	assert.Equal(t, "ABCDEF", e.Extensions()["pidbsqlid"])
+	assert.Equal(t, "SELECT 1", e.Extensions()["pidbsqlstatement"])
+
+	var data event
+	require.NoError(t, e.DataAs(&data))
+	assert.Equal(t, event{
+		Metric:    "db.load.avg",
+		Timestamp: time.Unix(0, 0).UTC(),
+		Value:     1.5,
+		Dimensions: map[string]string{
+			"db.sql.id":        "ABCDEF",
+			"db.sql.statement": "SELECT 1",
+		},
+	}, data)
+}
+
+func TestPollTopDimensions(t *testing.T) {
+	ceClient := adaptertest.NewTestClient()
+
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(5 * time.Minute)
+
+	piClient := &mockPIClient{
+		dimensionKeysResp: pi.DescribeDimensionKeysOutput{
+			AlignedStartTime: aws.Time(start),
+			AlignedEndTime:   aws.Time(end),
+			Keys: []*pi.DimensionKeyDescription{{
+				Dimensions: aws.StringMap(map[string]string{"db.wait_event.name": "CPU"}),
+				Total:      aws.Float64(2.5),
+				Partitions: aws.Float64Slice([]float64{2, 0.5}),
+			}},
+			PartitionKeys: []*pi.ResponsePartitionKey{{
+				Dimensions: aws.StringMap(map[string]string{"db.sql.id": "A"}),
+			}, {
+				Dimensions: aws.StringMap(map[string]string{"db.sql.id": "B"}),
+			}},
+		},
+	}
+
+	a := &adapter{
+		logger:          loggingtesting.TestLogger(t),
+		pIClient:        piClient,
+		ceClient:        ceClient,
+		arn:             tARN,
+		pollingInterval: 5 * time.Minute,
+		period:          5 * time.Minute,
+		topDimensions: []*pi.DescribeDimensionKeysInput{{
+			Metric:      aws.String("db.load.avg"),
+			GroupBy:     &pi.DimensionGroup{Group: aws.String("db.wait_event"), Limit: aws.Int64(10)},
+			PartitionBy: &pi.DimensionGroup{Group: aws.String("db.sql")},
+		}},
+		instances:        []v1alpha1.AWSPerformanceInsightsInstance{tInstance},
+		metricsWindowEnd: make(map[string]time.Time),
+	}
+
+	a.PollMetrics(end.Add(time.Second))
+
+	require.Len(t, piClient.dimensionKeysInputs, 1)
+	in := piClient.dimensionKeysInputs[0]
+	assert.Equal(t, tInstance.ResourceID, *in.Identifier)
+	assert.Equal(t, tInstance.ServiceType, *in.ServiceType)
+	assert.Equal(t, start, *in.StartTime)
+	assert.Equal(t, end, *in.EndTime)
+	assert.Nil(t, a.topDimensions[0].Identifier, "Query template was altered")
+
+	events := ceClient.Sent()
+	require.Len(t, events, 1)
+
+	e := events[0]
+	assert.Equal(t, "com.amazon.rds.pi.top_dimensions", e.Type())
+	assert.Equal(t, tInstance.ARN, e.Subject())
+	assert.Equal(t, end, e.Time())
+	assert.Equal(t, tInstance.ResourceID+"/db.load.avg/db.wait_event/db.sql/2021-01-01T12:05:00Z", e.ID())
+
+	var report TopDimensionsReport
+	require.NoError(t, e.DataAs(&report))
+	assert.Equal(t, TopDimensionsReport{
+		Metric:    "db.load.avg",
+		Group:     "db.wait_event",
+		StartTime: start,
+		EndTime:   end,
+		Keys: []DimensionKey{{
+			Dimensions: Dimensions{"db.wait_event.name": "CPU"},
+			Total:      2.5,
+			Partitions: []float64{2, 0.5},
+		}},
+		PartitionBy: []Dimensions{{"db.sql.id": "A"}, {"db.sql.id": "B"}},
+	}, report)
+}
+
+func TestMetricsWindow(t *testing.T) {
+	piClient := &mockPIClient{}
+
+	a := &adapter{
+		logger:           loggingtesting.TestLogger(t),
+		pIClient:         piClient,
+		ceClient:         adaptertest.NewTestClient(),
+		pollingInterval:  time.Minute,
+		period:           5 * time.Minute,
+		metricQueries:    []*pi.MetricQuery{{Metric: aws.String("db.load.avg")}},
+		instances:        []v1alpha1.AWSPerformanceInsightsInstance{tInstance},
+		metricsWindowEnd: make(map[string]time.Time),
+	}
+
+	t0 := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// ticks which don't close a window of the configured period are no-ops
+	for _, tick := range []time.Duration{
+		30 * time.Second, // 12:00:30 -> window [11:55,12:00)
+		90 * time.Second, // 12:01:30 -> no closed window
+		5 * time.Minute,  // 12:05:00 -> window [12:00,12:05)
+		6 * time.Minute,  // 12:06:00 -> no closed window
+		16 * time.Minute, // 12:16:00 (missed ticks) -> window [12:05,12:15)
+	} {
+		a.PollMetrics(t0.Add(tick))
+	}
+
+	require.Len(t, piClient.metricsInputs, 3)
+
+	expectWindows := [][2]time.Time{
+		{t0.Add(-5 * time.Minute), t0},
+		{t0, t0.Add(5 * time.Minute)},
+		{t0.Add(5 * time.Minute), t0.Add(15 * time.Minute)},
+	}
+	for i, in := range piClient.metricsInputs {
+		assert.Equal(t, expectWindows[i][0], *in.StartTime, "Unexpected start of window %d", i)
+		assert.Equal(t, expectWindows[i][1], *in.EndTime, "Unexpected end of window %d", i)
+	}
+}
+
+func TestDimensionExtensionName(t *testing.T) {
+	assert.Equal(t, "pidbsqlid", dimensionExtensionName("db.sql.id"))
+	assert.Equal(t, "pidbwaiteventname", dimensionExtensionName("db.wait_event.name"))
+	assert.Equal(t, "pidbsqltokenizedstat", dimensionExtensionName("db.sql_tokenized.statement"))
+}
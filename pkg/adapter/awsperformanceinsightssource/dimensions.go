@@ -0,0 +1,250 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsperformanceinsightssource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/pi"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+// TopDimensionsReport is the payload of an event reporting the top dimension
+// keys of a metric over a period of time.
+type TopDimensionsReport struct {
+	Metric      string         `json:"metric"`
+	Group       string         `json:"group"`
+	StartTime   time.Time      `json:"startTime"`
+	EndTime     time.Time      `json:"endTime"`
+	Keys        []DimensionKey `json:"keys"`
+	PartitionBy []Dimensions   `json:"partitionBy,omitempty"`
+}
+
+// DimensionKey is the aggregated value of a metric for a given dimension key.
+type DimensionKey struct {
+	Dimensions Dimensions `json:"dimensions"`
+	Total      float64    `json:"total"`
+	// Value of the metric for each partition listed in PartitionBy.
+	Partitions []float64 `json:"partitions,omitempty"`
+}
+
+// Dimensions are the values of the dimensions of a key, indexed by dimension
+// name.
+type Dimensions map[string]string
+
+// Prefix of the CloudEvent extensions which carry dimension values.
+const dimensionExtensionPrefix = "pi"
+
+// Maximum length of CloudEvent extension names, as recommended by the
+// CloudEvents specification.
+const maxExtensionNameLength = 20
+
+// parseTopDimensions takes the JSON representation of the top dimensions
+// queries as passed in the environment, and returns them as
+// DescribeDimensionKeys inputs, without any instance or time range.
+func parseTopDimensions(rawQueries string) ([]*pi.DescribeDimensionKeysInput, error) {
+	if rawQueries == "" {
+		return nil, nil
+	}
+
+	queries := make([]v1alpha1.AWSPerformanceInsightsDimensionKeysQuery, 0)
+	if err := json.Unmarshal([]byte(rawQueries), &queries); err != nil {
+		return nil, err
+	}
+
+	inputs := make([]*pi.DescribeDimensionKeysInput, len(queries))
+	for i, q := range queries {
+		groupBy := q.GroupBy
+		inputs[i] = &pi.DescribeDimensionKeysInput{
+			Metric:      aws.String(q.Metric),
+			GroupBy:     toDimensionGroup(&groupBy),
+			PartitionBy: toDimensionGroup(q.PartitionBy),
+			Filter:      toFilter(q.Filter),
+		}
+	}
+
+	return inputs, nil
+}
+
+// toDimensionGroup converts a AWSPerformanceInsightsDimensionGroup to a
+// DimensionGroup.
+func toDimensionGroup(g *v1alpha1.AWSPerformanceInsightsDimensionGroup) *pi.DimensionGroup {
+	if g == nil {
+		return nil
+	}
+
+	dg := &pi.DimensionGroup{
+		Group: aws.String(g.Group),
+		Limit: g.Limit,
+	}
+	if len(g.Dimensions) > 0 {
+		dg.Dimensions = aws.StringSlice(g.Dimensions)
+	}
+
+	return dg
+}
+
+// toFilter converts the given dimension filter to its Performance Insights
+// API representation.
+func toFilter(f map[string]string) map[string]*string {
+	if len(f) == 0 {
+		return nil
+	}
+	return aws.StringMap(f)
+}
+
+// pollInstanceTopDimensions sends a report of the top dimension keys of each
+// top dimensions query for the given instance and time window.
+func (a *adapter) pollInstanceTopDimensions(inst v1alpha1.AWSPerformanceInsightsInstance, start, end time.Time) {
+
+	for _, tmpl := range a.topDimensions {
+		in := *tmpl
+		in.Identifier = aws.String(inst.ResourceID)
+		in.ServiceType = aws.String(inst.ServiceType)
+		in.StartTime = aws.Time(start)
+		in.EndTime = aws.Time(end)
+
+		report, err := a.describeTopDimensions(&in)
+		if err != nil {
+			a.logger.Errorw("Failed to describe top dimension keys of instance "+inst.ARN, zap.Error(err))
+			continue
+		}
+
+		if err := a.sendTopDimensionsEvent(inst, &in, report); err != nil {
+			a.logger.Errorw("Failed to send top dimensions event", zap.Error(err))
+		}
+	}
+}
+
+// describeTopDimensions returns a report of the dimension keys matching the
+// given input.
+func (a *adapter) describeTopDimensions(in *pi.DescribeDimensionKeysInput) (*TopDimensionsReport, error) {
+	report := &TopDimensionsReport{
+		Metric:    aws.StringValue(in.Metric),
+		Group:     aws.StringValue(in.GroupBy.Group),
+		StartTime: aws.TimeValue(in.StartTime),
+		EndTime:   aws.TimeValue(in.EndTime),
+		Keys:      make([]DimensionKey, 0),
+	}
+
+	for {
+		out, err := a.pIClient.DescribeDimensionKeys(in)
+		if err != nil {
+			return nil, err
+		}
+
+		if out.AlignedStartTime != nil {
+			report.StartTime = *out.AlignedStartTime
+		}
+		if out.AlignedEndTime != nil {
+			report.EndTime = *out.AlignedEndTime
+		}
+
+		for _, k := range out.Keys {
+			report.Keys = append(report.Keys, DimensionKey{
+				Dimensions: aws.StringValueMap(k.Dimensions),
+				Total:      aws.Float64Value(k.Total),
+				Partitions: aws.Float64ValueSlice(k.Partitions),
+			})
+		}
+
+		// partition keys are identical across pages
+		if report.PartitionBy == nil {
+			for _, pk := range out.PartitionKeys {
+				report.PartitionBy = append(report.PartitionBy, aws.StringValueMap(pk.Dimensions))
+			}
+		}
+
+		if out.NextToken == nil {
+			break
+		}
+		next := *in
+		next.NextToken = out.NextToken
+		in = &next
+	}
+
+	return report, nil
+}
+
+// sendTopDimensionsEvent sends the given report of top dimension keys.
+func (a *adapter) sendTopDimensionsEvent(inst v1alpha1.AWSPerformanceInsightsInstance,
+	in *pi.DescribeDimensionKeysInput, report *TopDimensionsReport) error {
+
+	event := cloudevents.NewEvent(cloudevents.VersionV1)
+	event.SetID(topDimensionsEventID(inst, in, report.EndTime))
+	event.SetType(v1alpha1.AWSEventType(a.arn.Service, v1alpha1.AWSPerformanceInsightsTopDimensionsEventType))
+	event.SetSource(a.arn.String())
+	event.SetSubject(inst.ARN)
+	event.SetTime(report.EndTime)
+	event.SetExtension("pimetric", report.Metric)
+	event.SetExtension("pigroup", report.Group)
+	if err := event.SetData(cloudevents.ApplicationJSON, report); err != nil {
+		return fmt.Errorf("failed to set event data: %w", err)
+	}
+
+	if result := a.ceClient.Send(context.Background(), event); !cloudevents.IsACK(result) {
+		return fmt.Errorf("failed to send event: %w", result)
+	}
+
+	return nil
+}
+
+// topDimensionsEventID returns a deterministic CloudEvent ID for the report of
+// the given top dimensions query for the time window ending at the given time.
+func topDimensionsEventID(inst v1alpha1.AWSPerformanceInsightsInstance,
+	in *pi.DescribeDimensionKeysInput, end time.Time) string {
+
+	id := inst.ResourceID + "/" + aws.StringValue(in.Metric) + "/" + aws.StringValue(in.GroupBy.Group)
+	if in.PartitionBy != nil {
+		id += "/" + aws.StringValue(in.PartitionBy.Group)
+	}
+	if len(in.Filter) > 0 {
+		id += "/" + dimensionsHash(aws.StringValueMap(in.Filter))
+	}
+	return id + "/" + end.UTC().Format(time.RFC3339)
+}
+
+// setDimensionExtensions sets a CloudEvent extension for each of the given
+// dimensions. Extension names only allow lower-case alphanumeric characters,
+// so "db.sql.id" becomes "pidbsqlid". Names longer than 20 characters are
+// truncated, so "db.sql_tokenized.statement" becomes "pidbsqltokenizedstat".
+func setDimensionExtensions(event *cloudevents.Event, dims map[string]string) {
+	for name, val := range dims {
+		event.SetExtension(dimensionExtensionName(name), val)
+	}
+}
+
+// dimensionExtensionName returns the name of the CloudEvent extension for
+// the given dimension.
+func dimensionExtensionName(dim string) string {
+	ext := dimensionExtensionPrefix + common.StripNonAlphanumCharsAndMapToLower(dim)
+	if len(ext) > maxExtensionNameLength {
+		ext = ext[:maxExtensionNameLength]
+	}
+
+	return ext
+}
@@ -0,0 +1,137 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsperformanceinsightssource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/pi"
+
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	k8sclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/logging"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/env"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/poller"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client"
+)
+
+// Granularity of the datapoints applied when the source doesn't specify one,
+// in seconds. Same as the value set by the reconciler on single-tenant adapters.
+const defaultPeriod int64 = 60
+
+// NewMTEnvConfig satisfies env.ConfigConstructor.
+// Returns an accessor for the source's multi-tenant adapter envConfig.
+func NewMTEnvConfig() env.ConfigAccessor {
+	return &env.Config{}
+}
+
+// NewMTAdapter returns a constructor for the source's multi-tenant adapter.
+func NewMTAdapter(component string) pkgadapter.AdapterConstructor {
+	return func(ctx context.Context, _ pkgadapter.EnvConfigAccessor, ceClient cloudevents.Client) pkgadapter.Adapter {
+		logger := logging.FromContext(ctx)
+		ns := injection.GetNamespaceScope(ctx)
+		secrCli := k8sclient.Get(ctx).CoreV1().Secrets(ns)
+
+		return poller.New(logger, ceClient,
+			jobBuilder(logger, secrCli),
+			poller.WithCredentials(jobConfig, secrCli, jobCredentials),
+			status.NewPatcher(component, client.Get(ctx).SourcesV1alpha1()),
+		)
+	}
+}
+
+// jobBuilder returns a poller.JobBuilder which polls the metrics of the
+// database instances resolved by the reconciler, using the credentials
+// referenced in each source's spec.
+func jobBuilder(logger *zap.SugaredLogger, secrCli coreclientv1.SecretInterface) poller.JobBuilder {
+	return func(ctx context.Context, src v1alpha1.EventSource, ceClient cloudevents.Client) (pkgadapter.Adapter, error) {
+		typedSrc := src.(*v1alpha1.AWSPerformanceInsightsSource)
+
+		if len(typedSrc.Status.Instances) == 0 {
+			return nil, errors.New("no database instance was resolved from the ARN yet")
+		}
+
+		srcARN := arn.ARN(typedSrc.Spec.ARN)
+
+		sess, err := poller.NewAWSSession(secrCli, &typedSrc.Spec.Credentials,
+			aws.NewConfig().WithRegion(srcARN.Region),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var metricQueries string
+		if qs := typedSrc.Spec.MetricQueries; len(qs) > 0 {
+			q, _ := json.Marshal(qs)
+			metricQueries = string(q)
+		}
+
+		var topDimensions string
+		if tds := typedSrc.Spec.TopDimensions; len(tds) > 0 {
+			td, _ := json.Marshal(tds)
+			topDimensions = string(td)
+		}
+
+		instances, _ := json.Marshal(typedSrc.Status.Instances)
+
+		period := defaultPeriod
+		if p := typedSrc.Spec.Period; p != nil {
+			period = *p
+		}
+
+		logger := logger.With(zap.String("source", src.GetNamespace()+"/"+src.GetName()))
+
+		return newAdapter(logger, pi.New(sess), ceClient, srcARN,
+			time.Duration(typedSrc.Spec.PollingInterval), time.Duration(period)*time.Second,
+			typedSrc.Spec.Metrics, metricQueries, topDimensions, string(instances))
+	}
+}
+
+// jobConfig implements poller.ConfigFunc.
+// The database instances are part of the configuration because they are
+// resolved asynchronously by the reconciler.
+func jobConfig(src v1alpha1.EventSource) interface{} {
+	typedSrc := src.(*v1alpha1.AWSPerformanceInsightsSource)
+
+	return struct {
+		v1alpha1.AWSPerformanceInsightsSourceSpec
+		Instances []v1alpha1.AWSPerformanceInsightsInstance
+	}{
+		typedSrc.Spec,
+		typedSrc.Status.Instances,
+	}
+}
+
+// jobCredentials implements poller.CredentialsFunc.
+func jobCredentials(src v1alpha1.EventSource) *v1alpha1.AWSSecurityCredentials {
+	return &src.(*v1alpha1.AWSPerformanceInsightsSource).Spec.Credentials
+}
@@ -20,12 +20,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 
+	"k8s.io/apimachinery/pkg/labels"
 	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
@@ -35,11 +37,12 @@ import (
 	"knative.dev/pkg/logging"
 
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/awssnssource/handler"
-	"github.com/triggermesh/aws-event-sources/pkg/adapter/awssnssource/status"
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/env"
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/router"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
 	"github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client"
+	informerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/informers/sources/v1alpha1/awssnssource"
 	snsclient "github.com/triggermesh/aws-event-sources/pkg/client/sns"
 	"github.com/triggermesh/aws-event-sources/pkg/routing"
 )
@@ -51,9 +54,20 @@ type adapter struct {
 	ceClient cloudevents.Client
 	snsCg    snsclient.ClientGetter
 
+	// verifier of SNS message signatures, shared by all handlers to
+	// share its cache of signing certificates
+	sigVerifier *handler.SignatureVerifier
+
+	// router of the internal HTTP server, which serves debugging
+	// endpoints that must not be exposed publicly
+	internalRouter *router.Router
+
 	// fields accessed during object reconciliation
 	router        *router.Router
 	statusPatcher *status.Patcher
+
+	// URL paths of sources for which no handler could be registered
+	failedPaths sync.Map
 }
 
 // Check the interfaces adapter should implement.
@@ -76,17 +90,43 @@ func NewAdapter(component string) pkgadapter.AdapterConstructor {
 
 		ns := injection.GetNamespaceScope(ctx)
 		secrGetter := secretGetter(k8sclient.Get(ctx).CoreV1().Secrets(ns))
-		srcClient := client.Get(ctx).SourcesV1alpha1().AWSSNSSources(ns)
+		srcClient := client.Get(ctx).SourcesV1alpha1()
 
-		return &adapter{
+		mustRegisterStatsView()
+
+		a := &adapter{
 			logger: logging.FromContext(ctx),
 
 			ceClient: ceClient,
 			snsCg:    snsclient.NewClientGetter(secrGetter),
 
+			sigVerifier: handler.NewSignatureVerifier(),
+
+			internalRouter: &router.Router{},
+
 			router:        &router.Router{},
 			statusPatcher: status.NewPatcher(component, srcClient),
 		}
+
+		srcInformer := informerv1alpha1.Get(ctx)
+
+		// The URL path of a source consists of its namespace and name
+		// (see routing.URLPath), so these single-segment paths can
+		// never collide with the path of a source.
+		a.router.RegisterPath(healthPath, &readinessChecker{
+			synced: srcInformer.Informer().HasSynced,
+			lister: func() ([]*v1alpha1.AWSSNSSource, error) {
+				return srcInformer.Lister().AWSSNSSources(ns).List(labels.Everything())
+			},
+			isServed: a.isServed,
+		})
+
+		// Routes reveal the namespaces and names of all sources, and
+		// are therefore only listed on the internal port, which is
+		// not exposed by the Knative Service.
+		a.internalRouter.RegisterPath(routesPath, a.router.RoutesHandler())
+
+		return a
 	}
 }
 
@@ -96,8 +136,15 @@ func secretGetter(cli coreclientv1.SecretInterface) snsclient.NamespacedSecretsG
 	}
 }
 
+// URL paths of the adapter's own HTTP endpoints.
+const (
+	healthPath = "/health"
+	routesPath = "/routes"
+)
+
 const (
 	serverPort                uint16 = 8080
+	internalServerPort        uint16 = 8081
 	serverShutdownGracePeriod        = time.Second * 10
 )
 
@@ -108,12 +155,34 @@ func (a *adapter) Start(ctx context.Context) error {
 		Handler: a,
 	}
 
-	return runHandler(ctx, server)
+	internalServer := &http.Server{
+		Addr:    fmt.Sprint(":", internalServerPort),
+		Handler: a.internalRouter,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// each server is stopped when the other one returns
+	internalErrCh := make(chan error)
+	go func() {
+		err := runHandler(ctx, internalServer)
+		cancel()
+		internalErrCh <- err
+	}()
+
+	err := runHandler(ctx, server)
+	cancel()
+
+	if internalErr := <-internalErrCh; internalErr != nil && err == nil {
+		return fmt.Errorf("running internal HTTP handler: %w", internalErr)
+	}
+	return err
 }
 
-// runHandler runs the HTTP event handler until ctx get cancelled.
+// runHandler runs the given HTTP handler until ctx get cancelled.
 func runHandler(ctx context.Context, s *http.Server) error {
-	logging.FromContext(ctx).Info("Starting HTTP event handler")
+	logging.FromContext(ctx).Info("Starting HTTP handler on address ", s.Addr)
 
 	errCh := make(chan error)
 	go func() {
@@ -129,7 +198,7 @@ func runHandler(ctx context.Context, s *http.Server) error {
 
 	select {
 	case <-ctx.Done():
-		logging.FromContext(ctx).Info("HTTP event handler is shutting down")
+		logging.FromContext(ctx).Info("HTTP handler on address ", s.Addr, " is shutting down")
 
 		ctx, cancel := context.WithTimeout(context.Background(), serverShutdownGracePeriod)
 		defer cancel()
@@ -153,23 +222,43 @@ func (a *adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // RegisterHandlerFor implements MTAdapter.
 func (a *adapter) RegisterHandlerFor(ctx context.Context, src *v1alpha1.AWSSNSSource) error {
+	urlPath := routing.URLPath(src)
+
 	snsCli, err := a.snsCg.Get(src)
 	if err != nil {
+		a.failedPaths.Store(urlPath, struct{}{})
 		return fmt.Errorf("obtaining SNS client: %w", err)
 	}
 
-	h := handler.New(src, a.logger, a.ceClient, snsCli)
+	h := handler.New(src, a.logger, a.ceClient, snsCli, a.sigVerifier)
 
-	a.router.RegisterPath(routing.URLPath(src), h)
+	a.router.RegisterPath(urlPath, instrumentHandler(h, src))
+	a.failedPaths.Delete(urlPath)
 	return nil
 }
 
 // DeregisterHandlerFor implements MTAdapter.
 func (a *adapter) DeregisterHandlerFor(ctx context.Context, src *v1alpha1.AWSSNSSource) error {
-	a.router.DeregisterPath(routing.URLPath(src))
+	urlPath := routing.URLPath(src)
+
+	a.router.DeregisterPath(urlPath)
+	a.failedPaths.Delete(urlPath)
 	return nil
 }
 
+// isServed returns whether the adapter has handled the given source, either
+// by registering a HTTP handler for it or by failing to do so. A source which
+// can not be served must not prevent the adapter from serving other sources.
+func (a *adapter) isServed(src *v1alpha1.AWSSNSSource) bool {
+	urlPath := routing.URLPath(src)
+
+	if a.router.HasPath(urlPath) {
+		return true
+	}
+	_, failed := a.failedPaths.Load(urlPath)
+	return failed
+}
+
 // PropagateCondition implements MTAdapter.
 func (a *adapter) PropagateCondition(ctx context.Context, src *v1alpha1.AWSSNSSource, cond *apis.Condition) error {
 	return status.PropagateCondition(ctx, a.statusPatcher, src, cond)
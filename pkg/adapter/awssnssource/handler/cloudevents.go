@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/aws/aws-sdk-go/service/sns"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+// Name of the subscription attribute which enables raw message delivery.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-large-payload-raw-message-delivery.html
+const subsAttrRawMessageDelivery = "RawMessageDelivery"
+
+const (
+	snsMsgAttrDataTypeBinary    = "Binary"
+	ceExtensionSNSMessagePrefix = "snsmsg"
+)
+
+var eventType = v1alpha1.AWSEventType(sns.ServiceName, v1alpha1.AWSSNSGenericEventType)
+
+// messageAttribute is an attribute of a SNS message.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-message-attributes.html
+type messageAttribute struct {
+	Type  string
+	Value string
+}
+
+// newNotificationEvent returns a CloudEvent for the given SNS notification,
+// which was parsed from the given message body.
+func (h *Handler) newNotificationEvent(notif *message, body []byte) (*cloudevents.Event, error) {
+	event := cloudevents.NewEvent()
+	event.SetType(eventType)
+	event.SetSource(notif.TopicARN)
+	event.SetID(notif.MessageID)
+	event.SetSubject(notif.Subject)
+
+	if t, err := time.Parse(time.RFC3339, notif.Timestamp); err == nil {
+		event.SetTime(t)
+	}
+
+	for name, val := range ceExtensionAttrsForMessage(notif.MessageAttributes) {
+		event.SetExtension(name, val)
+	}
+
+	if !h.unwrapMessage {
+		if err := event.SetData(cloudevents.ApplicationJSON, json.RawMessage(body)); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+
+	if err := setMessageData(&event, []byte(notif.Message)); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// newRawNotificationEvent returns a CloudEvent for a SNS message delivered
+// raw, without JSON envelope, in the given request.
+func (h *Handler) newRawNotificationEvent(r *http.Request, body []byte) (*cloudevents.Event, error) {
+	event := cloudevents.NewEvent()
+	event.SetType(eventType)
+	event.SetSource(h.topicARN)
+	event.SetID(r.Header.Get(headerMsgIDKey))
+	event.SetTime(time.Now())
+
+	if err := setMessageData(&event, body); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// setMessageData sets the given SNS message as the data of a CloudEvent.
+// Messages which are valid JSON documents are set with the application/json
+// content type, all other messages with the text/plain content type.
+func setMessageData(event *cloudevents.Event, msg []byte) error {
+	if json.Valid(msg) {
+		return event.SetData(cloudevents.ApplicationJSON, json.RawMessage(msg))
+	}
+	return event.SetData(cloudevents.TextPlain, msg)
+}
+
+// ceExtensionAttrsForMessage returns a collection of CloudEvents extension
+// attributes translated from the given SNS message attributes.
+//
+// Attributes with a Binary data type are excluded.
+// The resulting extension attribute name is composed of the 'snsmsg' prefix,
+// followed by the lowercase name of the SNS message attribute, from which all
+// non-alphanumeric characters have been removed (e.g. "snsmsgmyattribute").
+//
+// https://github.com/cloudevents/spec/blob/v1.0.1/spec.md#extension-context-attributes
+func ceExtensionAttrsForMessage(attrs map[string]messageAttribute) map[string]interface{} {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	ceExtAttrs := make(map[string]interface{}, len(attrs))
+
+	for name, attr := range attrs {
+		if attr.Type != snsMsgAttrDataTypeBinary {
+			ceExtAttrs[ceExtensionSNSMessagePrefix+common.StripNonAlphanumCharsAndMapToLower(name)] = attr.Value
+		}
+	}
+
+	return ceExtAttrs
+}
@@ -19,10 +19,14 @@ package handler
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"go.uber.org/zap"
@@ -37,9 +41,10 @@ import (
 )
 
 const (
-	headerMsgTypeKey  = "X-Amz-Sns-Message-Type"
-	headerMsgIDKey    = "X-Amz-Sns-Message-Id"
-	headerTopicARNKey = "X-Amz-Sns-Topic-Arn"
+	headerMsgTypeKey     = "X-Amz-Sns-Message-Type"
+	headerMsgIDKey       = "X-Amz-Sns-Message-Id"
+	headerTopicARNKey    = "X-Amz-Sns-Topic-Arn"
+	headerRawDeliveryKey = "X-Amz-Sns-Rawdelivery"
 
 	headerMsgTypeNotification = "Notification"
 	headerMsgTypeSubsConfirm  = "SubscriptionConfirmation"
@@ -50,9 +55,14 @@ type Handler struct {
 	logger *zap.SugaredLogger
 
 	ceClient cloudevents.Client
-	eventSrc string
 	sink     string
 
+	topicARN      string
+	unwrapMessage bool
+	rawDelivery   bool
+	token         string
+
+	verifier  *SignatureVerifier
 	snsClient snsclient.Client
 }
 
@@ -61,20 +71,39 @@ var _ http.Handler = (*Handler)(nil)
 
 // New returns an initialized Handler.
 func New(src *v1alpha1.AWSSNSSource, logger *zap.SugaredLogger,
-	ceClient cloudevents.Client, snsClient snsclient.Client) *Handler {
+	ceClient cloudevents.Client, snsClient snsclient.Client, verifier *SignatureVerifier) *Handler {
 
 	return &Handler{
 		logger: logger.With(zap.String(logkey.Key, src.Namespace+"/"+src.Name)),
 
 		ceClient: ceClient,
-		eventSrc: src.AsEventSource(),
 		sink:     src.Status.SinkURI.String(),
 
+		topicARN:      src.Spec.ARN.String(),
+		unwrapMessage: src.Spec.UnwrapMessage,
+		rawDelivery:   isRawMessageDeliveryEnabled(src),
+		token:         src.SubscriptionToken(),
+
+		verifier:  verifier,
 		snsClient: snsClient,
 	}
 }
 
-var eventType = v1alpha1.AWSEventType(sns.ServiceName, v1alpha1.AWSSNSGenericEventType)
+// isRawMessageDeliveryEnabled returns whether the subscription of the given
+// source delivers messages without their JSON envelope.
+func isRawMessageDeliveryEnabled(src *v1alpha1.AWSSNSSource) bool {
+	if src.Spec.RawMessageDelivery != nil {
+		return *src.Spec.RawMessageDelivery
+	}
+
+	val := src.Spec.SubscriptionAttributes[subsAttrRawMessageDelivery]
+	if val == nil {
+		return false
+	}
+
+	enabled, _ := strconv.ParseBool(*val)
+	return enabled
+}
 
 // ServeHTTP implements http.Handler.
 // Confirms subscriptions when the incoming payload is a subscription
@@ -101,27 +130,42 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		h.logger.Debug("Request body: ", string(body))
 
-		notif := &notification{}
-		if err := json.Unmarshal(body, notif); err != nil {
-			handleError("Failed to parse notification", err, http.StatusBadRequest, h.logger, w)
-			return
-		}
+		var event *cloudevents.Event
+
+		// Messages delivered raw are not wrapped in a JSON envelope, and
+		// therefore not signed either.
+		if r.Header.Get(headerRawDeliveryKey) == "true" {
+			if err := h.verifyRawDelivery(r); err != nil {
+				handleError("Failed to verify raw notification", err, http.StatusForbidden, h.logger, w)
+				return
+			}
 
-		event := cloudevents.NewEvent()
-		event.SetType(eventType)
-		event.SetSource(h.eventSrc)
-		event.SetID(r.Header.Get(headerMsgIDKey))
-		event.SetSubject(notif.Subject)
+			event, err = h.newRawNotificationEvent(r, body)
 
-		if err := event.SetData(cloudevents.ApplicationJSON, json.RawMessage(body)); err != nil {
+		} else {
+			notif := &message{}
+			if err := json.Unmarshal(body, notif); err != nil {
+				handleError("Failed to parse notification", err, http.StatusBadRequest, h.logger, w)
+				return
+			}
+
+			if err := h.verifyMessage(r, notif); err != nil {
+				handleError("Failed to verify notification", err, http.StatusForbidden, h.logger, w)
+				return
+			}
+
+			event, err = h.newNotificationEvent(notif, body)
+		}
+
+		if err != nil {
 			handleError("Failed to set event data", err, http.StatusInternalServerError, h.logger, w)
 			return
 		}
 
 		ctx := cloudevents.ContextWithTarget(context.Background(), h.sink)
 
-		if result := h.ceClient.Send(ctx, event); !cloudevents.IsACK(result) {
-			handleError("Failed to send CloudEvent", err, http.StatusInternalServerError, h.logger, w)
+		if result := h.ceClient.Send(ctx, *event); !cloudevents.IsACK(result) {
+			handleError("Failed to send CloudEvent", result, http.StatusInternalServerError, h.logger, w)
 			return
 		}
 
@@ -136,14 +180,19 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		h.logger.Debug("Request body: ", string(body))
 
-		subsConfirm := &subscriptionConfirmation{}
+		subsConfirm := &message{}
 		if err := json.Unmarshal(body, subsConfirm); err != nil {
 			handleError("Failed to parse subscription confirmation", err, http.StatusBadRequest, h.logger, w)
 			return
 		}
 
+		if err := h.verifyMessage(r, subsConfirm); err != nil {
+			handleError("Failed to verify subscription confirmation", err, http.StatusForbidden, h.logger, w)
+			return
+		}
+
 		resp, err := h.snsClient.ConfirmSubscription(&sns.ConfirmSubscriptionInput{
-			TopicArn: aws.String(r.Header.Get(headerTopicARNKey)),
+			TopicArn: aws.String(subsConfirm.TopicARN),
 			Token:    aws.String(subsConfirm.Token),
 		})
 		if err != nil {
@@ -164,14 +213,93 @@ func handleError(msg string, err error, httpCode int, logger *zap.SugaredLogger,
 	http.Error(w, fmt.Sprint(msg, ": ", err), httpCode)
 }
 
-// notification represents a SNS message of type Notification.
-// https://docs.aws.amazon.com/sns/latest/dg/sns-message-and-json-formats.html#http-notification-json
-type notification struct {
-	Subject string
+// verifyMessage ensures that the given SNS message was signed by SNS and
+// published to the source's topic.
+func (h *Handler) verifyMessage(r *http.Request, msg *message) error {
+	if msgType := r.Header.Get(headerMsgTypeKey); msg.Type != msgType {
+		return fmt.Errorf("message of type %q does not match type %q from request headers", msg.Type, msgType)
+	}
+
+	if msg.TopicARN != h.topicARN {
+		return fmt.Errorf("message was not published to topic %s", h.topicARN)
+	}
+
+	if err := h.verifier.Verify(msg); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	return nil
+}
+
+// verifyRawDelivery ensures that raw deliveries are accepted by the source,
+// and that the given raw message was delivered by the source's subscription
+// to the source's topic.
+// Raw messages are not signed, so their origin can only be established by the
+// subscription token included in the URL of the source's endpoint.
+func (h *Handler) verifyRawDelivery(r *http.Request) error {
+	if !h.rawDelivery {
+		return errors.New("raw message delivery is not enabled on the subscription")
+	}
+
+	token := r.URL.Query().Get(v1alpha1.AWSSNSSubscriptionTokenParam)
+	if h.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		return errors.New("missing or invalid subscription token")
+	}
+
+	if r.Header.Get(headerTopicARNKey) != h.topicARN {
+		return fmt.Errorf("message was not published to topic %s", h.topicARN)
+	}
+	return nil
+}
+
+// message represents a SNS message of type Notification or SubscriptionConfirmation.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-message-and-json-formats.html
+type message struct {
+	Type              string
+	MessageID         string `json:"MessageId"`
+	TopicARN          string `json:"TopicArn"`
+	Subject           string
+	Message           string
+	Timestamp         string
+	Token             string
+	MessageAttributes map[string]messageAttribute
+	SubscribeURL      string
+	SignatureVersion  string
+	Signature         string
+	SigningCertURL    string
 }
 
-// subscriptionConfirmation represents a SNS message of type SubscriptionConfirmation
-// https://docs.aws.amazon.com/sns/latest/dg/sns-message-and-json-formats.html#http-subscription-confirmation-json
-type subscriptionConfirmation struct {
-	Token string
+// stringToSign returns the canonical representation of the message which is
+// signed by SNS.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+func (m *message) stringToSign() []byte {
+	var b strings.Builder
+
+	write := func(key, val string) {
+		b.WriteString(key)
+		b.WriteByte('\n')
+		b.WriteString(val)
+		b.WriteByte('\n')
+	}
+
+	write("Message", m.Message)
+	write("MessageId", m.MessageID)
+
+	if m.Type == headerMsgTypeNotification {
+		if m.Subject != "" {
+			write("Subject", m.Subject)
+		}
+		write("Timestamp", m.Timestamp)
+		write("TopicArn", m.TopicARN)
+		write("Type", m.Type)
+
+		return []byte(b.String())
+	}
+
+	write("SubscribeURL", m.SubscribeURL)
+	write("Timestamp", m.Timestamp)
+	write("Token", m.Token)
+	write("TopicArn", m.TopicARN)
+	write("Type", m.Type)
+
+	return []byte(b.String())
 }
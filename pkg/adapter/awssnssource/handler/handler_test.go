@@ -17,12 +17,16 @@ limitations under the License.
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -38,14 +42,17 @@ import (
 const (
 	tMsgID      = "00000000-0000-0000-0000-000000000000"
 	tMsgSubject = "My test message"
-	tEventSrc   = "test.source"
 
 	tTopicARN = "arn:aws:sns:us-fake-0:123456789012:MyTopic"
+
+	tToken = "00000000-0000-0000-0000-000000000001"
 )
 
 func TestHandler(t *testing.T) {
+	pki := newTestPKI(t)
+
 	t.Run("unsupported method", func(t *testing.T) {
-		h := newTestHandler(t)
+		h, _ := newTestHandler(t, pki)
 
 		req, err := http.NewRequest(http.MethodGet, "", nil)
 		require.NoError(t, err)
@@ -57,7 +64,7 @@ func TestHandler(t *testing.T) {
 	})
 
 	t.Run("missing message type header", func(t *testing.T) {
-		h := newTestHandler(t)
+		h, _ := newTestHandler(t, pki)
 
 		msgBody := strings.NewReader(tNotifMsg)
 		req := newPostRequest(t, msgBody)
@@ -71,10 +78,10 @@ func TestHandler(t *testing.T) {
 	t.Run("valid subscription confirmation", func(t *testing.T) {
 		snsClient := &mockedSNSClient{}
 
-		h := newTestHandler(t)
+		h, certURL := newTestHandler(t, pki)
 		h.snsClient = snsClient
 
-		msgBody := strings.NewReader(tSubsConfirmMsg)
+		msgBody := signedBody(t, pki, certURL, tSubsConfirmMsg)
 		req := newPostRequest(t, msgBody)
 		req.Header.Set(headerMsgTypeKey, headerMsgTypeSubsConfirm)
 
@@ -90,10 +97,10 @@ func TestHandler(t *testing.T) {
 			failConfirmSubscription: true,
 		}
 
-		h := newTestHandler(t)
+		h, certURL := newTestHandler(t, pki)
 		h.snsClient = snsClient
 
-		msgBody := strings.NewReader(tSubsConfirmMsg)
+		msgBody := signedBody(t, pki, certURL, tSubsConfirmMsg)
 		req := newPostRequest(t, msgBody)
 		req.Header.Set(headerMsgTypeKey, headerMsgTypeSubsConfirm)
 
@@ -108,10 +115,10 @@ func TestHandler(t *testing.T) {
 	t.Run("valid notification", func(t *testing.T) {
 		ceClient := adaptertest.NewTestClient()
 
-		h := newTestHandler(t)
+		h, certURL := newTestHandler(t, pki)
 		h.ceClient = ceClient
 
-		msgBody := strings.NewReader(tNotifMsg)
+		msgBody := signedBody(t, pki, certURL, tNotifMsg)
 
 		req := newPostRequest(t, msgBody)
 		req.Header.Set(headerMsgTypeKey, headerMsgTypeNotification)
@@ -127,15 +134,182 @@ func TestHandler(t *testing.T) {
 
 		event := sentEvents[0]
 		assert.Equal(t, "com.amazon.sns.notification", event.Type())
-		assert.Equal(t, tEventSrc, event.Source())
+		assert.Equal(t, tTopicARN, event.Source())
 		assert.Equal(t, tMsgID, event.ID())
 		assert.Equal(t, tMsgSubject, event.Subject())
+		assert.Equal(t, "2012-05-02T00:54:06.655Z", event.Time().Format(time.RFC3339Nano))
+		assert.Equal(t, cloudevents.ApplicationJSON, event.DataContentType())
+		assert.Equal(t, map[string]interface{}{
+			"snsmsgmyattribute": "my value",
+			"snsmsgmycount":     "42",
+		}, event.Extensions())
+
+		msg := &message{}
+		require.NoError(t, event.DataAs(msg))
+		assert.Equal(t, "Hello world!", msg.Message)
+	})
+
+	t.Run("valid notification with unwrapped message", func(t *testing.T) {
+		testCases := map[string]struct {
+			msg               string
+			expectContentType string
+		}{
+			"text message": {
+				msg:               "Hello world!",
+				expectContentType: cloudevents.TextPlain,
+			},
+			"JSON message": {
+				msg:               `{"greeting":"Hello world!"}`,
+				expectContentType: cloudevents.ApplicationJSON,
+			},
+		}
+
+		for name, tc := range testCases {
+			//nolint:scopelint
+			t.Run(name, func(t *testing.T) {
+				ceClient := adaptertest.NewTestClient()
+
+				h, certURL := newTestHandler(t, pki)
+				h.ceClient = ceClient
+				h.unwrapMessage = true
+
+				notif := &message{}
+				require.NoError(t, json.Unmarshal([]byte(tNotifMsg), notif))
+				notif.Message = tc.msg
+
+				rawNotif, err := json.Marshal(notif)
+				require.NoError(t, err)
+
+				msgBody := signedBody(t, pki, certURL, string(rawNotif))
+
+				req := newPostRequest(t, msgBody)
+				req.Header.Set(headerMsgTypeKey, headerMsgTypeNotification)
+				req.Header.Set(headerMsgIDKey, tMsgID)
+
+				rr := httptest.NewRecorder()
+				h.ServeHTTP(rr, req)
+
+				assert.Equal(t, http.StatusOK, rr.Code)
+
+				sentEvents := ceClient.Sent()
+				require.Len(t, sentEvents, 1)
+
+				event := sentEvents[0]
+				assert.Equal(t, tc.expectContentType, event.DataContentType())
+				assert.Equal(t, tc.msg, string(event.Data()))
+			})
+		}
+	})
+
+	t.Run("valid raw notification", func(t *testing.T) {
+		ceClient := adaptertest.NewTestClient()
+
+		h, _ := newTestHandler(t, pki)
+		h.ceClient = ceClient
+		h.rawDelivery = true
+
+		msgBody := strings.NewReader(`{"greeting":"Hello world!"}`)
+
+		req := newPostRequest(t, msgBody)
+		req.URL.RawQuery = "token=" + tToken
+		req.Header.Set(headerMsgTypeKey, headerMsgTypeNotification)
+		req.Header.Set(headerMsgIDKey, tMsgID)
+		req.Header.Set(headerTopicARNKey, tTopicARN)
+		req.Header.Set(headerRawDeliveryKey, "true")
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		sentEvents := ceClient.Sent()
+		require.Len(t, sentEvents, 1)
+
+		event := sentEvents[0]
+		assert.Equal(t, "com.amazon.sns.notification", event.Type())
+		assert.Equal(t, tTopicARN, event.Source())
+		assert.Equal(t, tMsgID, event.ID())
+		assert.Equal(t, cloudevents.ApplicationJSON, event.DataContentType())
+		assert.Equal(t, `{"greeting":"Hello world!"}`, string(event.Data()))
+	})
+
+	t.Run("raw notification without raw delivery", func(t *testing.T) {
+		ceClient := adaptertest.NewTestClient()
+
+		h, _ := newTestHandler(t, pki)
+		h.ceClient = ceClient
+
+		msgBody := strings.NewReader("Hello world!")
+
+		req := newPostRequest(t, msgBody)
+		req.Header.Set(headerMsgTypeKey, headerMsgTypeNotification)
+		req.Header.Set(headerMsgIDKey, tMsgID)
+		req.Header.Set(headerTopicARNKey, tTopicARN)
+		req.Header.Set(headerRawDeliveryKey, "true")
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "raw message delivery is not enabled")
+
+		require.Empty(t, ceClient.Sent())
+	})
+
+	t.Run("raw notification with an invalid subscription token", func(t *testing.T) {
+		ceClient := adaptertest.NewTestClient()
+
+		h, _ := newTestHandler(t, pki)
+		h.ceClient = ceClient
+		h.rawDelivery = true
+
+		msgBody := strings.NewReader("Hello world!")
+
+		req := newPostRequest(t, msgBody)
+		req.URL.RawQuery = "token=not-" + tToken
+		req.Header.Set(headerMsgTypeKey, headerMsgTypeNotification)
+		req.Header.Set(headerMsgIDKey, tMsgID)
+		req.Header.Set(headerTopicARNKey, tTopicARN)
+		req.Header.Set(headerRawDeliveryKey, "true")
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "missing or invalid subscription token")
+
+		require.Empty(t, ceClient.Sent())
+	})
+
+	t.Run("raw notification from another topic", func(t *testing.T) {
+		ceClient := adaptertest.NewTestClient()
+
+		h, _ := newTestHandler(t, pki)
+		h.ceClient = ceClient
+		h.rawDelivery = true
+
+		msgBody := strings.NewReader("Hello world!")
+
+		req := newPostRequest(t, msgBody)
+		req.URL.RawQuery = "token=" + tToken
+		req.Header.Set(headerMsgTypeKey, headerMsgTypeNotification)
+		req.Header.Set(headerMsgIDKey, tMsgID)
+		req.Header.Set(headerTopicARNKey, tTopicARN+"Other")
+		req.Header.Set(headerRawDeliveryKey, "true")
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "message was not published to topic")
+
+		require.Empty(t, ceClient.Sent())
 	})
 
 	t.Run("invalid notification payload", func(t *testing.T) {
 		ceClient := adaptertest.NewTestClient()
 
-		h := newTestHandler(t)
+		h, _ := newTestHandler(t, pki)
 		h.ceClient = ceClient
 
 		msgBody := strings.NewReader("{ not a JSON }")
@@ -152,15 +326,97 @@ func TestHandler(t *testing.T) {
 
 		require.Empty(t, ceClient.Sent())
 	})
+
+	t.Run("unsigned notification", func(t *testing.T) {
+		ceClient := adaptertest.NewTestClient()
+
+		h, _ := newTestHandler(t, pki)
+		h.ceClient = ceClient
+
+		msgBody := strings.NewReader(tNotifMsg)
+
+		req := newPostRequest(t, msgBody)
+		req.Header.Set(headerMsgTypeKey, headerMsgTypeNotification)
+		req.Header.Set(headerMsgIDKey, tMsgID)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Failed to verify notification:")
+
+		require.Empty(t, ceClient.Sent())
+	})
+
+	t.Run("notification from another topic", func(t *testing.T) {
+		ceClient := adaptertest.NewTestClient()
+
+		h, certURL := newTestHandler(t, pki)
+		h.ceClient = ceClient
+		h.topicARN = tTopicARN + "Other"
+
+		msgBody := signedBody(t, pki, certURL, tNotifMsg)
+
+		req := newPostRequest(t, msgBody)
+		req.Header.Set(headerMsgTypeKey, headerMsgTypeNotification)
+		req.Header.Set(headerMsgIDKey, tMsgID)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "message was not published to topic")
+
+		require.Empty(t, ceClient.Sent())
+	})
+
+	t.Run("unsigned subscription confirmation", func(t *testing.T) {
+		snsClient := &mockedSNSClient{}
+
+		h, _ := newTestHandler(t, pki)
+		h.snsClient = snsClient
+
+		msgBody := strings.NewReader(tSubsConfirmMsg)
+		req := newPostRequest(t, msgBody)
+		req.Header.Set(headerMsgTypeKey, headerMsgTypeSubsConfirm)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.False(t, snsClient.calledConfirmSubscribe)
+	})
 }
 
-func newTestHandler(t *testing.T) *Handler {
+func newTestHandler(t *testing.T, pki *testPKI) (*Handler, string /*cert URL*/) {
 	t.Helper()
 
-	return &Handler{
+	v, certURL, _ := newTestSignatureVerifier(t, pki)
+
+	h := &Handler{
 		logger:   logtesting.TestLogger(t),
-		eventSrc: tEventSrc,
+		topicARN: tTopicARN,
+		token:    tToken,
+		verifier: v,
 	}
+
+	return h, certURL
+}
+
+// signedBody returns a request body containing the given SNS message, signed
+// with the given PKI's signing certificate served at certURL.
+func signedBody(t *testing.T, pki *testPKI, certURL, rawMsg string) io.Reader {
+	t.Helper()
+
+	msg := &message{}
+	require.NoError(t, json.Unmarshal([]byte(rawMsg), msg))
+
+	pki.sign(t, msg, signatureVersionSHA256, certURL)
+
+	body, err := json.Marshal(msg)
+	require.NoError(t, err)
+
+	return bytes.NewReader(body)
 }
 
 func newPostRequest(t *testing.T, body io.Reader) *http.Request {
@@ -198,6 +454,11 @@ const tNotifMsg = `{
   "Subject": "` + tMsgSubject + `",
   "Message": "Hello world!",
   "Timestamp": "2012-05-02T00:54:06.655Z",
+  "MessageAttributes": {
+    "My-Attribute": {"Type": "String", "Value": "my value"},
+    "MyCount": {"Type": "Number", "Value": "42"},
+    "MyBinary": {"Type": "Binary", "Value": "SGVsbG8gd29ybGQh"}
+  },
   "SignatureVersion": "1",
   "Signature": "EXAMPLEw6JRN...",
   "SigningCertURL": "https://sns.us-fake-0.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",
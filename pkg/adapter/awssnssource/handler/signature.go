@@ -0,0 +1,182 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"crypto"
+	"crypto/rsa"
+	_ "crypto/sha1" // register hash function used by signature version 1
+	_ "crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Versions of the signature of SNS messages.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+const (
+	signatureVersionSHA1   = "1"
+	signatureVersionSHA256 = "2"
+)
+
+// certHostPattern matches the hosts SNS signing certificates are served from.
+var certHostPattern = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+const certDownloadTimeout = 10 * time.Second
+
+// SignatureVerifier verifies the signature of SNS messages.
+// Signing certificates are cached, so they are downloaded only once for all
+// messages they signed.
+type SignatureVerifier struct {
+	httpClient *http.Client
+
+	// pool of trusted root certificates, nil for the system pool
+	roots *x509.CertPool
+	// pattern of the allowed hosts for signing certificates
+	hostPattern *regexp.Regexp
+
+	certsMu sync.RWMutex
+	certs   map[string]*x509.Certificate
+}
+
+// NewSignatureVerifier returns an initialized SignatureVerifier.
+func NewSignatureVerifier() *SignatureVerifier {
+	return &SignatureVerifier{
+		httpClient: &http.Client{
+			Timeout: certDownloadTimeout,
+		},
+		hostPattern: certHostPattern,
+		certs:       make(map[string]*x509.Certificate),
+	}
+}
+
+// Verify verifies the signature of the given SNS message.
+func (v *SignatureVerifier) Verify(msg *message) error {
+	var hash crypto.Hash
+
+	switch msg.SignatureVersion {
+	case signatureVersionSHA1:
+		hash = crypto.SHA1
+	case signatureVersionSHA256:
+		hash = crypto.SHA256
+	case "":
+		return errors.New("message is not signed")
+	default:
+		return fmt.Errorf("unsupported signature version %q", msg.SignatureVersion)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+
+	cert, err := v.certificate(msg.SigningCertURL)
+	if err != nil {
+		return fmt.Errorf("obtaining signing certificate: %w", err)
+	}
+
+	// x509.Certificate.CheckSignature rejects SHA-1 signatures, which SNS
+	// still produces with signature version 1, so the signature is
+	// verified against the certificate's public key directly.
+	pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported public key type %T", cert.PublicKey)
+	}
+
+	h := hash.New()
+	_, _ = h.Write(msg.stringToSign())
+
+	if err := rsa.VerifyPKCS1v15(pubKey, hash, h.Sum(nil), sig); err != nil {
+		return fmt.Errorf("verifying signature: %w", err)
+	}
+	return nil
+}
+
+// certificate returns the signing certificate located at the given URL.
+func (v *SignatureVerifier) certificate(certURL string) (*x509.Certificate, error) {
+	v.certsMu.RLock()
+	cert, ok := v.certs[certURL]
+	v.certsMu.RUnlock()
+
+	if ok && time.Now().Before(cert.NotAfter) {
+		return cert, nil
+	}
+
+	cert, err := v.downloadCertificate(certURL)
+	if err != nil {
+		return nil, err
+	}
+
+	v.certsMu.Lock()
+	v.certs[certURL] = cert
+	v.certsMu.Unlock()
+
+	return cert, nil
+}
+
+// downloadCertificate downloads and validates the certificate located at the
+// given URL.
+func (v *SignatureVerifier) downloadCertificate(certURL string) (*x509.Certificate, error) {
+	u, err := url.Parse(certURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate URL: %w", err)
+	}
+
+	if u.Scheme != "https" || !v.hostPattern.MatchString(u.Hostname()) || !strings.HasSuffix(u.Path, ".pem") {
+		return nil, fmt.Errorf("untrusted certificate URL %q", certURL)
+	}
+
+	resp, err := v.httpClient.Get(certURL)
+	if err != nil {
+		return nil, fmt.Errorf("downloading certificate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading certificate: server responded with status %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate: %w", err)
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: v.roots}); err != nil {
+		return nil, fmt.Errorf("verifying certificate: %w", err)
+	}
+
+	return cert, nil
+}
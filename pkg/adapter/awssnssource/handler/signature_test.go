@@ -0,0 +1,250 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tCertPath = "/SimpleNotificationService-0123456789abcdef.pem"
+
+func TestSignatureVerifier(t *testing.T) {
+	pki := newTestPKI(t)
+
+	testCases := map[string]struct {
+		sigVersion string
+		tamperFn   func(*message)
+		tweakFn    func(v *SignatureVerifier, certURL *string)
+		expectErr  string
+	}{
+		"Valid signature version 1": {
+			sigVersion: signatureVersionSHA1,
+		},
+		"Valid signature version 2": {
+			sigVersion: signatureVersionSHA256,
+		},
+		"Unsigned message": {
+			sigVersion: signatureVersionSHA256,
+			tamperFn: func(m *message) {
+				m.SignatureVersion = ""
+				m.Signature = ""
+			},
+			expectErr: "message is not signed",
+		},
+		"Unsupported signature version": {
+			sigVersion: signatureVersionSHA256,
+			tamperFn: func(m *message) {
+				m.SignatureVersion = "3"
+			},
+			expectErr: `unsupported signature version "3"`,
+		},
+		"Tampered message": {
+			sigVersion: signatureVersionSHA256,
+			tamperFn: func(m *message) {
+				m.Message = "Goodbye world!"
+			},
+			expectErr: "verifying signature",
+		},
+		"Untrusted certificate host": {
+			sigVersion: signatureVersionSHA256,
+			tweakFn: func(v *SignatureVerifier, _ *string) {
+				v.hostPattern = certHostPattern
+			},
+			expectErr: "untrusted certificate URL",
+		},
+		"Certificate URL without PEM extension": {
+			sigVersion: signatureVersionSHA256,
+			tweakFn: func(_ *SignatureVerifier, certURL *string) {
+				*certURL = strings.TrimSuffix(*certURL, ".pem") + ".crt"
+			},
+			expectErr: "untrusted certificate URL",
+		},
+		"Certificate issued by an untrusted CA": {
+			sigVersion: signatureVersionSHA256,
+			tweakFn: func(v *SignatureVerifier, _ *string) {
+				v.roots = newTestPKI(t).roots
+			},
+			expectErr: "verifying certificate",
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			v, certURL, _ := newTestSignatureVerifier(t, pki)
+			if tc.tweakFn != nil {
+				tc.tweakFn(v, &certURL)
+			}
+
+			msg := &message{}
+			require.NoError(t, json.Unmarshal([]byte(tNotifMsg), msg))
+			pki.sign(t, msg, tc.sigVersion, certURL)
+
+			if tc.tamperFn != nil {
+				tc.tamperFn(msg)
+			}
+
+			err := v.Verify(msg)
+
+			if tc.expectErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectErr)
+		})
+	}
+
+	t.Run("Certificate is cached", func(t *testing.T) {
+		v, certURL, certDownloads := newTestSignatureVerifier(t, pki)
+
+		for _, raw := range []string{tNotifMsg, tSubsConfirmMsg} {
+			msg := &message{}
+			require.NoError(t, json.Unmarshal([]byte(raw), msg))
+			pki.sign(t, msg, signatureVersionSHA256, certURL)
+
+			assert.NoError(t, v.Verify(msg))
+		}
+
+		assert.EqualValues(t, 1, atomic.LoadInt32(certDownloads))
+	})
+}
+
+// testPKI is a locally generated public key infrastructure which mimics the
+// one of SNS signing certificates.
+type testPKI struct {
+	// pool containing the root CA
+	roots *x509.CertPool
+	// PEM encoded signing certificate, issued by the root CA
+	certPEM []byte
+	// private key of the signing certificate
+	key *rsa.PrivateKey
+}
+
+// newTestPKI generates a root CA and a signing certificate issued by that CA.
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	return &testPKI{
+		roots:   roots,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:     key,
+	}
+}
+
+// sign signs the given message with the PKI's signing certificate, which is
+// expected to be served at the given URL.
+func (p *testPKI) sign(t *testing.T, msg *message, sigVersion, certURL string) {
+	t.Helper()
+
+	hash := crypto.SHA256
+	if sigVersion == signatureVersionSHA1 {
+		hash = crypto.SHA1
+	}
+
+	msg.SignatureVersion = sigVersion
+	msg.SigningCertURL = certURL
+
+	h := hash.New()
+	_, _ = h.Write(msg.stringToSign())
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, hash, h.Sum(nil))
+	require.NoError(t, err)
+
+	msg.Signature = base64.StdEncoding.EncodeToString(sig)
+}
+
+// newTestSignatureVerifier returns a SignatureVerifier which trusts the given
+// PKI, the URL at which the PKI's signing certificate is served, and a
+// counter of downloads of that certificate.
+func newTestSignatureVerifier(t *testing.T, pki *testPKI) (*SignatureVerifier, string, *int32) {
+	t.Helper()
+
+	var certDownloads int32
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tCertPath {
+			http.NotFound(w, r)
+			return
+		}
+
+		atomic.AddInt32(&certDownloads, 1)
+		_, _ = w.Write(pki.certPEM)
+	}))
+	t.Cleanup(srv.Close)
+
+	v := NewSignatureVerifier()
+	v.httpClient = srv.Client()
+	v.roots = pki.roots
+	v.hostPattern = regexp.MustCompile(`^127\.0\.0\.1$`)
+
+	return v, srv.URL + tCertPath, &certDownloads
+}
@@ -0,0 +1,86 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssnssource
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+// readinessChecker is a HTTP handler which reports the adapter as ready once
+// the informer of source objects has synced, and all existing sources have
+// been handled by the adapter.
+//
+// Readiness is never revoked once it has been reported, so that sources which
+// are created afterwards don't cause the handlers of other sources to be
+// temporarily taken out of service.
+type readinessChecker struct {
+	synced   cache.InformerSynced
+	lister   func() ([]*v1alpha1.AWSSNSSource, error)
+	isServed func(*v1alpha1.AWSSNSSource) bool
+
+	ready int32
+}
+
+// Check that readinessChecker implements http.Handler.
+var _ http.Handler = (*readinessChecker)(nil)
+
+// ServeHTTP implements http.Handler.
+func (c *readinessChecker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	if !c.isReady() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isReady returns whether the adapter is ready to serve requests.
+func (c *readinessChecker) isReady() bool {
+	if atomic.LoadInt32(&c.ready) == 1 {
+		return true
+	}
+
+	if !c.synced() {
+		return false
+	}
+
+	srcs, err := c.lister()
+	if err != nil {
+		return false
+	}
+
+	for _, src := range srcs {
+		// sources which are being deleted or don't have a sink yet
+		// aren't expected to have a handler
+		if src.DeletionTimestamp != nil || src.Status.SinkURI == nil {
+			continue
+		}
+
+		if !c.isServed(src) {
+			return false
+		}
+	}
+
+	atomic.StoreInt32(&c.ready, 1)
+
+	return true
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssnssource
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/routing"
+)
+
+func TestReadinessChecker(t *testing.T) {
+	const otherName = "other"
+
+	var synced bool
+	servedPaths := make(map[string]struct{})
+
+	c := &readinessChecker{
+		synced: func() bool { return synced },
+		lister: func() ([]*v1alpha1.AWSSNSSource, error) {
+			srcNoSink := newEventSource(noSink)
+			srcNoSink.Name = otherName + "-nosink"
+
+			srcDeleted := newEventSource(deleted)
+			srcDeleted.Name = otherName + "-deleted"
+
+			return []*v1alpha1.AWSSNSSource{
+				newEventSource(),
+				srcNoSink,
+				srcDeleted,
+			}, nil
+		},
+		isServed: func(src *v1alpha1.AWSSNSSource) bool {
+			_, served := servedPaths[routing.URLPath(src)]
+			return served
+		},
+	}
+
+	// informer not synced
+
+	assert.False(t, c.isReady())
+
+	// informer synced, but some source not served
+
+	synced = true
+	assert.False(t, c.isReady())
+
+	resp := probeHandler(t, c, healthPath)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+
+	// all sources served, except the ones which aren't expected to be
+
+	servedPaths[tURLPath] = struct{}{}
+	assert.True(t, c.isReady())
+
+	resp = probeHandler(t, c, healthPath)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	// readiness is not revoked once reported
+
+	delete(servedPaths, tURLPath)
+	synced = false
+	assert.True(t, c.isReady())
+}
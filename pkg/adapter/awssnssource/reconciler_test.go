@@ -43,8 +43,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sns"
 
-	"github.com/triggermesh/aws-event-sources/pkg/adapter/awssnssource/status"
 	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/router"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
 	adaptesting "github.com/triggermesh/aws-event-sources/pkg/adapter/testing"
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
 	fakeinjectionclient "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client/fake"
@@ -152,13 +152,11 @@ func reconcilerCtor() adaptesting.Ctor {
 		srcClienset := fakeinjectionclient.Get(ctx)
 
 		a := &adapter{
-			logger:   logtesting.TestLogger(t),
-			ceClient: adaptertest.NewTestClient(),
-			snsCg:    snsClientGetterFromContext(ctx),
-			router:   &router.Router{},
-			statusPatcher: status.NewPatcher(tComponent,
-				srcClienset.SourcesV1alpha1().AWSSNSSources(tNs),
-			),
+			logger:        logtesting.TestLogger(t),
+			ceClient:      adaptertest.NewTestClient(),
+			snsCg:         snsClientGetterFromContext(ctx),
+			router:        &router.Router{},
+			statusPatcher: status.NewPatcher(tComponent, srcClienset.SourcesV1alpha1()),
 		}
 
 		// inject adapter into test data so that table tests can perform
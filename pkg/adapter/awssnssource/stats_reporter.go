@@ -0,0 +1,206 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssnssource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"knative.dev/pkg/metrics"
+	"knative.dev/pkg/metrics/metricskey"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+const (
+	metricNameRequestCount     = "request_count"
+	metricNameRequestLatencies = "request_latencies"
+)
+
+var (
+	tagKeyResourceGroup     = tag.MustNewKey(metricskey.LabelResourceGroup)
+	tagKeyNamespace         = tag.MustNewKey(metricskey.LabelNamespaceName)
+	tagKeyName              = tag.MustNewKey(metricskey.LabelName)
+	tagKeyResponseCode      = tag.MustNewKey(metricskey.LabelResponseCode)
+	tagKeyResponseCodeClass = tag.MustNewKey(metricskey.LabelResponseCodeClass)
+)
+
+// requestCountM records the number of HTTP requests served by the handler of
+// a source.
+var requestCountM = stats.Int64(
+	metricNameRequestCount,
+	"Number of HTTP requests served by the handler of the source",
+	stats.UnitDimensionless,
+)
+
+// requestLatencyM records the time it took the handler of a source to serve
+// HTTP requests.
+var requestLatencyM = stats.Float64(
+	metricNameRequestLatencies,
+	"Time spent serving HTTP requests by the handler of the source",
+	stats.UnitMilliseconds,
+)
+
+// mustRegisterStatsView registers an OpenCensus stats view for the source's
+// metrics and panics in case of error.
+func mustRegisterStatsView() {
+	tagKeys := []tag.Key{
+		tagKeyResourceGroup,
+		tagKeyNamespace,
+		tagKeyName,
+		tagKeyResponseCode,
+		tagKeyResponseCodeClass,
+	}
+
+	err := view.Register(
+		&view.View{
+			Measure:     requestCountM,
+			Description: requestCountM.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     tagKeys,
+		},
+		&view.View{
+			Measure:     requestLatencyM,
+			Description: requestLatencyM.Description(),
+			Aggregation: view.Distribution(metrics.Buckets125(1, 10000)...),
+			TagKeys:     tagKeys,
+		},
+	)
+	if err != nil {
+		panic(fmt.Errorf("error registering OpenCensus stats view: %w", err))
+	}
+}
+
+// statsReporter collects and reports stats about the HTTP handler of a source.
+type statsReporter struct {
+	// context that holds pre-populated OpenCensus tags
+	tagsCtx context.Context
+}
+
+// mustNewStatsReporter returns a new statsReporter initialized with tags
+// identifying the given source and panics in case of error.
+func mustNewStatsReporter(src *v1alpha1.AWSSNSSource) *statsReporter {
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(tagKeyResourceGroup, sources.AWSSNSSourceResource.String()),
+		tag.Insert(tagKeyNamespace, src.Namespace),
+		tag.Insert(tagKeyName, src.Name),
+	)
+	if err != nil {
+		panic(fmt.Errorf("error creating OpenCensus tags: %w", err))
+	}
+
+	return &statsReporter{
+		tagsCtx: ctx,
+	}
+}
+
+// reportRequest increments requestCountM and records the given latency in
+// requestLatencyM.
+func (r *statsReporter) reportRequest(responseCode int, latency time.Duration) {
+	ctx, err := tag.New(r.tagsCtx,
+		tag.Insert(tagKeyResponseCode, strconv.Itoa(responseCode)),
+		tag.Insert(tagKeyResponseCodeClass, metrics.ResponseCodeClass(responseCode)),
+	)
+	if err != nil {
+		return
+	}
+
+	metrics.RecordBatch(ctx,
+		requestCountM.M(1),
+		requestLatencyM.M(float64(latency)/float64(time.Millisecond)),
+	)
+}
+
+// instrumentedHandler is a HTTP handler which reports stats about the
+// requests served by the handler it wraps.
+type instrumentedHandler struct {
+	h  http.Handler
+	sr *statsReporter
+
+	// description of the handler in routes listings
+	desc string
+}
+
+// Check the interfaces instrumentedHandler should implement.
+var (
+	_ http.Handler = (*instrumentedHandler)(nil)
+	_ fmt.Stringer = (*instrumentedHandler)(nil)
+)
+
+// instrumentHandler wraps the given HTTP handler of a source into an
+// instrumentedHandler.
+func instrumentHandler(h http.Handler, src *v1alpha1.AWSSNSSource) *instrumentedHandler {
+	return &instrumentedHandler{
+		h:    h,
+		sr:   mustNewStatsReporter(src),
+		desc: src.Namespace + "/" + src.Name,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (ih *instrumentedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rw := &statusRecorder{ResponseWriter: w}
+
+	start := time.Now()
+	ih.h.ServeHTTP(rw, r)
+	ih.sr.reportRequest(rw.statusCode(), time.Since(start))
+}
+
+// String implements fmt.Stringer.
+func (ih *instrumentedHandler) String() string {
+	return ih.desc
+}
+
+// statusRecorder is a http.ResponseWriter which records the status code of
+// the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter.
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// statusCode returns the recorded status code. Handlers which don't write
+// anything implicitly respond with the status OK.
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
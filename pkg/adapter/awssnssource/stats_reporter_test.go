@@ -0,0 +1,72 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssnssource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedHandler(t *testing.T) {
+	src := newEventSource()
+
+	h := instrumentHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}), src)
+
+	assert.Equal(t, tKey, h.String())
+
+	resp := probeHandler(t, h, tURLPath)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestStatusRecorder(t *testing.T) {
+	testCases := map[string]struct {
+		write      func(http.ResponseWriter)
+		expectCode int
+	}{
+		"No write": {
+			write:      func(http.ResponseWriter) {},
+			expectCode: http.StatusOK,
+		},
+		"Body only": {
+			write: func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte("hello"))
+			},
+			expectCode: http.StatusOK,
+		},
+		"Explicit status": {
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusAccepted)
+				w.WriteHeader(http.StatusInternalServerError) // superfluous
+			},
+			expectCode: http.StatusAccepted,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			rw := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+			tc.write(rw)
+			assert.Equal(t, tc.expectCode, rw.statusCode())
+		})
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 
@@ -57,9 +58,26 @@ type envConfig struct {
 	// Name of a message processor which takes care of converting SQS
 	// messages to CloudEvents.
 	//
-	// Supported values: [ default s3 ]
+	// Supported values: [ default s3 codecommit ]
 	MessageProcessor string `envconfig:"SQS_MESSAGE_PROCESSOR" default:"default"`
 
+	// Glob patterns of the CodeCommit branches to process events for.
+	// Only used by the "codecommit" message processor. Events about all
+	// branches are processed when empty.
+	CodeCommitBranches []string `envconfig:"CODECOMMIT_BRANCHES"`
+
+	// Enrichment of S3 events with information about the objects they
+	// refer to. Only used by the "s3" message processor.
+	S3Enrichment bool `envconfig:"S3_ENRICHMENT"`
+	// Whether to include the tags of S3 objects.
+	S3EnrichmentTags bool `envconfig:"S3_ENRICHMENT_TAGS"`
+	// Maximum size, in bytes, of the S3 objects which content is included
+	// inline. Content is never inlined when zero.
+	S3EnrichmentInlineMaxSize int64 `envconfig:"S3_ENRICHMENT_INLINE_MAX_SIZE"`
+	// Validity of presigned URLs to S3 objects. No presigned URL is
+	// generated when zero.
+	S3EnrichmentPresignExpiration time.Duration `envconfig:"S3_ENRICHMENT_PRESIGN_EXPIRATION"`
+
 	// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-visibility-timeout.html
 	// Visibility timeout to set on all messages received by this event source.
 	// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-visibility-timeout.html
@@ -109,10 +127,28 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 
 	arn := common.MustParseARN(env.ARN)
 
+	cfg := session.Must(session.NewSession(aws.NewConfig().
+		WithRegion(arn.Region),
+	))
+
 	var msgPrcsr MessageProcessor
 	switch env.MessageProcessor {
 	case "s3":
-		msgPrcsr = &s3MessageProcessor{ceSourceFallback: arn.String()}
+		s3Prcsr := &s3MessageProcessor{ceSourceFallback: arn.String()}
+		if env.S3Enrichment {
+			// S3 buckets can only deliver events to queues located in
+			// the same region, so the queue's region is also the
+			// bucket's region
+			s3Prcsr.enricher = &s3ObjectEnricher{
+				cli:               s3.New(cfg),
+				tags:              env.S3EnrichmentTags,
+				inlineMaxSize:     env.S3EnrichmentInlineMaxSize,
+				presignExpiration: env.S3EnrichmentPresignExpiration,
+			}
+		}
+		msgPrcsr = s3Prcsr
+	case "codecommit":
+		msgPrcsr = &codecommitMessageProcessor{ceSourceFallback: arn.String(), branches: env.CodeCommitBranches}
 	case "default":
 		msgPrcsr = &defaultMessageProcessor{ceSource: arn.String()}
 	default:
@@ -121,27 +157,28 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 
 	var visibilityTimeoutSeconds *int64
 	if vt := env.VisibilityTimeout; vt != nil {
-		if *vt < 0 || *vt > 12*time.Hour {
-			logger.Warn("Ignoring out of bounds visibility timeout (", *vt, ")")
-		} else {
-			vts := durationInSeconds(*vt)
-			visibilityTimeoutSeconds = &vts
-		}
+		visibilityTimeoutSeconds = visibilityTimeoutInSeconds(*vt, logger)
 	}
 
-	cfg := session.Must(session.NewSession(aws.NewConfig().
-		WithRegion(arn.Region),
-	))
-
 	// allocate generous buffer sizes to limit blocking on surges of new
 	// messages coming from receivers
 	const batchSizePerProc = 9
-	queueBufferSizeProcess := maxReceiveMsgBatchSize * runtime.GOMAXPROCS(-1) * batchSizePerProc
-	queueBufferSizeDelete := queueBufferSizeProcess
+	queueBufferSize := maxReceiveMsgBatchSize * runtime.GOMAXPROCS(-1) * batchSizePerProc
+
+	return newQueueAdapter(logger, mt, sqs.New(cfg), ceClient, arn, msgPrcsr,
+		visibilityTimeoutSeconds, queueBufferSize)
+}
+
+// newQueueAdapter returns an adapter which processes messages from the SQS
+// queue identified by the given ARN, using processing and deletion queues of
+// the given buffer size.
+func newQueueAdapter(logger *zap.SugaredLogger, mt *pkgadapter.MetricTag,
+	sqsClient sqsiface.SQSAPI, ceClient cloudevents.Client, arn arn.ARN, msgPrcsr MessageProcessor,
+	visibilityTimeoutSeconds *int64, queueBufferSize int) *adapter {
 
 	sr := mustNewStatsReporter(mt)
-	sr.reportQueueCapacityProcess(queueBufferSizeProcess)
-	sr.reportQueueCapacityDelete(queueBufferSizeDelete)
+	sr.reportQueueCapacityProcess(queueBufferSize)
+	sr.reportQueueCapacityDelete(queueBufferSize)
 
 	return &adapter{
 		logger: logger,
@@ -149,7 +186,7 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 		mt: mt,
 		sr: sr,
 
-		sqsClient: sqs.New(cfg),
+		sqsClient: sqsClient,
 		ceClient:  ceClient,
 
 		arn: arn,
@@ -158,13 +195,25 @@ func NewAdapter(ctx context.Context, envAcc pkgadapter.EnvConfigAccessor, ceClie
 
 		visibilityTimeoutSeconds: visibilityTimeoutSeconds,
 
-		processQueue: make(chan *sqs.Message, queueBufferSizeProcess),
-		deleteQueue:  make(chan *sqs.Message, queueBufferSizeDelete),
+		processQueue: make(chan *sqs.Message, queueBufferSize),
+		deleteQueue:  make(chan *sqs.Message, queueBufferSize),
 
 		deletePeriod: maxDeleteMsgPeriod,
 	}
 }
 
+// visibilityTimeoutInSeconds returns the given visibility timeout as a number
+// of seconds, or nil if it is out of the bounds accepted by SQS.
+func visibilityTimeoutInSeconds(vt time.Duration, logger *zap.SugaredLogger) *int64 {
+	if vt < 0 || vt > 12*time.Hour {
+		logger.Warn("Ignoring out of bounds visibility timeout (", vt, ")")
+		return nil
+	}
+
+	vts := durationInSeconds(vt)
+	return &vts
+}
+
 // Start implements adapter.Adapter.
 func (a *adapter) Start(ctx context.Context) error {
 	go health.Start(ctx)
@@ -180,17 +229,26 @@ func (a *adapter) Start(ctx context.Context) error {
 	queueURL := *url.QueueUrl
 	a.logger.Infof("Listening to SQS queue at URL: %s", queueURL)
 
-	msgCtx, cancel := context.WithCancel(pkgadapter.ContextWithMetricTag(ctx, a.mt))
-	defer cancel()
-
-	var wg sync.WaitGroup
-
 	// This event source spends most of its time waiting for the network,
 	// so we can run more than one of each receiver|processor|deleter for
 	// each available thread.
 	const instancesPerProc = 3
 
-	for i := 0; i < runtime.GOMAXPROCS(-1)*instancesPerProc; i++ {
+	a.runQueueWorkers(ctx, queueURL, runtime.GOMAXPROCS(-1)*instancesPerProc)
+
+	return nil
+}
+
+// runQueueWorkers runs the given number of instances of each message
+// receiver|processor|deleter for the SQS queue at the given URL, until ctx
+// gets cancelled.
+func (a *adapter) runQueueWorkers(ctx context.Context, queueURL string, instances int) {
+	msgCtx, cancel := context.WithCancel(pkgadapter.ContextWithMetricTag(ctx, a.mt))
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < instances; i++ {
 		// TODO(antoineco): spawn and terminate receivers dynamically
 		// based on the current amount of messages being processed to
 		// optimize costs generated by ReceiveMessage API requests.
@@ -219,8 +277,6 @@ func (a *adapter) Start(ctx context.Context) error {
 
 	a.logger.Info("Waiting for message handlers to terminate")
 	wg.Wait()
-
-	return nil
 }
 
 // queueLookup finds the URL for a given queue name in the user's account.
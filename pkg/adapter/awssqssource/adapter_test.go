@@ -215,6 +215,12 @@ func (*standardMockSQSClient) GetQueueUrl(*sqs.GetQueueUrlInput) (*sqs.GetQueueU
 	}, nil
 }
 
+func (c *standardMockSQSClient) GetQueueUrlWithContext(_ context.Context, //nolint:golint,stylecheck
+	in *sqs.GetQueueUrlInput, _ ...request.Option) (*sqs.GetQueueUrlOutput, error) {
+
+	return c.GetQueueUrl(in)
+}
+
 func (c *standardMockSQSClient) ReceiveMessageWithContext(_ context.Context,
 	in *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
 
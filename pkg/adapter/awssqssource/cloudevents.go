@@ -20,6 +20,8 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/service/sqs"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common"
 )
 
 const sqsMgsAttrDataTypeBinary = "Binary"
@@ -57,34 +59,5 @@ func ceExtensionAttrsForMessage(msg *sqs.Message) map[string]interface{} {
 //  - https://docs.aws.amazon.com/AWSSimpleQueueService/latest/SQSDeveloperGuide/sqs-message-metadata.html#message-attribute-components
 //  - https://github.com/cloudevents/spec/blob/v1.0.1/spec.md#context-attributes
 func ceExtensionAttrsForMessageAttr(attrName string) string {
-	return ceExtensionSQSMessagePrefix + stripNonAlphanumCharsAndMapToLower(attrName)
-}
-
-// stripNonAlphanumCharsAndMapToLower applies the following transformations to
-// the given string:
-//  - strips all non alphanumeric characters
-//  - maps all Unicode letters to their lower case
-func stripNonAlphanumCharsAndMapToLower(s string) string {
-	var stripped strings.Builder
-	stripped.Grow(len(s))
-
-	// operate on bytes instead of runes, since all alphanumeric characters
-	// are represented in a single byte
-	for i := 0; i < len(s); i++ {
-		b := s[i]
-
-		if ('a' <= b && b <= 'z') ||
-			('A' <= b && b <= 'Z') ||
-			('0' <= b && b <= '9') {
-
-			if 'A' <= b && b <= 'Z' {
-				// shift from upper to lower case
-				b += 'a' - 'A'
-			}
-
-			stripped.WriteByte(b)
-		}
-	}
-
-	return stripped.String()
+	return ceExtensionSQSMessagePrefix + common.StripNonAlphanumCharsAndMapToLower(attrName)
 }
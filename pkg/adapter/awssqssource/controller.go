@@ -0,0 +1,75 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssqssource
+
+import (
+	"context"
+
+	"k8s.io/client-go/tools/cache"
+
+	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/apis"
+	pkgcontroller "knative.dev/pkg/controller"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/controller"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	informerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/informers/sources/v1alpha1/awssqssource"
+	reconcilerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/reconciler/sources/v1alpha1/awssqssource"
+)
+
+// MTAdapter allows the multi-tenant adapter to expose methods the reconciler
+// can call while reconciling a source object.
+type MTAdapter interface {
+	// Starts receiving messages from the SQS queue of the given source.
+	RegisterReceiverFor(context.Context, *v1alpha1.AWSSQSSource) error
+	// Stops receiving messages from the SQS queue of the given source.
+	DeregisterReceiverFor(context.Context, *v1alpha1.AWSSQSSource) error
+	// Propagates a status condition to the status of the given source.
+	PropagateCondition(context.Context, *v1alpha1.AWSSQSSource, *apis.Condition) error
+}
+
+// NewController returns a constructor for the event source's Reconciler.
+func NewController(component string) pkgadapter.ControllerConstructor {
+	return func(ctx context.Context, a pkgadapter.Adapter) *pkgcontroller.Impl {
+		mta := a.(MTAdapter)
+
+		r := &Reconciler{
+			adapter: mta,
+		}
+		impl := reconcilerv1alpha1.NewImpl(ctx, r, controller.Opts(component))
+
+		informer := informerv1alpha1.Get(ctx).Informer()
+
+		informer.AddEventHandler(pkgcontroller.HandleAll(impl.Enqueue))
+
+		// Sources are not finalized by the adapter, so they may vanish
+		// from the cache before a reconciliation observes their
+		// deletion.
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				if src, ok := obj.(*v1alpha1.AWSSQSSource); ok {
+					_ = mta.DeregisterReceiverFor(ctx, src)
+				}
+			},
+		})
+
+		return impl
+	}
+}
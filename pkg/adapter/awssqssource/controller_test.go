@@ -0,0 +1,30 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssqssource
+
+import (
+	"testing"
+
+	adaptesting "github.com/triggermesh/aws-event-sources/pkg/adapter/testing"
+
+	// Link fake informers accessed by our controller
+	_ "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/informers/sources/v1alpha1/awssqssource/fake"
+)
+
+func TestNewController(t *testing.T) {
+	adaptesting.TestControllerConstructor(t, NewController("controller-test"), &mtAdapter{})
+}
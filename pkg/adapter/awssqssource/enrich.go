@@ -0,0 +1,148 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssqssource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Name of the CloudEvent extension attribute which reports errors that occurred
+// while enriching an event.
+const ceExtensionS3EnrichmentError = "s3enrichmenterror"
+
+// Maximum duration of all S3 API calls performed to enrich a single event.
+const s3EnrichmentTimeout = 10 * time.Second
+
+// s3ObjectEnricher enriches S3 events with information about the object they
+// refer to.
+type s3ObjectEnricher struct {
+	cli s3iface.S3API
+
+	// whether to include the tags of the object
+	tags bool
+	// maximum size of the objects which content is included inline, in bytes
+	inlineMaxSize int64
+	// validity of presigned URLs, none is generated when zero
+	presignExpiration time.Duration
+}
+
+// enrich adds information about the object identified by the given bucket name
+// and key to the "object" element of an event's data.
+// Enrichment stops at the first error, and the information collected until
+// then is kept.
+func (e *s3ObjectEnricher) enrich(bucket, key string, object map[string]interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s3EnrichmentTimeout)
+	defer cancel()
+
+	versionID, _ := object["versionId"].(string)
+
+	var version *string
+	if versionID != "" {
+		version = &versionID
+	}
+
+	head, err := e.cli.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: version,
+	})
+	if err != nil {
+		return fmt.Errorf("reading object metadata: %w", err)
+	}
+
+	object["contentType"] = aws.StringValue(head.ContentType)
+	object["lastModified"] = aws.TimeValue(head.LastModified)
+	if len(head.Metadata) > 0 {
+		object["metadata"] = aws.StringValueMap(head.Metadata)
+	}
+
+	if e.tags {
+		tagging, err := e.cli.GetObjectTaggingWithContext(ctx, &s3.GetObjectTaggingInput{
+			Bucket:    &bucket,
+			Key:       &key,
+			VersionId: version,
+		})
+		if err != nil {
+			return fmt.Errorf("reading object tags: %w", err)
+		}
+
+		tags := make(map[string]string, len(tagging.TagSet))
+		for _, t := range tagging.TagSet {
+			tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+		}
+		object["tags"] = tags
+	}
+
+	switch size := aws.Int64Value(head.ContentLength); {
+	case e.inlineMaxSize > 0 && size <= e.inlineMaxSize:
+		obj, err := e.cli.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket:    &bucket,
+			Key:       &key,
+			VersionId: version,
+		})
+		if err != nil {
+			return fmt.Errorf("reading object content: %w", err)
+		}
+		defer obj.Body.Close()
+
+		// guard against objects which were overwritten with a larger
+		// content since they were inspected
+		content, err := ioutil.ReadAll(io.LimitReader(obj.Body, e.inlineMaxSize+1))
+		if err != nil {
+			return fmt.Errorf("reading object content: %w", err)
+		}
+		if int64(len(content)) > e.inlineMaxSize {
+			return fmt.Errorf("object content exceeds the maximum inline size of %d bytes", e.inlineMaxSize)
+		}
+
+		// serialized to JSON as a base64-encoded string
+		object["content"] = content
+
+	case e.presignExpiration > 0:
+		req, _ := e.cli.GetObjectRequest(&s3.GetObjectInput{
+			Bucket:    &bucket,
+			Key:       &key,
+			VersionId: version,
+		})
+		url, err := req.Presign(e.presignExpiration)
+		if err != nil {
+			return fmt.Errorf("presigning object URL: %w", err)
+		}
+
+		object["presignedURL"] = url
+	}
+
+	return nil
+}
+
+// isS3ObjectRemovedEvent returns whether the given name of a S3 event or
+// EventBridge detail type refers to an object which no longer exists, and
+// therefore can not be enriched.
+func isS3ObjectRemovedEvent(name string) bool {
+	return strings.HasPrefix(name, "ObjectRemoved:") ||
+		strings.HasPrefix(name, "LifecycleExpiration:") ||
+		name == "Object Deleted"
+}
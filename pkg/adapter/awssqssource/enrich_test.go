@@ -0,0 +1,235 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssqssource
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestS3MessageProcessorEnrichment(t *testing.T) {
+	const tContent = "Hello, World!"
+
+	testCases := map[string]struct {
+		body     string
+		enricher *s3ObjectEnricher
+
+		expectObjectAttrs []string
+		expectContent     string
+		expectErrExt      bool
+		expectNoCalls     bool
+	}{
+		"metadata, tags and inline content": {
+			body: makeS3RecordBody("ObjectCreated:Put"),
+			enricher: &s3ObjectEnricher{
+				tags:          true,
+				inlineMaxSize: 1024,
+			},
+			expectObjectAttrs: []string{"contentType", "lastModified", "metadata", "tags", "content"},
+			expectContent:     tContent,
+		},
+		"object too large to be inlined, presigned URL": {
+			body: makeS3RecordBody("ObjectCreated:Put"),
+			enricher: &s3ObjectEnricher{
+				inlineMaxSize:     4,
+				presignExpiration: time.Hour,
+			},
+			expectObjectAttrs: []string{"contentType", "lastModified", "metadata", "presignedURL"},
+		},
+		"EventBridge event": {
+			body:              makeS3EventBridgeEventBody("Object Created"),
+			enricher:          &s3ObjectEnricher{},
+			expectObjectAttrs: []string{"contentType", "lastModified", "metadata"},
+		},
+		"removed object": {
+			body:          makeS3RecordBody("ObjectRemoved:Delete"),
+			enricher:      &s3ObjectEnricher{},
+			expectNoCalls: true,
+		},
+		"API error": {
+			body: makeS3RecordBody("ObjectCreated:Put"),
+			enricher: &s3ObjectEnricher{
+				tags: true,
+			},
+			expectErrExt: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			cli := &mockS3Client{
+				S3API:   newPresigningS3Client(),
+				content: tContent,
+				failAll: tc.expectErrExt,
+			}
+			tc.enricher.cli = cli
+
+			p := &s3MessageProcessor{
+				ceSourceFallback: makeARN(tQueueArnResource).String(),
+				enricher:         tc.enricher,
+			}
+
+			msg := &sqs.Message{
+				MessageId: aws.String(tMsgIDPrefix + "001"),
+				Body:      aws.String(tc.body),
+			}
+
+			events, err := p.Process(msg)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+
+			event := events[0]
+
+			if tc.expectNoCalls {
+				assert.Zero(t, cli.calls, "S3 API should not be called")
+			}
+
+			errExt, hasErrExt := event.Extensions()[ceExtensionS3EnrichmentError]
+			if tc.expectErrExt {
+				assert.True(t, hasErrExt, "Event should have an enrichment error extension")
+				assert.Contains(t, errExt, "reading object metadata")
+				return
+			}
+			assert.False(t, hasErrExt, "Unexpected enrichment error: %v", errExt)
+
+			object := objectFromEventData(t, event)
+
+			for _, attr := range tc.expectObjectAttrs {
+				assert.Contains(t, object, attr)
+			}
+
+			if tc.expectContent != "" {
+				content, err := base64.StdEncoding.DecodeString(object["content"].(string))
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectContent, string(content))
+			}
+		})
+	}
+}
+
+// objectFromEventData returns the "object" element of the data of an event
+// created by the S3 message processor.
+func objectFromEventData(t *testing.T, event *cloudevents.Event) map[string]interface{} {
+	t.Helper()
+
+	data := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(event.Data(), &data))
+
+	if s3prop, ok := data["s3"].(map[string]interface{}); ok {
+		data = s3prop
+	}
+
+	object, ok := data["object"].(map[string]interface{})
+	require.True(t, ok, "Event data has no object element")
+
+	return object
+}
+
+// makeS3RecordBody returns the body of a SQS message containing a S3 event
+// notification record with the given event name.
+func makeS3RecordBody(eventName string) string {
+	return `{"Records": [{
+		"eventName": "` + eventName + `",
+		"s3": {
+			"bucket": {"name": "my-bucket", "arn": "arn:aws:s3:::my-bucket"},
+			"object": {"key": "path/to/object", "size": 13}
+		}
+	}]}`
+}
+
+// newPresigningS3Client returns a S3 client which can presign requests without
+// network access.
+func newPresigningS3Client() s3iface.S3API {
+	return s3.New(session.Must(session.NewSession(aws.NewConfig().
+		WithRegion("us-fake-0").
+		WithCredentials(credentials.NewStaticCredentials("fake-id", "fake-secret", "")),
+	)))
+}
+
+// mockS3Client is a S3 client which returns canned responses about a single
+// object with the given content.
+type mockS3Client struct {
+	s3iface.S3API
+
+	content string
+	failAll bool
+
+	calls int
+}
+
+var errMockS3 = errors.New("mock S3 error")
+
+func (c *mockS3Client) HeadObjectWithContext(aws.Context, *s3.HeadObjectInput,
+	...request.Option) (*s3.HeadObjectOutput, error) {
+
+	c.calls++
+	if c.failAll {
+		return nil, errMockS3
+	}
+
+	return &s3.HeadObjectOutput{
+		ContentType:   aws.String("text/plain"),
+		ContentLength: aws.Int64(int64(len(c.content))),
+		LastModified:  aws.Time(time.Unix(0, 0)),
+		Metadata:      aws.StringMap(map[string]string{"Author": "me"}),
+	}, nil
+}
+
+func (c *mockS3Client) GetObjectTaggingWithContext(aws.Context, *s3.GetObjectTaggingInput,
+	...request.Option) (*s3.GetObjectTaggingOutput, error) {
+
+	c.calls++
+	if c.failAll {
+		return nil, errMockS3
+	}
+
+	return &s3.GetObjectTaggingOutput{
+		TagSet: []*s3.Tag{{Key: aws.String("project"), Value: aws.String("test")}},
+	}, nil
+}
+
+func (c *mockS3Client) GetObjectWithContext(aws.Context, *s3.GetObjectInput,
+	...request.Option) (*s3.GetObjectOutput, error) {
+
+	c.calls++
+	if c.failAll {
+		return nil, errMockS3
+	}
+
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewBufferString(c.content)),
+	}, nil
+}
@@ -0,0 +1,274 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssqssource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/apis"
+	k8sclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/logging"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/env"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/health"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client"
+	sqsclient "github.com/triggermesh/aws-event-sources/pkg/client/sqs"
+)
+
+// Number of instances of each message receiver|processor|deleter running for
+// each queue served by the multi-tenant adapter.
+const mtInstancesPerQueue = 1
+
+// mtAdapter implements the source's multi-tenant adapter.
+type mtAdapter struct {
+	logger *zap.SugaredLogger
+
+	ceClient cloudevents.Client
+	sqsCg    sqsclient.ClientGetter
+
+	// fields accessed during object reconciliation
+	mu            sync.Mutex
+	receivers     map[types.NamespacedName]*queueReceiver
+	statusPatcher *status.Patcher
+}
+
+// queueReceiver is a pool of workers processing messages from the SQS queue
+// of a single source.
+type queueReceiver struct {
+	// properties of the source the receiver was started for
+	spec v1alpha1.AWSSQSSourceSpec
+	sink string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop stops all workers of the receiver and waits for their termination.
+func (r *queueReceiver) stop() {
+	r.cancel()
+	<-r.done
+}
+
+// Check the interfaces mtAdapter should implement.
+var (
+	_ pkgadapter.Adapter = (*mtAdapter)(nil)
+	_ MTAdapter          = (*mtAdapter)(nil)
+)
+
+// NewMTEnvConfig satisfies env.ConfigConstructor.
+// Returns an accessor for the source's multi-tenant adapter envConfig.
+func NewMTEnvConfig() env.ConfigAccessor {
+	return &env.Config{}
+}
+
+// NewMTAdapter returns a constructor for the source's multi-tenant adapter.
+func NewMTAdapter(component string) pkgadapter.AdapterConstructor {
+	return func(ctx context.Context, _ pkgadapter.EnvConfigAccessor,
+		ceClient cloudevents.Client) pkgadapter.Adapter {
+
+		mustRegisterStatsView()
+
+		ns := injection.GetNamespaceScope(ctx)
+		secrGetter := secretGetter(k8sclient.Get(ctx).CoreV1().Secrets(ns))
+		srcClient := client.Get(ctx).SourcesV1alpha1()
+
+		return &mtAdapter{
+			logger: logging.FromContext(ctx),
+
+			ceClient: ceClient,
+			sqsCg:    sqsclient.NewClientGetter(secrGetter),
+
+			receivers:     make(map[types.NamespacedName]*queueReceiver),
+			statusPatcher: status.NewPatcher(component, srcClient),
+		}
+	}
+}
+
+func secretGetter(cli coreclientv1.SecretInterface) sqsclient.NamespacedSecretsGetter {
+	return func(string) coreclientv1.SecretInterface {
+		return cli
+	}
+}
+
+// Start implements adapter.Adapter.
+func (a *mtAdapter) Start(ctx context.Context) error {
+	go health.Start(ctx)
+	health.MarkReady()
+
+	<-ctx.Done()
+
+	a.logger.Info("Stopping all queue receivers")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for key, r := range a.receivers {
+		r.stop()
+		delete(a.receivers, key)
+	}
+
+	return nil
+}
+
+// RegisterReceiverFor implements MTAdapter.
+func (a *mtAdapter) RegisterReceiverFor(ctx context.Context, src *v1alpha1.AWSSQSSource) error {
+	key := types.NamespacedName{Namespace: src.Namespace, Name: src.Name}
+	sink := src.Status.SinkURI.String()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if r, exists := a.receivers[key]; exists {
+		if r.sink == sink && equality.Semantic.DeepEqual(&r.spec, &src.Spec) {
+			return nil
+		}
+
+		// the source was updated, its receiver is restarted with the
+		// new properties
+		r.stop()
+		delete(a.receivers, key)
+	}
+
+	sqsCli, err := a.sqsCg.Get(src)
+	if err != nil {
+		return &receiverError{
+			reason: v1alpha1.AWSSQSReasonNoClient,
+			err:    fmt.Errorf("obtaining SQS client: %w", err),
+		}
+	}
+
+	queueARN := arn.ARN(src.Spec.ARN)
+
+	url, err := sqsCli.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{
+		QueueName:              &queueARN.Resource,
+		QueueOwnerAWSAccountId: &queueARN.AccountID,
+	})
+	if err != nil {
+		return &receiverError{
+			reason: v1alpha1.AWSSQSReasonQueueNotFound,
+			err:    fmt.Errorf("finding URL of SQS queue %s: %s", queueARN.Resource, toErrMsg(err)),
+		}
+	}
+
+	logger := a.logger.With(zap.String("source", key.String()))
+
+	var visibilityTimeoutSeconds *int64
+	if ro := src.Spec.ReceiveOptions; ro != nil && ro.VisibilityTimeout != nil {
+		visibilityTimeoutSeconds = visibilityTimeoutInSeconds(time.Duration(*ro.VisibilityTimeout), logger)
+	}
+
+	mt := &pkgadapter.MetricTag{
+		ResourceGroup: sources.AWSSQSSourceResource.String(),
+		Namespace:     src.Namespace,
+		Name:          src.Name,
+	}
+
+	msgPrcsr := &defaultMessageProcessor{ceSource: src.AsEventSource()}
+
+	qa := newQueueAdapter(logger, mt, sqsCli, a.ceClient, queueARN, msgPrcsr,
+		visibilityTimeoutSeconds, maxReceiveMsgBatchSize*mtInstancesPerQueue)
+
+	// events are sent to the sink of the source the receiver was started for
+	rcvCtx, cancel := context.WithCancel(cloudevents.ContextWithTarget(context.Background(), sink))
+
+	r := &queueReceiver{
+		spec:   *src.Spec.DeepCopy(),
+		sink:   sink,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(r.done)
+		logger.Info("Listening to SQS queue at URL: ", *url.QueueUrl)
+		qa.runQueueWorkers(rcvCtx, *url.QueueUrl, mtInstancesPerQueue)
+	}()
+
+	a.receivers[key] = r
+
+	return nil
+}
+
+// DeregisterReceiverFor implements MTAdapter.
+func (a *mtAdapter) DeregisterReceiverFor(ctx context.Context, src *v1alpha1.AWSSQSSource) error {
+	key := types.NamespacedName{Namespace: src.Namespace, Name: src.Name}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if r, exists := a.receivers[key]; exists {
+		r.stop()
+		delete(a.receivers, key)
+	}
+
+	return nil
+}
+
+// PropagateCondition implements MTAdapter.
+func (a *mtAdapter) PropagateCondition(ctx context.Context, src *v1alpha1.AWSSQSSource, cond *apis.Condition) error {
+	return status.PropagateCondition(ctx, a.statusPatcher, src, cond)
+}
+
+// receiverError is returned when a receiver can not be started for a source.
+type receiverError struct {
+	// reason to set on the ReceiverStarted condition of the source
+	reason string
+	err    error
+}
+
+// Error implements the error interface.
+func (e *receiverError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap allows receiverError to be unwrapped by errors.Unwrap.
+func (e *receiverError) Unwrap() error {
+	return e.err
+}
+
+// toErrMsg attempts to extract the message from the given error if it is an
+// AWS error.
+// Those errors are particularly verbose and include a unique request ID that
+// would cause an infinite loop of reconciliations when appended to a status
+// condition.
+func toErrMsg(err error) string {
+	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
+		return awserr.SprintError(awsErr.Code(), awsErr.Message(), "", awsErr.OrigErr())
+	}
+	return err.Error()
+}
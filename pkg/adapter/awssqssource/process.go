@@ -19,13 +19,18 @@ package awssqssource
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"path"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 
+	"github.com/aws/aws-sdk-go/service/codecommit"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/sqs"
 
@@ -81,6 +86,7 @@ type MessageProcessor interface {
 var (
 	_ MessageProcessor = (*defaultMessageProcessor)(nil)
 	_ MessageProcessor = (*s3MessageProcessor)(nil)
+	_ MessageProcessor = (*codecommitMessageProcessor)(nil)
 )
 
 // defaultMessageProcessor is the default message processor.
@@ -121,15 +127,20 @@ type s3MessageProcessor struct {
 	// this value is set as the "source" CE context attribute when the S3
 	// processor handles messages which are not originating from S3
 	ceSourceFallback string
+
+	// enriches events with information about S3 objects, if not nil
+	enricher *s3ObjectEnricher
 }
 
 // Process implements MessageProcessor.
 //
 // This processor discards everything from the given message except its body,
 // which must be in JSON format. If the body contains multiple records, each
-// record is converted to an individual event.
+// record is converted to an individual event. Events delivered by Amazon
+// EventBridge are also supported.
 //
 // Expected events structure: https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-content-structure.html
+// Expected EventBridge events structure: https://docs.aws.amazon.com/AmazonS3/latest/userguide/ev-events.html
 func (p *s3MessageProcessor) Process(msg *sqs.Message) ([]*cloudevents.Event, error) {
 	var events []*cloudevents.Event
 
@@ -157,7 +168,7 @@ func (p *s3MessageProcessor) Process(msg *sqs.Message) ([]*cloudevents.Event, er
 	switch {
 	case hasRecords:
 		for _, record := range records {
-			event, err := makeS3EventFromRecord(record)
+			event, err := p.makeS3EventFromRecord(record)
 			if err != nil {
 				return nil, fmt.Errorf("creating CloudEvent from S3 event record: %w", err)
 			}
@@ -165,6 +176,14 @@ func (p *s3MessageProcessor) Process(msg *sqs.Message) ([]*cloudevents.Event, er
 			events = append(events, event)
 		}
 
+	case isS3EventBridgePayload(bodyData):
+		event, err := p.makeS3EventFromEventBridgeEvent([]byte(*msg.Body))
+		if err != nil {
+			return nil, fmt.Errorf("creating CloudEvent from S3 EventBridge event: %w", err)
+		}
+
+		events = append(events, event)
+
 	// special case: test events are sent whenever event notifications are
 	// re-configured in a S3 bucket
 	case isTestEventPayload(bodyData):
@@ -195,7 +214,29 @@ func (p *s3MessageProcessor) Process(msg *sqs.Message) ([]*cloudevents.Event, er
 }
 
 // makeS3EventFromRecord returns a CloudEvent for the given S3 event record.
-func makeS3EventFromRecord(record interface{}) (*cloudevents.Event, error) {
+func (p *s3MessageProcessor) makeS3EventFromRecord(record interface{}) (*cloudevents.Event, error) {
+	recordData := record.(map[string]interface{})
+	eventName := recordData["eventName"].(string)
+
+	s3prop := recordData["s3"].(map[string]interface{})
+	bucketProp := s3prop["bucket"].(map[string]interface{})
+	objectProp := s3prop["object"].(map[string]interface{})
+	bucketARN := bucketProp["arn"].(string)
+	objectKey := objectProp["key"].(string)
+
+	var enrichErr error
+	if p.enricher != nil && !isS3ObjectRemovedEvent(eventName) {
+		bucketName, _ := bucketProp["name"].(string)
+
+		// object keys are URL-encoded in event notifications
+		key, err := url.QueryUnescape(objectKey)
+		if err != nil {
+			key = objectKey
+		}
+
+		enrichErr = p.enricher.enrich(bucketName, key, objectProp)
+	}
+
 	recBytes, err := json.Marshal(record)
 	if err != nil {
 		return nil, fmt.Errorf("serializing S3 event record: %w", err)
@@ -203,17 +244,13 @@ func makeS3EventFromRecord(record interface{}) (*cloudevents.Event, error) {
 
 	data := json.RawMessage(recBytes)
 
-	recordData := record.(map[string]interface{})
-	eventName := recordData["eventName"].(string)
-
-	s3prop := recordData["s3"].(map[string]interface{})
-	bucketARN := s3prop["bucket"].(map[string]interface{})["arn"].(string)
-	objectKey := s3prop["object"].(map[string]interface{})["key"].(string)
-
 	event := cloudevents.NewEvent()
 	event.SetType(v1alpha1.AWSEventType(s3.ServiceName, ceTypeFromS3Event(eventName)))
 	event.SetSource(bucketARN)
 	event.SetSubject(objectKey)
+	if enrichErr != nil {
+		event.SetExtension(ceExtensionS3EnrichmentError, enrichErr.Error())
+	}
 	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
 		return nil, fmt.Errorf("setting CloudEvent data: %w", err)
 	}
@@ -221,6 +258,104 @@ func makeS3EventFromRecord(record interface{}) (*cloudevents.Event, error) {
 	return &event, nil
 }
 
+// s3EventBridgeEvent is an EventBridge event originating from S3.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/ev-events.html
+type s3EventBridgeEvent struct {
+	ID         string          `json:"id"`
+	DetailType string          `json:"detail-type"`
+	Time       time.Time       `json:"time"`
+	Resources  []string        `json:"resources"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// s3EventBridgeEventDetail contains the attributes of the "detail" element of
+// a s3EventBridgeEvent which are relevant to the processor.
+type s3EventBridgeEventDetail struct {
+	Bucket struct {
+		Name string `json:"name"`
+	} `json:"bucket"`
+	Object struct {
+		Key string `json:"key"`
+	} `json:"object"`
+}
+
+// makeS3EventFromEventBridgeEvent returns a CloudEvent for the given S3 event
+// delivered by Amazon EventBridge.
+func (p *s3MessageProcessor) makeS3EventFromEventBridgeEvent(body []byte) (*cloudevents.Event, error) {
+	var ebEvent s3EventBridgeEvent
+	var detail s3EventBridgeEventDetail
+
+	if err := json.Unmarshal(body, &ebEvent); err != nil {
+		return nil, fmt.Errorf("deserializing EventBridge event: %w", err)
+	}
+	if err := json.Unmarshal(ebEvent.Detail, &detail); err != nil {
+		return nil, fmt.Errorf("deserializing EventBridge event detail: %w", err)
+	}
+
+	bucketARN := "arn:aws:s3:::" + detail.Bucket.Name
+	if len(ebEvent.Resources) > 0 {
+		bucketARN = ebEvent.Resources[0]
+	}
+
+	var enrichErr error
+	if p.enricher != nil && !isS3ObjectRemovedEvent(ebEvent.DetailType) {
+		ebEvent.Detail, enrichErr = p.enrichEventBridgeEventDetail(detail.Bucket.Name, detail.Object.Key, ebEvent.Detail)
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetType(v1alpha1.AWSEventType(s3.ServiceName, v1alpha1.AWSS3EventTypeForDetailType(ebEvent.DetailType)))
+	event.SetSource(bucketARN)
+	event.SetSubject(detail.Object.Key)
+	event.SetID(ebEvent.ID)
+	event.SetTime(ebEvent.Time)
+	if enrichErr != nil {
+		event.SetExtension(ceExtensionS3EnrichmentError, enrichErr.Error())
+	}
+	if err := event.SetData(cloudevents.ApplicationJSON, ebEvent.Detail); err != nil {
+		return nil, fmt.Errorf("setting CloudEvent data: %w", err)
+	}
+
+	return &event, nil
+}
+
+// enrichEventBridgeEventDetail returns a copy of the given "detail" element of
+// a S3 EventBridge event, enriched with information about the object it refers
+// to. The original detail is returned alongside the error if the enrichment
+// can not be applied at all.
+func (p *s3MessageProcessor) enrichEventBridgeEventDetail(bucket, key string,
+	detail json.RawMessage) (json.RawMessage, error) {
+
+	detailData := make(map[string]interface{})
+	if err := json.Unmarshal(detail, &detailData); err != nil {
+		return detail, fmt.Errorf("deserializing EventBridge event detail: %w", err)
+	}
+
+	objectProp, ok := detailData["object"].(map[string]interface{})
+	if !ok {
+		return detail, errors.New("EventBridge event detail has no object element")
+	}
+
+	enrichErr := p.enricher.enrich(bucket, key, objectProp)
+
+	enrichedDetail, err := json.Marshal(detailData)
+	if err != nil {
+		return detail, fmt.Errorf("serializing EventBridge event detail: %w", err)
+	}
+
+	return enrichedDetail, enrichErr
+}
+
+// isS3EventBridgePayload checks whether the provided payload data corresponds
+// to a S3 event delivered by Amazon EventBridge.
+func isS3EventBridgePayload(data map[string]interface{}) bool {
+	if v, ok := data["source"].(string); !ok || v != "aws.s3" {
+		return false
+	}
+
+	_, ok := data["detail-type"].(string)
+	return ok
+}
+
 // ceTypeFromS3Event returns the name of a S3 event in a format that is
 // suitable for the "type" context attribute of a CloudEvent.
 func ceTypeFromS3Event(eventName string) string {
@@ -249,3 +384,125 @@ func isTestEventPayload(data map[string]interface{}) bool {
 
 	return true
 }
+
+// codecommitMessageProcessor processes messages originating from CodeCommit
+// repositories, delivered by Amazon EventBridge.
+type codecommitMessageProcessor struct {
+	// this value is set as the "source" CE context attribute when the
+	// CodeCommit processor handles messages which are not originating
+	// from CodeCommit
+	ceSourceFallback string
+
+	// glob patterns of the branches to process events for
+	branches []string
+}
+
+// codecommitEvent is an EventBridge event originating from CodeCommit.
+// https://docs.aws.amazon.com/codecommit/latest/userguide/monitoring-events.html
+type codecommitEvent struct {
+	ID        string          `json:"id"`
+	Source    string          `json:"source"`
+	Time      time.Time       `json:"time"`
+	Resources []string        `json:"resources"`
+	Detail    json.RawMessage `json:"detail"`
+}
+
+// codecommitEventDetail contains the attributes of the "detail" element of a
+// codecommitEvent which are relevant to the processor.
+type codecommitEventDetail struct {
+	Event string `json:"event"`
+
+	// repository state changes
+	ReferenceType string `json:"referenceType"`
+	ReferenceName string `json:"referenceName"`
+
+	// pull request state changes and comments
+	PullRequestID        string `json:"pullRequestId"`
+	DestinationReference string `json:"destinationReference"`
+}
+
+// Process implements MessageProcessor.
+//
+// This processor discards everything from the given message except its body,
+// which must be an EventBridge event in JSON format. Events about branches
+// which do not match the processor's glob patterns are discarded.
+func (p *codecommitMessageProcessor) Process(msg *sqs.Message) ([]*cloudevents.Event, error) {
+	var ccEvent codecommitEvent
+	var detail codecommitEventDetail
+
+	err := json.Unmarshal([]byte(*msg.Body), &ccEvent)
+	if err == nil {
+		err = json.Unmarshal(ccEvent.Detail, &detail)
+	}
+
+	// instead of discarding non-CodeCommit events, fall back to the
+	// default processor's behaviour
+	if err != nil || ccEvent.Source != "aws.codecommit" || detail.Event == "" {
+		event, err := makeSQSEvent(msg, p.ceSourceFallback)
+		if err != nil {
+			return nil, fmt.Errorf("creating CloudEvent from SQS message: %w", err)
+		}
+
+		return []*cloudevents.Event{event}, nil
+	}
+
+	if !p.matchesBranch(&detail) {
+		return nil, nil
+	}
+
+	ceSource := p.ceSourceFallback
+	if len(ccEvent.Resources) > 0 {
+		ceSource = ccEvent.Resources[0]
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetType(v1alpha1.AWSEventType(codecommit.ServiceName, v1alpha1.AWSCodeCommitEventTypeForEvent(detail.Event)))
+	event.SetSource(ceSource)
+	event.SetID(ccEvent.ID)
+	event.SetTime(ccEvent.Time)
+
+	switch {
+	case detail.ReferenceName != "":
+		event.SetSubject(detail.ReferenceName)
+	case detail.PullRequestID != "":
+		event.SetSubject(detail.PullRequestID)
+	}
+
+	if err := event.SetData(cloudevents.ApplicationJSON, ccEvent.Detail); err != nil {
+		return nil, fmt.Errorf("setting CloudEvent data: %w", err)
+	}
+
+	return []*cloudevents.Event{&event}, nil
+}
+
+// matchesBranch returns whether the branch referenced by the given event
+// detail matches one of the processor's glob patterns.
+// Events which do not reference any branch, such as tag updates and comments
+// on commits, always match.
+func (p *codecommitMessageProcessor) matchesBranch(detail *codecommitEventDetail) bool {
+	var branch string
+
+	switch {
+	case detail.ReferenceType == "branch":
+		branch = detail.ReferenceName
+	case detail.DestinationReference != "":
+		branch = strings.TrimPrefix(detail.DestinationReference, "refs/heads/")
+	default:
+		return true
+	}
+
+	var hasPatterns bool
+
+	for _, pattern := range p.branches {
+		if pattern == "" {
+			continue
+		}
+		hasPatterns = true
+
+		if match, _ := path.Match(pattern, branch); match {
+			return true
+		}
+	}
+
+	return !hasPatterns
+}
@@ -0,0 +1,263 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssqssource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestCodeCommitMessageProcessor(t *testing.T) {
+	const tRepoARN = "arn:aws:codecommit:us-fake-0:123456789012:MyRepo"
+
+	fallbackSource := makeARN(tQueueArnResource).String()
+
+	testCases := map[string]struct {
+		branches []string
+		body     string
+
+		expectEvent   bool
+		expectType    string
+		expectSource  string
+		expectSubject string
+	}{
+		"branch update, all branches": {
+			body: makeCodeCommitEventBody("referenceUpdated",
+				`"referenceType": "branch", "referenceName": "main"`),
+			expectEvent:   true,
+			expectType:    "com.amazon.codecommit.push",
+			expectSource:  tRepoARN,
+			expectSubject: "main",
+		},
+		"branch creation, matching glob": {
+			branches: []string{"main", "feature/*"},
+			body: makeCodeCommitEventBody("referenceCreated",
+				`"referenceType": "branch", "referenceName": "feature/foo"`),
+			expectEvent:   true,
+			expectType:    "com.amazon.codecommit.reference_created",
+			expectSource:  tRepoARN,
+			expectSubject: "feature/foo",
+		},
+		"branch deletion, non-matching glob": {
+			branches: []string{"main", "feature/*"},
+			body: makeCodeCommitEventBody("referenceDeleted",
+				`"referenceType": "branch", "referenceName": "fix/bar"`),
+			expectEvent: false,
+		},
+		"tag creation is not filtered by branch": {
+			branches: []string{"main"},
+			body: makeCodeCommitEventBody("referenceCreated",
+				`"referenceType": "tag", "referenceName": "v1.0.0"`),
+			expectEvent:   true,
+			expectType:    "com.amazon.codecommit.reference_created",
+			expectSource:  tRepoARN,
+			expectSubject: "v1.0.0",
+		},
+		"pull request merged, matching destination": {
+			branches: []string{"main"},
+			body: makeCodeCommitEventBody("pullRequestMergeStatusUpdated",
+				`"pullRequestId": "42", "destinationReference": "refs/heads/main", "isMerged": "True"`),
+			expectEvent:   true,
+			expectType:    "com.amazon.codecommit.pull_request_merge_status_updated",
+			expectSource:  tRepoARN,
+			expectSubject: "42",
+		},
+		"pull request approval, non-matching destination": {
+			branches: []string{"main"},
+			body: makeCodeCommitEventBody("pullRequestApprovalStateChanged",
+				`"pullRequestId": "42", "destinationReference": "refs/heads/develop"`),
+			expectEvent: false,
+		},
+		"comment on commit": {
+			branches: []string{"main"},
+			body:     makeCodeCommitEventBody("commentOnCommitCreated", `"commentId": "abc"`),
+
+			expectEvent:  true,
+			expectType:   "com.amazon.codecommit.comment_on_commit_created",
+			expectSource: tRepoARN,
+		},
+		"not a CodeCommit event": {
+			body:         `{"hello": "world"}`,
+			expectEvent:  true,
+			expectType:   "com.amazon.sqs.message",
+			expectSource: fallbackSource,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			p := &codecommitMessageProcessor{
+				ceSourceFallback: fallbackSource,
+				branches:         tc.branches,
+			}
+
+			msg := &sqs.Message{
+				MessageId: aws.String(tMsgIDPrefix + "001"),
+				Body:      aws.String(tc.body),
+			}
+
+			events, err := p.Process(msg)
+			require.NoError(t, err)
+
+			if !tc.expectEvent {
+				assert.Empty(t, events)
+				return
+			}
+
+			require.Len(t, events, 1)
+			event := events[0]
+
+			assert.Equal(t, tc.expectType, event.Type())
+			assert.Equal(t, tc.expectSource, event.Source())
+			assert.Equal(t, tc.expectSubject, event.Subject())
+		})
+	}
+}
+
+func TestS3MessageProcessor(t *testing.T) {
+	const tBucketARN = "arn:aws:s3:::my-bucket"
+
+	fallbackSource := makeARN(tQueueArnResource).String()
+
+	testCases := map[string]struct {
+		body string
+
+		expectNumEvents int
+		expectType      string
+		expectSource    string
+		expectSubject   string
+	}{
+		"event notification record": {
+			body: `{"Records": [{
+				"eventName": "ObjectCreated:Put",
+				"s3": {
+					"bucket": {"name": "my-bucket", "arn": "arn:aws:s3:::my-bucket"},
+					"object": {"key": "path/to/object"}
+				}
+			}]}`,
+			expectNumEvents: 1,
+			expectType:      "com.amazon.s3.objectcreated",
+			expectSource:    tBucketARN,
+			expectSubject:   "path/to/object",
+		},
+		"test event": {
+			body:            `{"Service": "Amazon S3", "Event": "s3:TestEvent", "Bucket": "my-bucket"}`,
+			expectNumEvents: 1,
+			expectType:      "com.amazon.s3.testevent",
+			expectSource:    tBucketARN,
+		},
+		"EventBridge, object created": {
+			body:            makeS3EventBridgeEventBody("Object Created"),
+			expectNumEvents: 1,
+			expectType:      "com.amazon.s3.objectcreated",
+			expectSource:    tBucketARN,
+			expectSubject:   "path/to/object",
+		},
+		"EventBridge, object tags added": {
+			body:            makeS3EventBridgeEventBody("Object Tags Added"),
+			expectNumEvents: 1,
+			expectType:      "com.amazon.s3.objecttagging",
+			expectSource:    tBucketARN,
+			expectSubject:   "path/to/object",
+		},
+		"EventBridge, object restore completed": {
+			body:            makeS3EventBridgeEventBody("Object Restore Completed"),
+			expectNumEvents: 1,
+			expectType:      "com.amazon.s3.objectrestore",
+			expectSource:    tBucketARN,
+			expectSubject:   "path/to/object",
+		},
+		"not a S3 event": {
+			body:            `{"hello": "world"}`,
+			expectNumEvents: 1,
+			expectType:      "com.amazon.sqs.message",
+			expectSource:    fallbackSource,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			p := &s3MessageProcessor{
+				ceSourceFallback: fallbackSource,
+			}
+
+			msg := &sqs.Message{
+				MessageId: aws.String(tMsgIDPrefix + "001"),
+				Body:      aws.String(tc.body),
+			}
+
+			events, err := p.Process(msg)
+			require.NoError(t, err)
+			require.Len(t, events, tc.expectNumEvents)
+
+			event := events[0]
+
+			assert.Equal(t, tc.expectType, event.Type())
+			assert.Equal(t, tc.expectSource, event.Source())
+			assert.Equal(t, tc.expectSubject, event.Subject())
+		})
+	}
+}
+
+// makeCodeCommitEventBody returns the body of a SQS message containing a
+// CodeCommit event with the given name and extra detail attributes.
+func makeCodeCommitEventBody(event, detailAttrs string) string {
+	return `{
+		"version": "0",
+		"id": "01234567-0123-0123-0123-0123456789ab",
+		"detail-type": "CodeCommit Event",
+		"source": "aws.codecommit",
+		"account": "123456789012",
+		"time": "2021-01-01T00:00:00Z",
+		"region": "us-fake-0",
+		"resources": ["arn:aws:codecommit:us-fake-0:123456789012:MyRepo"],
+		"detail": {
+			"event": "` + event + `",
+			"repositoryName": "MyRepo",
+			` + detailAttrs + `
+		}
+	}`
+}
+
+// makeS3EventBridgeEventBody returns the body of a SQS message containing a
+// S3 event with the given detail type, delivered by Amazon EventBridge.
+func makeS3EventBridgeEventBody(detailType string) string {
+	return `{
+		"version": "0",
+		"id": "01234567-0123-0123-0123-0123456789ab",
+		"detail-type": "` + detailType + `",
+		"source": "aws.s3",
+		"account": "123456789012",
+		"time": "2021-01-01T00:00:00Z",
+		"region": "us-fake-0",
+		"resources": ["arn:aws:s3:::my-bucket"],
+		"detail": {
+			"version": "0",
+			"bucket": {"name": "my-bucket"},
+			"object": {"key": "path/to/object", "size": 5},
+			"request-id": "N4N7GDK58NMKJ12R",
+			"requester": "123456789012"
+		}
+	}`
+}
@@ -0,0 +1,397 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssqssource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgotesting "k8s.io/client-go/testing"
+
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+	pkgapis "knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/reconciler"
+	rt "knative.dev/pkg/reconciler/testing"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
+	adaptesting "github.com/triggermesh/aws-event-sources/pkg/adapter/testing"
+	"github.com/triggermesh/aws-event-sources/pkg/apis"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	fakeinjectionclient "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client/fake"
+	reconcilerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/reconciler/sources/v1alpha1/awssqssource"
+	sqsclient "github.com/triggermesh/aws-event-sources/pkg/client/sqs"
+	eventtesting "github.com/triggermesh/aws-event-sources/pkg/testing/event"
+)
+
+func TestReconcile(t *testing.T) {
+	testCases := rt.TableTest{
+		// Creation/Deletion
+
+		{
+			Name: "Initial receiver registration",
+			Key:  tKey,
+			Ctx:  statusMockClockContext(),
+			Objects: []runtime.Object{
+				newEventSource(),
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				receiverStartedPatch(),
+			},
+			PostConditions: []func(*testing.T, *rt.TableRow){
+				isRegistered,
+			},
+		},
+		{
+			Name: "Source deleted",
+			Key:  tKey,
+			Objects: []runtime.Object{
+				newEventSource(deleted),
+			},
+			PostConditions: []func(*testing.T, *rt.TableRow){
+				isDeregistered,
+			},
+		},
+		{
+			Name: "Source not multi-tenant",
+			Key:  tKey,
+			Objects: []runtime.Object{
+				newEventSource(singleTenant),
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				// no patch
+			},
+			PostConditions: []func(*testing.T, *rt.TableRow){
+				isDeregistered,
+			},
+		},
+
+		// Lifecycle
+
+		{
+			Name: "Receiver previously started",
+			Key:  tKey,
+			Objects: []runtime.Object{
+				newEventSource(receiverStarted),
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				// no patch
+			},
+			PostConditions: []func(*testing.T, *rt.TableRow){
+				isRegistered,
+			},
+		},
+
+		// Errors
+
+		{
+			Name: "Sink not ready",
+			Key:  tKey,
+			Objects: []runtime.Object{
+				newEventSource(noSink),
+			},
+			WantEvents: []string{
+				sinkMissingEvent(),
+			},
+			PostConditions: []func(*testing.T, *rt.TableRow){
+				isDeregistered,
+			},
+			WantErr: true,
+		},
+		{
+			Name: "Error fetching credentials",
+			Key:  tKey,
+			Ctx:  failingSQSClientGetterContext(),
+			Objects: []runtime.Object{
+				newEventSource(),
+			},
+			WantPatches: []clientgotesting.PatchActionImpl{
+				receiverNotStartedPatch(),
+			},
+			WantEvents: []string{
+				failGetSQSClientEvent(),
+			},
+			PostConditions: []func(*testing.T, *rt.TableRow){
+				isDeregistered,
+			},
+			WantErr: true,
+		},
+
+		// Edge cases
+
+		{
+			Name:    "Reconcile a non-existing object",
+			Key:     tKey,
+			Objects: nil,
+			WantErr: false,
+		},
+	}
+
+	ctor := reconcilerCtor()
+
+	testCases.Test(t, adaptesting.MakeFactory(ctor))
+}
+
+// reconcilerCtor returns a Ctor for a AWSSQSSource Reconciler.
+func reconcilerCtor() adaptesting.Ctor {
+	return func(t *testing.T, ctx context.Context, tr *rt.TableRow, ls *adaptesting.Listers) controller.Reconciler {
+
+		srcClienset := fakeinjectionclient.Get(ctx)
+
+		a := &mtAdapter{
+			logger:        logtesting.TestLogger(t),
+			ceClient:      adaptertest.NewTestClient(),
+			sqsCg:         sqsClientGetterFromContext(ctx),
+			receivers:     make(map[types.NamespacedName]*queueReceiver),
+			statusPatcher: status.NewPatcher(tComponent, srcClienset.SourcesV1alpha1()),
+		}
+
+		// inject adapter into test data so that table tests can perform
+		// assertions on it
+		if tr.OtherTestData == nil {
+			tr.OtherTestData = make(map[string]interface{}, 1)
+		}
+		tr.OtherTestData[testAdapterDataKey] = a
+
+		r := &Reconciler{
+			adapter: a,
+		}
+
+		return reconcilerv1alpha1.NewReconciler(ctx, logging.FromContext(ctx),
+			srcClienset, ls.GetAWSSQSSourceLister(),
+			controller.GetEventRecorder(ctx), r)
+	}
+}
+
+const (
+	tNs   = "testns"
+	tName = "test"
+	tKey  = tNs + "/" + tName
+
+	tComponent = "test-component"
+)
+
+var tSinkURI = &pkgapis.URL{
+	Scheme: "http",
+	Host:   "default.default.svc.example.com",
+	Path:   "/",
+}
+
+/* Event sources */
+
+// sourceOption is a functional option for an event source.
+type sourceOption func(*v1alpha1.AWSSQSSource)
+
+// newEventSource returns a test source object with pre-filled attributes.
+func newEventSource(opts ...sourceOption) *v1alpha1.AWSSQSSource {
+	src := &v1alpha1.AWSSQSSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: tNs,
+			Name:      tName,
+			Annotations: map[string]string{
+				v1alpha1.MultiTenantAnnotation: "true",
+			},
+		},
+		Spec: v1alpha1.AWSSQSSourceSpec{
+			ARN: apis.ARN(makeARN(tQueueArnResource)),
+		},
+		Status: v1alpha1.EventSourceStatus{
+			SourceStatus: duckv1.SourceStatus{
+				SinkURI: tSinkURI,
+			},
+		},
+	}
+
+	// *reconcilerImpl.Reconcile calls this method before any reconciliation loop. Calling it here ensures that the
+	// object is initialized in the same manner, and prevents tests from wrongly reporting unexpected status updates.
+	reconciler.PreProcessReconcile(context.Background(), src)
+
+	for _, opt := range opts {
+		opt(src)
+	}
+
+	return src
+}
+
+// receiverStarted sets the ReceiverStarted status condition.
+func receiverStarted(src *v1alpha1.AWSSQSSource) {
+	src.Status.Conditions = append(src.Status.Conditions, pkgapis.Condition{
+		Type:     v1alpha1.AWSSQSConditionReceiverStarted,
+		Status:   corev1.ConditionTrue,
+		Severity: pkgapis.ConditionSeverityInfo,
+		// LastTransitionTime can be omitted, it is excluded from the
+		// comparison if the above fields already match
+	})
+}
+
+// singleTenant removes the multi-tenant annotation from the source.
+func singleTenant(src *v1alpha1.AWSSQSSource) {
+	delete(src.Annotations, v1alpha1.MultiTenantAnnotation)
+}
+
+// noSink ensures the sink URI is absent from the source's status.
+func noSink(src *v1alpha1.AWSSQSSource) {
+	src.Status.SinkURI = nil
+}
+
+// deleted marks the source as deleted.
+func deleted(src *v1alpha1.AWSSQSSource) {
+	t := metav1.Unix(0, 0)
+	src.SetDeletionTimestamp(&t)
+}
+
+/* Events */
+
+func sinkMissingEvent() string {
+	return eventtesting.Eventf(corev1.EventTypeWarning, ReasonSourceNotReady,
+		"Event sink URL wasn't resolved yet. Skipping adapter configuration")
+}
+func failGetSQSClientEvent() string {
+	return eventtesting.Eventf(corev1.EventTypeWarning, "InternalError", "registering queue receiver: "+
+		"obtaining SQS client: assert.AnError general error for testing")
+}
+
+/* Patches */
+
+func receiverStartedPatch() clientgotesting.PatchActionImpl {
+	return clientgotesting.PatchActionImpl{
+		Name:      tName,
+		PatchType: types.JSONPatchType,
+		Patch: []byte(`[{` +
+			`"op":"add",` +
+			`"path":"/status/conditions/2",` +
+			`"value":{` +
+			`"lastTransitionTime":"1970-01-01T00:00:00Z",` +
+			`"severity":"Info",` +
+			`"status":"True",` +
+			`"type":"` + v1alpha1.AWSSQSConditionReceiverStarted + `"` +
+			`}` +
+			`}]`,
+		),
+	}
+}
+
+func receiverNotStartedPatch() clientgotesting.PatchActionImpl {
+	return clientgotesting.PatchActionImpl{
+		Name:      tName,
+		PatchType: types.JSONPatchType,
+		Patch: []byte(`[{` +
+			`"op":"add",` +
+			`"path":"/status/conditions/2",` +
+			`"value":{` +
+			`"lastTransitionTime":"1970-01-01T00:00:00Z",` +
+			`"message":"obtaining SQS client: assert.AnError general error for testing",` +
+			`"reason":"` + v1alpha1.AWSSQSReasonNoClient + `",` +
+			`"severity":"Info",` +
+			`"status":"False",` +
+			`"type":"` + v1alpha1.AWSSQSConditionReceiverStarted + `"` +
+			`}` +
+			`}]`,
+		),
+	}
+}
+
+/* Test contexts */
+
+// fakeClock returns a time that is always the 0 epoch.
+type fakeClock struct{}
+
+// Now implements status.Clock.
+func (*fakeClock) Now() pkgapis.VolatileTime {
+	return pkgapis.VolatileTime{
+		Inner: metav1.Unix(0, 0),
+	}
+}
+
+func statusMockClockContext() context.Context {
+	return status.WithClock(context.Background(), &fakeClock{})
+}
+
+var sqscgKey struct{}
+
+// failingClientGetter is a sqs.ClientGetter that always returns an error.
+type failingClientGetter struct{}
+
+// Get implements sqs.ClientGetter.
+func (*failingClientGetter) Get(*v1alpha1.AWSSQSSource) (sqsclient.Client, error) {
+	return nil, assert.AnError
+}
+
+var _ sqsclient.ClientGetter = (*failingClientGetter)(nil)
+
+// failingSQSClientGetterContext returns a context with a failingClientGetter
+// attached.
+func failingSQSClientGetterContext() context.Context {
+	return status.WithClock(
+		context.WithValue(context.Background(), sqscgKey, &failingClientGetter{}),
+		&fakeClock{},
+	)
+}
+
+// sqsClientGetterFromContext returns the sqs.ClientGetter associated with the
+// context, or a mocked client getter as a fall back.
+func sqsClientGetterFromContext(ctx context.Context) sqsclient.ClientGetter {
+	if cg, ok := ctx.Value(sqscgKey).(sqsclient.ClientGetter); ok {
+		return cg
+	}
+	return staticClientGetter(&standardMockSQSClient{})
+}
+
+/* Adapter */
+
+const testAdapterDataKey = "adapter"
+
+// staticClientGetter transforms the given client interface into a
+// ClientGetter.
+func staticClientGetter(cli sqsclient.Client) sqsclient.ClientGetterFunc {
+	return func(*v1alpha1.AWSSQSSource) (sqsclient.Client, error) {
+		return cli, nil
+	}
+}
+
+// isRegistered verifies that a queue receiver was started for the test
+// source, then stops it.
+func isRegistered(t *testing.T, tr *rt.TableRow) {
+	a := tr.OtherTestData[testAdapterDataKey].(*mtAdapter)
+
+	key := types.NamespacedName{Namespace: tNs, Name: tName}
+
+	r, exists := a.receivers[key]
+	if assert.True(t, exists, "Expected queue receiver") {
+		r.stop()
+	}
+}
+
+// isDeregistered verifies that no queue receiver is running for the test
+// source.
+func isDeregistered(t *testing.T, tr *rt.TableRow) {
+	a := tr.OtherTestData[testAdapterDataKey].(*mtAdapter)
+
+	key := types.NamespacedName{Namespace: tNs, Name: tName}
+
+	_, exists := a.receivers[key]
+	assert.False(t, exists, "Expected no queue receiver")
+}
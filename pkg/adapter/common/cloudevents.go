@@ -0,0 +1,48 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import "strings"
+
+// StripNonAlphanumCharsAndMapToLower applies the following transformations to
+// the given string:
+//  - strips all non alphanumeric characters
+//  - maps all Unicode letters to their lower case
+func StripNonAlphanumCharsAndMapToLower(s string) string {
+	var stripped strings.Builder
+	stripped.Grow(len(s))
+
+	// operate on bytes instead of runes, since all alphanumeric characters
+	// are represented in a single byte
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+
+		if ('a' <= b && b <= 'z') ||
+			('A' <= b && b <= 'Z') ||
+			('0' <= b && b <= '9') {
+
+			if 'A' <= b && b <= 'Z' {
+				// shift from upper to lower case
+				b += 'a' - 'A'
+			}
+
+			stripped.WriteByte(b)
+		}
+	}
+
+	return stripped.String()
+}
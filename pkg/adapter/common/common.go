@@ -73,11 +73,21 @@ func MustParseKinesisResource(resource string) string /*stream*/ {
 // MustParseCognitoUserPoolResource parses the resource segment of a Cognito User Pool
 // ARN and panics in case of error.
 func MustParseCognitoUserPoolResource(resource string) string {
-	elements, err := parseResource(resource, expectCognitoUserPoolResourceFmt)
+	userPoolID, err := ParseCognitoUserPoolResource(resource)
 	if err != nil {
 		panic(err)
 	}
-	return elements[0]
+	return userPoolID
+}
+
+// ParseCognitoUserPoolResource parses the resource segment of a Cognito User
+// Pool ARN.
+func ParseCognitoUserPoolResource(resource string) (string /*userPoolId*/, error) {
+	elements, err := parseResource(resource, expectCognitoUserPoolResourceFmt)
+	if err != nil {
+		return "", err
+	}
+	return elements[0], nil
 }
 
 // parseResource parses the resource segment of a ARN and panics in case of
@@ -103,6 +103,12 @@ func Start(ctx context.Context) {
 	}
 }
 
+// Handler returns the default HTTP health handler, for applications which
+// serve it from their own HTTP server instead of calling Start.
+func Handler() http.Handler {
+	return &defaultHandler
+}
+
 // MarkReady indicates that the application is ready to operate.
 func MarkReady() {
 	if defaultHandler.isReady() {
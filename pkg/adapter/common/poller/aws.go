@@ -0,0 +1,71 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poller
+
+import (
+	"fmt"
+
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	pkgaws "github.com/triggermesh/aws-event-sources/pkg/aws"
+)
+
+// NewAWSSession returns an AWS session which signs requests using the
+// security credentials referenced in a source's spec.
+func NewAWSSession(cli coreclientv1.SecretInterface, creds *v1alpha1.AWSSecurityCredentials,
+	cfg *aws.Config) (*session.Session, error) {
+
+	c, err := pkgaws.Credentials(cli, creds)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving AWS security credentials: %w", err)
+	}
+
+	sess, err := session.NewSession(cfg.WithCredentials(credentials.NewStaticCredentialsFromCreds(*c)))
+	if err != nil {
+		return nil, fmt.Errorf("creating AWS session: %w", err)
+	}
+	return sess, nil
+}
+
+// CredentialsFunc returns the AWS security credentials referenced in the spec
+// of the given source object.
+type CredentialsFunc func(v1alpha1.EventSource) *v1alpha1.AWSSecurityCredentials
+
+// WithCredentials returns a ConfigFunc which complements the properties
+// returned by the given ConfigFunc with the values of the AWS security
+// credentials referenced by each source, so that the polling loop of a source
+// is replaced after its credentials get rotated.
+func WithCredentials(config ConfigFunc, cli coreclientv1.SecretInterface, creds CredentialsFunc) ConfigFunc {
+	return func(src v1alpha1.EventSource) interface{} {
+		// credentials which can not be retrieved are reported by the
+		// JobBuilder, which retrieves them in the same way
+		c, _ := pkgaws.Credentials(cli, creds(src))
+
+		return struct {
+			Config      interface{}
+			Credentials *credentials.Value
+		}{
+			Config:      config(src),
+			Credentials: c,
+		}
+	}
+}
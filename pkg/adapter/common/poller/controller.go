@@ -0,0 +1,211 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poller
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	pkgcontroller "knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	internalclientsetscheme "github.com/triggermesh/aws-event-sources/pkg/client/generated/clientset/internalclientset/scheme"
+)
+
+func init() {
+	// allows recording events about source objects
+	_ = internalclientsetscheme.AddToScheme(scheme.Scheme)
+}
+
+// resyncPeriod is the period at which sources are reconciled again while
+// their polling loop is running, so that external changes which don't
+// trigger a reconciliation, such as rotated credentials, are picked up.
+const resyncPeriod = 5 * time.Minute
+
+// InformerFunc returns the informer of the source objects of a given kind.
+type InformerFunc func(context.Context) cache.SharedIndexInformer
+
+// NewController returns a constructor for a controller which reconciles the
+// source objects returned by the given informer on behalf of a Poller.
+func NewController(component string, informer InformerFunc) pkgadapter.ControllerConstructor {
+	return func(ctx context.Context, a pkgadapter.Adapter) *pkgcontroller.Impl {
+		p := a.(*Poller)
+
+		inf := informer(ctx)
+
+		r := &Reconciler{
+			LeaderAwareFuncs: reconciler.LeaderAwareFuncs{
+				PromoteFunc: func(bkt reconciler.Bucket, enq func(reconciler.Bucket, types.NamespacedName)) error {
+					for _, obj := range inf.GetStore().List() {
+						enq(bkt, keyOf(obj.(v1alpha1.EventSource)))
+					}
+					return nil
+				},
+				// Polling loops are stopped whenever the controller
+				// loses its leadership.
+				DemoteFunc: func(reconciler.Bucket) {
+					p.StopAll()
+				},
+			},
+			poller:   p,
+			indexer:  inf.GetIndexer(),
+			recorder: newRecorder(ctx, component),
+		}
+
+		impl := pkgcontroller.NewImpl(r, logging.FromContext(ctx), component)
+		r.enqueueAfter = impl.EnqueueKeyAfter
+
+		p.SetNotifyFunc(impl.EnqueueKey)
+
+		inf.AddEventHandler(pkgcontroller.HandleAll(impl.Enqueue))
+		inf.AddEventHandler(deleteHandler(p))
+
+		return impl
+	}
+}
+
+// Reconciler implements controller.Reconciler for source objects of any kind
+// served by a Poller.
+type Reconciler struct {
+	reconciler.LeaderAwareFuncs
+
+	poller   *Poller
+	indexer  cache.Indexer
+	recorder record.EventRecorder
+
+	enqueueAfter func(types.NamespacedName, time.Duration)
+}
+
+// Check the interfaces Reconciler should implement.
+var (
+	_ pkgcontroller.Reconciler = (*Reconciler)(nil)
+	_ reconciler.LeaderAware   = (*Reconciler)(nil)
+)
+
+// Reconcile implements controller.Reconciler.
+// Only the leader replica of the multi-tenant adapter polls on behalf of
+// sources, otherwise events would be sent once per replica.
+func (r *Reconciler) Reconcile(ctx context.Context, key string) error {
+	logger := logging.FromContext(ctx)
+
+	obj, exists, err := r.indexer.GetByKey(key)
+	switch {
+	case err != nil:
+		return err
+	case !exists:
+		// the polling loop of deleted sources is stopped by the
+		// controller's delete handler
+		logger.Debugf("Resource %q no longer exists", key)
+		return nil
+	}
+
+	// don't modify the informer's copy
+	src := obj.(v1alpha1.EventSource).DeepCopyObject().(v1alpha1.EventSource)
+	srcKey := keyOf(src)
+
+	ctx = pkgcontroller.WithEventRecorder(ctx, r.recorder)
+
+	var event reconciler.Event
+
+	switch {
+	case src.GetDeletionTimestamp() != nil:
+		event = r.poller.FinalizeSource(ctx, src)
+	case !r.IsLeaderFor(srcKey):
+		event = r.poller.ObserveSource(ctx, src)
+	default:
+		event = r.poller.ReconcileSource(ctx, src)
+		if event == nil {
+			r.enqueueAfter(srcKey, resyncPeriod)
+		}
+	}
+
+	if event == nil {
+		return nil
+	}
+
+	var recEvent *reconciler.ReconcilerEvent
+	if reconciler.EventAs(event, &recEvent) {
+		logger.Infow("Returned an event", zap.Any("event", event))
+		r.recorder.Eventf(src, recEvent.EventType, recEvent.Reason, recEvent.Format, recEvent.Args...)
+
+		// the event was wrapped inside an error, consider the
+		// reconciliation as failed
+		if _, isEvent := event.(*reconciler.ReconcilerEvent); !isEvent {
+			return event
+		}
+		return nil
+	}
+
+	logger.Errorw("Returned an error", zap.Error(event))
+	r.recorder.Event(src, corev1.EventTypeWarning, "InternalError", event.Error())
+	return event
+}
+
+// newRecorder returns an EventRecorder which records events on behalf of the
+// given component.
+func newRecorder(ctx context.Context, component string) record.EventRecorder {
+	if recorder := pkgcontroller.GetEventRecorder(ctx); recorder != nil {
+		return recorder
+	}
+
+	logger := logging.FromContext(ctx)
+
+	eventBroadcaster := record.NewBroadcaster()
+	watches := []watch.Interface{
+		eventBroadcaster.StartLogging(logger.Named("event-broadcaster").Infof),
+		eventBroadcaster.StartRecordingToSink(
+			&typedcorev1.EventSinkImpl{Interface: kubeclient.Get(ctx).CoreV1().Events("")}),
+	}
+	go func() {
+		<-ctx.Done()
+		for _, w := range watches {
+			w.Stop()
+		}
+	}()
+
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component})
+}
+
+// deleteHandler returns an event handler which stops the polling loops of
+// deleted source objects. Sources are not finalized by the adapter, so they
+// may vanish from the cache before a reconciliation observes their deletion.
+func deleteHandler(p *Poller) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if src, ok := obj.(v1alpha1.EventSource); ok {
+				p.Stop(src)
+			}
+		},
+	}
+}
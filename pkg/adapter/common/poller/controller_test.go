@@ -0,0 +1,160 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"knative.dev/pkg/reconciler"
+	rt "knative.dev/pkg/reconciler/testing"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	fakeclientset "github.com/triggermesh/aws-event-sources/pkg/client/generated/clientset/internalclientset/fake"
+	informerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/informers/sources/v1alpha1/awscloudwatchlogssource"
+
+	// Link fake informers accessed by our controller
+	_ "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/informers/sources/v1alpha1/awscloudwatchlogssource/fake"
+)
+
+func TestNewController(t *testing.T) {
+	informer := func(ctx context.Context) cache.SharedIndexInformer {
+		return informerv1alpha1.Get(ctx).Informer()
+	}
+
+	ctx, informers := rt.SetupFakeContext(t)
+
+	// expected informers: Source
+	require.Len(t, informers, 1, "Unexpected number of injected informers")
+
+	impl := NewController("controller-test", informer)(ctx, &Poller{})
+
+	r, ok := impl.Reconciler.(*Reconciler)
+	require.True(t, ok, "Unexpected type of Reconciler")
+
+	assert.NotNil(t, r.poller)
+	assert.NotNil(t, r.indexer)
+	assert.NotNil(t, r.recorder)
+	assert.NotNil(t, r.enqueueAfter)
+	assert.NotNil(t, r.PromoteFunc)
+	assert.NotNil(t, r.DemoteFunc)
+}
+
+func TestReconcile(t *testing.T) {
+	testCases := map[string]struct {
+		src          *v1alpha1.AWSCloudWatchLogsSource
+		isLeader     bool
+		expectJob    bool
+		expectResync bool
+		expectEvent  string
+	}{
+		"Leader": {
+			src:          newEventSource(),
+			isLeader:     true,
+			expectJob:    true,
+			expectResync: true,
+		},
+		"Not leader": {
+			src: newEventSource(),
+		},
+		"Deleted": {
+			src:         newEventSource(deletedSource),
+			isLeader:    true,
+			expectEvent: "Normal " + ReasonPollerStopped + " Poller stopped",
+		},
+		"Leader without sink": {
+			src:         newEventSource(noSink),
+			isLeader:    true,
+			expectJob:   true,
+			expectEvent: "Warning " + ReasonSourceNotReady + " Event sink URL wasn't resolved yet. Skipping poller configuration",
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			p := newTestPoller(t, fakeJobBuilder(make(chan *fakeJob, 1), false), nil)
+			defer p.StopAll()
+
+			p.statusPatcher = status.NewPatcher(tComponent, fakeclientset.NewSimpleClientset(tc.src).SourcesV1alpha1())
+
+			// a job which was started by a previous reconciliation
+			require.NoError(t, p.Run(context.Background(), newEventSource()))
+
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.NoError(t, indexer.Add(tc.src))
+
+			recorder := record.NewFakeRecorder(1)
+
+			var resynced bool
+
+			r := &Reconciler{
+				poller:   p,
+				indexer:  indexer,
+				recorder: recorder,
+				enqueueAfter: func(types.NamespacedName, time.Duration) {
+					resynced = true
+				},
+			}
+
+			if tc.isLeader {
+				require.NoError(t, r.Promote(reconciler.UniversalBucket(), nil))
+			}
+
+			_ = r.Reconcile(context.Background(), tNs+"/"+tName)
+
+			if tc.expectJob {
+				assert.Len(t, p.jobs, 1, "Expected the job to be running")
+			} else {
+				assert.Empty(t, p.jobs, "Expected the job to be stopped")
+			}
+
+			assert.Equal(t, tc.expectResync, resynced)
+
+			if tc.expectEvent == "" {
+				assert.Empty(t, recorder.Events, "Unexpected event")
+			} else {
+				require.Len(t, recorder.Events, 1, "Expected an event")
+				assert.Equal(t, tc.expectEvent, <-recorder.Events)
+			}
+		})
+	}
+}
+
+func TestReconcileDeletedFromCache(t *testing.T) {
+	r := &Reconciler{
+		indexer: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}),
+	}
+
+	assert.NoError(t, r.Reconcile(context.Background(), tNs+"/"+tName))
+}
+
+// deletedSource marks the source as deleted.
+func deletedSource(src *v1alpha1.AWSCloudWatchLogsSource) {
+	t := metav1.Unix(0, 0)
+	src.SetDeletionTimestamp(&t)
+}
@@ -0,0 +1,328 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package poller allows running the polling loops of multiple source objects
+// inside a single multi-tenant adapter.
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+
+	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/health"
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+// Bounds of the delay before a poller which stopped unexpectedly is
+// restarted.
+const (
+	minRestartDelay = 5 * time.Second
+	maxRestartDelay = 5 * time.Minute
+)
+
+// JobBuilder returns the polling loop of the given source object, in the form
+// of a single-tenant adapter which sends events using the given CloudEvents
+// client. That adapter must poll until its context is cancelled, and must not
+// serve any health endpoint of its own.
+type JobBuilder func(context.Context, v1alpha1.EventSource, cloudevents.Client) (pkgadapter.Adapter, error)
+
+// ConfigFunc returns the properties of the given source object which the
+// result of a JobBuilder depends on. The polling loop of a source is replaced
+// whenever these properties change.
+type ConfigFunc func(v1alpha1.EventSource) interface{}
+
+// Poller runs the polling loops of all source objects of a given kind which
+// are served by the same multi-tenant adapter.
+type Poller struct {
+	logger *zap.SugaredLogger
+
+	ceClient cloudevents.Client
+
+	build  JobBuilder
+	config ConfigFunc
+
+	statusPatcher *status.Patcher
+
+	// called whenever the polling loop of a source stops or restarts, so
+	// that the outcome can be reflected in the status of that source
+	notify func(types.NamespacedName)
+
+	mu   sync.Mutex
+	jobs map[types.NamespacedName]*job
+}
+
+// Poller implements adapter.Adapter.
+var _ pkgadapter.Adapter = (*Poller)(nil)
+
+// New returns a Poller which builds polling loops using the given JobBuilder.
+func New(logger *zap.SugaredLogger, ceClient cloudevents.Client,
+	build JobBuilder, config ConfigFunc, statusPatcher *status.Patcher) *Poller {
+
+	return &Poller{
+		logger: logger,
+
+		ceClient: ceClient,
+
+		build:  build,
+		config: config,
+
+		statusPatcher: statusPatcher,
+
+		notify: func(types.NamespacedName) {},
+
+		jobs: make(map[types.NamespacedName]*job),
+	}
+}
+
+// SetNotifyFunc sets the function which gets called with the key of a source
+// whenever the polling loop of that source stops or restarts.
+// It is meant to be set to the enqueueing function of the source's controller,
+// and must be set before the Poller starts polling.
+func (p *Poller) SetNotifyFunc(fn func(types.NamespacedName)) {
+	p.notify = fn
+}
+
+// Start implements adapter.Adapter.
+// It serves the health endpoint of the multi-tenant adapter, and stops all
+// polling loops upon termination.
+func (p *Poller) Start(ctx context.Context) error {
+	go health.Start(ctx)
+	health.MarkReady()
+
+	<-ctx.Done()
+
+	p.logger.Info("Stopping all pollers")
+	p.StopAll()
+
+	return nil
+}
+
+// job is the polling loop of a single source object.
+type job struct {
+	// properties of the source the job was built from
+	config interface{}
+	sink   string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu sync.Mutex
+	// reason the polling loop is not running, if any
+	err error
+}
+
+// stop stops the polling loop and waits for its termination.
+func (j *job) stop() {
+	j.cancel()
+	<-j.done
+}
+
+// setErr records the reason why the polling loop is not running.
+func (j *job) setErr(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.err = err
+}
+
+// getErr returns the reason why the polling loop is not running, if any.
+func (j *job) getErr() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Run ensures the polling loop of the given source is running with the
+// source's current properties. It returns a *StartError if the polling loop
+// couldn't be started, or if it stopped unexpectedly.
+func (p *Poller) Run(ctx context.Context, src v1alpha1.EventSource) error {
+	key := keyOf(src)
+	cfg := p.config(src)
+	sink := src.GetStatusManager().SinkURI.String()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if j, exists := p.jobs[key]; exists {
+		if j.sink == sink && equality.Semantic.DeepEqual(j.config, cfg) {
+			if err := j.getErr(); err != nil {
+				return &StartError{Reason: v1alpha1.ReasonPollerFailed, Err: err}
+			}
+			return nil
+		}
+
+		// the source was updated, its polling loop is restarted with
+		// the new properties
+		j.stop()
+		delete(p.jobs, key)
+	}
+
+	// events are sent to the sink of the source the job is built for
+	ceClient := &sinkClient{
+		Client: p.ceClient,
+		sink:   sink,
+	}
+
+	a, err := p.build(ctx, src, ceClient)
+	if err != nil {
+		return &StartError{Reason: v1alpha1.ReasonPollerNotStarted, Err: err}
+	}
+
+	jobCtx, cancel := context.WithCancel(context.Background())
+
+	j := &job{
+		config: cfg,
+		sink:   sink,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go p.runJob(jobCtx, key, j, a)
+
+	p.jobs[key] = j
+
+	return nil
+}
+
+// Stop stops the polling loop of the given source, if it is running.
+func (p *Poller) Stop(src v1alpha1.EventSource) {
+	key := keyOf(src)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if j, exists := p.jobs[key]; exists {
+		j.stop()
+		delete(p.jobs, key)
+	}
+}
+
+// StopAll stops all running polling loops.
+func (p *Poller) StopAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, j := range p.jobs {
+		j.stop()
+		delete(p.jobs, key)
+	}
+}
+
+// runJob runs the given polling loop until its context is cancelled. A loop
+// which stops unexpectedly, either by returning or by panicking, is restarted
+// after a delay without affecting the loops of other sources.
+func (p *Poller) runJob(ctx context.Context, key types.NamespacedName, j *job, a pkgadapter.Adapter) {
+	defer close(j.done)
+
+	logger := p.logger.With(zap.String("source", key.String()))
+
+	backoff := common.NewBackoff(minRestartDelay, maxRestartDelay)
+
+	for {
+		logger.Info("Starting poller")
+
+		err := runIsolated(ctx, a)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("poller returned unexpectedly")
+		}
+
+		delay := backoff.Duration()
+
+		logger.Errorw("Poller stopped unexpectedly, restarting in "+delay.String(), zap.Error(err))
+		j.setErr(err)
+		p.notify(key)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		j.setErr(nil)
+		p.notify(key)
+	}
+}
+
+// runIsolated starts the given adapter and recovers from any panic that may
+// occur during its execution.
+func runIsolated(ctx context.Context, a pkgadapter.Adapter) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("poller panicked: %v", r)
+		}
+	}()
+
+	return a.Start(ctx)
+}
+
+// sinkClient is a CloudEvents client which sends all events to the same sink,
+// regardless of the target set in the context of each request.
+type sinkClient struct {
+	cloudevents.Client
+	sink string
+}
+
+// Send implements cloudevents.Client.
+func (c *sinkClient) Send(ctx context.Context, event cloudevents.Event) protocol.Result {
+	return c.Client.Send(cloudevents.ContextWithTarget(ctx, c.sink), event)
+}
+
+// Request implements cloudevents.Client.
+func (c *sinkClient) Request(ctx context.Context, event cloudevents.Event) (*cloudevents.Event, protocol.Result) {
+	return c.Client.Request(cloudevents.ContextWithTarget(ctx, c.sink), event)
+}
+
+// keyOf returns the key of the given source object.
+func keyOf(src v1alpha1.EventSource) types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: src.GetNamespace(),
+		Name:      src.GetName(),
+	}
+}
+
+// StartError is returned when the polling loop of a source is not running.
+type StartError struct {
+	// reason to set on the PollerStarted condition of the source
+	Reason string
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *StartError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap allows StartError to be unwrapped by errors.Unwrap.
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,442 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakek8sclient "k8s.io/client-go/kubernetes/fake"
+
+	pkgadapter "knative.dev/eventing/pkg/adapter/v2"
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/reconciler"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	fakeclientset "github.com/triggermesh/aws-event-sources/pkg/client/generated/clientset/internalclientset/fake"
+)
+
+const (
+	tNs   = "testns"
+	tName = "test"
+
+	tComponent = "test-adapter"
+)
+
+var tSinkURI = &apis.URL{
+	Scheme: "http",
+	Host:   "default.default.svc.example.com",
+	Path:   "/",
+}
+
+func TestPollerLifecycle(t *testing.T) {
+	jobs := make(chan *fakeJob, 1)
+	p := newTestPoller(t, fakeJobBuilder(jobs, false), nil)
+
+	src := newEventSource()
+
+	require.NoError(t, p.Run(context.Background(), src))
+	j1 := <-jobs
+	waitStarted(t, j1)
+
+	// unchanged source, the running job is kept
+
+	require.NoError(t, p.Run(context.Background(), src))
+	assert.Len(t, jobs, 0, "Expected no new job to be built")
+	assert.False(t, j1.isStopped(), "Expected the job to be running")
+
+	// updated source, the job is replaced
+
+	src.Generation++
+	src.Spec.Credentials.AccessKeyID.Value = "new-key"
+
+	require.NoError(t, p.Run(context.Background(), src))
+	j2 := <-jobs
+	waitStarted(t, j2)
+	assert.True(t, j1.isStopped(), "Expected the previous job to be stopped")
+
+	// new sink, the job is replaced
+
+	src.Status.SinkURI = &apis.URL{Scheme: "http", Host: "new.example.com"}
+
+	require.NoError(t, p.Run(context.Background(), src))
+	j3 := <-jobs
+	waitStarted(t, j3)
+	assert.True(t, j2.isStopped(), "Expected the previous job to be stopped")
+
+	// deleted source
+
+	p.Stop(src)
+	assert.True(t, j3.isStopped(), "Expected the job to be stopped")
+	assert.Empty(t, p.jobs)
+}
+
+func TestPollerSendsToSink(t *testing.T) {
+	jobs := make(chan *fakeJob, 1)
+	p := newTestPoller(t, fakeJobBuilder(jobs, false), nil)
+
+	require.NoError(t, p.Run(context.Background(), newEventSource()))
+	j := <-jobs
+	defer p.StopAll()
+
+	ceClient := p.ceClient.(*adaptertest.TestCloudEventsClient)
+
+	event := cloudevents.NewEvent()
+	event.SetID("0")
+	event.SetType("test.type")
+	event.SetSource("test.source")
+
+	require.True(t, cloudevents.IsACK(j.ceClient.Send(context.Background(), event)))
+
+	sent := ceClient.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "test.source", sent[0].Source())
+	assert.Equal(t, tSinkURI.String(), j.ceClient.(*sinkClient).sink)
+}
+
+func TestPollerIsolatesFailures(t *testing.T) {
+	notifications := make(chan types.NamespacedName, 1)
+
+	failingJobs := make(chan *fakeJob, 1)
+	pFailing := newTestPoller(t, fakeJobBuilder(failingJobs, true), notifications)
+
+	src := newEventSource()
+
+	require.NoError(t, pFailing.Run(context.Background(), src))
+	<-failingJobs
+	defer pFailing.StopAll()
+
+	select {
+	case key := <-notifications:
+		assert.Equal(t, types.NamespacedName{Namespace: tNs, Name: tName}, key)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for notification of the job failure")
+	}
+
+	err := pFailing.Run(context.Background(), src)
+	startErr := (*StartError)(nil)
+	require.True(t, errors.As(err, &startErr), "Expected a StartError")
+	assert.Equal(t, v1alpha1.ReasonPollerFailed, startErr.Reason)
+	assert.EqualError(t, err, "poller panicked: "+assert.AnError.Error())
+}
+
+func TestPollerBuildError(t *testing.T) {
+	p := newTestPoller(t, func(context.Context, v1alpha1.EventSource, cloudevents.Client) (pkgadapter.Adapter, error) {
+		return nil, assert.AnError
+	}, nil)
+
+	err := p.Run(context.Background(), newEventSource())
+
+	startErr := (*StartError)(nil)
+	require.True(t, errors.As(err, &startErr), "Expected a StartError")
+	assert.Equal(t, v1alpha1.ReasonPollerNotStarted, startErr.Reason)
+	assert.Empty(t, p.jobs)
+}
+
+func TestReconcileSource(t *testing.T) {
+	testCases := map[string]struct {
+		src           *v1alpha1.AWSCloudWatchLogsSource
+		build         JobBuilder
+		expectCond    *apis.Condition
+		expectNoPatch bool
+		expectErr     bool
+	}{
+		"Poller started": {
+			src:   newEventSource(),
+			build: fakeJobBuilder(make(chan *fakeJob, 1), false),
+			expectCond: &apis.Condition{
+				Type:     v1alpha1.ConditionPollerStarted,
+				Status:   corev1.ConditionTrue,
+				Severity: apis.ConditionSeverityInfo,
+			},
+		},
+		"Poller previously started": {
+			src:   newEventSource(pollerStarted),
+			build: fakeJobBuilder(make(chan *fakeJob, 1), false),
+			expectCond: &apis.Condition{
+				Type:     v1alpha1.ConditionPollerStarted,
+				Status:   corev1.ConditionTrue,
+				Severity: apis.ConditionSeverityInfo,
+			},
+			expectNoPatch: true,
+		},
+		"Poller can not be built": {
+			src: newEventSource(),
+			build: func(context.Context, v1alpha1.EventSource, cloudevents.Client) (pkgadapter.Adapter, error) {
+				return nil, assert.AnError
+			},
+			expectCond: &apis.Condition{
+				Type:     v1alpha1.ConditionPollerStarted,
+				Status:   corev1.ConditionFalse,
+				Severity: apis.ConditionSeverityInfo,
+				Reason:   v1alpha1.ReasonPollerNotStarted,
+				Message:  assert.AnError.Error(),
+			},
+			expectErr: true,
+		},
+		"Sink not ready": {
+			src:           newEventSource(noSink),
+			build:         fakeJobBuilder(make(chan *fakeJob, 1), false),
+			expectNoPatch: true,
+			expectErr:     true,
+		},
+		"Source not multi-tenant": {
+			src:           newEventSource(singleTenant),
+			build:         fakeJobBuilder(make(chan *fakeJob, 1), false),
+			expectNoPatch: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			cs := fakeclientset.NewSimpleClientset(tc.src)
+			srcCli := cs.SourcesV1alpha1().AWSCloudWatchLogsSources(tNs)
+
+			p := newTestPoller(t, tc.build, nil)
+			defer p.StopAll()
+
+			p.statusPatcher = status.NewPatcher(tComponent, cs.SourcesV1alpha1())
+
+			err := p.ReconcileSource(context.Background(), tc.src)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tc.expectNoPatch {
+				for _, a := range cs.Actions() {
+					assert.NotEqual(t, "patch", a.GetVerb(), "Unexpected patch")
+				}
+			}
+
+			if tc.expectCond == nil {
+				return
+			}
+
+			src, err := srcCli.Get(context.Background(), tName, metav1.GetOptions{})
+			require.NoError(t, err)
+
+			cond := src.Status.GetCondition(v1alpha1.ConditionPollerStarted)
+			require.NotNil(t, cond, "Expected a PollerStarted condition")
+			cond.LastTransitionTime = apis.VolatileTime{}
+			assert.Equal(t, tc.expectCond, cond)
+			assert.NotNil(t, src.Status.GetCondition(v1alpha1.ConditionReady),
+				"Expected other conditions to be preserved")
+		})
+	}
+}
+
+func TestSinkNotReadyIsPermanent(t *testing.T) {
+	p := newTestPoller(t, fakeJobBuilder(make(chan *fakeJob, 1), false), nil)
+
+	err := p.ReconcileSource(context.Background(), newEventSource(noSink))
+	assert.True(t, controller.IsPermanentError(err), "Expected a permanent error")
+}
+
+func TestWithCredentials(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: tNs,
+			Name:      "creds",
+		},
+		Data: map[string][]byte{
+			"keyId":  []byte("key"),
+			"secret": []byte("secret"),
+		},
+	}
+
+	secrCli := fakek8sclient.NewSimpleClientset(secret).CoreV1().Secrets(tNs)
+
+	config := WithCredentials(
+		func(src v1alpha1.EventSource) interface{} {
+			return src.(*v1alpha1.AWSCloudWatchLogsSource).Spec
+		},
+		secrCli,
+		func(src v1alpha1.EventSource) *v1alpha1.AWSSecurityCredentials {
+			return &src.(*v1alpha1.AWSCloudWatchLogsSource).Spec.Credentials
+		},
+	)
+
+	src := newEventSource()
+	src.Spec.Credentials = v1alpha1.AWSSecurityCredentials{
+		AccessKeyID: v1alpha1.ValueFromField{
+			ValueFromSecret: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "creds"},
+				Key:                  "keyId",
+			},
+		},
+		SecretAccessKey: v1alpha1.ValueFromField{
+			ValueFromSecret: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "creds"},
+				Key:                  "secret",
+			},
+		},
+	}
+
+	cfg := config(src)
+	assert.Equal(t, cfg, config(src), "Expected identical configs for identical credentials")
+
+	secret.Data["secret"] = []byte("rotated-secret")
+	_, err := secrCli.Update(context.Background(), secret, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	assert.NotEqual(t, cfg, config(src), "Expected configs to differ after a rotation of credentials")
+}
+
+// newTestPoller returns a Poller for tests, which sends the keys of the
+// sources it notifies about to the given channel, if not nil.
+func newTestPoller(t *testing.T, build JobBuilder, notifications chan<- types.NamespacedName) *Poller {
+	p := New(logtesting.TestLogger(t), adaptertest.NewTestClient(), build,
+		func(src v1alpha1.EventSource) interface{} {
+			return src.(*v1alpha1.AWSCloudWatchLogsSource).Spec
+		},
+		status.NewPatcher(tComponent, fakeclientset.NewSimpleClientset().SourcesV1alpha1()),
+	)
+
+	if notifications != nil {
+		p.SetNotifyFunc(func(key types.NamespacedName) {
+			select {
+			case notifications <- key:
+			default:
+			}
+		})
+	}
+
+	return p
+}
+
+/* Event sources */
+
+// sourceOption is a functional option for an event source.
+type sourceOption func(*v1alpha1.AWSCloudWatchLogsSource)
+
+// newEventSource returns a test source object with pre-filled attributes.
+func newEventSource(opts ...sourceOption) *v1alpha1.AWSCloudWatchLogsSource {
+	src := &v1alpha1.AWSCloudWatchLogsSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  tNs,
+			Name:       tName,
+			Generation: 1,
+			Annotations: map[string]string{
+				v1alpha1.MultiTenantAnnotation: "true",
+			},
+		},
+	}
+
+	src.Status.SinkURI = tSinkURI
+
+	// *reconcilerImpl.Reconcile calls this method before any reconciliation loop. Calling it here ensures that the
+	// object is initialized in the same manner, and prevents tests from wrongly reporting unexpected status updates.
+	reconciler.PreProcessReconcile(context.Background(), src)
+
+	for _, opt := range opts {
+		opt(src)
+	}
+
+	return src
+}
+
+// pollerStarted sets the PollerStarted status condition.
+func pollerStarted(src *v1alpha1.AWSCloudWatchLogsSource) {
+	stMan := src.GetStatusManager()
+	stMan.Manage(stMan).MarkTrue(v1alpha1.ConditionPollerStarted)
+}
+
+// noSink ensures the sink URI is absent from the source's status.
+func noSink(src *v1alpha1.AWSCloudWatchLogsSource) {
+	src.Status.SinkURI = nil
+}
+
+// singleTenant removes the multi-tenant annotation from the source.
+func singleTenant(src *v1alpha1.AWSCloudWatchLogsSource) {
+	delete(src.Annotations, v1alpha1.MultiTenantAnnotation)
+}
+
+/* Jobs */
+
+// fakeJobBuilder returns a JobBuilder which sends every job it builds to the
+// given channel. Jobs either run until stopped, or panic immediately.
+func fakeJobBuilder(jobs chan<- *fakeJob, panics bool) JobBuilder {
+	return func(_ context.Context, _ v1alpha1.EventSource, ceClient cloudevents.Client) (pkgadapter.Adapter, error) {
+		j := &fakeJob{
+			ceClient: ceClient,
+			panics:   panics,
+			started:  make(chan struct{}),
+			stopped:  make(chan struct{}),
+		}
+		jobs <- j
+		return j, nil
+	}
+}
+
+// fakeJob is a pkgadapter.Adapter which records its lifecycle.
+type fakeJob struct {
+	ceClient cloudevents.Client
+	panics   bool
+
+	started chan struct{}
+	stopped chan struct{}
+}
+
+// Start implements pkgadapter.Adapter.
+func (j *fakeJob) Start(ctx context.Context) error {
+	if j.panics {
+		panic(assert.AnError)
+	}
+
+	close(j.started)
+	<-ctx.Done()
+	close(j.stopped)
+
+	return nil
+}
+
+func (j *fakeJob) isStopped() bool {
+	select {
+	case <-j.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitStarted waits until the given job is started.
+func waitStarted(t *testing.T, j *fakeJob) {
+	t.Helper()
+
+	select {
+	case <-j.started:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for job to start")
+	}
+}
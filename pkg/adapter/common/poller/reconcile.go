@@ -0,0 +1,90 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/pkg/apis"
+	pkgcontroller "knative.dev/pkg/controller"
+	"knative.dev/pkg/reconciler"
+
+	"github.com/triggermesh/aws-event-sources/pkg/adapter/common/status"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+// Reasons for API Events
+const (
+	ReasonSourceNotReady = "NotReady"
+	ReasonPollerStopped  = "PollerStopped"
+)
+
+// ReconcileSource ensures that the polling loop of the given source is running
+// if that source is served by the multi-tenant adapter, and propagates the
+// outcome to the source's status.
+func (p *Poller) ReconcileSource(ctx context.Context, src v1alpha1.EventSource) reconciler.Event {
+	if !v1alpha1.IsMultiTenant(src) {
+		p.Stop(src)
+		return nil
+	}
+
+	if src.GetStatusManager().SinkURI == nil {
+		// Mark that error as permanent so we don't retry until the
+		// source's status has been updated, which automatically
+		// triggers a new reconciliation.
+		return pkgcontroller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonSourceNotReady,
+			"Event sink URL wasn't resolved yet. Skipping poller configuration"))
+	}
+
+	cond := &apis.Condition{
+		Type:   v1alpha1.ConditionPollerStarted,
+		Status: corev1.ConditionTrue,
+	}
+
+	err := p.Run(ctx, src)
+	if startErr := (*StartError)(nil); errors.As(err, &startErr) {
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = startErr.Reason
+		cond.Message = startErr.Error()
+		err = fmt.Errorf("running poller: %w", err)
+	}
+
+	if err := status.PropagateCondition(ctx, p.statusPatcher, src, cond); err != nil {
+		return fmt.Errorf("propagating status condition: %w", err)
+	}
+
+	return err
+}
+
+// ObserveSource stops the polling loop of the given source. Only the leader
+// replica of the multi-tenant adapter polls on behalf of sources, otherwise
+// events would be sent once per replica.
+func (p *Poller) ObserveSource(ctx context.Context, src v1alpha1.EventSource) reconciler.Event {
+	p.Stop(src)
+	return nil
+}
+
+// FinalizeSource stops the polling loop of the given source, which is being
+// deleted.
+func (p *Poller) FinalizeSource(ctx context.Context, src v1alpha1.EventSource) reconciler.Event {
+	p.Stop(src)
+	return reconciler.NewEvent(corev1.EventTypeNormal, ReasonPollerStopped, "Poller stopped")
+}
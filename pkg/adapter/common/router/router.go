@@ -17,37 +17,134 @@ limitations under the License.
 package router
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 )
 
 // Router routes incoming HTTP requests to the adequate handler based on their
 // URL path.
+//
+// Handlers are registered either for an exact URL path (e.g. "/foo/bar"), or
+// for a subtree pattern terminated by a slash (e.g. "/foo/"), which matches all
+// URL paths that begin with it. Exact paths take precedence over patterns, and
+// longer patterns take precedence over shorter ones.
 type Router struct {
-	// map of URL path to HTTP handler
+	// map of URL path or pattern to HTTP handler
 	handlers sync.Map
 }
 
 // Check that Router implements http.Handler.
 var _ http.Handler = (*Router)(nil)
 
-// RegisterPath registers a HTTP handler for serving requests at the given URL path.
+// RegisterPath registers a HTTP handler for serving requests at the given URL
+// path or pattern.
 func (r *Router) RegisterPath(urlPath string, h http.Handler) {
 	r.handlers.Store(urlPath, h)
 }
 
-// DeregisterPath de-registers the HTTP handler for the given URL path.
+// DeregisterPath de-registers the HTTP handler for the given URL path or pattern.
 func (r *Router) DeregisterPath(urlPath string) {
 	r.handlers.Delete(urlPath)
 }
 
+// HasPath returns whether a HTTP handler is registered for the given URL path
+// or pattern.
+func (r *Router) HasPath(urlPath string) bool {
+	_, ok := r.handlers.Load(urlPath)
+	return ok
+}
+
 // ServeHTTP implements http.Handler.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	h, ok := r.handlers.Load(req.URL.Path)
-	if !ok {
+	h := r.match(req.URL.Path)
+	if h == nil {
 		http.Error(w, "No handler for path "+req.URL.Path, http.StatusNotFound)
 		return
 	}
 
-	h.(http.Handler).ServeHTTP(w, req)
+	h.ServeHTTP(w, req)
+}
+
+// match returns the HTTP handler which should serve requests at the given URL
+// path, or nil if no registered path or pattern matches.
+func (r *Router) match(urlPath string) http.Handler {
+	if h, ok := r.handlers.Load(urlPath); ok {
+		return h.(http.Handler)
+	}
+
+	var h http.Handler
+	var matchLen int
+
+	r.handlers.Range(func(key, val interface{}) bool {
+		pattern := key.(string)
+
+		if !strings.HasSuffix(pattern, "/") || !strings.HasPrefix(urlPath, pattern) {
+			return true
+		}
+
+		if len(pattern) > matchLen {
+			h = val.(http.Handler)
+			matchLen = len(pattern)
+		}
+		return true
+	})
+
+	return h
+}
+
+// Route describes a HTTP handler registered in a Router.
+type Route struct {
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+}
+
+// Routes returns the routes currently registered in the Router, sorted by URL
+// path.
+//
+// Handlers which implement fmt.Stringer are described by their String method,
+// other handlers by their Go type.
+func (r *Router) Routes() []Route {
+	var routes []Route
+
+	r.handlers.Range(func(key, val interface{}) bool {
+		var desc string
+		if s, ok := val.(fmt.Stringer); ok {
+			desc = s.String()
+		} else {
+			desc = fmt.Sprintf("%T", val)
+		}
+
+		routes = append(routes, Route{
+			Path:    key.(string),
+			Handler: desc,
+		})
+		return true
+	})
+
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Path < routes[j].Path
+	})
+
+	return routes
+}
+
+// RoutesHandler returns a HTTP handler which responds with the list of routes
+// currently registered in the Router, in JSON format. It is intended to be
+// used for debugging purposes.
+func (r *Router) RoutesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		routes := r.Routes()
+		if routes == nil {
+			routes = []Route{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(routes); err != nil {
+			http.Error(w, "Failed to encode routes: "+err.Error(), http.StatusInternalServerError)
+		}
+	})
 }
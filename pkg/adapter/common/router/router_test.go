@@ -17,6 +17,7 @@ limitations under the License.
 package router
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"sort"
@@ -66,6 +67,63 @@ func TestRouter(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, resp.Code)
 }
 
+func TestRouterPatterns(t *testing.T) {
+	r := &Router{}
+
+	r.RegisterPath("/foo/", responder("foo-subtree"))
+	r.RegisterPath("/foo/bar/", responder("foobar-subtree"))
+	r.RegisterPath("/foo/bar", responder("foobar"))
+
+	testCases := map[string]string{
+		"/foo/":          "foo-subtree",
+		"/foo/baz":       "foo-subtree",
+		"/foo/bar":       "foobar",
+		"/foo/bar/":      "foobar-subtree",
+		"/foo/bar/baz/x": "foobar-subtree",
+	}
+
+	for urlPath, expectHandler := range testCases {
+		resp := recordResponse(t, r, urlPath)
+		assert.Equal(t, expectHandler, resp.Header().Get(headerHandlerName), "Unexpected handler for "+urlPath)
+	}
+
+	// patterns only match paths within their subtree
+
+	resp := recordResponse(t, r, "/foo")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = recordResponse(t, r, "/foobar")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestRoutes(t *testing.T) {
+	r := &Router{}
+
+	// new router lists no route
+
+	resp := recordResponse(t, r.RoutesHandler(), "/")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, "[]", resp.Body.String())
+
+	r.RegisterPath("/foo", responder("foo"))
+	r.RegisterPath("/bar", namedResponder("bar"))
+
+	expectRoutes := []Route{
+		{Path: "/bar", Handler: "bar"},
+		{Path: "/foo", Handler: "http.HandlerFunc"},
+	}
+
+	assert.Equal(t, expectRoutes, r.Routes())
+
+	resp = recordResponse(t, r.RoutesHandler(), "/")
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+	var gotRoutes []Route
+	err := json.Unmarshal(resp.Body.Bytes(), &gotRoutes)
+	require.NoError(t, err)
+	assert.Equal(t, expectRoutes, gotRoutes)
+}
+
 // responder returns a HTTP handler that responds to requests with a header
 // containing the given handler's name.
 func responder(name string) http.Handler {
@@ -75,6 +133,19 @@ func responder(name string) http.Handler {
 	})
 }
 
+// namedResponder is a HTTP handler that describes itself with its name.
+type namedResponder string
+
+// ServeHTTP implements http.Handler.
+func (r namedResponder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	responder(string(r)).ServeHTTP(w, req)
+}
+
+// String implements fmt.Stringer.
+func (r namedResponder) String() string {
+	return string(r)
+}
+
 // handlersKeys returns the keys of all the handlers currently registered in
 // the given Router, sorted lexically.
 func handlersKeys(r *Router) []string {
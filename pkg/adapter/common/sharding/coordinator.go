@@ -0,0 +1,587 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	coordv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	coordclientv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+
+	"knative.dev/pkg/kmeta"
+)
+
+// DefaultLeaseDuration is the duration after which a Lease which wasn't
+// renewed by its holder is considered expired.
+// Callers of Sync should synchronize at a significantly shorter interval.
+const DefaultLeaseDuration = 45 * time.Second
+
+// Metadata of Lease objects.
+const (
+	groupLabel = "sharding.triggermesh.io/group"
+	roleLabel  = "sharding.triggermesh.io/role"
+
+	roleMember = "member"
+	roleShard  = "shard"
+
+	shardAnnotation      = "sharding.triggermesh.io/shard"
+	checkpointAnnotation = "sharding.triggermesh.io/checkpoint"
+	completedAnnotation  = "sharding.triggermesh.io/completed"
+	startedAnnotation    = "sharding.triggermesh.io/started-at"
+)
+
+// Coordinator coordinates the ownership of shards between the members of a
+// group of replicas.
+type Coordinator struct {
+	logger *zap.SugaredLogger
+
+	cli   coordclientv1.LeaseInterface
+	owner *metav1.OwnerReference
+
+	group         string
+	identity      string
+	leaseDuration time.Duration
+
+	// allows overriding the current time in tests
+	now func() time.Time
+
+	mu sync.Mutex
+	// shards currently held by the replica, indexed by shard ID
+	owned map[string]*heldShard
+	// shards which were processed until their end and must never be
+	// acquired again
+	completed map[string]struct{}
+}
+
+// heldShard is the state of a shard held by the replica.
+type heldShard struct {
+	checkpoint string
+	startedAt  time.Time
+	handedOver bool
+	completed  bool
+	revoked    bool
+}
+
+// Assignment is the outcome of a synchronization of shards ownership.
+type Assignment struct {
+	// Shards owned by the replica. Records of these shards should be
+	// processed, starting after the shard's LastCheckpoint, or from the
+	// time the shard StartedAt if it WasHandedOver without a checkpoint.
+	Owned []string
+	// Shards which were previously owned by the replica but are now
+	// assigned to another replica. Processing of these shards should be
+	// stopped, then the shards released using Release.
+	Revoked []string
+}
+
+// NewCoordinator returns a Coordinator for the replica with the given identity
+// inside the given group. The Leases are created with the given owner
+// (optional) to allow their garbage collection.
+func NewCoordinator(logger *zap.SugaredLogger, cli coordclientv1.LeaseInterface, owner *metav1.OwnerReference,
+	group, identity string, leaseDuration time.Duration) *Coordinator {
+
+	return &Coordinator{
+		logger: logger,
+
+		cli:   cli,
+		owner: owner,
+
+		group:         group,
+		identity:      identity,
+		leaseDuration: leaseDuration,
+
+		now: time.Now,
+
+		owned:     make(map[string]*heldShard),
+		completed: make(map[string]struct{}),
+	}
+}
+
+// Sync renews the membership of the replica, then acquires, renews or revokes
+// the ownership of the given shards depending on the current set of live
+// members.
+func (c *Coordinator) Sync(ctx context.Context, shardIDs []string) (*Assignment, error) {
+	if err := c.renewMembership(ctx); err != nil {
+		return nil, fmt.Errorf("renewing membership: %w", err)
+	}
+
+	members, err := c.liveMembers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing members: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	assignment := &Assignment{}
+
+	current := make(map[string]struct{}, len(shardIDs))
+
+	for _, shardID := range shardIDs {
+		current[shardID] = struct{}{}
+
+		held, isHeld := c.owned[shardID]
+
+		if ownerOf(shardID, members) != c.identity {
+			if isHeld {
+				held.revoked = true
+				assignment.Revoked = append(assignment.Revoked, shardID)
+			}
+			continue
+		}
+
+		switch {
+		case isHeld && held.revoked:
+			// the shard can only be acquired again once it has
+			// been released by its previous processor
+			assignment.Revoked = append(assignment.Revoked, shardID)
+
+		case isHeld:
+			if err := c.renewShard(ctx, shardID, held); err != nil {
+				c.logger.Warnw("Lost ownership of shard "+shardID, zap.Error(err))
+				delete(c.owned, shardID)
+				assignment.Revoked = append(assignment.Revoked, shardID)
+				continue
+			}
+			assignment.Owned = append(assignment.Owned, shardID)
+
+		default:
+			if _, isCompleted := c.completed[shardID]; isCompleted {
+				continue
+			}
+
+			acquired, err := c.acquireShard(ctx, shardID)
+			if err != nil {
+				c.logger.Errorw("Failed to acquire shard "+shardID, zap.Error(err))
+				continue
+			}
+			if acquired {
+				assignment.Owned = append(assignment.Owned, shardID)
+			}
+		}
+	}
+
+	// shards which disappeared from the stream
+	for shardID, held := range c.owned {
+		if _, exists := current[shardID]; !exists {
+			held.revoked = true
+			assignment.Revoked = append(assignment.Revoked, shardID)
+		}
+	}
+	for shardID := range c.completed {
+		if _, exists := current[shardID]; !exists {
+			delete(c.completed, shardID)
+		}
+	}
+
+	sort.Strings(assignment.Owned)
+	sort.Strings(assignment.Revoked)
+
+	return assignment, nil
+}
+
+// LastCheckpoint returns the last checkpoint recorded for the given shard,
+// or an empty string if the shard was never checkpointed.
+func (c *Coordinator) LastCheckpoint(shardID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if held, isHeld := c.owned[shardID]; isHeld {
+		return held.checkpoint
+	}
+	return ""
+}
+
+// WasHandedOver returns whether the given shard was previously held by
+// another replica. Such shard may contain records which were never processed
+// if it has no checkpoint.
+func (c *Coordinator) WasHandedOver(shardID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if held, isHeld := c.owned[shardID]; isHeld {
+		return held.handedOver
+	}
+	return false
+}
+
+// StartedAt returns the time at which the given shard was acquired for the
+// first time, by any replica. The records of a shard which WasHandedOver
+// without a checkpoint should be processed from that time on, because the
+// previous owner started reading the newest records of the shard at that
+// time.
+func (c *Coordinator) StartedAt(shardID string) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if held, isHeld := c.owned[shardID]; isHeld {
+		return held.startedAt
+	}
+	return time.Time{}
+}
+
+// SetCheckpoint records the position of the last processed record in the
+// given shard. The checkpoint is persisted upon the next call to Sync or
+// Release.
+func (c *Coordinator) SetCheckpoint(shardID, checkpoint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if held, isHeld := c.owned[shardID]; isHeld {
+		held.checkpoint = checkpoint
+	}
+}
+
+// SetCompleted records that all the records of the given shard were
+// processed, which is the case of closed shards. The shard is never acquired
+// again by any replica once it has been released.
+func (c *Coordinator) SetCompleted(shardID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if held, isHeld := c.owned[shardID]; isHeld {
+		held.completed = true
+	}
+}
+
+// Release persists the last checkpoint of the given shard and relinquishes
+// its ownership, so that another replica can take it over without waiting
+// for the expiration of its Lease.
+func (c *Coordinator) Release(ctx context.Context, shardID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	held, isHeld := c.owned[shardID]
+	if !isHeld {
+		return nil
+	}
+	delete(c.owned, shardID)
+
+	if held.completed {
+		c.completed[shardID] = struct{}{}
+	}
+
+	name := c.shardLeaseName(shardID)
+
+	l, err := c.cli.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf("getting Lease %q: %w", name, err)
+	}
+
+	// the Lease expired and was acquired by another replica, whose
+	// checkpoint must be preserved
+	if !isHeldBy(l, c.identity) {
+		return nil
+	}
+
+	l = l.DeepCopy()
+	setCheckpoint(l, held.checkpoint)
+	if held.completed {
+		metav1.SetMetaDataAnnotation(&l.ObjectMeta, completedAnnotation, "true")
+	}
+	l.Spec.HolderIdentity = nil
+
+	if _, err := c.cli.Update(ctx, l, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("updating Lease %q: %w", name, err)
+	}
+	return nil
+}
+
+// Leave deletes the membership Lease of the replica, which causes its shards
+// to be reassigned to the remaining members. Shards should be released before
+// leaving the group.
+func (c *Coordinator) Leave(ctx context.Context) error {
+	name := c.memberLeaseName()
+
+	err := c.cli.Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("deleting Lease %q: %w", name, err)
+	}
+	return nil
+}
+
+// renewMembership creates or renews the membership Lease of the replica.
+func (c *Coordinator) renewMembership(ctx context.Context) error {
+	name := c.memberLeaseName()
+
+	l, err := c.cli.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		l = c.newLease(name, roleMember)
+		if _, err := c.cli.Create(ctx, l, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("creating Lease %q: %w", name, err)
+		}
+		return nil
+
+	case err != nil:
+		return fmt.Errorf("getting Lease %q: %w", name, err)
+	}
+
+	l = l.DeepCopy()
+	c.renew(l)
+
+	if _, err := c.cli.Update(ctx, l, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("updating Lease %q: %w", name, err)
+	}
+	return nil
+}
+
+// liveMembers returns the sorted identities of all members of the group whose
+// membership didn't expire, including the current replica.
+func (c *Coordinator) liveMembers(ctx context.Context) ([]string, error) {
+	sel := labels.SelectorFromSet(labels.Set{
+		groupLabel: groupLabelValue(c.group),
+		roleLabel:  roleMember,
+	})
+
+	leases, err := c.cli.List(ctx, metav1.ListOptions{LabelSelector: sel.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	members := []string{c.identity}
+	for i := range leases.Items {
+		l := &leases.Items[i]
+		if holder := l.Spec.HolderIdentity; holder != nil && *holder != c.identity && !c.isExpired(l) {
+			members = append(members, *holder)
+		}
+	}
+
+	sort.Strings(members)
+
+	return members, nil
+}
+
+// acquireShard attempts to take the ownership of the given shard, and returns
+// whether it succeeded. Ownership can only be taken over if the shard's Lease
+// is free, or was not renewed by its holder in time, and if the shard wasn't
+// processed until its end.
+func (c *Coordinator) acquireShard(ctx context.Context, shardID string) (bool, error) {
+	name := c.shardLeaseName(shardID)
+
+	l, err := c.cli.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		l = c.newLease(name, roleShard)
+		startedAt := l.Spec.AcquireTime.Time
+		metav1.SetMetaDataAnnotation(&l.ObjectMeta, shardAnnotation, shardID)
+		metav1.SetMetaDataAnnotation(&l.ObjectMeta, startedAnnotation, startedAt.Format(time.RFC3339Nano))
+
+		if _, err := c.cli.Create(ctx, l, metav1.CreateOptions{}); err != nil {
+			return false, fmt.Errorf("creating Lease %q: %w", name, err)
+		}
+		c.owned[shardID] = &heldShard{
+			startedAt: startedAt,
+		}
+		return true, nil
+
+	case err != nil:
+		return false, fmt.Errorf("getting Lease %q: %w", name, err)
+	}
+
+	if l.Annotations[completedAnnotation] == "true" {
+		c.completed[shardID] = struct{}{}
+		return false, nil
+	}
+
+	if holder := l.Spec.HolderIdentity; holder != nil && *holder != c.identity && !c.isExpired(l) {
+		return false, nil
+	}
+
+	l = l.DeepCopy()
+	if !isHeldBy(l, c.identity) {
+		acquireTime := metav1.NewMicroTime(c.now())
+		l.Spec.AcquireTime = &acquireTime
+
+		var transitions int32
+		if t := l.Spec.LeaseTransitions; t != nil {
+			transitions = *t
+		}
+		transitions++
+		l.Spec.LeaseTransitions = &transitions
+	}
+	c.renew(l)
+
+	if _, err := c.cli.Update(ctx, l, metav1.UpdateOptions{}); err != nil {
+		return false, fmt.Errorf("updating Lease %q: %w", name, err)
+	}
+
+	c.owned[shardID] = &heldShard{
+		checkpoint: l.Annotations[checkpointAnnotation],
+		startedAt:  startedAt(l),
+		handedOver: true,
+	}
+
+	return true, nil
+}
+
+// renewShard renews the Lease of a shard held by the replica, and persists
+// the last checkpoint of that shard.
+func (c *Coordinator) renewShard(ctx context.Context, shardID string, held *heldShard) error {
+	name := c.shardLeaseName(shardID)
+
+	l, err := c.cli.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting Lease %q: %w", name, err)
+	}
+
+	if !isHeldBy(l, c.identity) {
+		return fmt.Errorf("lease %q is held by another member", name)
+	}
+
+	l = l.DeepCopy()
+	setCheckpoint(l, held.checkpoint)
+	c.renew(l)
+
+	if _, err := c.cli.Update(ctx, l, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("updating Lease %q: %w", name, err)
+	}
+	return nil
+}
+
+// newLease returns a Lease with the given name and role, held by the replica.
+func (c *Coordinator) newLease(name, role string) *coordv1.Lease {
+	l := &coordv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				groupLabel: groupLabelValue(c.group),
+				roleLabel:  role,
+			},
+		},
+	}
+
+	if c.owner != nil {
+		l.OwnerReferences = []metav1.OwnerReference{*c.owner}
+	}
+
+	acquireTime := metav1.NewMicroTime(c.now())
+	l.Spec.AcquireTime = &acquireTime
+
+	c.renew(l)
+
+	return l
+}
+
+// renew marks the given Lease as held and renewed by the replica.
+func (c *Coordinator) renew(l *coordv1.Lease) {
+	identity := c.identity
+	l.Spec.HolderIdentity = &identity
+
+	durationSec := int32(c.leaseDuration.Seconds())
+	l.Spec.LeaseDurationSeconds = &durationSec
+
+	renewTime := metav1.NewMicroTime(c.now())
+	l.Spec.RenewTime = &renewTime
+}
+
+// isExpired returns whether the given Lease wasn't renewed within its
+// duration.
+func (c *Coordinator) isExpired(l *coordv1.Lease) bool {
+	if l.Spec.RenewTime == nil || l.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	expiry := l.Spec.RenewTime.Add(time.Duration(*l.Spec.LeaseDurationSeconds) * time.Second)
+	return c.now().After(expiry)
+}
+
+// memberLeaseName returns the name of the replica's membership Lease.
+func (c *Coordinator) memberLeaseName() string {
+	return kmeta.ChildName(c.group+"-member-", c.identity)
+}
+
+// shardLeaseName returns the name of the Lease of the given shard.
+func (c *Coordinator) shardLeaseName(shardID string) string {
+	return kmeta.ChildName(c.group+"-", strings.ToLower(shardID))
+}
+
+// groupLabelValue returns a representation of the given group name which is
+// suitable for usage as a label value.
+func groupLabelValue(group string) string {
+	return kmeta.ChildName(group, "")
+}
+
+// isHeldBy returns whether the given Lease is held by the given identity.
+func isHeldBy(l *coordv1.Lease, identity string) bool {
+	return l.Spec.HolderIdentity != nil && *l.Spec.HolderIdentity == identity
+}
+
+// startedAt returns the time at which the shard of the given Lease was
+// acquired for the first time. Leases which were created without that
+// information fall back to their creation time.
+func startedAt(l *coordv1.Lease) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, l.Annotations[startedAnnotation]); err == nil {
+		return t
+	}
+	return l.CreationTimestamp.Time
+}
+
+// setCheckpoint records the given checkpoint in the annotations of a Lease.
+func setCheckpoint(l *coordv1.Lease, checkpoint string) {
+	if checkpoint != "" {
+		metav1.SetMetaDataAnnotation(&l.ObjectMeta, checkpointAnnotation, checkpoint)
+	}
+}
+
+// ownerOf returns the member which should own the given shard, using
+// rendezvous (highest random weight) hashing. Compared to a modulo-based
+// distribution, it only reassigns the shards of a member which joins or
+// leaves the group.
+func ownerOf(shardID string, members []string) string {
+	var owner string
+	var maxWeight uint64
+
+	for _, m := range members {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(m))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(shardID))
+
+		if w := mix64(h.Sum64()); owner == "" || w > maxWeight {
+			owner, maxWeight = m, w
+		}
+	}
+
+	return owner
+}
+
+// mix64 is the finalizer of MurmurHash3. It ensures that FNV hashes of inputs
+// which only differ by a few trailing bytes are uniformly distributed, which
+// is otherwise not the case in the high bits of the hash.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,352 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	coordclientv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+
+	logtesting "knative.dev/pkg/logging/testing"
+)
+
+const (
+	tNs    = "test-ns"
+	tGroup = "awskinesissource-test"
+)
+
+func TestOwnerOf(t *testing.T) {
+	shards := makeShardIDs(100)
+	members := []string{"replica-a", "replica-b", "replica-c"}
+
+	counts := make(map[string]int, len(members))
+	for _, s := range shards {
+		counts[ownerOf(s, members)]++
+	}
+	for _, m := range members {
+		assert.NotZero(t, counts[m], "Expected member %s to own at least one shard", m)
+	}
+
+	// a member leaving the group only causes its own shards to move
+	for _, s := range shards {
+		owner := ownerOf(s, members)
+		newOwner := ownerOf(s, members[:2])
+
+		if owner != "replica-c" {
+			assert.Equal(t, owner, newOwner, "Shard %s moved between remaining members", s)
+		}
+	}
+}
+
+func TestCoordinatorRebalance(t *testing.T) {
+	ctx := context.Background()
+
+	cli := fake.NewSimpleClientset().CoordinationV1().Leases(tNs)
+	clock := newFakeClock()
+
+	shards := makeShardIDs(8)
+
+	a := newTestCoordinator(t, cli, clock, "replica-a")
+
+	// a single replica owns all shards
+
+	asgmt, err := a.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Equal(t, shards, asgmt.Owned)
+	assert.Empty(t, asgmt.Revoked)
+
+	for _, s := range shards {
+		assert.Empty(t, a.LastCheckpoint(s), "Unexpected checkpoint for new shard")
+		a.SetCheckpoint(s, "seq-a-"+s)
+	}
+
+	// a second replica joins and can't steal shards that are still held
+
+	b := newTestCoordinator(t, cli, clock, "replica-b")
+
+	asgmt, err = b.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Empty(t, asgmt.Owned, "Shards were acquired before being released")
+
+	var expectA, expectB []string
+	for _, s := range shards {
+		switch ownerOf(s, []string{"replica-a", "replica-b"}) {
+		case "replica-a":
+			expectA = append(expectA, s)
+		case "replica-b":
+			expectB = append(expectB, s)
+		}
+	}
+	require.NotEmpty(t, expectA)
+	require.NotEmpty(t, expectB)
+
+	// the first replica observes the new member and gives up some shards
+
+	clock.Step(time.Second)
+
+	asgmt, err = a.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Equal(t, expectA, asgmt.Owned)
+	assert.Equal(t, expectB, asgmt.Revoked)
+
+	for _, s := range asgmt.Revoked {
+		require.NoError(t, a.Release(ctx, s))
+	}
+
+	// the second replica resumes from the checkpoints of the first one
+
+	asgmt, err = b.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Equal(t, expectB, asgmt.Owned)
+	assert.Empty(t, asgmt.Revoked)
+
+	for _, s := range expectB {
+		assert.Equal(t, "seq-a-"+s, b.LastCheckpoint(s))
+	}
+
+	// checkpoints of retained shards are persisted
+
+	for _, s := range expectA {
+		l, err := cli.Get(ctx, a.shardLeaseName(s), metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "seq-a-"+s, l.Annotations[checkpointAnnotation])
+	}
+}
+
+func TestCoordinatorTakeover(t *testing.T) {
+	ctx := context.Background()
+
+	cli := fake.NewSimpleClientset().CoordinationV1().Leases(tNs)
+	clock := newFakeClock()
+
+	shards := makeShardIDs(8)
+
+	a := newTestCoordinator(t, cli, clock, "replica-a")
+	b := newTestCoordinator(t, cli, clock, "replica-b")
+
+	// both members must be known to each other before shards get
+	// acquired, otherwise all shards go to the first replica
+	_, err := a.Sync(ctx, nil)
+	require.NoError(t, err)
+
+	asgmtB, err := b.Sync(ctx, shards)
+	require.NoError(t, err)
+	require.NotEmpty(t, asgmtB.Owned)
+
+	for _, s := range asgmtB.Owned {
+		b.SetCheckpoint(s, "seq-b-"+s)
+	}
+
+	// persist checkpoints
+	_, err = b.Sync(ctx, shards)
+	require.NoError(t, err)
+
+	asgmtA, err := a.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Len(t, asgmtA.Owned, len(shards)-len(asgmtB.Owned))
+
+	// the second replica stops renewing its Leases
+
+	clock.Step(DefaultLeaseDuration + time.Second)
+
+	asgmtA, err = a.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Equal(t, shards, asgmtA.Owned)
+
+	for _, s := range asgmtB.Owned {
+		assert.Equal(t, "seq-b-"+s, a.LastCheckpoint(s))
+	}
+
+	// the second replica comes back and finds out it lost its shards
+
+	asgmtB2, err := b.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Equal(t, asgmtB.Owned, asgmtB2.Revoked)
+	assert.Empty(t, asgmtB2.Owned)
+}
+
+func TestCoordinatorLeave(t *testing.T) {
+	ctx := context.Background()
+
+	cli := fake.NewSimpleClientset().CoordinationV1().Leases(tNs)
+	clock := newFakeClock()
+
+	shards := makeShardIDs(8)
+
+	a := newTestCoordinator(t, cli, clock, "replica-a")
+	b := newTestCoordinator(t, cli, clock, "replica-b")
+
+	_, err := a.Sync(ctx, nil)
+	require.NoError(t, err)
+
+	asgmtB, err := b.Sync(ctx, shards)
+	require.NoError(t, err)
+	require.NotEmpty(t, asgmtB.Owned)
+
+	for _, s := range asgmtB.Owned {
+		require.NoError(t, b.Release(ctx, s))
+	}
+	require.NoError(t, b.Leave(ctx))
+
+	// shards are taken over immediately, without waiting for the
+	// expiration of any Lease
+
+	asgmtA, err := a.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Equal(t, shards, asgmtA.Owned)
+
+	_, err = cli.Get(ctx, b.memberLeaseName(), metav1.GetOptions{})
+	assert.Error(t, err, "Expected membership Lease to be deleted")
+}
+
+func TestCoordinatorHandOver(t *testing.T) {
+	ctx := context.Background()
+
+	cli := fake.NewSimpleClientset().CoordinationV1().Leases(tNs)
+	clock := newFakeClock()
+
+	shards := makeShardIDs(1)
+
+	a := newTestCoordinator(t, cli, clock, "replica-a")
+	b := newTestCoordinator(t, cli, clock, "replica-b")
+
+	asgmtA, err := a.Sync(ctx, shards)
+	require.NoError(t, err)
+	require.Equal(t, shards, asgmtA.Owned)
+	assert.False(t, a.WasHandedOver(shards[0]), "Shard was never held before")
+
+	startedAt := clock.Now()
+	assert.Equal(t, startedAt, a.StartedAt(shards[0]))
+
+	// the first replica leaves before checkpointing any record
+
+	clock.Step(time.Minute)
+
+	require.NoError(t, a.Release(ctx, shards[0]))
+	require.NoError(t, a.Leave(ctx))
+
+	asgmtB, err := b.Sync(ctx, shards)
+	require.NoError(t, err)
+	require.Equal(t, shards, asgmtB.Owned)
+	assert.True(t, b.WasHandedOver(shards[0]), "Shard was previously held by another replica")
+	assert.Empty(t, b.LastCheckpoint(shards[0]))
+
+	// the shard is read from the time it was first acquired
+	assert.True(t, startedAt.Equal(b.StartedAt(shards[0])),
+		"Expected start time %s, got %s", startedAt, b.StartedAt(shards[0]))
+}
+
+func TestCoordinatorCompleted(t *testing.T) {
+	ctx := context.Background()
+
+	cli := fake.NewSimpleClientset().CoordinationV1().Leases(tNs)
+	clock := newFakeClock()
+
+	shards := makeShardIDs(2)
+	closedShard := shards[0]
+
+	a := newTestCoordinator(t, cli, clock, "replica-a")
+	b := newTestCoordinator(t, cli, clock, "replica-b")
+
+	asgmtA, err := a.Sync(ctx, shards)
+	require.NoError(t, err)
+	require.Equal(t, shards, asgmtA.Owned)
+
+	a.SetCompleted(closedShard)
+	require.NoError(t, a.Release(ctx, closedShard))
+
+	// a completed shard is neither acquired again by the replica which
+	// processed it...
+
+	asgmtA, err = a.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Equal(t, shards[1:], asgmtA.Owned)
+
+	l, err := cli.Get(ctx, a.shardLeaseName(closedShard), metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "true", l.Annotations[completedAnnotation])
+
+	// ...nor by any other replica
+
+	for _, s := range asgmtA.Owned {
+		require.NoError(t, a.Release(ctx, s))
+	}
+	require.NoError(t, a.Leave(ctx))
+
+	asgmtB, err := b.Sync(ctx, shards)
+	require.NoError(t, err)
+	assert.Equal(t, shards[1:], asgmtB.Owned)
+
+	// completed shards are forgotten once they disappear from the stream
+
+	_, err = b.Sync(ctx, shards[1:])
+	require.NoError(t, err)
+	assert.Empty(t, b.completed)
+}
+
+// newTestCoordinator returns a Coordinator which uses the given clock.
+func newTestCoordinator(t *testing.T, cli coordclientv1.LeaseInterface, clock *fakeClock,
+	identity string) *Coordinator {
+
+	owner := &metav1.OwnerReference{
+		APIVersion: "test/v1",
+		Kind:       "Test",
+		Name:       "test",
+		UID:        "00000000-0000-0000-0000-000000000000",
+	}
+
+	c := NewCoordinator(logtesting.TestLogger(t), cli, owner, tGroup, identity, DefaultLeaseDuration)
+	c.now = clock.Now
+
+	return c
+}
+
+// makeShardIDs returns a sorted list of n shard IDs.
+func makeShardIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("shardId-%012d", i)
+	}
+	return ids
+}
+
+// fakeClock is a clock which only advances when told so.
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the current time of the clock.
+func (c *fakeClock) Now() time.Time {
+	return c.t
+}
+
+// Step advances the clock by the given duration.
+func (c *fakeClock) Step(d time.Duration) {
+	c.t = c.t.Add(d)
+}
@@ -0,0 +1,27 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sharding distributes the shards of a data stream across the
+// replicas of an adapter, using Kubernetes Lease objects for coordination.
+//
+// Each replica maintains a "member" Lease which signals its liveness. The
+// owner of each shard is determined using rendezvous hashing over the set of
+// live members, so that only a minimal number of shards move between replicas
+// whenever a replica joins or leaves the group. Ownership of a shard is
+// materialized by a "shard" Lease, which also carries the last checkpoint
+// recorded by its holder, allowing the next holder to resume reading the
+// shard where the previous one stopped.
+package sharding
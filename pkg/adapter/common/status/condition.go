@@ -29,8 +29,8 @@ import (
 
 // PropagateCondition propagates a status condition to the status of the given
 // source object using the provided Patcher.
-func PropagateCondition(ctx context.Context, p *Patcher, src *v1alpha1.AWSSNSSource, cond *apis.Condition) error {
-	srcCpy := shallowSourceCopy(src)
+func PropagateCondition(ctx context.Context, p *Patcher, src v1alpha1.EventSource, cond *apis.Condition) error {
+	srcCpy := src.DeepCopyObject().(v1alpha1.EventSource)
 	stMan := srcCpy.GetStatusManager()
 	condMan := stMan.Manage(stMan)
 
@@ -53,22 +53,12 @@ func PropagateCondition(ctx context.Context, p *Patcher, src *v1alpha1.AWSSNSSou
 		return nil
 	}
 
-	if _, err = p.Patch(ctx, src.Name, patch); err != nil {
+	if err = p.Patch(ctx, src, patch); err != nil {
 		return fmt.Errorf("applying JSON patch: %w", err)
 	}
 	return nil
 }
 
-// shallowSourceCopy returns a shallow copy of the provided source object, with
-// the exception of Status.Conditions which are deeply copied. This allows
-// applying modifications to those status conditions, then generating a JSON
-// patch by comparing the before/after states.
-func shallowSourceCopy(src *v1alpha1.AWSSNSSource) *v1alpha1.AWSSNSSource {
-	srcCpy := *src
-	srcCpy.Status.Conditions = src.Status.Conditions.DeepCopy()
-	return &srcCpy
-}
-
 // forceTransitionTime forces the value of the status condition of the given
 // type to the value returned by the provided Clock.
 // This helper exists because Knative's SetConditon method always uses
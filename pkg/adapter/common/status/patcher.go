@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis/duck"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	clientv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/clientset/internalclientset/typed/sources/v1alpha1"
+)
+
+// NewPatcher returns a named Patcher which applies patches using the provided
+// client interface.
+func NewPatcher(component string, cli clientv1alpha1.SourcesV1alpha1Interface) *Patcher {
+	return &Patcher{
+		component: component,
+		cli:       cli,
+	}
+}
+
+// Patcher can apply patches to the status of source objects.
+type Patcher struct {
+	component string
+	cli       clientv1alpha1.SourcesV1alpha1Interface
+}
+
+// Patch applies the given JSON patch to the status of the given source object.
+func (p *Patcher) Patch(ctx context.Context, src v1alpha1.EventSource, patch duck.JSONPatch) error {
+	jsonPatch, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("serializing JSON patch: %w", err)
+	}
+
+	ns, name := src.GetNamespace(), src.GetName()
+
+	opts := metav1.PatchOptions{
+		FieldManager: p.component,
+	}
+
+	const pt = types.JSONPatchType
+	const subres = "status"
+
+	switch src.(type) {
+	case *v1alpha1.AWSCloudWatchSource:
+		_, err = p.cli.AWSCloudWatchSources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSCloudWatchLogsSource:
+		_, err = p.cli.AWSCloudWatchLogsSources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSCodeCommitSource:
+		_, err = p.cli.AWSCodeCommitSources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSCognitoIdentitySource:
+		_, err = p.cli.AWSCognitoIdentitySources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSCognitoUserPoolSource:
+		_, err = p.cli.AWSCognitoUserPoolSources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSDynamoDBSource:
+		_, err = p.cli.AWSDynamoDBSources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSKinesisSource:
+		_, err = p.cli.AWSKinesisSources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSPerformanceInsightsSource:
+		_, err = p.cli.AWSPerformanceInsightsSources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSS3Source:
+		_, err = p.cli.AWSS3Sources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSSNSSource:
+		_, err = p.cli.AWSSNSSources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	case *v1alpha1.AWSSQSSource:
+		_, err = p.cli.AWSSQSSources(ns).Patch(ctx, name, pt, jsonPatch, opts, subres)
+	default:
+		return fmt.Errorf("unsupported source type %T", src)
+	}
+
+	return err
+}
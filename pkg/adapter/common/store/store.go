@@ -0,0 +1,328 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package store allows adapters to persist their state across restarts.
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"knative.dev/pkg/kmeta"
+)
+
+// Store persists arbitrary values identified by a key.
+type Store interface {
+	// Load decodes the value stored under the given key into v, and
+	// returns whether such value was found.
+	Load(ctx context.Context, key string, v interface{}) (bool, error)
+	// Save stores the given value under the given key.
+	Save(ctx context.Context, key string, v interface{}) error
+}
+
+// ConfigMapStore is a Store which persists values, serialized to JSON, in the
+// data of a Kubernetes ConfigMap.
+//
+// The size of a ConfigMap is capped at 1 MiB, so values larger than
+// maxValueSize are split into chunks stored in ConfigMaps of their own. The
+// main ConfigMap then only references those chunks, and is updated after all
+// chunks were written, so that a value is never observed partially written.
+type ConfigMapStore struct {
+	cli   coreclientv1.ConfigMapInterface
+	name  string
+	owner *metav1.OwnerReference
+}
+
+// maxValueSize is the size above which a serialized value is split into
+// chunks. It leaves room for the other keys and the metadata of a ConfigMap.
+const maxValueSize = 768 * 1024
+
+// chunksKeySuffix is appended to a key to form the key which references the
+// chunks of a value, in the format "<checksum>/<number of chunks>".
+const chunksKeySuffix = ".chunks"
+
+// chunkDataKey is the key under which a chunk is stored in the binary data of
+// its ConfigMap.
+const chunkDataKey = "chunk"
+
+// Verify that ConfigMapStore implements Store.
+var _ Store = (*ConfigMapStore)(nil)
+
+// NewConfigMapStore returns a ConfigMapStore backed by the ConfigMap with the
+// given name. The ConfigMap is created upon the first call to Save, with the
+// given owner (optional) to allow its garbage collection.
+func NewConfigMapStore(cli coreclientv1.ConfigMapInterface, name string,
+	owner *metav1.OwnerReference) *ConfigMapStore {
+
+	return &ConfigMapStore{
+		cli:   cli,
+		name:  name,
+		owner: owner,
+	}
+}
+
+// Load implements Store.
+func (s *ConfigMapStore) Load(ctx context.Context, key string, v interface{}) (bool, error) {
+	cm, err := s.cli.Get(ctx, s.name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("getting ConfigMap %q: %w", s.name, err)
+	}
+
+	var data []byte
+
+	if ref, isChunked := cm.Data[key+chunksKeySuffix]; isChunked {
+		if data, err = s.loadChunks(ctx, key, ref); err != nil {
+			return false, err
+		}
+	} else {
+		val, exists := cm.Data[key]
+		if !exists {
+			return false, nil
+		}
+		data = []byte(val)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("deserializing value of key %q: %w", key, err)
+	}
+
+	return true, nil
+}
+
+// Save implements Store.
+func (s *ConfigMapStore) Save(ctx context.Context, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("serializing value of key %q: %w", key, err)
+	}
+
+	cm, err := s.cli.Get(ctx, s.name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		cm = nil
+	case err != nil:
+		return fmt.Errorf("getting ConfigMap %q: %w", s.name, err)
+	}
+
+	var prevRef string
+	if cm != nil {
+		prevRef = cm.Data[key+chunksKeySuffix]
+	}
+
+	var val, ref string
+	if len(data) > maxValueSize {
+		ref = chunksRef(data)
+		if ref != prevRef {
+			if err := s.saveChunks(ctx, key, ref, data); err != nil {
+				return err
+			}
+		}
+	} else {
+		val = string(data)
+	}
+
+	if cm == nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: s.name,
+			},
+			Data: makeValueData(key, val, ref),
+		}
+		if s.owner != nil {
+			cm.OwnerReferences = []metav1.OwnerReference{*s.owner}
+		}
+
+		if _, err := s.cli.Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("creating ConfigMap %q: %w", s.name, err)
+		}
+		return nil
+	}
+
+	if cm.Data[key] != val || prevRef != ref {
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = make(map[string]string, 2)
+		}
+		delete(cm.Data, key+chunksKeySuffix)
+		for k, v := range makeValueData(key, val, ref) {
+			cm.Data[k] = v
+		}
+
+		if _, err := s.cli.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("updating ConfigMap %q: %w", s.name, err)
+		}
+	}
+
+	// chunks of the previous value are no longer referenced
+	if prevRef != "" && prevRef != ref {
+		if err := s.deleteChunks(ctx, key, prevRef); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// makeValueData returns the data entries of the main ConfigMap which contain
+// either the given value, or the given reference to the chunks of that value.
+func makeValueData(key, val, ref string) map[string]string {
+	if ref != "" {
+		return map[string]string{
+			key:                   "",
+			key + chunksKeySuffix: ref,
+		}
+	}
+	return map[string]string{
+		key: val,
+	}
+}
+
+// saveChunks writes the given data to chunk ConfigMaps.
+func (s *ConfigMapStore) saveChunks(ctx context.Context, key, ref string, data []byte) error {
+	for i := 0; len(data) > 0; i++ {
+		n := maxValueSize
+		if len(data) < n {
+			n = len(data)
+		}
+
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: s.chunkName(key, ref, i),
+			},
+			BinaryData: map[string][]byte{
+				chunkDataKey: data[:n],
+			},
+		}
+		if s.owner != nil {
+			cm.OwnerReferences = []metav1.OwnerReference{*s.owner}
+		}
+
+		_, err := s.cli.Create(ctx, cm, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			// leftover of an interrupted write of the same value
+			_, err = s.cli.Update(ctx, cm, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf("writing ConfigMap %q: %w", cm.Name, err)
+		}
+
+		data = data[n:]
+	}
+
+	return nil
+}
+
+// loadChunks returns the data of the chunks referenced by ref.
+func (s *ConfigMapStore) loadChunks(ctx context.Context, key, ref string) ([]byte, error) {
+	numChunks, err := parseChunksRef(ref)
+	if err != nil {
+		return nil, fmt.Errorf("reading chunks reference of key %q: %w", key, err)
+	}
+
+	var data []byte
+
+	for i := 0; i < numChunks; i++ {
+		name := s.chunkName(key, ref, i)
+
+		cm, err := s.cli.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting ConfigMap %q: %w", name, err)
+		}
+
+		data = append(data, cm.BinaryData[chunkDataKey]...)
+	}
+
+	return data, nil
+}
+
+// deleteChunks deletes the chunk ConfigMaps referenced by ref.
+func (s *ConfigMapStore) deleteChunks(ctx context.Context, key, ref string) error {
+	numChunks, err := parseChunksRef(ref)
+	if err != nil {
+		return fmt.Errorf("reading chunks reference of key %q: %w", key, err)
+	}
+
+	for i := 0; i < numChunks; i++ {
+		name := s.chunkName(key, ref, i)
+
+		err := s.cli.Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting ConfigMap %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// chunkName returns the name of the ConfigMap which stores the i-th chunk of
+// the value referenced by ref. Names are derived from the checksum of the
+// value, so that the chunks of a new value never overwrite the chunks of the
+// value which is currently referenced.
+func (s *ConfigMapStore) chunkName(key, ref string, i int) string {
+	checksum := ref[:strings.IndexByte(ref, '/')]
+	return kmeta.ChildName(s.name, "-"+key+"-"+checksum+"-"+strconv.Itoa(i))
+}
+
+// chunksRef returns a reference to the chunks of the given data.
+func chunksRef(data []byte) string {
+	sum := sha256.Sum256(data)
+	numChunks := (len(data) + maxValueSize - 1) / maxValueSize
+
+	return hex.EncodeToString(sum[:8]) + "/" + strconv.Itoa(numChunks)
+}
+
+// parseChunksRef returns the number of chunks referenced by ref.
+func parseChunksRef(ref string) (int, error) {
+	i := strings.IndexByte(ref, '/')
+	if i < 0 {
+		return 0, fmt.Errorf("invalid format %q", ref)
+	}
+
+	n, err := strconv.Atoi(ref[i+1:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid number of chunks in %q: %w", ref, err)
+	}
+
+	return n, nil
+}
+
+// OwnerReference returns a reference to the given object, suitable for the
+// garbage collection of a ConfigMapStore's data together with this object.
+// Unlike a controller reference, it doesn't block the deletion of its owner.
+func OwnerReference(obj kmeta.OwnerRefable) *metav1.OwnerReference {
+	gvk := obj.GetGroupVersionKind()
+	meta := obj.GetObjectMeta()
+
+	return &metav1.OwnerReference{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Name:       meta.GetName(),
+		UID:        meta.GetUID(),
+	}
+}
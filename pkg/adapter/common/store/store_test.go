@@ -0,0 +1,162 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapStore(t *testing.T) {
+	const (
+		tNs   = "test-ns"
+		tName = "test-state"
+	)
+
+	type value struct {
+		Foo string `json:"foo"`
+		Bar int    `json:"bar"`
+	}
+
+	ctx := context.Background()
+
+	cmCli := fake.NewSimpleClientset().CoreV1().ConfigMaps(tNs)
+
+	owner := &metav1.OwnerReference{
+		APIVersion: "test/v1",
+		Kind:       "Test",
+		Name:       "test",
+		UID:        "00000000-0000-0000-0000-000000000000",
+	}
+
+	s := NewConfigMapStore(cmCli, tName, owner)
+
+	var v value
+
+	found, err := s.Load(ctx, "key1", &v)
+	require.NoError(t, err)
+	assert.False(t, found, "Expected no value before the ConfigMap exists")
+
+	// first write creates the ConfigMap
+
+	err = s.Save(ctx, "key1", value{Foo: "a", Bar: 1})
+	require.NoError(t, err)
+
+	cm, err := cmCli.Get(ctx, tName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []metav1.OwnerReference{*owner}, cm.OwnerReferences)
+	assert.Equal(t, `{"foo":"a","bar":1}`, cm.Data["key1"])
+
+	found, err = s.Load(ctx, "key2", &v)
+	require.NoError(t, err)
+	assert.False(t, found, "Expected no value for a missing key")
+
+	// subsequent writes update the ConfigMap
+
+	err = s.Save(ctx, "key2", value{Foo: "b", Bar: 2})
+	require.NoError(t, err)
+	err = s.Save(ctx, "key1", value{Foo: "c", Bar: 3})
+	require.NoError(t, err)
+
+	found, err = s.Load(ctx, "key1", &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, value{Foo: "c", Bar: 3}, v)
+
+	found, err = s.Load(ctx, "key2", &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, value{Foo: "b", Bar: 2}, v)
+}
+
+func TestConfigMapStoreLargeValue(t *testing.T) {
+	const (
+		tNs   = "test-ns"
+		tName = "test-state"
+	)
+
+	ctx := context.Background()
+
+	cmCli := fake.NewSimpleClientset().CoreV1().ConfigMaps(tNs)
+
+	s := NewConfigMapStore(cmCli, tName, nil)
+
+	// a value which doesn't fit in a single ConfigMap is split into chunks
+
+	large := strings.Repeat("a", 2*maxValueSize)
+
+	err := s.Save(ctx, "key1", large)
+	require.NoError(t, err)
+
+	cms, err := cmCli.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, cms.Items, 1+3, "Expected the value to be split into 3 chunks")
+
+	cm, err := cmCli.Get(ctx, tName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, cm.Data["key1"])
+	assert.NotEmpty(t, cm.Data["key1"+chunksKeySuffix])
+
+	var v string
+
+	found, err := s.Load(ctx, "key1", &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, large, v)
+
+	// chunks of a replaced value are deleted
+
+	largeUpdated := strings.Repeat("b", maxValueSize)
+
+	err = s.Save(ctx, "key1", largeUpdated)
+	require.NoError(t, err)
+
+	cms, err = cmCli.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, cms.Items, 1+2, "Expected the value to be split into 2 chunks")
+
+	found, err = s.Load(ctx, "key1", &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, largeUpdated, v)
+
+	// a value which fits in a single ConfigMap is stored inline again
+
+	err = s.Save(ctx, "key1", "small")
+	require.NoError(t, err)
+
+	cms, err = cmCli.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, cms.Items, 1, "Expected chunks to be deleted")
+
+	cm, err = cmCli.Get(ctx, tName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `"small"`, cm.Data["key1"])
+	assert.NotContains(t, cm.Data, "key1"+chunksKeySuffix)
+
+	found, err = s.Load(ctx, "key1", &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "small", v)
+}
@@ -75,3 +75,8 @@ func (l *Listers) GetSourcesObjects() []runtime.Object {
 func (l *Listers) GetAWSSNSSourceLister() listersv1alpha1.AWSSNSSourceLister {
 	return listersv1alpha1.NewAWSSNSSourceLister(l.IndexerFor(&v1alpha1.AWSSNSSource{}))
 }
+
+// GetAWSSQSSourceLister returns a Lister for AWSSQSSource objects.
+func (l *Listers) GetAWSSQSSourceLister() listersv1alpha1.AWSSQSSourceLister {
+	return listersv1alpha1.NewAWSSQSSourceLister(l.IndexerFor(&v1alpha1.AWSSQSSource{}))
+}
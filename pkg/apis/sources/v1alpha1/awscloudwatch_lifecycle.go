@@ -32,7 +32,7 @@ func (*AWSCloudWatchSource) GetGroupVersionKind() schema.GroupVersionKind {
 
 // GetConditionSet implements duckv1.KRShaped.
 func (*AWSCloudWatchSource) GetConditionSet() apis.ConditionSet {
-	return eventSourceConditionSet
+	return awsCloudWatchSourceConditionSet
 }
 
 // GetStatus implements duckv1.KRShaped.
@@ -49,14 +49,16 @@ func (s *AWSCloudWatchSource) GetSink() *duckv1.Destination {
 func (s *AWSCloudWatchSource) GetStatusManager() *EventSourceStatusManager {
 	return &EventSourceStatusManager{
 		ConditionSet:      s.GetConditionSet(),
-		EventSourceStatus: &s.Status,
+		EventSourceStatus: &s.Status.EventSourceStatus,
 	}
 }
 
 // Supported event types
 const (
-	AWSCloudWatchMetricEventType  = "metrics.metric"
-	AWSCloudWatchMessageEventType = "metrics.message"
+	AWSCloudWatchMetricEventType    = "metrics.metric"
+	AWSCloudWatchMessageEventType   = "metrics.message"
+	AWSCloudWatchDatapointEventType = "metrics.datapoint"
+	AWSCloudWatchAlarmEventType     = "alarms.state_change"
 )
 
 // Name of the CloudWatch service, as exposed in ARNs.
@@ -64,11 +66,28 @@ const (
 const ServiceCloudWatch = "cloudwatch"
 
 // GetEventTypes implements EventSource.
-func (*AWSCloudWatchSource) GetEventTypes() []string {
-	return []string{
-		AWSEventType(ServiceCloudWatch, AWSCloudWatchMetricEventType),
-		AWSEventType(ServiceCloudWatch, AWSCloudWatchMessageEventType),
+func (s *AWSCloudWatchSource) GetEventTypes() []string {
+	var types []string
+
+	if len(s.Spec.MetricQueries) > 0 {
+		metricEventType := AWSCloudWatchMetricEventType
+		if split := s.Spec.SplitDatapoints; split != nil && *split {
+			metricEventType = AWSCloudWatchDatapointEventType
+		}
+
+		types = append(types,
+			AWSEventType(ServiceCloudWatch, metricEventType),
+			AWSEventType(ServiceCloudWatch, AWSCloudWatchMessageEventType),
+		)
 	}
+
+	if s.Spec.Alarms != nil {
+		types = append(types,
+			AWSEventType(ServiceCloudWatch, AWSCloudWatchAlarmEventType),
+		)
+	}
+
+	return types
 }
 
 // AsEventSource implements EventSource.
@@ -76,9 +95,45 @@ func (s *AWSCloudWatchSource) AsEventSource() string {
 	return AWSCloudWatchSourceName(s.Namespace, s.Name)
 }
 
+// IsMultiTenant implements MultiTenant.
+func (s *AWSCloudWatchSource) IsMultiTenant() bool {
+	return optsIntoMultiTenancy(s)
+}
+
 // AWSCloudWatchSourceName returns a unique reference to the source suitable
 // for use as as a CloudEvent source.
 func AWSCloudWatchSourceName(ns, name string) string {
 	kind := strings.ToLower((*AWSCloudWatchSource)(nil).GetGroupVersionKind().Kind)
 	return "io.triggermesh." + kind + "." + ns + "." + name
 }
+
+// Status conditions
+const (
+	// AWSCloudWatchConditionSpecValid has status True when the source's spec
+	// passes validation.
+	AWSCloudWatchConditionSpecValid apis.ConditionType = "SpecValid"
+)
+
+// Reasons for status conditions
+const (
+	// AWSCloudWatchReasonInvalidSpec is set on a SpecValid condition when the source's spec fails validation.
+	AWSCloudWatchReasonInvalidSpec = "InvalidSpec"
+)
+
+// awsCloudWatchSourceConditionSet is a set of conditions for
+// AWSCloudWatchSource objects.
+var awsCloudWatchSourceConditionSet = NewEventSourceConditionSet(
+	AWSCloudWatchConditionSpecValid,
+)
+
+// MarkSpecValid sets the SpecValid condition to True.
+func (s *AWSCloudWatchSourceStatus) MarkSpecValid() {
+	awsCloudWatchSourceConditionSet.Manage(s).MarkTrue(AWSCloudWatchConditionSpecValid)
+}
+
+// MarkSpecInvalid sets the SpecValid condition to False with the given
+// message describing the validation failure.
+func (s *AWSCloudWatchSourceStatus) MarkSpecInvalid(msg string) {
+	awsCloudWatchSourceConditionSet.Manage(s).MarkFalse(AWSCloudWatchConditionSpecValid,
+		AWSCloudWatchReasonInvalidSpec, msg)
+}
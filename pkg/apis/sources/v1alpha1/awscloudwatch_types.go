@@ -34,14 +34,15 @@ type AWSCloudWatchSource struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   AWSCloudWatchSourceSpec `json:"spec,omitempty"`
-	Status EventSourceStatus       `json:"status,omitempty"`
+	Spec   AWSCloudWatchSourceSpec   `json:"spec,omitempty"`
+	Status AWSCloudWatchSourceStatus `json:"status,omitempty"`
 }
 
 // Check the interfaces the event source should be implementing.
 var (
 	_ runtime.Object = (*AWSCloudWatchSource)(nil)
 	_ EventSource    = (*AWSCloudWatchSource)(nil)
+	_ multiTenant    = (*AWSCloudWatchSource)(nil)
 )
 
 // AWSCloudWatchSourceSpec defines the desired state of the event source.
@@ -62,9 +63,29 @@ type AWSCloudWatchSourceSpec struct {
 	PollingInterval *apis.Duration `json:"pollingInterval,omitempty"`
 
 	// List of queries that determine what metrics will be sourced from Amazon CloudWatch.
+	// At most 500 of them can be metric or expression queries, which are
+	// all sent in a single request.
 	// +optional
 	MetricQueries []AWSCloudWatchMetricQuery `json:"metricQueries,omitempty"`
 
+	// Whether each datapoint returned by the metric queries should be sent as a separate event, instead
+	// of one event per query result.
+	// +optional
+	SplitDatapoints *bool `json:"splitDatapoints,omitempty"`
+
+	// Delay applied to the end of each polling window, to leave time for Amazon CloudWatch to aggregate
+	// late datapoints.
+	// Expressed as a duration string, which format is documented at https://pkg.go.dev/time#ParseDuration.
+	//
+	// Defaults to 0
+	//
+	// +optional
+	AggregationLag *apis.Duration `json:"aggregationLag,omitempty"`
+
+	// Selection of CloudWatch alarms whose state transitions are sourced from Amazon CloudWatch.
+	// +optional
+	Alarms *AWSCloudWatchAlarmSelector `json:"alarms,omitempty"`
+
 	// Credentials to interact with the Amazon CloudWatch API.
 	Credentials AWSSecurityCredentials `json:"credentials"`
 }
@@ -83,6 +104,10 @@ type AWSCloudWatchMetricQuery struct {
 	// Representation of a metric with statistics, period, and units, but no math expression.
 	// +optional
 	Metric *AWSCloudWatchMetricStat `json:"metric,omitempty"`
+	// Selection of metrics which are discovered periodically, each of them being queried with the same
+	// statistics, period and units.
+	// +optional
+	Discovery *AWSCloudWatchMetricDiscovery `json:"discovery,omitempty"`
 }
 
 type AWSCloudWatchMetricStat struct {
@@ -103,6 +128,49 @@ type AWSCloudWatchMetricDimension struct {
 	Value string `json:"value"`
 }
 
+// AWSCloudWatchMetricDiscovery selects metrics using the CloudWatch ListMetrics API.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_ListMetrics.html
+type AWSCloudWatchMetricDiscovery struct {
+	// Namespace of the metrics.
+	Namespace string `json:"namespace"`
+	// Name of the metrics. May contain the wildcards '*' and '?'.
+	// +optional
+	MetricName *string `json:"metricName,omitempty"`
+	// Dimensions the metrics must have.
+	// +optional
+	Dimensions []AWSCloudWatchMetricDimensionFilter `json:"dimensions,omitempty"`
+
+	Period int64  `json:"period"`         // metric resolution in seconds
+	Stat   string `json:"stat"`           // statistic type to use
+	Unit   string `json:"unit,omitempty"` // The unit of the metric being returned
+}
+
+// AWSCloudWatchMetricDimensionFilter filters metrics by dimension.
+type AWSCloudWatchMetricDimensionFilter struct {
+	// Name of the dimension.
+	Name string `json:"name"`
+	// Value of the dimension. May contain the wildcards '*' and '?'.
+	// Any value matches when omitted.
+	// +optional
+	Value *string `json:"value,omitempty"`
+}
+
+// AWSCloudWatchAlarmSelector selects CloudWatch alarms, either by name or by
+// name prefix.
+type AWSCloudWatchAlarmSelector struct {
+	// Names of the alarms to watch.
+	// +optional
+	Names []string `json:"names,omitempty"`
+	// Prefix shared by the names of all alarms to watch.
+	// +optional
+	NamePrefix *string `json:"namePrefix,omitempty"`
+}
+
+// AWSCloudWatchSourceStatus defines the observed state of the event source.
+type AWSCloudWatchSourceStatus struct {
+	EventSourceStatus `json:",inline"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // AWSCloudWatchSourceList contains a list of event sources.
@@ -0,0 +1,191 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"knative.dev/pkg/apis"
+)
+
+// Rules enforced by the CloudWatch API on the ID of a MetricDataQuery.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_MetricDataQuery.html
+var cloudWatchQueryIDRegexp = regexp.MustCompile(`^[a-z][a-zA-Z0-9_]{0,254}$`)
+
+// Maximum number of queries in a single GetMetricData request.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_GetMetricData.html
+const cloudWatchMaxMetricDataQueries = 500
+
+// Statistics supported by CloudWatch, including extended statistics such as
+// percentiles and trimmed means.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/Statistics-definitions.html
+var cloudWatchStatRegexp = regexp.MustCompile(`^(SampleCount|Average|Sum|Minimum|Maximum|IQM|` +
+	`(p|tm|wm|tc|ts)(\d{1,2}(\.\d{1,10})?|100)|(TM|WM|TC|TS|PR)\([^)]+\))$`)
+
+// Validate implements apis.Validatable.
+func (s *AWSCloudWatchSource) Validate(ctx context.Context) *apis.FieldError {
+	return s.Spec.Validate(ctx).ViaField("spec")
+}
+
+// Validate implements apis.Validatable.
+func (s *AWSCloudWatchSourceSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	if len(s.MetricQueries) == 0 && s.Alarms == nil {
+		errs = errs.Also(apis.ErrMissingOneOf("metricQueries", "alarms"))
+	}
+
+	// metrics and expressions are sent in a single request, since
+	// expressions may reference other queries
+	var numStatic int
+
+	names := make(map[string]struct{}, len(s.MetricQueries))
+	for i, q := range s.MetricQueries {
+		if q.Discovery == nil {
+			numStatic++
+		}
+
+		errs = errs.Also(q.Validate(ctx).ViaFieldIndex("metricQueries", i))
+
+		if _, dup := names[q.Name]; dup {
+			errs = errs.Also(apis.ErrGeneric("duplicate query name "+q.Name, "name").
+				ViaFieldIndex("metricQueries", i))
+		}
+		names[q.Name] = struct{}{}
+	}
+
+	if numStatic > cloudWatchMaxMetricDataQueries {
+		errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("at most %d metric and expression queries are allowed, got %d",
+			cloudWatchMaxMetricDataQueries, numStatic), "metricQueries"))
+	}
+
+	if s.Alarms != nil {
+		errs = errs.Also(s.Alarms.Validate(ctx).ViaField("alarms"))
+	}
+
+	return errs
+}
+
+// Validate implements apis.Validatable.
+func (q *AWSCloudWatchMetricQuery) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	if !cloudWatchQueryIDRegexp.MatchString(q.Name) {
+		errs = errs.Also(apis.ErrInvalidValue(q.Name, "name"))
+	}
+
+	var set []string
+	if q.Expression != nil {
+		set = append(set, "expression")
+		if *q.Expression == "" {
+			errs = errs.Also(apis.ErrMissingField("expression"))
+		}
+	}
+	if q.Metric != nil {
+		set = append(set, "metric")
+		errs = errs.Also(q.Metric.Validate(ctx).ViaField("metric"))
+	}
+	if q.Discovery != nil {
+		set = append(set, "discovery")
+		errs = errs.Also(q.Discovery.Validate(ctx).ViaField("discovery"))
+	}
+
+	switch len(set) {
+	case 0:
+		errs = errs.Also(apis.ErrMissingOneOf("expression", "metric", "discovery"))
+	case 1:
+	default:
+		errs = errs.Also(apis.ErrMultipleOneOf(set...))
+	}
+
+	return errs
+}
+
+// Validate implements apis.Validatable.
+func (m *AWSCloudWatchMetricStat) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	if m.Metric.MetricName == "" {
+		errs = errs.Also(apis.ErrMissingField("metric.metricName"))
+	}
+	if m.Metric.Namespace == "" {
+		errs = errs.Also(apis.ErrMissingField("metric.namespace"))
+	}
+
+	return errs.Also(validateCloudWatchStatistics(m.Period, m.Stat))
+}
+
+// Validate implements apis.Validatable.
+func (d *AWSCloudWatchMetricDiscovery) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	if d.Namespace == "" {
+		errs = errs.Also(apis.ErrMissingField("namespace"))
+	}
+	for i, dim := range d.Dimensions {
+		if dim.Name == "" {
+			errs = errs.Also(apis.ErrMissingField("name").ViaFieldIndex("dimensions", i))
+		}
+	}
+
+	return errs.Also(validateCloudWatchStatistics(d.Period, d.Stat))
+}
+
+// Validate implements apis.Validatable.
+func (a *AWSCloudWatchAlarmSelector) Validate(ctx context.Context) *apis.FieldError {
+	switch {
+	case len(a.Names) == 0 && a.NamePrefix == nil:
+		return apis.ErrMissingOneOf("names", "namePrefix")
+	case len(a.Names) > 0 && a.NamePrefix != nil:
+		return apis.ErrMultipleOneOf("names", "namePrefix")
+	}
+
+	var errs *apis.FieldError
+	for i, n := range a.Names {
+		if n == "" {
+			errs = errs.Also(apis.ErrInvalidArrayValue(n, "names", i))
+		}
+	}
+	if a.NamePrefix != nil && *a.NamePrefix == "" {
+		errs = errs.Also(apis.ErrInvalidValue(*a.NamePrefix, "namePrefix"))
+	}
+
+	return errs
+}
+
+// validateCloudWatchStatistics validates the period and statistic of a
+// metric query.
+func validateCloudWatchStatistics(period int64, stat string) *apis.FieldError {
+	var errs *apis.FieldError
+
+	// high-resolution metrics support periods of 1, 5, 10 and 30 seconds,
+	// other periods must be a multiple of 60
+	switch {
+	case period == 1, period == 5, period == 10, period == 30:
+	case period > 0 && period%60 == 0:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(period, "period"))
+	}
+
+	if !cloudWatchStatRegexp.MatchString(stat) {
+		errs = errs.Also(apis.ErrInvalidValue(stat, "stat"))
+	}
+
+	return errs
+}
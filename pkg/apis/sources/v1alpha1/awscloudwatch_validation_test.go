@@ -0,0 +1,149 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAWSCloudWatchSourceSpecValidate(t *testing.T) {
+	validMetric := func() *AWSCloudWatchMetricStat {
+		return &AWSCloudWatchMetricStat{
+			Metric: AWSCloudWatchMetric{
+				MetricName: "Duration",
+				Namespace:  "AWS/Lambda",
+			},
+			Period: 60,
+			Stat:   "p99.9",
+		}
+	}
+
+	expr := "SUM(METRICS())"
+	prefix := "prod-"
+
+	testCases := map[string]struct {
+		spec      AWSCloudWatchSourceSpec
+		expectErr string
+	}{
+		"valid metric and expression": {
+			spec: AWSCloudWatchSourceSpec{
+				MetricQueries: []AWSCloudWatchMetricQuery{
+					{Name: "m1", Metric: validMetric()},
+					{Name: "e1", Expression: &expr},
+				},
+			},
+		},
+		"valid alarms only": {
+			spec: AWSCloudWatchSourceSpec{
+				Alarms: &AWSCloudWatchAlarmSelector{NamePrefix: &prefix},
+			},
+		},
+		"nothing to source": {
+			spec:      AWSCloudWatchSourceSpec{},
+			expectErr: "expected exactly one, got neither: alarms, metricQueries",
+		},
+		"invalid query name": {
+			spec: AWSCloudWatchSourceSpec{
+				MetricQueries: []AWSCloudWatchMetricQuery{
+					{Name: "Bad-Name", Expression: &expr},
+				},
+			},
+			expectErr: "invalid value: Bad-Name: metricQueries[0].name",
+		},
+		"duplicate query names": {
+			spec: AWSCloudWatchSourceSpec{
+				MetricQueries: []AWSCloudWatchMetricQuery{
+					{Name: "q1", Expression: &expr},
+					{Name: "q1", Metric: validMetric()},
+				},
+			},
+			expectErr: "duplicate query name q1: metricQueries[1].name",
+		},
+		"expression and metric": {
+			spec: AWSCloudWatchSourceSpec{
+				MetricQueries: []AWSCloudWatchMetricQuery{
+					{Name: "q1", Expression: &expr, Metric: validMetric()},
+				},
+			},
+			expectErr: "expected exactly one, got both: metricQueries[0].expression, metricQueries[0].metric",
+		},
+		"invalid period and stat": {
+			spec: AWSCloudWatchSourceSpec{
+				MetricQueries: []AWSCloudWatchMetricQuery{{
+					Name: "q1",
+					Metric: &AWSCloudWatchMetricStat{
+						Metric: AWSCloudWatchMetric{MetricName: "Duration", Namespace: "AWS/Lambda"},
+						Period: 90,
+						Stat:   "sum",
+					},
+				}},
+			},
+			expectErr: "invalid value: 90: metricQueries[0].metric.period\n" +
+				"invalid value: sum: metricQueries[0].metric.stat",
+		},
+		"too many static queries": {
+			spec: AWSCloudWatchSourceSpec{
+				MetricQueries: func() []AWSCloudWatchMetricQuery {
+					qs := make([]AWSCloudWatchMetricQuery, 501)
+					for i := range qs {
+						qs[i] = AWSCloudWatchMetricQuery{Name: "q" + strconv.Itoa(i), Expression: &expr}
+					}
+					return qs
+				}(),
+			},
+			expectErr: "at most 500 metric and expression queries are allowed, got 501: metricQueries",
+		},
+		"alarm names and prefix": {
+			spec: AWSCloudWatchSourceSpec{
+				Alarms: &AWSCloudWatchAlarmSelector{Names: []string{"a1"}, NamePrefix: &prefix},
+			},
+			expectErr: "expected exactly one, got both: alarms.namePrefix, alarms.names",
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			err := tc.spec.Validate(context.Background())
+			if tc.expectErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestValidateCloudWatchStatistics(t *testing.T) {
+	for _, stat := range []string{"Sum", "SampleCount", "p90", "p99.99", "p100", "tm90", "TM(10%:90%)", "IQM"} {
+		assert.Nil(t, validateCloudWatchStatistics(60, stat), stat)
+	}
+	for _, stat := range []string{"", "sum", "p101", "avg"} {
+		assert.NotNil(t, validateCloudWatchStatistics(60, stat), stat)
+	}
+
+	for _, period := range []int64{1, 5, 10, 30, 60, 300, 3600} {
+		assert.Nil(t, validateCloudWatchStatistics(period, "Sum"), period)
+	}
+	for _, period := range []int64{0, -60, 2, 45, 90} {
+		assert.NotNil(t, validateCloudWatchStatistics(period, "Sum"), period)
+	}
+}
@@ -67,3 +67,8 @@ func (s *AWSCloudWatchLogsSource) GetEventTypes() []string {
 func (s *AWSCloudWatchLogsSource) AsEventSource() string {
 	return s.Spec.ARN.String()
 }
+
+// IsMultiTenant implements MultiTenant.
+func (s *AWSCloudWatchLogsSource) IsMultiTenant() bool {
+	return optsIntoMultiTenancy(s)
+}
@@ -42,6 +42,7 @@ type AWSCloudWatchLogsSource struct {
 var (
 	_ runtime.Object = (*AWSCloudWatchLogsSource)(nil)
 	_ EventSource    = (*AWSCloudWatchLogsSource)(nil)
+	_ multiTenant    = (*AWSCloudWatchLogsSource)(nil)
 )
 
 // AWSCloudWatchSourceSpec defines the desired state of the event source.
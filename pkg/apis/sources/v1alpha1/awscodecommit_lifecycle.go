@@ -17,6 +17,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strings"
+	"unicode"
+
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"knative.dev/pkg/apis"
@@ -30,6 +33,9 @@ func (s *AWSCodeCommitSource) GetGroupVersionKind() schema.GroupVersionKind {
 
 // GetConditionSet implements duckv1.KRShaped.
 func (s *AWSCodeCommitSource) GetConditionSet() apis.ConditionSet {
+	if s.UsesEventBridge() {
+		return awsCodeCommitSourceEventBridgeConditionSet
+	}
 	return eventSourceConditionSet
 }
 
@@ -47,16 +53,132 @@ func (s *AWSCodeCommitSource) GetSink() *duckv1.Destination {
 func (s *AWSCodeCommitSource) GetStatusManager() *EventSourceStatusManager {
 	return &EventSourceStatusManager{
 		ConditionSet:      s.GetConditionSet(),
-		EventSourceStatus: &s.Status,
+		EventSourceStatus: &s.Status.EventSourceStatus,
+	}
+}
+
+// Supported event types (see AWSCodeCommitSourceSpec)
+const (
+	AWSCodeCommitPushEventType        = "push"
+	AWSCodeCommitPullRequestEventType = "pull_request"
+	AWSCodeCommitReferenceEventType   = "reference"
+	AWSCodeCommitCommentEventType     = "comment"
+)
+
+// Types of the pull request events retrieved by polling.
+const (
+	AWSCodeCommitPullRequestOpenedEventType   = "pull_request_opened"
+	AWSCodeCommitPullRequestUpdatedEventType  = "pull_request_updated"
+	AWSCodeCommitPullRequestApprovedEventType = "pull_request_approved"
+	AWSCodeCommitPullRequestMergedEventType   = "pull_request_merged"
+	AWSCodeCommitPullRequestClosedEventType   = "pull_request_closed"
+)
+
+// Supported modes (see AWSCodeCommitSourceSpec)
+const (
+	AWSCodeCommitModePolling     = "polling"
+	AWSCodeCommitModeEventBridge = "eventbridge"
+)
+
+// UsesEventBridge returns whether the source receives events from Amazon
+// EventBridge instead of polling the CodeCommit API.
+func (s *AWSCodeCommitSource) UsesEventBridge() bool {
+	return s.Spec.Mode != nil && *s.Spec.Mode == AWSCodeCommitModeEventBridge
+}
+
+// awsCodeCommitEventBridgeEvents maps the event types accepted in the spec to
+// the names of the CodeCommit events delivered by Amazon EventBridge.
+// https://docs.aws.amazon.com/codecommit/latest/userguide/monitoring-events.html
+var awsCodeCommitEventBridgeEvents = map[string][]string{
+	AWSCodeCommitPushEventType: {
+		"referenceUpdated",
+	},
+	AWSCodeCommitReferenceEventType: {
+		"referenceCreated",
+		"referenceDeleted",
+	},
+	AWSCodeCommitPullRequestEventType: {
+		"pullRequestCreated",
+		"pullRequestSourceBranchUpdated",
+		"pullRequestStatusChanged",
+		"pullRequestMergeStatusUpdated",
+		"pullRequestApprovalStateChanged",
+		"pullRequestApprovalRuleCreated",
+		"pullRequestApprovalRuleUpdated",
+		"pullRequestApprovalRuleDeleted",
+		"pullRequestApprovalRuleOverridden",
+	},
+	AWSCodeCommitCommentEventType: {
+		"commentOnPullRequestCreated",
+		"commentOnPullRequestUpdated",
+		"commentOnCommitCreated",
+		"commentOnCommitUpdated",
+	},
+}
+
+// AWSCodeCommitEventBridgeEvents returns the names of the CodeCommit events
+// delivered by Amazon EventBridge for the given event types.
+func AWSCodeCommitEventBridgeEvents(eventTypes []string) []string {
+	var events []string
+	for _, typ := range eventTypes {
+		events = append(events, awsCodeCommitEventBridgeEvents[typ]...)
 	}
+	return events
+}
+
+// AWSCodeCommitEventTypeForEvent returns the type element of the CloudEvent
+// type matching the given CodeCommit event delivered by Amazon EventBridge.
+// Example: "pullRequestCreated" -> "pull_request_created"
+//
+// Reference updates are reported as "push", which is also the type of the
+// events retrieved by polling.
+func AWSCodeCommitEventTypeForEvent(event string) string {
+	if event == "referenceUpdated" {
+		return AWSCodeCommitPushEventType
+	}
+
+	var typ strings.Builder
+	typ.Grow(len(event) + 4)
+
+	for _, r := range event {
+		if unicode.IsUpper(r) {
+			typ.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+		typ.WriteRune(r)
+	}
+
+	return typ.String()
 }
 
 // GetEventTypes implements EventSource.
 func (s *AWSCodeCommitSource) GetEventTypes() []string {
-	types := make([]string, len(s.Spec.EventTypes))
+	if s.UsesEventBridge() {
+		events := AWSCodeCommitEventBridgeEvents(s.Spec.EventTypes)
+		types := make([]string, len(events))
+
+		for i, event := range events {
+			types[i] = AWSEventType(s.Spec.ARN.Service, AWSCodeCommitEventTypeForEvent(event))
+		}
+
+		return types
+	}
 
-	for i, typ := range s.Spec.EventTypes {
-		types[i] = AWSEventType(s.Spec.ARN.Service, typ)
+	var types []string
+
+	for _, typ := range s.Spec.EventTypes {
+		if typ != AWSCodeCommitPullRequestEventType {
+			types = append(types, AWSEventType(s.Spec.ARN.Service, typ))
+			continue
+		}
+
+		types = append(types,
+			AWSEventType(s.Spec.ARN.Service, AWSCodeCommitPullRequestOpenedEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCodeCommitPullRequestUpdatedEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCodeCommitPullRequestApprovedEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCodeCommitPullRequestMergedEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCodeCommitPullRequestClosedEventType),
+		)
 	}
 
 	return types
@@ -66,3 +188,44 @@ func (s *AWSCodeCommitSource) GetEventTypes() []string {
 func (s *AWSCodeCommitSource) AsEventSource() string {
 	return s.Spec.ARN.String()
 }
+
+// IsMultiTenant implements MultiTenant.
+// Sources running in "eventbridge" mode are always served by a dedicated SQS
+// adapter.
+func (s *AWSCodeCommitSource) IsMultiTenant() bool {
+	return optsIntoMultiTenancy(s) && !s.UsesEventBridge()
+}
+
+// Status conditions
+const (
+	// AWSCodeCommitConditionSubscribed has status True when an EventBridge
+	// rule forwards the events of a CodeCommit repository to the source's
+	// SQS queue.
+	AWSCodeCommitConditionSubscribed apis.ConditionType = "Subscribed"
+)
+
+// Reasons for status conditions
+const (
+	// AWSCodeCommitReasonNoClient is set on a Subscribed condition when an EventBridge/SQS API client cannot be obtained.
+	AWSCodeCommitReasonNoClient = "NoClient"
+	// AWSCodeCommitReasonAPIError is set on a Subscribed condition when the EventBridge/SQS API returns any other error.
+	AWSCodeCommitReasonAPIError = "APIError"
+)
+
+// awsCodeCommitSourceEventBridgeConditionSet is a set of conditions for
+// AWSCodeCommitSource objects which receive events from Amazon EventBridge.
+var awsCodeCommitSourceEventBridgeConditionSet = NewEventSourceConditionSet(
+	AWSCodeCommitConditionSubscribed,
+)
+
+// MarkSubscribed sets the Subscribed condition to True.
+func (s *AWSCodeCommitSourceStatus) MarkSubscribed() {
+	awsCodeCommitSourceEventBridgeConditionSet.Manage(s).MarkTrue(AWSCodeCommitConditionSubscribed)
+}
+
+// MarkNotSubscribed sets the Subscribed condition to False with the given
+// reason and associated message.
+func (s *AWSCodeCommitSourceStatus) MarkNotSubscribed(reason, msg string) {
+	awsCodeCommitSourceEventBridgeConditionSet.Manage(s).MarkFalse(AWSCodeCommitConditionSubscribed,
+		reason, msg)
+}
@@ -34,14 +34,15 @@ type AWSCodeCommitSource struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   AWSCodeCommitSourceSpec `json:"spec,omitempty"`
-	Status EventSourceStatus       `json:"status,omitempty"`
+	Spec   AWSCodeCommitSourceSpec   `json:"spec,omitempty"`
+	Status AWSCodeCommitSourceStatus `json:"status,omitempty"`
 }
 
 // Check the interfaces the event source should be implementing.
 var (
 	_ runtime.Object = (*AWSCodeCommitSource)(nil)
 	_ EventSource    = (*AWSCodeCommitSource)(nil)
+	_ multiTenant    = (*AWSCodeCommitSource)(nil)
 )
 
 // AWSCodeCommitSourceSpec defines the desired state of the event source.
@@ -52,15 +53,40 @@ type AWSCodeCommitSourceSpec struct {
 	// https://docs.aws.amazon.com/IAM/latest/UserGuide/list_awscodecommit.html#awscodecommit-resources-for-iam-policies
 	ARN apis.ARN `json:"arn"`
 	// Name of the Git branch this source observes.
-	Branch string `json:"branch"`
+	// Either Branch or Branches is required when events are retrieved by
+	// polling.
+	// +optional
+	Branch string `json:"branch,omitempty"`
+	// Glob patterns matching the names of the Git branches this source
+	// observes, using the syntax of Go's path.Match. When events are
+	// delivered by Amazon EventBridge, all branches are observed if both
+	// Branch and Branches are omitted.
+	// +optional
+	Branches []string `json:"branches,omitempty"`
 	// List of event types that should be processed by the source.
-	// Valid values: [push, pull_request]
+	// Valid values: [push, pull_request, reference, comment]
+	// The "reference" and "comment" types are only supported when events
+	// are delivered by Amazon EventBridge.
 	EventTypes []string `json:"eventTypes"`
 
-	// Credentials to interact with the Amazon CodeCommit API.
+	// Method used by the source to obtain events from the repository.
+	// Valid values: [polling, eventbridge]
+	// Defaults to "polling". In "eventbridge" mode, an EventBridge rule
+	// and a SQS queue are created to receive the repository's events.
+	// +optional
+	Mode *string `json:"mode,omitempty"`
+
+	// Credentials to interact with the Amazon CodeCommit API, as well as
+	// the Amazon EventBridge and SQS APIs in "eventbridge" mode.
 	Credentials AWSSecurityCredentials `json:"credentials"`
 }
 
+// AWSCodeCommitSourceStatus defines the observed state of the event source.
+type AWSCodeCommitSourceStatus struct {
+	EventSourceStatus `json:",inline"`
+	QueueARN          *apis.ARN `json:"queueARN,omitempty"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // AWSCodeCommitSourceList contains a list of event sources.
@@ -53,17 +53,78 @@ func (s *AWSCognitoUserPoolSource) GetStatusManager() *EventSourceStatusManager
 
 // Supported event types
 const (
-	AWSCognitoUserPoolGenericEventType = "sync_trigger"
+	AWSCognitoUserPoolUserCreatedEventType   = "user_created"
+	AWSCognitoUserPoolUserUpdatedEventType   = "user_updated"
+	AWSCognitoUserPoolUserDeletedEventType   = "user_deleted"
+	AWSCognitoUserPoolUserEnabledEventType   = "user_enabled"
+	AWSCognitoUserPoolUserDisabledEventType  = "user_disabled"
+	AWSCognitoUserPoolUserConfirmedEventType = "user_confirmed"
+)
+
+// Event types reported when group memberships are tracked.
+const (
+	AWSCognitoUserPoolUserAddedToGroupEventType     = "user_added_to_group"
+	AWSCognitoUserPoolUserRemovedFromGroupEventType = "user_removed_from_group"
+)
+
+// Event types reported when Cognito Lambda trigger payloads are ingested.
+const (
+	AWSCognitoUserPoolPreSignUpEventType                   = "pre_sign_up"
+	AWSCognitoUserPoolPostConfirmationEventType            = "post_confirmation"
+	AWSCognitoUserPoolPreAuthenticationEventType           = "pre_authentication"
+	AWSCognitoUserPoolPostAuthenticationEventType          = "post_authentication"
+	AWSCognitoUserPoolCustomMessageEventType               = "custom_message"
+	AWSCognitoUserPoolPreTokenGenerationEventType          = "pre_token_generation"
+	AWSCognitoUserPoolUserMigrationEventType               = "user_migration"
+	AWSCognitoUserPoolDefineAuthChallengeEventType         = "define_auth_challenge"
+	AWSCognitoUserPoolCreateAuthChallengeEventType         = "create_auth_challenge"
+	AWSCognitoUserPoolVerifyAuthChallengeResponseEventType = "verify_auth_challenge_response"
 )
 
 // GetEventTypes implements EventSource.
 func (s *AWSCognitoUserPoolSource) GetEventTypes() []string {
-	return []string{
-		AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolGenericEventType),
+	types := []string{
+		AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolUserCreatedEventType),
+		AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolUserUpdatedEventType),
+		AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolUserDeletedEventType),
+		AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolUserEnabledEventType),
+		AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolUserDisabledEventType),
+		AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolUserConfirmedEventType),
 	}
+
+	if s.Spec.TrackGroupMembership {
+		types = append(types,
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolUserAddedToGroupEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolUserRemovedFromGroupEventType),
+		)
+	}
+
+	if s.Spec.Triggers != nil {
+		types = append(types,
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolPreSignUpEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolPostConfirmationEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolPreAuthenticationEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolPostAuthenticationEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolCustomMessageEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolPreTokenGenerationEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolUserMigrationEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolDefineAuthChallengeEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolCreateAuthChallengeEventType),
+			AWSEventType(s.Spec.ARN.Service, AWSCognitoUserPoolVerifyAuthChallengeResponseEventType),
+		)
+	}
+
+	return types
 }
 
 // AsEventSource implements EventSource.
 func (s *AWSCognitoUserPoolSource) AsEventSource() string {
 	return s.Spec.ARN.String()
 }
+
+// IsMultiTenant implements MultiTenant.
+// Sources which ingest Cognito Lambda triggers are always served by a
+// dedicated adapter, which must be reachable over HTTP.
+func (s *AWSCognitoUserPoolSource) IsMultiTenant() bool {
+	return optsIntoMultiTenancy(s) && s.Spec.Triggers == nil
+}
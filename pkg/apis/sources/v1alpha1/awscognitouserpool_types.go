@@ -42,6 +42,7 @@ type AWSCognitoUserPoolSource struct {
 var (
 	_ runtime.Object = (*AWSCognitoUserPoolSource)(nil)
 	_ EventSource    = (*AWSCognitoUserPoolSource)(nil)
+	_ multiTenant    = (*AWSCognitoUserPoolSource)(nil)
 )
 
 // AWSCognitoUserPoolSourceSpec defines the desired state of the event source.
@@ -52,10 +53,29 @@ type AWSCognitoUserPoolSourceSpec struct {
 	// https://docs.aws.amazon.com/service-authorization/latest/reference/list_amazoncognitouserpools.html#amazoncognitouserpools-resources-for-iam-policies
 	ARN apis.ARN `json:"arn"`
 
+	// Whether changes of the users' group memberships are reported in
+	// addition to changes of the users themselves.
+	// +optional
+	TrackGroupMembership bool `json:"trackGroupMembership,omitempty"`
+
+	// Ingestion of Cognito Lambda trigger payloads forwarded to the
+	// source by a relay function. When set, the adapter is exposed over
+	// HTTP at the address reported in the source's status.
+	// +optional
+	Triggers *AWSCognitoUserPoolTriggers `json:"triggers,omitempty"`
+
 	// Credentials to interact with the Amazon Cognito API.
 	Credentials AWSSecurityCredentials `json:"credentials"`
 }
 
+// AWSCognitoUserPoolTriggers defines how Cognito Lambda trigger payloads are
+// ingested by the event source.
+type AWSCognitoUserPoolTriggers struct {
+	// Secret shared with the relay function, expected in the
+	// Authorization header of each request as a bearer token.
+	SharedSecret ValueFromField `json:"sharedSecret"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // AWSCognitoUserPoolSourceList contains a list of event sources.
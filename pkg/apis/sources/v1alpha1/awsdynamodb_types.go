@@ -54,6 +54,11 @@ type AWSDynamoDBSourceSpec struct {
 
 	// Credentials to interact with the Amazon DynamoDB API.
 	Credentials AWSSecurityCredentials `json:"credentials"`
+
+	// Number of replicas of the receive adapter. When set, the shards of
+	// the table's stream are distributed across replicas.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -30,7 +30,7 @@ func (*AWSPerformanceInsightsSource) GetGroupVersionKind() schema.GroupVersionKi
 
 // GetConditionSet implements duckv1.KRShaped.
 func (*AWSPerformanceInsightsSource) GetConditionSet() apis.ConditionSet {
-	return eventSourceConditionSet
+	return awsPerformanceInsightsSourceConditionSet
 }
 
 // GetStatus implements duckv1.KRShaped.
@@ -47,23 +47,78 @@ func (s *AWSPerformanceInsightsSource) GetSink() *duckv1.Destination {
 func (s *AWSPerformanceInsightsSource) GetStatusManager() *EventSourceStatusManager {
 	return &EventSourceStatusManager{
 		ConditionSet:      s.GetConditionSet(),
-		EventSourceStatus: &s.Status,
+		EventSourceStatus: &s.Status.EventSourceStatus,
 	}
 }
 
 // Supported event types
 const (
-	AWSPerformanceInsightsGenericEventType = "com.amazon.rds.pi.metric"
+	AWSPerformanceInsightsMetricEventType        = "pi.metric"
+	AWSPerformanceInsightsTopDimensionsEventType = "pi.top_dimensions"
 )
 
 // GetEventTypes implements EventSource.
 func (s *AWSPerformanceInsightsSource) GetEventTypes() []string {
-	return []string{
-		AWSEventType(s.Spec.ARN.Service, AWSPerformanceInsightsGenericEventType),
+	var types []string
+
+	if len(s.Spec.Metrics) > 0 || len(s.Spec.MetricQueries) > 0 {
+		types = append(types, AWSEventType(s.Spec.ARN.Service, AWSPerformanceInsightsMetricEventType))
+	}
+	if len(s.Spec.TopDimensions) > 0 {
+		types = append(types, AWSEventType(s.Spec.ARN.Service, AWSPerformanceInsightsTopDimensionsEventType))
 	}
+
+	return types
 }
 
 // AsEventSource implements EventSource.
 func (s *AWSPerformanceInsightsSource) AsEventSource() string {
 	return s.Spec.ARN.String()
 }
+
+// IsMultiTenant implements MultiTenant.
+func (s *AWSPerformanceInsightsSource) IsMultiTenant() bool {
+	return optsIntoMultiTenancy(s)
+}
+
+// Status conditions
+const (
+	// AWSPerformanceInsightsConditionInstancesResolved has status True when
+	// the database instances to receive metrics from have been resolved, and
+	// have Performance Insights enabled.
+	AWSPerformanceInsightsConditionInstancesResolved apis.ConditionType = "InstancesResolved"
+)
+
+// Reasons for status conditions
+const (
+	// AWSPerformanceInsightsReasonNoClient is set on a InstancesResolved condition when a RDS API client cannot be obtained.
+	AWSPerformanceInsightsReasonNoClient = "NoClient"
+	// AWSPerformanceInsightsReasonNotFound is set on a InstancesResolved condition when no database instance exists for the ARN.
+	AWSPerformanceInsightsReasonNotFound = "InstanceNotFound"
+	// AWSPerformanceInsightsReasonDisabled is set on a InstancesResolved condition when Performance Insights is disabled
+	// on the database instances.
+	AWSPerformanceInsightsReasonDisabled = "PerformanceInsightsDisabled"
+	// AWSPerformanceInsightsReasonAPIError is set on a InstancesResolved condition when the RDS API returns any other error.
+	AWSPerformanceInsightsReasonAPIError = "APIError"
+)
+
+// awsPerformanceInsightsSourceConditionSet is a set of conditions for
+// AWSPerformanceInsightsSource objects.
+var awsPerformanceInsightsSourceConditionSet = NewEventSourceConditionSet(
+	AWSPerformanceInsightsConditionInstancesResolved,
+)
+
+// MarkInstancesResolved sets the InstancesResolved condition to True and
+// reports the resolved database instances.
+func (s *AWSPerformanceInsightsSourceStatus) MarkInstancesResolved(instances []AWSPerformanceInsightsInstance) {
+	s.Instances = instances
+	awsPerformanceInsightsSourceConditionSet.Manage(s).MarkTrue(AWSPerformanceInsightsConditionInstancesResolved)
+}
+
+// MarkInstancesNotResolved sets the InstancesResolved condition to False with
+// the given reason and associated message.
+func (s *AWSPerformanceInsightsSourceStatus) MarkInstancesNotResolved(reason, msg string) {
+	s.Instances = nil
+	awsPerformanceInsightsSourceConditionSet.Manage(s).MarkFalse(AWSPerformanceInsightsConditionInstancesResolved,
+		reason, msg)
+}
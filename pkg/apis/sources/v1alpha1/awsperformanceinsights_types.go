@@ -34,21 +34,23 @@ type AWSPerformanceInsightsSource struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   AWSPerformanceInsightsSourceSpec `json:"spec,omitempty"`
-	Status EventSourceStatus                `json:"status,omitempty"`
+	Spec   AWSPerformanceInsightsSourceSpec   `json:"spec,omitempty"`
+	Status AWSPerformanceInsightsSourceStatus `json:"status,omitempty"`
 }
 
 // Check the interfaces the event source should be implementing.
 var (
 	_ runtime.Object = (*AWSPerformanceInsightsSource)(nil)
 	_ EventSource    = (*AWSPerformanceInsightsSource)(nil)
+	_ multiTenant    = (*AWSPerformanceInsightsSource)(nil)
 )
 
 // AWSPerformanceInsightsSourceSpec defines the desired state of the event source.
 type AWSPerformanceInsightsSourceSpec struct {
 	duckv1.SourceSpec `json:",inline"`
 
-	// ARN of the RDS instance to receive metrics for.
+	// ARN of the RDS instance or cluster to receive metrics for. Amazon Aurora and Amazon DocumentDB
+	// clusters are supported, in which case metrics are received for every instance of the cluster.
 	// https://docs.aws.amazon.com/service-authorization/latest/reference/list_amazonrds.html#amazonrds-resources-for-iam-policies
 	ARN apis.ARN `json:"arn"`
 
@@ -56,16 +58,98 @@ type AWSPerformanceInsightsSourceSpec struct {
 	// Expressed as a duration string, which format is documented at https://pkg.go.dev/time#ParseDuration.
 	PollingInterval apis.Duration `json:"pollingInterval"`
 
+	// Granularity, in seconds, of the datapoints retrieved from Amazon Performance Insights. Valid values
+	// are 1, 60, 300, 3600 and 86400. Defaults to 60.
+	// https://docs.aws.amazon.com/performance-insights/latest/APIReference/API_GetResourceMetrics.html
+	// +optional
+	Period *int64 `json:"period,omitempty"`
+
 	// List of queries that determine what metrics will be sourced from Amazon Performance Insights.
 	//
 	// Each item represents the 'metric' attribute of a MetricQuery.
 	// https://docs.aws.amazon.com/performance-insights/latest/APIReference/API_MetricQuery.html
-	Metrics []string `json:"metrics"`
+	// +optional
+	Metrics []string `json:"metrics,omitempty"`
+
+	// Structured queries that determine what metrics will be sourced from Amazon Performance Insights.
+	// Unlike Metrics, these queries allow metrics to be grouped by dimension and filtered.
+	// https://docs.aws.amazon.com/performance-insights/latest/APIReference/API_MetricQuery.html
+	// +optional
+	MetricQueries []AWSPerformanceInsightsMetricQuery `json:"metricQueries,omitempty"`
+
+	// Queries that determine the top dimension keys reported periodically for a metric, such as the SQL
+	// statements or wait events which contribute the most to the database load.
+	// https://docs.aws.amazon.com/performance-insights/latest/APIReference/API_DescribeDimensionKeys.html
+	// +optional
+	TopDimensions []AWSPerformanceInsightsDimensionKeysQuery `json:"topDimensions,omitempty"`
 
 	// Credentials to interact with the Amazon RDS and Performance Insights APIs.
 	Credentials AWSSecurityCredentials `json:"credentials"`
 }
 
+// AWSPerformanceInsightsMetricQuery is a query for a Performance Insights metric.
+type AWSPerformanceInsightsMetricQuery struct {
+	// Name of the metric, e.g. "db.load.avg".
+	Metric string `json:"metric"`
+	// Dimension group to group the metric by. One event is emitted per dimension key.
+	// +optional
+	GroupBy *AWSPerformanceInsightsDimensionGroup `json:"groupBy,omitempty"`
+	// Dimensions to filter the metric by, keyed by dimension name.
+	// +optional
+	Filter map[string]string `json:"filter,omitempty"`
+}
+
+// AWSPerformanceInsightsDimensionKeysQuery is a query for the top dimension
+// keys of a Performance Insights metric.
+type AWSPerformanceInsightsDimensionKeysQuery struct {
+	// Name of the metric, e.g. "db.load.avg".
+	Metric string `json:"metric"`
+	// Dimension group to rank dimension keys by.
+	GroupBy AWSPerformanceInsightsDimensionGroup `json:"groupBy"`
+	// Dimension group to break down the metric of each dimension key by.
+	// +optional
+	PartitionBy *AWSPerformanceInsightsDimensionGroup `json:"partitionBy,omitempty"`
+	// Dimensions to filter the metric by, keyed by dimension name.
+	// +optional
+	Filter map[string]string `json:"filter,omitempty"`
+}
+
+// AWSPerformanceInsightsDimensionGroup selects dimensions of a Performance
+// Insights metric.
+// https://docs.aws.amazon.com/performance-insights/latest/APIReference/API_DimensionGroup.html
+type AWSPerformanceInsightsDimensionGroup struct {
+	// Name of the dimension group, e.g. "db.sql" or "db.wait_event".
+	Group string `json:"group"`
+	// Dimensions of the group to return. All dimensions of the group are returned when omitted.
+	// +optional
+	Dimensions []string `json:"dimensions,omitempty"`
+	// Maximum number of dimension keys to return.
+	// +optional
+	Limit *int64 `json:"limit,omitempty"`
+}
+
+// AWSPerformanceInsightsSourceStatus defines the observed state of the event source.
+type AWSPerformanceInsightsSourceStatus struct {
+	EventSourceStatus `json:",inline"`
+
+	// Database instances which metrics are received from, resolved from the
+	// source's ARN.
+	// +optional
+	Instances []AWSPerformanceInsightsInstance `json:"instances,omitempty"`
+}
+
+// AWSPerformanceInsightsInstance identifies a database instance within
+// Amazon Performance Insights.
+type AWSPerformanceInsightsInstance struct {
+	// ARN of the database instance.
+	ARN string `json:"arn"`
+	// Identifier of the database instance within Performance Insights, as
+	// reported in the DbiResourceId attribute of the instance.
+	ResourceID string `json:"resourceID"`
+	// Performance Insights service type of the database instance.
+	ServiceType string `json:"serviceType"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // AWSPerformanceInsightsSourceList contains a list of event sources.
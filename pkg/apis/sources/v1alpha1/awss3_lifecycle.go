@@ -61,13 +61,152 @@ const (
 	AWSS3ObjCreatedEventType               = "objectcreated"
 	AWSS3ObjRemovedEventType               = "objectremoved"
 	AWSS3ObjRestoreEventType               = "objectrestore"
+	AWSS3ObjTaggingEventType               = "objecttagging"
+	AWSS3ObjACLEventType                   = "objectacl"
+	AWSS3LifecycleTransitionEventType      = "lifecycletransition"
+	AWSS3IntelligentTieringEventType       = "intelligenttiering"
 	AWSS3ReducedRedundancyLostObjEventType = "reducedredundancylostobject"
 	AWSS3ReplicationEventType              = "replication"
 	AWSS3TestEventType                     = "testevent"
 )
 
+// Supported modes (see AWSS3SourceSpec)
+const (
+	AWSS3ModeNotification = "notification"
+	AWSS3ModeEventBridge  = "eventbridge"
+)
+
+// UsesEventBridge returns whether the bucket delivers its events via Amazon
+// EventBridge instead of event notifications.
+func (s *AWSS3Source) UsesEventBridge() bool {
+	return s.Spec.Mode != nil && *s.Spec.Mode == AWSS3ModeEventBridge
+}
+
+// SelectsBuckets returns whether the source receives events from the buckets
+// selected by its bucket selector instead of a single bucket.
+func (s *AWSS3Source) SelectsBuckets() bool {
+	return s.Spec.BucketSelector != nil
+}
+
+// ManagesQueuePolicy returns whether the access policy of the source's SQS
+// queue should be managed by the reconciler.
+func (s *AWSS3Source) ManagesQueuePolicy() bool {
+	return s.Spec.QueueARN == nil || (s.Spec.ManageQueuePolicy != nil && *s.Spec.ManageQueuePolicy)
+}
+
+// awsS3EventBridgeDetailTypes maps the event types accepted in the spec,
+// stripped from their "s3:" prefix, to the "detail-type" of the matching
+// events delivered by Amazon EventBridge. Wildcards match all the detail types
+// of their category.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/EventBridge.html
+//
+// EventBridge does not deliver events about replication and lost objects.
+var awsS3EventBridgeDetailTypes = map[string][]string{
+	"ObjectCreated:*": {
+		"Object Created",
+	},
+	"ObjectRemoved:*": {
+		"Object Deleted",
+	},
+	"LifecycleExpiration:*": {
+		"Object Deleted",
+	},
+	"ObjectRestore:*": {
+		"Object Restore Initiated",
+		"Object Restore Completed",
+		"Object Restore Expired",
+	},
+	"ObjectRestore:Post": {
+		"Object Restore Initiated",
+	},
+	"ObjectRestore:Completed": {
+		"Object Restore Completed",
+	},
+	"ObjectRestore:Delete": {
+		"Object Restore Expired",
+	},
+	"ObjectTagging:*": {
+		"Object Tags Added",
+		"Object Tags Deleted",
+	},
+	"ObjectTagging:Put": {
+		"Object Tags Added",
+	},
+	"ObjectTagging:Delete": {
+		"Object Tags Deleted",
+	},
+	"ObjectAcl:Put": {
+		"Object ACL Updated",
+	},
+	"LifecycleTransition": {
+		"Object Storage Class Changed",
+	},
+	"IntelligentTiering": {
+		"Object Access Tier Changed",
+	},
+}
+
+// AWSS3EventBridgeDetailTypes returns the detail types of the S3 events
+// delivered by Amazon EventBridge for the given event types, without
+// duplicates.
+//
+// Event types which don't have a detail type of their own, such as
+// "s3:ObjectCreated:Put", match all the detail types of their category.
+func AWSS3EventBridgeDetailTypes(eventTypes []string) []string {
+	var detailTypes []string
+	seen := make(map[string]struct{})
+
+	for _, typ := range eventTypes {
+		typ = strings.TrimPrefix(typ, "s3:")
+
+		dts, found := awsS3EventBridgeDetailTypes[typ]
+		if !found {
+			dts = awsS3EventBridgeDetailTypes[strings.SplitN(typ, ":", 2)[0]+":*"]
+		}
+
+		for _, dt := range dts {
+			if _, isSeen := seen[dt]; !isSeen {
+				seen[dt] = struct{}{}
+				detailTypes = append(detailTypes, dt)
+			}
+		}
+	}
+
+	return detailTypes
+}
+
+// AWSS3EventTypeForDetailType returns the type element of the CloudEvent type
+// matching the given detail type of a S3 event delivered by Amazon
+// EventBridge. Types are identical to the ones of the matching S3 event
+// notifications.
+// Example: "Object Deleted" -> "objectremoved"
+func AWSS3EventTypeForDetailType(detailType string) string {
+	switch {
+	case detailType == "Object Created":
+		return AWSS3ObjCreatedEventType
+	case detailType == "Object Deleted":
+		return AWSS3ObjRemovedEventType
+	case strings.HasPrefix(detailType, "Object Restore "):
+		return AWSS3ObjRestoreEventType
+	case strings.HasPrefix(detailType, "Object Tags "):
+		return AWSS3ObjTaggingEventType
+	case detailType == "Object ACL Updated":
+		return AWSS3ObjACLEventType
+	case detailType == "Object Storage Class Changed":
+		return AWSS3LifecycleTransitionEventType
+	case detailType == "Object Access Tier Changed":
+		return AWSS3IntelligentTieringEventType
+	}
+
+	return strings.ToLower(strings.ReplaceAll(detailType, " ", ""))
+}
+
 // GetEventTypes implements EventSource.
 func (s *AWSS3Source) GetEventTypes() []string {
+	if s.UsesEventBridge() {
+		return s.eventBridgeEventTypes()
+	}
+
 	selectedTypes := make(map[string]struct{})
 	for _, t := range s.Spec.EventTypes {
 		if _, alreadySet := selectedTypes[s3EventTypeFromSpecEventType(t)]; !alreadySet {
@@ -89,6 +228,24 @@ func (s *AWSS3Source) GetEventTypes() []string {
 	return eventTypes
 }
 
+// eventBridgeEventTypes returns the types of the events delivered by Amazon
+// EventBridge for the event types selected in the source's spec.
+func (s *AWSS3Source) eventBridgeEventTypes() []string {
+	selectedTypes := make(map[string]struct{})
+	for _, dt := range AWSS3EventBridgeDetailTypes(s.Spec.EventTypes) {
+		selectedTypes[AWSEventType(s.Spec.ARN.Service, AWSS3EventTypeForDetailType(dt))] = struct{}{}
+	}
+
+	eventTypes := make([]string, 0, len(selectedTypes))
+	for t := range selectedTypes {
+		eventTypes = append(eventTypes, t)
+	}
+
+	sort.Strings(eventTypes)
+
+	return eventTypes
+}
+
 // s3EventTypeFromSpecEventType returns the type element of an event type
 // formatted as "s3:<type>:<other>", which is the format expected in the
 // object's spec.
@@ -107,18 +264,37 @@ func (s *AWSS3Source) AsEventSource() string {
 // Status conditions
 const (
 	// AWSS3ConditionSubscribed has status True when event notifications
-	// have been successfully enabled on a S3 bucket.
+	// have been successfully enabled on a S3 bucket, either towards the
+	// SQS queue or towards Amazon EventBridge.
 	AWSS3ConditionSubscribed apis.ConditionType = "Subscribed"
 )
 
 // Reasons for status conditions
 const (
-	// AWSS3ReasonNoClient is set on a Subscribed condition when a S3/SQS API client cannot be obtained.
+	// AWSS3ReasonInvalidSpec is set on a Subscribed condition when the source's spec fails validation.
+	AWSS3ReasonInvalidSpec = "InvalidSpec"
+	// AWSS3ReasonNoClient is set on a Subscribed condition when a S3/SQS/EventBridge API client cannot be obtained.
 	AWSS3ReasonNoClient = "NoClient"
 	// AWSS3ReasonNoBucket is set on a Subscribed condition when the S3 bucket does not exist.
 	AWSS3ReasonNoBucket = "BucketNotFound"
-	// AWSS3ReasonAPIError is set on a Subscribed condition when the S3/SQS API returns any other error.
+	// AWSS3ReasonNoQueue is set on a Subscribed condition when the user-provided SQS queue does not exist.
+	AWSS3ReasonNoQueue = "QueueNotFound"
+	// AWSS3ReasonAPIError is set on a Subscribed condition when the S3/SQS/EventBridge API returns any other error.
 	AWSS3ReasonAPIError = "APIError"
+	// AWSS3ReasonOverlappingFilter is set on a Subscribed condition when the source's event types and key filter
+	// overlap with another event notification configured on the bucket.
+	AWSS3ReasonOverlappingFilter = "OverlappingFilter"
+	// AWSS3ReasonUnsupportedSelector is set on a Subscribed condition when a bucket selector is used in a mode which
+	// doesn't support it.
+	AWSS3ReasonUnsupportedSelector = "UnsupportedSelector"
+	// AWSS3ReasonNoMatchingBucket is set on a Subscribed condition when no bucket matches the bucket selector.
+	AWSS3ReasonNoMatchingBucket = "NoMatchingBucket"
+	// AWSS3ReasonBucketsNotSubscribed is set on a Subscribed condition when some of the buckets matching the bucket
+	// selector could not be subscribed to.
+	AWSS3ReasonBucketsNotSubscribed = "BucketsNotSubscribed"
+	// AWSS3ReasonUnsupportedEventTypes is set on a Subscribed condition when none of the selected event types is
+	// delivered by Amazon EventBridge.
+	AWSS3ReasonUnsupportedEventTypes = "UnsupportedEventTypes"
 )
 
 // awsS3SourceConditionSet is a set of conditions for AWSS3Source objects.
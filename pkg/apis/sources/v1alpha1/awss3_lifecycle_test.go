@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis"
+)
+
+func TestAWSS3EventBridgeDetailTypes(t *testing.T) {
+	testCases := map[string]struct {
+		eventTypes        []string
+		expectDetailTypes []string
+	}{
+		"Wildcard": {
+			eventTypes:        []string{"s3:ObjectTagging:*"},
+			expectDetailTypes: []string{"Object Tags Added", "Object Tags Deleted"},
+		},
+		"Sub-type with own detail type": {
+			eventTypes:        []string{"s3:ObjectRestore:Completed"},
+			expectDetailTypes: []string{"Object Restore Completed"},
+		},
+		"Sub-types matching their category": {
+			eventTypes:        []string{"s3:ObjectCreated:Put", "s3:ObjectCreated:Copy", "s3:ObjectRemoved:Delete"},
+			expectDetailTypes: []string{"Object Created", "Object Deleted"},
+		},
+		"Type without sub-type": {
+			eventTypes:        []string{"s3:LifecycleTransition"},
+			expectDetailTypes: []string{"Object Storage Class Changed"},
+		},
+		"Not delivered by EventBridge": {
+			eventTypes:        []string{"s3:Replication:*", "s3:ReducedRedundancyLostObject"},
+			expectDetailTypes: nil,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectDetailTypes, AWSS3EventBridgeDetailTypes(tc.eventTypes))
+		})
+	}
+}
+
+func TestAWSS3SourceGetEventTypes(t *testing.T) {
+	src := &AWSS3Source{
+		Spec: AWSS3SourceSpec{
+			ARN: apis.ARN{
+				Partition: "aws",
+				Service:   "s3",
+				Region:    "us-east-1",
+				AccountID: "123456789012",
+				Resource:  "mybucket",
+			},
+			EventTypes: []string{"s3:ObjectCreated:*", "s3:ObjectRestore:*"},
+		},
+	}
+
+	expectNotificationTypes := []string{
+		"com.amazon.s3.objectcreated",
+		"com.amazon.s3.objectrestore",
+		"com.amazon.s3.testevent",
+	}
+	assert.Equal(t, expectNotificationTypes, src.GetEventTypes())
+
+	mode := AWSS3ModeEventBridge
+	src.Spec.Mode = &mode
+
+	expectEventBridgeTypes := []string{
+		"com.amazon.s3.objectcreated",
+		"com.amazon.s3.objectrestore",
+	}
+	assert.Equal(t, expectEventBridgeTypes, src.GetEventTypes())
+}
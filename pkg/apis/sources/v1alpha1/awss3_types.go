@@ -54,14 +54,31 @@ type AWSS3SourceSpec struct {
 	// Although not technically required by S3, we enforce that bucket ARNs
 	// include a region and an account ID, because this information is
 	// required by the reconciler to operate properly.
+	//
+	// The bucket name must be "*" when, and only when, BucketSelector is
+	// set, e.g. "arn:aws:s3:us-west-2:123456789012:*".
 	ARN apis.ARN `json:"arn"`
 
+	// Selection of multiple buckets by name prefix and/or tags, as an
+	// alternative to a single bucket. Only buckets located in the region
+	// of ARN are selected, and their selection is re-evaluated
+	// periodically.
+	// Not supported in "eventbridge" mode.
+	// +optional
+	BucketSelector *AWSS3BucketSelector `json:"bucketSelector,omitempty"`
+
 	// List of event types that the source should subscribe to.
 	// Accepted values:
 	// https://docs.aws.amazon.com/AmazonS3/latest/API/API_QueueConfiguration.html
 	// https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-how-to-event-types-and-destinations.html
 	EventTypes []string `json:"eventTypes"`
 
+	// Filter restricting the source to a subset of the bucket's objects,
+	// based on their key.
+	// https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-how-to-filtering.html
+	// +optional
+	Filter *AWSS3SourceFilter `json:"filter,omitempty"`
+
 	// SQS Queue ARN
 	// https://docs.aws.amazon.com/IAM/latest/UserGuide/list_amazonsqs.html#amazonsqs-resources-for-iam-policies
 	//
@@ -71,14 +88,106 @@ type AWSS3SourceSpec struct {
 	// +optional
 	QueueARN *apis.ARN `json:"queueARN,omitempty"`
 
-	// Credentials to interact with the Amazon S3 and SQS APIs.
+	// Whether to add a statement to the access policy of the SQS queue
+	// provided in QueueARN, allowing events to be delivered to it.
+	// Other statements of the policy are preserved, and the statement is
+	// removed when the source is deleted.
+	// Has no effect when QueueARN is not set, in which case the policy of
+	// the automatically created queue is always managed.
+	// +optional
+	ManageQueuePolicy *bool `json:"manageQueuePolicy,omitempty"`
+
+	// Method used by the bucket to deliver events to the SQS queue.
+	// Valid values: [notification, eventbridge]
+	// Defaults to "notification", in which the bucket's event notifications
+	// configuration targets the queue directly. In "eventbridge" mode,
+	// the bucket sends its events to Amazon EventBridge, and an
+	// EventBridge rule forwards them to the queue.
+	// +optional
+	Mode *string `json:"mode,omitempty"`
+
+	// Enrichment of events with information about the S3 object they
+	// refer to. Events are not enriched when unset.
+	// +optional
+	Enrichment *AWSS3SourceEnrichment `json:"enrichment,omitempty"`
+
+	// Credentials to interact with the Amazon S3 and SQS APIs, as well as
+	// the Amazon EventBridge API in "eventbridge" mode.
 	Credentials AWSSecurityCredentials `json:"credentials"`
 }
 
+// AWSS3BucketSelector selects S3 buckets by name prefix and/or tags.
+// A bucket is selected when it matches all the criteria.
+type AWSS3BucketSelector struct {
+	// Prefix shared by the names of all selected buckets.
+	// +optional
+	NamePrefix *string `json:"namePrefix,omitempty"`
+	// Tags which selected buckets must have.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// AWSS3SourceFilter selects the objects for which the source receives events.
+type AWSS3SourceFilter struct {
+	// Prefix that object keys must start with, e.g. "images/".
+	// +optional
+	Prefix *string `json:"prefix,omitempty"`
+	// Suffix that object keys must end with, e.g. ".jpg".
+	// +optional
+	Suffix *string `json:"suffix,omitempty"`
+}
+
+// AWSS3SourceEnrichment defines how events are enriched with information about
+// the S3 object they refer to.
+//
+// The metadata of the object, as returned by the HeadObject API, is always
+// included. Errors which occur during the enrichment of an event are reported
+// in the "s3enrichmenterror" extension attribute of that event.
+type AWSS3SourceEnrichment struct {
+	// Whether to include the tags of the object.
+	// +optional
+	Tags *bool `json:"tags,omitempty"`
+
+	// Maximum size, in bytes, of the objects which content is included
+	// inline, encoded in base64. Content is never inlined when unset.
+	// Limited to 1 MiB, to keep events within the size accepted by most
+	// event brokers.
+	// +optional
+	InlineContentMaxSize *int64 `json:"inlineContentMaxSize,omitempty"`
+
+	// Validity of a presigned URL which allows event consumers to
+	// download the object. This URL is only generated when the content of
+	// the object isn't already included inline.
+	// Expressed as a duration string, which format is documented at https://pkg.go.dev/time#ParseDuration.
+	// +optional
+	PresignedURLExpiration *apis.Duration `json:"presignedURLExpiration,omitempty"`
+}
+
 // AWSS3SourceStatus defines the observed state of the event source.
 type AWSS3SourceStatus struct {
 	EventSourceStatus `json:",inline"`
 	QueueARN          *apis.ARN `json:"queueARN,omitempty"`
+	RuleARN           *apis.ARN `json:"ruleARN,omitempty"`
+
+	// Subscription state of the buckets selected by the source's bucket
+	// selector.
+	// +optional
+	Buckets []AWSS3BucketStatus `json:"buckets,omitempty"`
+}
+
+// AWSS3BucketStatus is the subscription state of a single bucket selected by a
+// bucket selector.
+type AWSS3BucketStatus struct {
+	// Name of the bucket.
+	Name string `json:"name"`
+	// Whether event notifications are configured in the bucket.
+	Subscribed bool `json:"subscribed"`
+	// Reason of the last failure, if any.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message describing the last failure, if any.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
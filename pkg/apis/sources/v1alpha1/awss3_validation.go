@@ -0,0 +1,53 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	"knative.dev/pkg/apis"
+)
+
+// Bucket name which denotes a selection of multiple buckets in the ARN of an
+// AWSS3Source.
+const awsS3WildcardBucketName = "*"
+
+// Validate implements apis.Validatable.
+func (s *AWSS3Source) Validate(ctx context.Context) *apis.FieldError {
+	return s.Spec.Validate(ctx).ViaField("spec")
+}
+
+// Validate implements apis.Validatable.
+func (s *AWSS3SourceSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	isWildcard := s.ARN.Resource == awsS3WildcardBucketName
+
+	switch {
+	case isWildcard && s.BucketSelector == nil:
+		err := apis.ErrMissingField("bucketSelector")
+		err.Details = `A bucket selector is required when the bucket name in "arn" is "*"`
+		errs = errs.Also(err)
+
+	case !isWildcard && s.BucketSelector != nil:
+		err := apis.ErrInvalidValue(s.ARN.String(), "arn")
+		err.Details = `The bucket name must be "*" when a bucket selector is set`
+		errs = errs.Also(err)
+	}
+
+	return errs
+}
@@ -0,0 +1,86 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis"
+)
+
+func TestAWSS3SourceSpecValidate(t *testing.T) {
+	bucketARN := apis.ARN{
+		Partition: "aws",
+		Service:   "s3",
+		Region:    "us-west-2",
+		AccountID: "123456789012",
+		Resource:  "my-bucket",
+	}
+
+	wildcardARN := bucketARN
+	wildcardARN.Resource = "*"
+
+	prefix := "logs-"
+	sel := &AWSS3BucketSelector{NamePrefix: &prefix}
+
+	testCases := map[string]struct {
+		spec      AWSS3SourceSpec
+		expectErr string
+	}{
+		"single bucket": {
+			spec: AWSS3SourceSpec{
+				ARN: bucketARN,
+			},
+		},
+		"bucket selector": {
+			spec: AWSS3SourceSpec{
+				ARN:            wildcardARN,
+				BucketSelector: sel,
+			},
+		},
+		"wildcard bucket name without bucket selector": {
+			spec: AWSS3SourceSpec{
+				ARN: wildcardARN,
+			},
+			expectErr: "missing field(s): bucketSelector\n" +
+				`A bucket selector is required when the bucket name in "arn" is "*"`,
+		},
+		"bucket selector with concrete bucket name": {
+			spec: AWSS3SourceSpec{
+				ARN:            bucketARN,
+				BucketSelector: sel,
+			},
+			expectErr: "invalid value: arn:aws:s3:us-west-2:123456789012:my-bucket: arn\n" +
+				`The bucket name must be "*" when a bucket selector is set`,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			err := tc.spec.Validate(context.Background())
+			if tc.expectErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectErr)
+		})
+	}
+}
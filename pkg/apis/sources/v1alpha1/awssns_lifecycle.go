@@ -78,6 +78,32 @@ func (*AWSSNSSource) IsMultiTenant() bool {
 	return true
 }
 
+// AWSSNSSubscriptionTokenParam is the name of the query parameter which
+// carries the subscription token of a source in the URL of its endpoint.
+const AWSSNSSubscriptionTokenParam = "token"
+
+// SubscriptionToken returns the token which authenticates the deliveries to
+// the source's endpoint that are not signed by SNS, such as raw deliveries.
+func (s *AWSSNSSource) SubscriptionToken() string {
+	return string(s.UID)
+}
+
+// SubscriptionURL returns the URL of the source's endpoint which is subscribed
+// to the SNS topic, or nil if the source isn't addressable yet.
+func (s *AWSSNSSource) SubscriptionURL() *apis.URL {
+	if s.Status.Address == nil || s.Status.Address.URL == nil {
+		return nil
+	}
+
+	u := s.Status.Address.URL.DeepCopy()
+
+	q := u.URL().Query()
+	q.Set(AWSSNSSubscriptionTokenParam, s.SubscriptionToken())
+	u.RawQuery = q.Encode()
+
+	return u
+}
+
 // Status conditions
 const (
 	// AWSSNSConditionSubscribed has status True when the event source's HTTP(S) endpoint has been subscribed to the
@@ -95,6 +121,8 @@ const (
 const (
 	// AWSSNSReasonNoURL is set on a Subscribed condition when the adapter URL is empty.
 	AWSSNSReasonNoURL = "MissingAdapterURL"
+	// AWSSNSReasonInvalidSpec is set on a Subscribed condition when the source's spec fails validation.
+	AWSSNSReasonInvalidSpec = "InvalidSpec"
 	// AWSSNSReasonNoClient is set on a Subscribed condition when a SNS API client cannot be obtained.
 	AWSSNSReasonNoClient = "NoClient"
 	// AWSSNSReasonPending is set on a Subscribed condition when the SNS subscription is pending confirmation.
@@ -59,9 +59,40 @@ type AWSSNSSourceSpec struct {
 	// For a list of supported subscription attributes, please refer to the following resources:
 	//  * https://docs.aws.amazon.com/sns/latest/api/API_SetSubscriptionAttributes.html
 	//  * https://docs.aws.amazon.com/sns/latest/dg/sns-how-it-works.html
+	//
+	// Deprecated: use the typed fields FilterPolicy, FilterPolicyScope,
+	// RawMessageDelivery and DeliveryPolicy instead. Those fields can not be
+	// combined with the corresponding entries of this map.
 	// +optional
 	SubscriptionAttributes map[string]*string `json:"subscriptionAttributes,omitempty"`
 
+	// Rules for filtering the messages delivered to the event source.
+	// https://docs.aws.amazon.com/sns/latest/dg/sns-subscription-filter-policies.html
+	// +optional
+	FilterPolicy *runtime.RawExtension `json:"filterPolicy,omitempty"`
+
+	// Part of the message the filter policy applies to, either
+	// "MessageAttributes" (default) or "MessageBody".
+	// https://docs.aws.amazon.com/sns/latest/dg/sns-message-filtering-scope.html
+	// +optional
+	FilterPolicyScope *string `json:"filterPolicyScope,omitempty"`
+
+	// Whether messages are delivered to the event source without the SNS
+	// notification envelope.
+	// https://docs.aws.amazon.com/sns/latest/dg/sns-large-payload-raw-message-delivery.html
+	// +optional
+	RawMessageDelivery *bool `json:"rawMessageDelivery,omitempty"`
+
+	// Policy that defines how SNS retries failed deliveries to the event source.
+	// https://docs.aws.amazon.com/sns/latest/dg/sns-message-delivery-retries.html
+	// +optional
+	DeliveryPolicy *runtime.RawExtension `json:"deliveryPolicy,omitempty"`
+
+	// Whether events carry the message published to the topic as their
+	// data, instead of the entire SNS notification.
+	// +optional
+	UnwrapMessage bool `json:"unwrapMessage,omitempty"`
+
 	// Credentials to interact with the Amazon SNS API.
 	Credentials AWSSecurityCredentials `json:"credentials"`
 }
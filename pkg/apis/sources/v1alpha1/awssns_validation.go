@@ -0,0 +1,114 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"knative.dev/pkg/apis"
+)
+
+// Scopes of a SNS subscription filter policy.
+// https://docs.aws.amazon.com/sns/latest/dg/sns-message-filtering-scope.html
+const (
+	AWSSNSFilterPolicyScopeMessageAttributes = "MessageAttributes"
+	AWSSNSFilterPolicyScopeMessageBody       = "MessageBody"
+)
+
+// Validate implements apis.Validatable.
+func (s *AWSSNSSource) Validate(ctx context.Context) *apis.FieldError {
+	return s.Spec.Validate(ctx).ViaField("spec")
+}
+
+// Validate implements apis.Validatable.
+func (s *AWSSNSSourceSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	if s.FilterPolicy != nil {
+		errs = errs.Also(validateSNSFilterPolicy(s.FilterPolicy).ViaField("filterPolicy"))
+	}
+
+	if s.FilterPolicyScope != nil {
+		switch *s.FilterPolicyScope {
+		case AWSSNSFilterPolicyScopeMessageAttributes, AWSSNSFilterPolicyScopeMessageBody:
+		default:
+			errs = errs.Also(apis.ErrInvalidValue(*s.FilterPolicyScope, "filterPolicyScope"))
+		}
+	}
+
+	if s.DeliveryPolicy != nil {
+		if _, err := jsonObject(s.DeliveryPolicy); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(err.Error(), "deliveryPolicy"))
+		}
+	}
+
+	typedAttrs := []struct {
+		field string
+		attr  string
+		isSet bool
+	}{
+		{"filterPolicy", "FilterPolicy", s.FilterPolicy != nil},
+		{"filterPolicyScope", "FilterPolicyScope", s.FilterPolicyScope != nil},
+		{"rawMessageDelivery", "RawMessageDelivery", s.RawMessageDelivery != nil},
+		{"deliveryPolicy", "DeliveryPolicy", s.DeliveryPolicy != nil},
+	}
+	for _, a := range typedAttrs {
+		if _, inMap := s.SubscriptionAttributes[a.attr]; a.isSet && inMap {
+			errs = errs.Also(apis.ErrMultipleOneOf(a.field, "subscriptionAttributes."+a.attr))
+		}
+	}
+
+	return errs
+}
+
+// validateSNSFilterPolicy validates the structure of a SNS filter policy.
+// The value of each top-level key must be either a list of matching
+// conditions, or a nested policy in case the policy applies to the message
+// body.
+func validateSNSFilterPolicy(p *runtime.RawExtension) *apis.FieldError {
+	policy, err := jsonObject(p)
+	if err != nil {
+		return apis.ErrInvalidValue(err.Error(), apis.CurrentField)
+	}
+	if len(policy) == 0 {
+		return apis.ErrInvalidValue("policy is empty", apis.CurrentField)
+	}
+
+	var errs *apis.FieldError
+	for k, v := range policy {
+		switch v.(type) {
+		case []interface{}, map[string]interface{}:
+		default:
+			errs = errs.Also(apis.ErrInvalidValue(v, k))
+		}
+	}
+
+	return errs
+}
+
+// jsonObject decodes the given raw JSON document, which is expected to be a
+// JSON object.
+func jsonObject(r *runtime.RawExtension) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(r.Raw, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
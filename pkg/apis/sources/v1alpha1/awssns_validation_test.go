@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestAWSSNSSourceSpecValidate(t *testing.T) {
+	rawJSON := func(s string) *runtime.RawExtension {
+		return &runtime.RawExtension{Raw: []byte(s)}
+	}
+	str := func(s string) *string { return &s }
+	rawDelivery := true
+
+	testCases := map[string]struct {
+		spec      AWSSNSSourceSpec
+		expectErr string
+	}{
+		"no subscription attribute": {
+			spec: AWSSNSSourceSpec{},
+		},
+		"valid typed attributes": {
+			spec: AWSSNSSourceSpec{
+				FilterPolicy:       rawJSON(`{"store":["example_corp"],"price_usd":[{"numeric":[">=",100]}]}`),
+				FilterPolicyScope:  str(AWSSNSFilterPolicyScopeMessageAttributes),
+				RawMessageDelivery: &rawDelivery,
+				DeliveryPolicy:     rawJSON(`{"healthyRetryPolicy":{"numRetries":5}}`),
+			},
+		},
+		"nested filter policy": {
+			spec: AWSSNSSourceSpec{
+				FilterPolicy:      rawJSON(`{"customer":{"interests":["rugby"]}}`),
+				FilterPolicyScope: str(AWSSNSFilterPolicyScopeMessageBody),
+			},
+		},
+		"filter policy is not an object": {
+			spec: AWSSNSSourceSpec{
+				FilterPolicy: rawJSON(`["blue"]`),
+			},
+			expectErr: "invalid value: json: cannot unmarshal array into Go value of type map[string]interface {}: " +
+				"filterPolicy",
+		},
+		"empty filter policy": {
+			spec: AWSSNSSourceSpec{
+				FilterPolicy: rawJSON(`{}`),
+			},
+			expectErr: "invalid value: policy is empty: filterPolicy",
+		},
+		"invalid filter policy condition": {
+			spec: AWSSNSSourceSpec{
+				FilterPolicy: rawJSON(`{"color":"blue"}`),
+			},
+			expectErr: "invalid value: blue: filterPolicy.color",
+		},
+		"invalid filter policy scope": {
+			spec: AWSSNSSourceSpec{
+				FilterPolicy:      rawJSON(`{"color":["blue"]}`),
+				FilterPolicyScope: str("MessageHeaders"),
+			},
+			expectErr: "invalid value: MessageHeaders: filterPolicyScope",
+		},
+		"delivery policy is not an object": {
+			spec: AWSSNSSourceSpec{
+				DeliveryPolicy: rawJSON(`5`),
+			},
+			expectErr: "invalid value: json: cannot unmarshal number into Go value of type map[string]interface {}: " +
+				"deliveryPolicy",
+		},
+		"typed attribute also set in map": {
+			spec: AWSSNSSourceSpec{
+				SubscriptionAttributes: map[string]*string{
+					"RawMessageDelivery": str("false"),
+				},
+				RawMessageDelivery: &rawDelivery,
+			},
+			expectErr: "expected exactly one, got both: rawMessageDelivery, subscriptionAttributes.RawMessageDelivery",
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			err := tc.spec.Validate(context.Background())
+			if tc.expectErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectErr)
+		})
+	}
+}
@@ -67,3 +67,27 @@ func (s *AWSSQSSource) GetEventTypes() []string {
 func (s *AWSSQSSource) AsEventSource() string {
 	return s.Spec.ARN.String()
 }
+
+// IsMultiTenant implements MultiTenant.
+func (s *AWSSQSSource) IsMultiTenant() bool {
+	return optsIntoMultiTenancy(s)
+}
+
+// Status conditions
+const (
+	// AWSSQSConditionReceiverStarted indicates that the multi-tenant
+	// adapter started receiving messages from the source's queue.
+	// It is not part of the ConditionSet registered for the AWSSQSSource
+	// type, and will therefore automatically be propagated by Knative with
+	// a severity of "Info".
+	AWSSQSConditionReceiverStarted = "ReceiverStarted"
+)
+
+// Reasons for status conditions
+const (
+	// AWSSQSReasonNoClient is set on a ReceiverStarted condition when a SQS API client cannot be obtained.
+	AWSSQSReasonNoClient = "NoClient"
+	// AWSSQSReasonQueueNotFound is set on a ReceiverStarted condition when the URL of the SQS queue can not be
+	// determined.
+	AWSSQSReasonQueueNotFound = "QueueNotFound"
+)
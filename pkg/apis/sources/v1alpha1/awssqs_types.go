@@ -42,6 +42,7 @@ type AWSSQSSource struct {
 var (
 	_ runtime.Object = (*AWSSQSSource)(nil)
 	_ EventSource    = (*AWSSQSSource)(nil)
+	_ multiTenant    = (*AWSSQSSource)(nil)
 )
 
 // AWSSQSSourceSpec defines the desired state of the event source.
@@ -28,6 +28,11 @@ const (
 	ConditionSinkProvided apis.ConditionType = "SinkProvided"
 	// ConditionDeployed has status True when the source's adapter is up and running.
 	ConditionDeployed apis.ConditionType = "Deployed"
+
+	// ConditionPollerStarted has status True when the multi-tenant adapter of a polling source started polling
+	// on behalf of that source. It is not part of any ConditionSet, and is therefore automatically propagated by
+	// Knative with a severity of "Info".
+	ConditionPollerStarted apis.ConditionType = "PollerStarted"
 )
 
 // Reasons for status conditions
@@ -42,4 +47,9 @@ const (
 	ReasonRBACNotBound = "RBACNotBound"
 	// ReasonUnavailable is set on a Deployed condition when an adapter in unavailable.
 	ReasonUnavailable = "AdapterUnavailable"
+
+	// ReasonPollerNotStarted is set on a PollerStarted condition when the poller of a source can not be created.
+	ReasonPollerNotStarted = "PollerNotStarted"
+	// ReasonPollerFailed is set on a PollerStarted condition when the poller of a source stopped unexpectedly.
+	ReasonPollerFailed = "PollerFailed"
 )
@@ -27,6 +27,32 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSCloudWatchAlarmSelector) DeepCopyInto(out *AWSCloudWatchAlarmSelector) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamePrefix != nil {
+		in, out := &in.NamePrefix, &out.NamePrefix
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSCloudWatchAlarmSelector.
+func (in *AWSCloudWatchAlarmSelector) DeepCopy() *AWSCloudWatchAlarmSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSCloudWatchAlarmSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSCloudWatchLogsSource) DeepCopyInto(out *AWSCloudWatchLogsSource) {
 	*out = *in
@@ -149,6 +175,55 @@ func (in *AWSCloudWatchMetricDimension) DeepCopy() *AWSCloudWatchMetricDimension
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSCloudWatchMetricDimensionFilter) DeepCopyInto(out *AWSCloudWatchMetricDimensionFilter) {
+	*out = *in
+	if in.Value != nil {
+		in, out := &in.Value, &out.Value
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSCloudWatchMetricDimensionFilter.
+func (in *AWSCloudWatchMetricDimensionFilter) DeepCopy() *AWSCloudWatchMetricDimensionFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSCloudWatchMetricDimensionFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSCloudWatchMetricDiscovery) DeepCopyInto(out *AWSCloudWatchMetricDiscovery) {
+	*out = *in
+	if in.MetricName != nil {
+		in, out := &in.MetricName, &out.MetricName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Dimensions != nil {
+		in, out := &in.Dimensions, &out.Dimensions
+		*out = make([]AWSCloudWatchMetricDimensionFilter, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSCloudWatchMetricDiscovery.
+func (in *AWSCloudWatchMetricDiscovery) DeepCopy() *AWSCloudWatchMetricDiscovery {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSCloudWatchMetricDiscovery)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSCloudWatchMetricQuery) DeepCopyInto(out *AWSCloudWatchMetricQuery) {
 	*out = *in
@@ -162,6 +237,11 @@ func (in *AWSCloudWatchMetricQuery) DeepCopyInto(out *AWSCloudWatchMetricQuery)
 		*out = new(AWSCloudWatchMetricStat)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Discovery != nil {
+		in, out := &in.Discovery, &out.Discovery
+		*out = new(AWSCloudWatchMetricDiscovery)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -269,6 +349,21 @@ func (in *AWSCloudWatchSourceSpec) DeepCopyInto(out *AWSCloudWatchSourceSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SplitDatapoints != nil {
+		in, out := &in.SplitDatapoints, &out.SplitDatapoints
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AggregationLag != nil {
+		in, out := &in.AggregationLag, &out.AggregationLag
+		*out = new(apis.Duration)
+		**out = **in
+	}
+	if in.Alarms != nil {
+		in, out := &in.Alarms, &out.Alarms
+		*out = new(AWSCloudWatchAlarmSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Credentials.DeepCopyInto(&out.Credentials)
 	return
 }
@@ -283,6 +378,23 @@ func (in *AWSCloudWatchSourceSpec) DeepCopy() *AWSCloudWatchSourceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSCloudWatchSourceStatus) DeepCopyInto(out *AWSCloudWatchSourceStatus) {
+	*out = *in
+	in.EventSourceStatus.DeepCopyInto(&out.EventSourceStatus)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSCloudWatchSourceStatus.
+func (in *AWSCloudWatchSourceStatus) DeepCopy() *AWSCloudWatchSourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSCloudWatchSourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSCodeCommitSource) DeepCopyInto(out *AWSCodeCommitSource) {
 	*out = *in
@@ -349,11 +461,21 @@ func (in *AWSCodeCommitSourceSpec) DeepCopyInto(out *AWSCodeCommitSourceSpec) {
 	*out = *in
 	in.SourceSpec.DeepCopyInto(&out.SourceSpec)
 	out.ARN = in.ARN
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.EventTypes != nil {
 		in, out := &in.EventTypes, &out.EventTypes
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Mode != nil {
+		in, out := &in.Mode, &out.Mode
+		*out = new(string)
+		**out = **in
+	}
 	in.Credentials.DeepCopyInto(&out.Credentials)
 	return
 }
@@ -368,6 +490,28 @@ func (in *AWSCodeCommitSourceSpec) DeepCopy() *AWSCodeCommitSourceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSCodeCommitSourceStatus) DeepCopyInto(out *AWSCodeCommitSourceStatus) {
+	*out = *in
+	in.EventSourceStatus.DeepCopyInto(&out.EventSourceStatus)
+	if in.QueueARN != nil {
+		in, out := &in.QueueARN, &out.QueueARN
+		*out = new(apis.ARN)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSCodeCommitSourceStatus.
+func (in *AWSCodeCommitSourceStatus) DeepCopy() *AWSCodeCommitSourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSCodeCommitSourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSCognitoIdentitySource) DeepCopyInto(out *AWSCognitoIdentitySource) {
 	*out = *in
@@ -514,6 +658,11 @@ func (in *AWSCognitoUserPoolSourceSpec) DeepCopyInto(out *AWSCognitoUserPoolSour
 	*out = *in
 	in.SourceSpec.DeepCopyInto(&out.SourceSpec)
 	out.ARN = in.ARN
+	if in.Triggers != nil {
+		in, out := &in.Triggers, &out.Triggers
+		*out = new(AWSCognitoUserPoolTriggers)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Credentials.DeepCopyInto(&out.Credentials)
 	return
 }
@@ -528,6 +677,23 @@ func (in *AWSCognitoUserPoolSourceSpec) DeepCopy() *AWSCognitoUserPoolSourceSpec
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSCognitoUserPoolTriggers) DeepCopyInto(out *AWSCognitoUserPoolTriggers) {
+	*out = *in
+	in.SharedSecret.DeepCopyInto(&out.SharedSecret)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSCognitoUserPoolTriggers.
+func (in *AWSCognitoUserPoolTriggers) DeepCopy() *AWSCognitoUserPoolTriggers {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSCognitoUserPoolTriggers)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSDynamoDBSource) DeepCopyInto(out *AWSDynamoDBSource) {
 	*out = *in
@@ -595,6 +761,11 @@ func (in *AWSDynamoDBSourceSpec) DeepCopyInto(out *AWSDynamoDBSourceSpec) {
 	in.SourceSpec.DeepCopyInto(&out.SourceSpec)
 	out.ARN = in.ARN
 	in.Credentials.DeepCopyInto(&out.Credentials)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -675,6 +846,11 @@ func (in *AWSKinesisSourceSpec) DeepCopyInto(out *AWSKinesisSourceSpec) {
 	in.SourceSpec.DeepCopyInto(&out.SourceSpec)
 	out.ARN = in.ARN
 	in.Credentials.DeepCopyInto(&out.Credentials)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -688,6 +864,105 @@ func (in *AWSKinesisSourceSpec) DeepCopy() *AWSKinesisSourceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPerformanceInsightsDimensionGroup) DeepCopyInto(out *AWSPerformanceInsightsDimensionGroup) {
+	*out = *in
+	if in.Dimensions != nil {
+		in, out := &in.Dimensions, &out.Dimensions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Limit != nil {
+		in, out := &in.Limit, &out.Limit
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPerformanceInsightsDimensionGroup.
+func (in *AWSPerformanceInsightsDimensionGroup) DeepCopy() *AWSPerformanceInsightsDimensionGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPerformanceInsightsDimensionGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPerformanceInsightsDimensionKeysQuery) DeepCopyInto(out *AWSPerformanceInsightsDimensionKeysQuery) {
+	*out = *in
+	in.GroupBy.DeepCopyInto(&out.GroupBy)
+	if in.PartitionBy != nil {
+		in, out := &in.PartitionBy, &out.PartitionBy
+		*out = new(AWSPerformanceInsightsDimensionGroup)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPerformanceInsightsDimensionKeysQuery.
+func (in *AWSPerformanceInsightsDimensionKeysQuery) DeepCopy() *AWSPerformanceInsightsDimensionKeysQuery {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPerformanceInsightsDimensionKeysQuery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPerformanceInsightsInstance) DeepCopyInto(out *AWSPerformanceInsightsInstance) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPerformanceInsightsInstance.
+func (in *AWSPerformanceInsightsInstance) DeepCopy() *AWSPerformanceInsightsInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPerformanceInsightsInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPerformanceInsightsMetricQuery) DeepCopyInto(out *AWSPerformanceInsightsMetricQuery) {
+	*out = *in
+	if in.GroupBy != nil {
+		in, out := &in.GroupBy, &out.GroupBy
+		*out = new(AWSPerformanceInsightsDimensionGroup)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPerformanceInsightsMetricQuery.
+func (in *AWSPerformanceInsightsMetricQuery) DeepCopy() *AWSPerformanceInsightsMetricQuery {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPerformanceInsightsMetricQuery)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSPerformanceInsightsSource) DeepCopyInto(out *AWSPerformanceInsightsSource) {
 	*out = *in
@@ -754,11 +1029,30 @@ func (in *AWSPerformanceInsightsSourceSpec) DeepCopyInto(out *AWSPerformanceInsi
 	*out = *in
 	in.SourceSpec.DeepCopyInto(&out.SourceSpec)
 	out.ARN = in.ARN
+	if in.Period != nil {
+		in, out := &in.Period, &out.Period
+		*out = new(int64)
+		**out = **in
+	}
 	if in.Metrics != nil {
 		in, out := &in.Metrics, &out.Metrics
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.MetricQueries != nil {
+		in, out := &in.MetricQueries, &out.MetricQueries
+		*out = make([]AWSPerformanceInsightsMetricQuery, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TopDimensions != nil {
+		in, out := &in.TopDimensions, &out.TopDimensions
+		*out = make([]AWSPerformanceInsightsDimensionKeysQuery, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Credentials.DeepCopyInto(&out.Credentials)
 	return
 }
@@ -773,6 +1067,72 @@ func (in *AWSPerformanceInsightsSourceSpec) DeepCopy() *AWSPerformanceInsightsSo
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPerformanceInsightsSourceStatus) DeepCopyInto(out *AWSPerformanceInsightsSourceStatus) {
+	*out = *in
+	in.EventSourceStatus.DeepCopyInto(&out.EventSourceStatus)
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]AWSPerformanceInsightsInstance, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPerformanceInsightsSourceStatus.
+func (in *AWSPerformanceInsightsSourceStatus) DeepCopy() *AWSPerformanceInsightsSourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPerformanceInsightsSourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3BucketSelector) DeepCopyInto(out *AWSS3BucketSelector) {
+	*out = *in
+	if in.NamePrefix != nil {
+		in, out := &in.NamePrefix, &out.NamePrefix
+		*out = new(string)
+		**out = **in
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3BucketSelector.
+func (in *AWSS3BucketSelector) DeepCopy() *AWSS3BucketSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3BucketSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3BucketStatus) DeepCopyInto(out *AWSS3BucketStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3BucketStatus.
+func (in *AWSS3BucketStatus) DeepCopy() *AWSS3BucketStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3BucketStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSS3Source) DeepCopyInto(out *AWSS3Source) {
 	*out = *in
@@ -801,6 +1161,63 @@ func (in *AWSS3Source) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceEnrichment) DeepCopyInto(out *AWSS3SourceEnrichment) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = new(bool)
+		**out = **in
+	}
+	if in.InlineContentMaxSize != nil {
+		in, out := &in.InlineContentMaxSize, &out.InlineContentMaxSize
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PresignedURLExpiration != nil {
+		in, out := &in.PresignedURLExpiration, &out.PresignedURLExpiration
+		*out = new(apis.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceEnrichment.
+func (in *AWSS3SourceEnrichment) DeepCopy() *AWSS3SourceEnrichment {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceEnrichment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSS3SourceFilter) DeepCopyInto(out *AWSS3SourceFilter) {
+	*out = *in
+	if in.Prefix != nil {
+		in, out := &in.Prefix, &out.Prefix
+		*out = new(string)
+		**out = **in
+	}
+	if in.Suffix != nil {
+		in, out := &in.Suffix, &out.Suffix
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSS3SourceFilter.
+func (in *AWSS3SourceFilter) DeepCopy() *AWSS3SourceFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSS3SourceFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSS3SourceList) DeepCopyInto(out *AWSS3SourceList) {
 	*out = *in
@@ -839,16 +1256,41 @@ func (in *AWSS3SourceSpec) DeepCopyInto(out *AWSS3SourceSpec) {
 	*out = *in
 	in.SourceSpec.DeepCopyInto(&out.SourceSpec)
 	out.ARN = in.ARN
+	if in.BucketSelector != nil {
+		in, out := &in.BucketSelector, &out.BucketSelector
+		*out = new(AWSS3BucketSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.EventTypes != nil {
 		in, out := &in.EventTypes, &out.EventTypes
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(AWSS3SourceFilter)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.QueueARN != nil {
 		in, out := &in.QueueARN, &out.QueueARN
 		*out = new(apis.ARN)
 		**out = **in
 	}
+	if in.ManageQueuePolicy != nil {
+		in, out := &in.ManageQueuePolicy, &out.ManageQueuePolicy
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Mode != nil {
+		in, out := &in.Mode, &out.Mode
+		*out = new(string)
+		**out = **in
+	}
+	if in.Enrichment != nil {
+		in, out := &in.Enrichment, &out.Enrichment
+		*out = new(AWSS3SourceEnrichment)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Credentials.DeepCopyInto(&out.Credentials)
 	return
 }
@@ -872,6 +1314,16 @@ func (in *AWSS3SourceStatus) DeepCopyInto(out *AWSS3SourceStatus) {
 		*out = new(apis.ARN)
 		**out = **in
 	}
+	if in.RuleARN != nil {
+		in, out := &in.RuleARN, &out.RuleARN
+		*out = new(apis.ARN)
+		**out = **in
+	}
+	if in.Buckets != nil {
+		in, out := &in.Buckets, &out.Buckets
+		*out = make([]AWSS3BucketStatus, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -966,6 +1418,26 @@ func (in *AWSSNSSourceSpec) DeepCopyInto(out *AWSSNSSourceSpec) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.FilterPolicy != nil {
+		in, out := &in.FilterPolicy, &out.FilterPolicy
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FilterPolicyScope != nil {
+		in, out := &in.FilterPolicyScope, &out.FilterPolicyScope
+		*out = new(string)
+		**out = **in
+	}
+	if in.RawMessageDelivery != nil {
+		in, out := &in.RawMessageDelivery, &out.RawMessageDelivery
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DeliveryPolicy != nil {
+		in, out := &in.DeliveryPolicy, &out.DeliveryPolicy
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Credentials.DeepCopyInto(&out.Credentials)
 	return
 }
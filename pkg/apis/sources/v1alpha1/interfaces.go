@@ -58,6 +58,18 @@ func IsMultiTenant(src EventSource) bool {
 	return ok && mt.IsMultiTenant()
 }
 
+// MultiTenantAnnotation is the annotation which, when set to "true" on a
+// source object, opts that source into being served by the multi-tenant
+// adapter of its namespace instead of a dedicated adapter. It is only honored
+// by source types which support both kinds of adapters.
+const MultiTenantAnnotation = "sources.triggermesh.io/multiTenant"
+
+// optsIntoMultiTenancy returns whether the given object is annotated for
+// being served by a multi-tenant adapter.
+func optsIntoMultiTenancy(obj metav1.Object) bool {
+	return obj.GetAnnotations()[MultiTenantAnnotation] == "true"
+}
+
 type sourceKey struct{}
 
 // WithSource returns a copy of the parent context in which the value
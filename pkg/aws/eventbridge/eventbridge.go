@@ -0,0 +1,89 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventbridge contains helpers for Amazon EventBridge.
+package eventbridge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+)
+
+// OwnerTagKey is the key of the tag which identifies the owner of a rule.
+const OwnerTagKey = "owned-by"
+
+// EnsureRuleTarget ensures that the resource with the given ARN is a target of
+// the rule with the given name, under the given target ID.
+func EnsureRuleTarget(ctx context.Context, cli eventbridgeiface.EventBridgeAPI,
+	ruleName, targetID, targetARN string) error {
+
+	targets, err := cli.ListTargetsByRuleWithContext(ctx, &eventbridge.ListTargetsByRuleInput{
+		Rule: &ruleName,
+	})
+	if err != nil {
+		return fmt.Errorf("listing targets: %w", err)
+	}
+
+	for _, t := range targets.Targets {
+		if aws.StringValue(t.Id) == targetID && aws.StringValue(t.Arn) == targetARN {
+			return nil
+		}
+	}
+
+	out, err := cli.PutTargetsWithContext(ctx, &eventbridge.PutTargetsInput{
+		Rule: &ruleName,
+		Targets: []*eventbridge.Target{{
+			Id:  &targetID,
+			Arn: &targetARN,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("putting targets: %w", err)
+	}
+
+	if aws.Int64Value(out.FailedEntryCount) > 0 {
+		entry := out.FailedEntries[0]
+		return awserr.New(aws.StringValue(entry.ErrorCode), aws.StringValue(entry.ErrorMessage), nil)
+	}
+
+	return nil
+}
+
+// AssertRuleOwnership returns whether the rule with the given ARN is tagged as
+// owned by the given owner.
+func AssertRuleOwnership(ctx context.Context, cli eventbridgeiface.EventBridgeAPI,
+	ruleARN, owner string) (bool, error) {
+
+	out, err := cli.ListTagsForResourceWithContext(ctx, &eventbridge.ListTagsForResourceInput{
+		ResourceARN: &ruleARN,
+	})
+	if err != nil {
+		return false, fmt.Errorf("listing tags of EventBridge rule: %w", err)
+	}
+
+	for _, t := range out.Tags {
+		if aws.StringValue(t.Key) == OwnerTagKey {
+			return aws.StringValue(t.Value) == owner, nil
+		}
+	}
+
+	return false, nil
+}
@@ -57,6 +57,7 @@ type PolicyStatementPrincipal struct {
 // See https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_elements_condition_operators.html
 type PolicyStatementCondition struct {
 	ArnEquals    map[string]string `json:"ArnEquals,omitempty"`
+	ArnLike      map[string]string `json:"ArnLike,omitempty"`
 	StringEquals map[string]string `json:"StringEquals,omitempty"`
 }
 
@@ -87,6 +88,14 @@ func NewPolicyStatement(effect PolicyStatementEffect, opts ...PolicyStatementOpt
 // PolicyStatementOpt is a functional option for a PolicyStatement.
 type PolicyStatementOpt func(*PolicyStatement)
 
+// StatementID sets the Sid of a PolicyStatement, which otherwise defaults to
+// a random UUID.
+func StatementID(sid string) PolicyStatementOpt {
+	return func(s *PolicyStatement) {
+		s.Sid = sid
+	}
+}
+
 // PrincipalService adds a "Service" to the Principal.
 func PrincipalService(service string) PolicyStatementOpt {
 	return func(s *PolicyStatement) {
@@ -133,6 +142,18 @@ func ConditionArnEquals(key, val string) PolicyStatementOpt {
 	}
 }
 
+// ConditionArnLike sets a Condition of type "ArnLike".
+func ConditionArnLike(key, val string) PolicyStatementOpt {
+	return func(s *PolicyStatement) {
+		alc := &s.Condition.ArnLike
+		if *alc == nil {
+			valAlc := make(map[string]string, 1)
+			*alc = valAlc
+		}
+		(*alc)[key] = val
+	}
+}
+
 // ConditionStringEquals sets a Condition of type "StringEquals".
 func ConditionStringEquals(key, val string) PolicyStatementOpt {
 	return func(s *PolicyStatement) {
@@ -28,6 +28,9 @@ import (
 	"github.com/triggermesh/aws-event-sources/pkg/aws/iam"
 )
 
+// OwnerTagKey is the key of the tag which identifies the owner of a queue.
+const OwnerTagKey = "owned-by"
+
 // CreateQueue creates a queue with the given name and optional tags.
 //
 // Naming restrictions are described at https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_CreateQueue.html
@@ -54,10 +57,16 @@ func SetQueuePolicy(cli sqsiface.SQSAPI, url string, pol iam.Policy) error {
 		return fmt.Errorf("serializing queue policy to JSON: %w", err)
 	}
 
+	return SetQueuePolicyDocument(cli, url, string(polJson))
+}
+
+// SetQueuePolicyDocument sets the Policy attribute of the queue with the given
+// URL to the given JSON policy document.
+func SetQueuePolicyDocument(cli sqsiface.SQSAPI, url, doc string) error {
 	attrs := &sqs.SetQueueAttributesInput{
 		QueueUrl: &url,
 		Attributes: aws.StringMap(map[string]string{
-			sqs.QueueAttributeNamePolicy: string(polJson),
+			sqs.QueueAttributeNamePolicy: doc,
 		}),
 	}
 
@@ -137,6 +146,22 @@ func QueueURL(cli sqsiface.SQSAPI, name string) (string /*url*/, error) {
 	return *resp.QueueUrl, nil
 }
 
+// QueueURLWithOwner returns the URL of the queue identified by name, which
+// belongs to the given AWS account.
+func QueueURLWithOwner(cli sqsiface.SQSAPI, name, accountID string) (string /*url*/, error) {
+	queue := &sqs.GetQueueUrlInput{
+		QueueName:              &name,
+		QueueOwnerAWSAccountId: &accountID,
+	}
+
+	resp, err := cli.GetQueueUrl(queue)
+	if err != nil {
+		return "", fmt.Errorf("getting URL of queue %q: %w", *queue.QueueName, err)
+	}
+
+	return *resp.QueueUrl, nil
+}
+
 // QueueTags returns the tags of the queue with the given URL.
 func QueueTags(cli sqsiface.SQSAPI, url string) (map[string]string, error) {
 	queue := &sqs.ListQueueTagsInput{
@@ -150,3 +175,14 @@ func QueueTags(cli sqsiface.SQSAPI, url string) (map[string]string, error) {
 
 	return aws.StringValueMap(resp.Tags), nil
 }
+
+// AssertQueueOwnership returns whether the queue with the given URL is tagged
+// as owned by the given owner.
+func AssertQueueOwnership(cli sqsiface.SQSAPI, url, owner string) (bool, error) {
+	tags, err := QueueTags(cli, url)
+	if err != nil {
+		return false, err
+	}
+
+	return tags[OwnerTagKey] == owner, nil
+}
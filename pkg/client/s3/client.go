@@ -24,6 +24,8 @@ import (
 	awscore "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/sqs"
@@ -36,12 +38,15 @@ import (
 // Client is an alias for the S3API interface.
 type Client = s3iface.S3API
 
-// SQSClient is an alias for the S3API interface.
+// SQSClient is an alias for the SQSAPI interface.
 type SQSClient = sqsiface.SQSAPI
 
-// ClientGetter can obtain S3 and SQS clients.
+// EventBridgeClient is an alias for the EventBridgeAPI interface.
+type EventBridgeClient = eventbridgeiface.EventBridgeAPI
+
+// ClientGetter can obtain S3, SQS and EventBridge clients.
 type ClientGetter interface {
-	Get(*v1alpha1.AWSS3Source) (Client, SQSClient, error)
+	Get(*v1alpha1.AWSS3Source) (Client, SQSClient, EventBridgeClient, error)
 }
 
 // NewClientGetter returns a ClientGetter for the given secrets getter.
@@ -63,10 +68,10 @@ type ClientGetterWithSecretGetter struct {
 var _ ClientGetter = (*ClientGetterWithSecretGetter)(nil)
 
 // Get implements ClientGetter.
-func (g *ClientGetterWithSecretGetter) Get(src *v1alpha1.AWSS3Source) (Client, SQSClient, error) {
+func (g *ClientGetterWithSecretGetter) Get(src *v1alpha1.AWSS3Source) (Client, SQSClient, EventBridgeClient, error) {
 	creds, err := aws.Credentials(g.sg(src.Namespace), &src.Spec.Credentials)
 	if err != nil {
-		return nil, nil, fmt.Errorf("retrieving AWS security credentials: %w", err)
+		return nil, nil, nil, fmt.Errorf("retrieving AWS security credentials: %w", err)
 	}
 
 	sess := session.Must(session.NewSession(awscore.NewConfig().
@@ -74,16 +79,16 @@ func (g *ClientGetterWithSecretGetter) Get(src *v1alpha1.AWSS3Source) (Client, S
 		WithCredentials(credentials.NewStaticCredentialsFromCreds(*creds)),
 	))
 
-	return s3.New(sess), sqs.New(sess), nil
+	return s3.New(sess), sqs.New(sess), eventbridge.New(sess), nil
 }
 
 // ClientGetterFunc allows the use of ordinary functions as ClientGetter.
-type ClientGetterFunc func(*v1alpha1.AWSS3Source) (Client, SQSClient, error)
+type ClientGetterFunc func(*v1alpha1.AWSS3Source) (Client, SQSClient, EventBridgeClient, error)
 
 // ClientGetterFunc implements ClientGetter.
 var _ ClientGetter = (ClientGetterFunc)(nil)
 
 // Get implements ClientGetter.
-func (f ClientGetterFunc) Get(src *v1alpha1.AWSS3Source) (Client, SQSClient, error) {
+func (f ClientGetterFunc) Get(src *v1alpha1.AWSS3Source) (Client, SQSClient, EventBridgeClient, error) {
 	return f(src)
 }
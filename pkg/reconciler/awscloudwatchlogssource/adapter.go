@@ -36,6 +36,12 @@ const envPollingInterval = "POLLING_INTERVAL"
 
 const defaultPollingInterval = 5 * time.Minute
 
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "CLOUDWATCHLOGS_MULTI_TENANT"
+
+const healthPortName = "health"
+
 // adapterConfig contains properties used to configure the source's adapter.
 // These are automatically populated by envconfig.
 type adapterConfig struct {
@@ -50,6 +56,10 @@ var _ common.AdapterDeploymentBuilder = (*Reconciler)(nil)
 
 // BuildAdapter implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *appsv1.Deployment {
+	if v1alpha1.IsMultiTenant(src) {
+		return r.buildMTAdapter(src)
+	}
+
 	typedSrc := src.(*v1alpha1.AWSCloudWatchLogsSource)
 
 	pollingInterval := defaultPollingInterval
@@ -67,6 +77,21 @@ func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *
 	)
 }
 
+// buildMTAdapter returns the multi-tenant adapter which polls the log groups of
+// all sources that opted into multi-tenancy in the namespace of the given
+// source.
+func (r *Reconciler) buildMTAdapter(src v1alpha1.EventSource) *appsv1.Deployment {
+	return common.NewMTAdapterDeployment(src,
+		resource.Image(r.adapterCfg.Image),
+
+		resource.EnvVar(envMultiTenant, "true"),
+		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
+
+		resource.Port(healthPortName, 8080),
+		resource.Probe("/health", healthPortName),
+	)
+}
+
 // RBACOwners implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) RBACOwners(namespace string) ([]kmeta.OwnerRefable, error) {
 	srcs, err := r.srcLister(namespace).List(labels.Everything())
@@ -45,10 +45,20 @@ func TestReconcileSource(t *testing.T) {
 	}
 
 	ctor := reconcilerCtor(adapterCfg)
-	src := newEventSource()
 	ab := adapterBuilder(adapterCfg)
 
-	TestReconcileAdapter(t, ctor, src, ab)
+	t.Run("Single-tenant", func(t *testing.T) {
+		TestReconcileAdapter(t, ctor, newEventSource(), ab)
+	})
+
+	t.Run("Multi-tenant", func(t *testing.T) {
+		src := newEventSource()
+		src.Annotations = map[string]string{
+			v1alpha1.MultiTenantAnnotation: "true",
+		}
+
+		TestReconcileAdapter(t, ctor, src, ab)
+	})
 }
 
 // reconcilerCtor returns a Ctor for a AWSCloudWatchLogsSource Reconciler.
@@ -19,6 +19,7 @@ package awscloudwatchsource
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -36,11 +37,20 @@ import (
 const (
 	envRegion          = "AWS_REGION"
 	envQueries         = "QUERIES"
+	envAlarms          = "ALARMS"
 	envPollingInterval = "POLLING_INTERVAL"
+	envSplitDatapoints = "SPLIT_DATAPOINTS"
+	envAggregationLag  = "AGGREGATION_LAG"
 )
 
 const defaultPollingInterval = 5 * time.Minute
 
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "CLOUDWATCH_MULTI_TENANT"
+
+const healthPortName = "health"
+
 // adapterConfig contains properties used to configure the source's adapter.
 // These are automatically populated by envconfig.
 type adapterConfig struct {
@@ -55,6 +65,10 @@ var _ common.AdapterDeploymentBuilder = (*Reconciler)(nil)
 
 // BuildAdapter implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *appsv1.Deployment {
+	if v1alpha1.IsMultiTenant(src) {
+		return r.buildMTAdapter(src)
+	}
+
 	typedSrc := src.(*v1alpha1.AWSCloudWatchSource)
 
 	var queries string
@@ -63,17 +77,36 @@ func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *
 		queries = string(q)
 	}
 
+	var alarms string
+	if as := typedSrc.Spec.Alarms; as != nil {
+		a, _ := json.Marshal(as)
+		alarms = string(a)
+	}
+
 	pollingInterval := defaultPollingInterval
 	if f := typedSrc.Spec.PollingInterval; f != nil && time.Duration(*f).Nanoseconds() > 0 {
 		pollingInterval = time.Duration(*f)
 	}
 
+	var splitDatapoints bool
+	if split := typedSrc.Spec.SplitDatapoints; split != nil {
+		splitDatapoints = *split
+	}
+
+	var aggregationLag time.Duration
+	if l := typedSrc.Spec.AggregationLag; l != nil && time.Duration(*l).Nanoseconds() > 0 {
+		aggregationLag = time.Duration(*l)
+	}
+
 	return common.NewAdapterDeployment(src, sinkURI,
 		resource.Image(r.adapterCfg.Image),
 
 		resource.EnvVar(envRegion, typedSrc.Spec.Region),
 		resource.EnvVar(envQueries, queries),
+		resource.EnvVar(envAlarms, alarms),
 		resource.EnvVar(envPollingInterval, pollingInterval.String()),
+		resource.EnvVar(envSplitDatapoints, strconv.FormatBool(splitDatapoints)),
+		resource.EnvVar(envAggregationLag, aggregationLag.String()),
 		resource.EnvVars(common.MakeSecurityCredentialsEnvVars(typedSrc.Spec.Credentials)...),
 		resource.EnvVar(common.EnvNamespace, src.GetNamespace()),
 		resource.EnvVar(common.EnvName, src.GetName()),
@@ -81,6 +114,21 @@ func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *
 	)
 }
 
+// buildMTAdapter returns the multi-tenant adapter which polls the metrics and alarms of
+// all sources that opted into multi-tenancy in the namespace of the given
+// source.
+func (r *Reconciler) buildMTAdapter(src v1alpha1.EventSource) *appsv1.Deployment {
+	return common.NewMTAdapterDeployment(src,
+		resource.Image(r.adapterCfg.Image),
+
+		resource.EnvVar(envMultiTenant, "true"),
+		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
+
+		resource.Port(healthPortName, 8080),
+		resource.Probe("/health", healthPortName),
+	)
+}
+
 // RBACOwners implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) RBACOwners(namespace string) ([]kmeta.OwnerRefable, error) {
 	srcs, err := r.srcLister(namespace).List(labels.Everything())
@@ -24,6 +24,7 @@ import (
 	"knative.dev/eventing/pkg/reconciler/source"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
 
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
 	informerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/informers/sources/v1alpha1/awscloudwatchsource"
@@ -62,6 +63,12 @@ func NewController(
 		impl.EnqueueControllerOf,
 	)
 
+	// multi-tenant adapters are owned by a ServiceAccount instead of the
+	// source they serve
+	common.WatchMTAdapterDeployment(ctx, typ,
+		common.EnqueueObjectsInNamespaceOf(informer.Informer(), impl.FilteredGlobalResync, logging.FromContext(ctx)),
+	)
+
 	informer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
 
 	return impl
@@ -19,6 +19,9 @@ package awscloudwatchsource
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/pkg/controller"
 	"knative.dev/pkg/reconciler"
 
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
@@ -43,5 +46,14 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, src *v1alpha1.AWSCloudWa
 	// inject source into context for usage in reconciliation logic
 	ctx = v1alpha1.WithSource(ctx, src)
 
+	// an invalid spec would cause the adapter to crash, so we refrain from
+	// deploying it until the spec gets fixed
+	if err := src.Validate(ctx); err != nil {
+		src.Status.MarkSpecInvalid(err.Error())
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning,
+			common.ReasonInvalidSpec, "Invalid spec: %s", err))
+	}
+	src.Status.MarkSpecValid()
+
 	return r.base.ReconcileSource(ctx, r)
 }
@@ -22,6 +22,8 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
 
 	"knative.dev/eventing/pkg/reconciler/source"
 	"knative.dev/pkg/controller"
@@ -34,6 +36,7 @@ import (
 	reconcilerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/reconciler/sources/v1alpha1/awscloudwatchsource"
 	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
 	. "github.com/triggermesh/aws-event-sources/pkg/reconciler/testing"
+	eventtesting "github.com/triggermesh/aws-event-sources/pkg/testing/event"
 )
 
 func TestReconcileSource(t *testing.T) {
@@ -43,10 +46,20 @@ func TestReconcileSource(t *testing.T) {
 	}
 
 	ctor := reconcilerCtor(adapterCfg)
-	src := newEventSource()
 	ab := adapterBuilder(adapterCfg)
 
-	TestReconcileAdapter(t, ctor, src, ab)
+	t.Run("Single-tenant", func(t *testing.T) {
+		TestReconcileAdapter(t, ctor, newEventSource(), ab)
+	})
+
+	t.Run("Multi-tenant", func(t *testing.T) {
+		src := newEventSource()
+		src.Annotations = map[string]string{
+			v1alpha1.MultiTenantAnnotation: "true",
+		}
+
+		TestReconcileAdapter(t, ctor, src, ab)
+	})
 }
 
 // reconcilerCtor returns a Ctor for a AWSCloudWatchSource Reconciler.
@@ -65,7 +78,7 @@ func reconcilerCtor(cfg *adapterConfig) Ctor {
 }
 
 // newEventSource returns a populated source object.
-func newEventSource() *v1alpha1.AWSCloudWatchSource {
+func newEventSource(opts ...sourceOption) *v1alpha1.AWSCloudWatchSource {
 	pollingInterval := apis.Duration(5 * time.Minute)
 
 	src := &v1alpha1.AWSCloudWatchSource{
@@ -84,8 +97,8 @@ func newEventSource() *v1alpha1.AWSCloudWatchSource {
 						Namespace:  "AWS/Lambda",
 					},
 					Period: 60,
-					Stat:   "sum",
-					Unit:   "seconds",
+					Stat:   "Sum",
+					Unit:   "Seconds",
 				},
 			}},
 			PollingInterval: &pollingInterval,
@@ -108,11 +121,18 @@ func newEventSource() *v1alpha1.AWSCloudWatchSource {
 				},
 			},
 		},
-		Status: v1alpha1.EventSourceStatus{},
 	}
 
+	// assume the spec was already validated to ensure generic tests only
+	// observe status changes related to the adapter
+	src.Status.MarkSpecValid()
+
 	Populate(src)
 
+	for _, opt := range opts {
+		opt(src)
+	}
+
 	return src
 }
 
@@ -123,3 +143,63 @@ func adapterBuilder(cfg *adapterConfig) common.AdapterDeploymentBuilder {
 		adapterCfg: cfg,
 	}
 }
+
+// TestReconcileSpecValidation contains tests specific to the CloudWatch source.
+func TestReconcileSpecValidation(t *testing.T) {
+	adapterCfg := &adapterConfig{
+		Image:   "registry/image:tag",
+		configs: &source.EmptyVarsGenerator{},
+	}
+
+	testCases := rt.TableTest{
+		{
+			Name: "Query without expression nor metric",
+			Key:  tKey,
+			Objects: []runtime.Object{
+				newEventSource(withEmptyQuery),
+			},
+			WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+				Object: newEventSource(withEmptyQuery, specInvalid),
+			}},
+			WantEvents: []string{
+				invalidSpecEvent(),
+			},
+			WantErr: true,
+		},
+	}
+
+	testCases.Test(t, MakeFactory(reconcilerCtor(adapterCfg)))
+}
+
+const (
+	tNs   = "testns"
+	tName = "test"
+	tKey  = tNs + "/" + tName
+)
+
+/* Event sources */
+
+// sourceOption is a functional option for an event source.
+type sourceOption func(*v1alpha1.AWSCloudWatchSource)
+
+// withEmptyQuery sets a metric query which defines neither an expression nor
+// a metric on the source.
+func withEmptyQuery(src *v1alpha1.AWSCloudWatchSource) {
+	src.Spec.MetricQueries = []v1alpha1.AWSCloudWatchMetricQuery{{
+		Name: "testquery",
+	}}
+}
+
+// specInvalid sets the SpecValid status condition to False.
+func specInvalid(src *v1alpha1.AWSCloudWatchSource) {
+	src.Status.MarkSpecInvalid(invalidSpecMsg)
+}
+
+/* Events */
+
+const invalidSpecMsg = "expected exactly one, got neither: " +
+	"spec.metricQueries[0].discovery, spec.metricQueries[0].expression, spec.metricQueries[0].metric"
+
+func invalidSpecEvent() string {
+	return eventtesting.Eventf(corev1.EventTypeWarning, common.ReasonInvalidSpec, "Invalid spec: "+invalidSpecMsg)
+}
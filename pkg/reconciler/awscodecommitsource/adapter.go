@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
+	kr "k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/labels"
 
 	"knative.dev/eventing/pkg/reconciler/source"
@@ -34,14 +35,29 @@ import (
 
 const (
 	envBranch     = "BRANCH"
+	envBranches   = "BRANCHES"
 	envEventTypes = "EVENT_TYPES"
 )
 
+const (
+	envMessageProcessor   = "SQS_MESSAGE_PROCESSOR"
+	envCodeCommitBranches = "CODECOMMIT_BRANCHES"
+)
+
+const healthPortName = "health"
+
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "CODECOMMIT_MULTI_TENANT"
+
 // adapterConfig contains properties used to configure the source's adapter.
 // These are automatically populated by envconfig.
 type adapterConfig struct {
 	// Container image
 	Image string `default:"gcr.io/triggermesh/awscodecommitsource"`
+	// Container image used when events are delivered by Amazon EventBridge.
+	// Reuses the adapter from the SQS source.
+	SQSImage string `envconfig:"AWSSQSSOURCE_IMAGE" default:"gcr.io/triggermesh/awssqssource"`
 	// Configuration accessor for logging/metrics/tracing
 	configs source.ConfigAccessor
 }
@@ -51,16 +67,86 @@ var _ common.AdapterDeploymentBuilder = (*Reconciler)(nil)
 
 // BuildAdapter implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *appsv1.Deployment {
+	if v1alpha1.IsMultiTenant(src) {
+		return r.buildMTAdapter(src)
+	}
+
 	typedSrc := src.(*v1alpha1.AWSCodeCommitSource)
 
+	if typedSrc.UsesEventBridge() {
+		return r.buildSQSAdapter(typedSrc, sinkURI)
+	}
+
 	return common.NewAdapterDeployment(src, sinkURI,
 		resource.Image(r.adapterCfg.Image),
 
 		resource.EnvVar(common.EnvARN, typedSrc.Spec.ARN.String()),
 		resource.EnvVar(envBranch, typedSrc.Spec.Branch),
+		resource.EnvVar(envBranches, strings.Join(typedSrc.Spec.Branches, ",")),
 		resource.EnvVar(envEventTypes, strings.Join(typedSrc.Spec.EventTypes, ",")),
 		resource.EnvVars(common.MakeSecurityCredentialsEnvVars(typedSrc.Spec.Credentials)...),
+		resource.EnvVar(common.EnvNamespace, src.GetNamespace()),
+		resource.EnvVar(common.EnvName, src.GetName()),
+		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
+	)
+}
+
+// buildSQSAdapter returns a SQS source adapter which consumes the CodeCommit
+// events sent by Amazon EventBridge to the source's SQS queue.
+func (r *Reconciler) buildSQSAdapter(src *v1alpha1.AWSCodeCommitSource, sinkURI *apis.URL) *appsv1.Deployment {
+	// the status is our only source of truth regarding the queue that
+	// receives events from the EventBridge rule
+	queueARN := src.Status.QueueARN
+
+	return common.NewAdapterDeployment(src, sinkURI,
+		resource.Image(r.adapterCfg.SQSImage),
+
+		resource.EnvVar(common.EnvARN, queueARN.String()),
+		resource.EnvVars(common.MakeSecurityCredentialsEnvVars(src.Spec.Credentials)...),
+		resource.EnvVar(common.EnvNamespace, src.GetNamespace()),
+		resource.EnvVar(common.EnvName, src.GetName()),
+		resource.EnvVar(envMessageProcessor, "codecommit"),
+		resource.EnvVar(envCodeCommitBranches, strings.Join(branchPatterns(src), ",")),
 		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
+
+		resource.Port(healthPortName, 8080),
+		resource.Port("metrics", 9090),
+		resource.Probe("/health", healthPortName),
+
+		// See awssqssource/adapter.go for an justification for these values.
+		resource.Requests(
+			*kr.NewMilliQuantity(90, kr.DecimalSI),     // 90m
+			*kr.NewQuantity(1024*1024*30, kr.BinarySI), // 30Mi
+		),
+		resource.Limits(
+			*kr.NewMilliQuantity(1000, kr.DecimalSI),   // 1
+			*kr.NewQuantity(1024*1024*45, kr.BinarySI), // 45Mi
+		),
+	)
+}
+
+// branchPatterns returns all the branch glob patterns of the given source.
+// Git does not allow glob characters in branch names, so the name of a single
+// branch is itself a valid pattern.
+func branchPatterns(src *v1alpha1.AWSCodeCommitSource) []string {
+	if src.Spec.Branch == "" {
+		return src.Spec.Branches
+	}
+	return append([]string{src.Spec.Branch}, src.Spec.Branches...)
+}
+
+// buildMTAdapter returns the multi-tenant adapter which polls the repositories of
+// all sources that opted into multi-tenancy in the namespace of the given
+// source.
+func (r *Reconciler) buildMTAdapter(src v1alpha1.EventSource) *appsv1.Deployment {
+	return common.NewMTAdapterDeployment(src,
+		resource.Image(r.adapterCfg.Image),
+
+		resource.EnvVar(envMultiTenant, "true"),
+		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
+
+		resource.Port(healthPortName, 8080),
+		resource.Probe("/health", healthPortName),
 	)
 }
 
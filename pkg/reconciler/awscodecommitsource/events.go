@@ -0,0 +1,35 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscodecommitsource
+
+const (
+	// ReasonQueueCreated indicates that a SQS queue was created for receiving CodeCommit events.
+	ReasonQueueCreated = "QueueCreated"
+	// ReasonQueueDeleted indicates that a SQS queue used for receiving CodeCommit events was deleted.
+	ReasonQueueDeleted = "QueueDeleted"
+	// ReasonFailedQueue indicates a failure while synchronizing the SQS queue for receiving CodeCommit events.
+	ReasonFailedQueue = "FailedQueue"
+
+	// ReasonSubscribed indicates that an EventBridge rule was configured for a CodeCommit repository.
+	ReasonSubscribed = "Subscribed"
+	// ReasonUnsubscribed indicates that the EventBridge rule of a CodeCommit repository was deleted.
+	ReasonUnsubscribed = "Unsubscribed"
+	// ReasonFailedSubscribe indicates a failure while configuring an EventBridge rule for a CodeCommit repository.
+	ReasonFailedSubscribe = "FailedSubscribe"
+	// ReasonFailedUnsubscribe indicates a failure while deleting the EventBridge rule of a CodeCommit repository.
+	ReasonFailedUnsubscribe = "FailedUnsubscribe"
+)
@@ -0,0 +1,253 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscodecommitsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/reconciler"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	awssqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/aws/iam"
+	"github.com/triggermesh/aws-event-sources/pkg/aws/sqs"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/event"
+)
+
+// ensureQueue ensures the existence of a SQS queue for receiving CodeCommit
+// events from EventBridge.
+func ensureQueue(ctx context.Context, cli sqsiface.SQSAPI) (string /*arn*/, error) {
+	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := src.(*v1alpha1.AWSCodeCommitSource)
+
+	status := &typedSrc.Status
+
+	queueName := resourceName(typedSrc)
+
+	queueURL, err := sqs.QueueURL(cli, queueName)
+	switch {
+	case common.IsNotFound(err):
+		queueURL, err = sqs.CreateQueue(cli, queueName, queueTags(typedSrc))
+		if err != nil {
+			status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonAPIError, "Unable to create SQS queue")
+			return "", fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedQueue,
+				"Error creating SQS queue for CodeCommit events: %s", common.ToErrMsg(err)))
+		}
+		event.Normal(ctx, ReasonQueueCreated, "Created SQS queue %q", queueURL)
+
+	case common.IsAWSError(err):
+		// All documented API errors require some user intervention and
+		// are not to be retried.
+		status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonAPIError, "Request to SQS API got rejected")
+		return "", controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedQueue,
+			"Failed to synchronize SQS queue: %s", common.ToErrMsg(err)))
+
+	case err != nil:
+		status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonAPIError, "Cannot synchronize SQS queue")
+		return "", fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedQueue,
+			"Failed to determine URL of SQS queue: %s", common.ToErrMsg(err)))
+	}
+
+	getAttrs := []string{awssqs.QueueAttributeNameQueueArn, awssqs.QueueAttributeNamePolicy}
+	queueAttrs, err := sqs.QueueAttributes(cli, queueURL, getAttrs)
+	if err != nil {
+		return "", fmt.Errorf("getting attributes of SQS queue: %w", err)
+	}
+
+	queueARN := queueAttrs[awssqs.QueueAttributeNameQueueArn]
+
+	queueARNStruct, err := arnStrToARN(queueARN)
+	if err != nil {
+		return queueARN, fmt.Errorf("converting ARN string to structured ARN: %w", err)
+	}
+
+	// it is essential that we propagate the queue's ARN here,
+	// otherwise BuildAdapter() won't be able to configure the SQS
+	// adapter properly
+	status.QueueARN = queueARNStruct
+
+	currentPol := unmarshalQueuePolicy(queueAttrs[awssqs.QueueAttributeNamePolicy])
+	desiredPol := makeQueuePolicy(queueARN, typedSrc)
+
+	if err := syncQueuePolicy(cli, queueURL, currentPol, desiredPol); err != nil {
+		status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonAPIError, "Cannot synchronize SQS queue")
+		return queueARN, fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedQueue,
+			"Error synchronizing policy of SQS queue: %s", common.ToErrMsg(err)))
+	}
+
+	return queueARN, nil
+}
+
+// ensureNoQueue ensures that the SQS queue created for receiving CodeCommit
+// events is deleted.
+func ensureNoQueue(ctx context.Context, cli sqsiface.SQSAPI) error {
+	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := src.(*v1alpha1.AWSCodeCommitSource)
+
+	queueURL, err := sqs.QueueURL(cli, resourceName(typedSrc))
+	switch {
+	case common.IsNotFound(err):
+		event.Warn(ctx, ReasonUnsubscribed, "Queue not found, skipping deletion")
+		return nil
+	case common.IsDenied(err):
+		// it is unlikely that we recover from auth errors in the
+		// finalizer, so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Authorization error getting SQS queue. Ignoring: %s", common.ToErrMsg(err))
+		return nil
+	case err != nil:
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Failed to determine URL of SQS queue: %s", common.ToErrMsg(err))
+	}
+
+	owns, err := sqs.AssertQueueOwnership(cli, queueURL, sourceID(typedSrc))
+	if err != nil {
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Failed to verify owner of SQS queue: %s", common.ToErrMsg(err))
+	}
+
+	if !owns {
+		event.Warn(ctx, ReasonUnsubscribed, "Queue %q is not owned by this source instance, "+
+			"skipping deletion", queueURL)
+		return nil
+	}
+
+	err = sqs.DeleteQueue(cli, queueURL)
+	switch {
+	case common.IsDenied(err):
+		// it is unlikely that we recover from auth errors in the
+		// finalizer, so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Authorization error deleting SQS queue. Ignoring: %s", common.ToErrMsg(err))
+		return nil
+	case err != nil:
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error deleting SQS queue: %s", common.ToErrMsg(err))
+	}
+
+	event.Normal(ctx, ReasonQueueDeleted, "Deleted SQS queue %q", queueURL)
+
+	return nil
+}
+
+// syncQueuePolicy ensures that a SQS queue has the right permissions to
+// receive messages from the EventBridge rule of the given source.
+func syncQueuePolicy(cli sqsiface.SQSAPI, queueURL string, current, desired iam.Policy) error {
+	if equalPolicies(desired, current) {
+		return nil
+	}
+
+	if err := sqs.SetQueuePolicy(cli, queueURL, desired); err != nil {
+		return fmt.Errorf("setting policy of SQS queue: %w", err)
+	}
+
+	return nil
+}
+
+// equalPolicies returns whether two SQS policies are semantically equal.
+// Statements are compared in order.
+func equalPolicies(a, b iam.Policy) bool {
+	if len(a.Statement) != len(b.Statement) {
+		return false
+	}
+
+	for i := range a.Statement {
+		if !equalPolicyStatements(a.Statement[i], b.Statement[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equalPolicyStatements returns whether two statements of a SQS policy are
+// semantically equal.
+func equalPolicyStatements(a, b iam.PolicyStatement) bool {
+	if a.Effect != b.Effect {
+		return false
+	}
+	if !reflect.DeepEqual(a.Principal, b.Principal) {
+		return false
+	}
+	if !reflect.DeepEqual(a.Condition, b.Condition) {
+		return false
+	}
+	if !reflect.DeepEqual(a.Action, b.Action) {
+		return false
+	}
+	return reflect.DeepEqual(a.Resource, b.Resource)
+}
+
+// makeQueuePolicy creates an IAM policy for the given SQS queue ARN and source instance.
+func makeQueuePolicy(queueARN string, src *v1alpha1.AWSCodeCommitSource) iam.Policy {
+	return iam.NewPolicy(
+		newEventBridgeToSQSPolicyStatement(queueARN, ruleARN(src)),
+	)
+}
+
+// newEventBridgeToSQSPolicyStatement returns an IAM Policy Statement that
+// allows an EventBridge rule to send events to the given SQS queue.
+// Ref. https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-use-resource-based.html#eb-sqs-permissions
+func newEventBridgeToSQSPolicyStatement(queueARN, ruleARN string) iam.PolicyStatement {
+	return iam.NewPolicyStatement(iam.EffectAllow,
+		iam.PrincipalService("events.amazonaws.com"),
+		iam.ConditionArnEquals("aws:SourceArn", ruleARN),
+		iam.Action("sqs:SendMessage"),
+		iam.Resource(queueARN),
+	)
+}
+
+// unmarshalQueuePolicy deserializes an IAM policy string.
+func unmarshalQueuePolicy(polStr string) iam.Policy {
+	var pol iam.Policy
+	_ = json.Unmarshal([]byte(polStr), &pol)
+
+	// if an error occured, the policy will be empty syncQueuePolicy() will
+	// simply enforce the desired state
+	return pol
+}
+
+// queueTags returns a set of tags containing information from the given source
+// instance to set on a SQS queue.
+func queueTags(src *v1alpha1.AWSCodeCommitSource) map[string]string {
+	return map[string]string{
+		"repository-arn": src.Spec.ARN.String(),
+		sqs.OwnerTagKey:  sourceID(src),
+	}
+}
+
+// arnStrToARN returns the given ARN string as a structured ARN.
+func arnStrToARN(arnStr string) (*apis.ARN, error) {
+	arn, err := arn.Parse(arnStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ARN string: %w", err)
+	}
+
+	apiARN := apis.ARN(arn)
+	return &apiARN, nil
+}
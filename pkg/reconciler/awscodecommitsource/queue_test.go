@@ -0,0 +1,75 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscodecommitsource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/aws-event-sources/pkg/aws/iam"
+)
+
+func TestEqualPolicies(t *testing.T) {
+	const (
+		queueARN      = "arn:aws:sqs:us-test-0:123456789012:my-queue"
+		ruleARN       = "arn:aws:events:us-test-0:123456789012:rule/my-rule"
+		otherRuleARN  = "arn:aws:events:us-test-0:123456789012:rule/other-rule"
+		otherQueueARN = "arn:aws:sqs:us-test-0:123456789012:other-queue"
+	)
+
+	stmt := newEventBridgeToSQSPolicyStatement(queueARN, ruleARN)
+	otherStmt := newEventBridgeToSQSPolicyStatement(queueARN, otherRuleARN)
+
+	testCases := map[string]struct {
+		a, b        iam.Policy
+		expectEqual bool
+	}{
+		"Same statements": {
+			a:           iam.NewPolicy(stmt, otherStmt),
+			b:           iam.NewPolicy(stmt, otherStmt),
+			expectEqual: true,
+		},
+		"No statement": {
+			a:           iam.NewPolicy(),
+			b:           iam.NewPolicy(),
+			expectEqual: true,
+		},
+		"Different number of statements": {
+			a:           iam.NewPolicy(stmt),
+			b:           iam.NewPolicy(stmt, otherStmt),
+			expectEqual: false,
+		},
+		"Different first statement": {
+			a:           iam.NewPolicy(stmt),
+			b:           iam.NewPolicy(newEventBridgeToSQSPolicyStatement(otherQueueARN, ruleARN)),
+			expectEqual: false,
+		},
+		"Different subsequent statement": {
+			a:           iam.NewPolicy(stmt, otherStmt),
+			b:           iam.NewPolicy(stmt, stmt),
+			expectEqual: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectEqual, equalPolicies(tc.a, tc.b))
+		})
+	}
+}
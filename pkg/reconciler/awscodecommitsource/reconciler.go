@@ -18,13 +18,20 @@ package awscodecommitsource
 
 import (
 	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
 
 	"knative.dev/pkg/reconciler"
 
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	codecommitclient "github.com/triggermesh/aws-event-sources/pkg/client/codecommit"
 	reconcilerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/reconciler/sources/v1alpha1/awscodecommitsource"
 	listersv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/listers/sources/v1alpha1"
 	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/event"
 )
 
 // Reconciler implements controller.Reconciler for the event source type.
@@ -33,15 +40,115 @@ type Reconciler struct {
 	adapterCfg *adapterConfig
 
 	srcLister func(namespace string) listersv1alpha1.AWSCodeCommitSourceNamespaceLister
+
+	// Getter than can obtain clients for interacting with the EventBridge
+	// and SQS APIs
+	cg codecommitclient.ClientGetter
 }
 
 // Check that our Reconciler implements Interface
 var _ reconcilerv1alpha1.Interface = (*Reconciler)(nil)
 
+// Check that our Reconciler implements Finalizer
+var _ reconcilerv1alpha1.Finalizer = (*Reconciler)(nil)
+
 // ReconcileKind implements Interface.ReconcileKind.
 func (r *Reconciler) ReconcileKind(ctx context.Context, src *v1alpha1.AWSCodeCommitSource) reconciler.Event {
 	// inject source into context for usage in reconciliation logic
 	ctx = v1alpha1.WithSource(ctx, src)
 
-	return r.base.ReconcileSource(ctx, r)
+	if !src.UsesEventBridge() {
+		// the source may have been switched from the "eventbridge"
+		// mode, in which case the resources that were created for
+		// receiving events from EventBridge are no longer needed
+		if src.Status.QueueARN != nil {
+			if err := r.ensureUnsubscribed(ctx); err != nil {
+				return err
+			}
+			src.Status.QueueARN = nil
+		}
+
+		return r.base.ReconcileSource(ctx, r)
+	}
+
+	ebClient, sqsClient, err := r.cg.Get(src)
+	if err != nil {
+		src.Status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonNoClient, "Cannot obtain AWS API clients")
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Error creating AWS API clients: %s", err))
+	}
+
+	queueARN, err := ensureQueue(ctx, sqsClient)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile SQS queue: %w", err)
+	}
+
+	if err := r.base.ReconcileSource(ctx, r); err != nil {
+		return fmt.Errorf("failed to reconcile SQS event source adapter: %w", err)
+	}
+
+	return ensureRule(ctx, ebClient, queueARN)
+}
+
+// FinalizeKind is called when the resource is deleted.
+func (r *Reconciler) FinalizeKind(ctx context.Context, src *v1alpha1.AWSCodeCommitSource) reconciler.Event {
+	if !src.UsesEventBridge() && src.Status.QueueARN == nil {
+		// nothing was created outside of the cluster
+		return nil
+	}
+
+	// inject source into context for usage in finalization logic
+	ctx = v1alpha1.WithSource(ctx, src)
+
+	// The finalizer blocks the deletion of the source object until
+	// ensureUnsubscribed succeeds to ensure that we don't leave any
+	// dangling EventBridge rule or SQS queue behind us.
+	return r.ensureUnsubscribed(ctx)
+}
+
+// ensureUnsubscribed ensures that the EventBridge rule and SQS queue created
+// for receiving events from the CodeCommit repository are deleted.
+func (r *Reconciler) ensureUnsubscribed(ctx context.Context) error {
+	src := v1alpha1.SourceFromContext(ctx).(*v1alpha1.AWSCodeCommitSource)
+
+	ebClient, sqsClient, err := r.cg.Get(src)
+	switch {
+	case common.IsNotFound(err):
+		// the finalizer is unlikely to recover from a missing Secret,
+		// so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Secret missing while finalizing event source. Ignoring: %s", err)
+		return nil
+	case err != nil:
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error creating AWS API clients: %s", err)
+	}
+
+	// The rule must be deleted first, otherwise it would keep sending
+	// events to a queue that no longer exists.
+	if err := ensureNoRule(ctx, ebClient); err != nil {
+		return fmt.Errorf("failed to finalize EventBridge rule: %w", err)
+	}
+
+	if err := ensureNoQueue(ctx, sqsClient); err != nil {
+		return fmt.Errorf("failed to finalize SQS queue: %w", err)
+	}
+
+	return nil
+}
+
+// sourceID returns an ID that identifies the given source instance in AWS
+// resources such as EventBridge rules and SQS queues.
+func sourceID(src v1alpha1.EventSource) string {
+	return "io.triggermesh.awscodecommitsources." + src.GetNamespace() + "." + src.GetName()
+}
+
+// resourceName returns a name matching the given source instance, which is
+// suitable for both EventBridge rules (max. 64 characters) and SQS queues
+// (max. 80 characters).
+func resourceName(src v1alpha1.EventSource) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(sourceID(src)))
+
+	return "codecommit-events_" + strconv.FormatUint(h.Sum64(), 16)
 }
@@ -18,17 +18,28 @@ package awscodecommitsource
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/codecommit"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 
 	"knative.dev/eventing/pkg/reconciler/source"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 	rt "knative.dev/pkg/reconciler/testing"
 
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources"
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
 	fakeinjectionclient "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client/fake"
 	reconcilerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/reconciler/sources/v1alpha1/awscodecommitsource"
@@ -43,10 +54,20 @@ func TestReconcileSource(t *testing.T) {
 	}
 
 	ctor := reconcilerCtor(adapterCfg)
-	src := newEventSource()
 	ab := adapterBuilder(adapterCfg)
 
-	TestReconcileAdapter(t, ctor, src, ab)
+	t.Run("Single-tenant", func(t *testing.T) {
+		TestReconcileAdapter(t, ctor, newEventSource(), ab)
+	})
+
+	t.Run("Multi-tenant", func(t *testing.T) {
+		src := newEventSource()
+		src.Annotations = map[string]string{
+			v1alpha1.MultiTenantAnnotation: "true",
+		}
+
+		TestReconcileAdapter(t, ctor, src, ab)
+	})
 }
 
 // reconcilerCtor returns a Ctor for a AWSCodeCommitSource Reconciler.
@@ -92,6 +113,10 @@ func newEventSource() *v1alpha1.AWSCodeCommitSource {
 		},
 	}
 
+	// assume finalizer is already set to prevent the generated reconciler
+	// from generating an extra Patch action
+	src.Finalizers = []string{sources.AWSCodeCommitSourceResource.String()}
+
 	Populate(src)
 
 	return src
@@ -104,3 +129,140 @@ func adapterBuilder(cfg *adapterConfig) common.AdapterDeploymentBuilder {
 		adapterCfg: cfg,
 	}
 }
+
+// TestEnsureRule contains tests specific to the "eventbridge" mode.
+func TestEnsureRule(t *testing.T) {
+	const tQueueARN = "arn:aws:sqs:us-west-2:123456789012:codecommit-events"
+
+	newSource := func() *v1alpha1.AWSCodeCommitSource {
+		src := newEventSource()
+		src.Spec.Mode = aws.String(v1alpha1.AWSCodeCommitModeEventBridge)
+		src.Spec.EventTypes = []string{"push", "reference"}
+		return src
+	}
+
+	currentPattern, err := makeEventPattern(newSource())
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		client *mockedEventBridgeClient
+
+		expectPutRule    bool
+		expectPutTargets bool
+	}{
+		"Rule does not exist": {
+			client: &mockedEventBridgeClient{
+				describeRuleErr: awserr.New(eventbridge.ErrCodeResourceNotFoundException, "not found", nil),
+			},
+			expectPutRule:    true,
+			expectPutTargets: true,
+		},
+		"Rule has an outdated event pattern": {
+			client: &mockedEventBridgeClient{
+				eventPattern: `{"source":["aws.codecommit"],"resources":["arn:aws:codecommit:us-west-2:123456789012:triggermeshtest"]}`,
+				targets:      []*eventbridge.Target{{Id: aws.String(ruleTargetID), Arn: aws.String(tQueueARN)}},
+			},
+			expectPutRule:    true,
+			expectPutTargets: false,
+		},
+		"Rule is up to date but has no target": {
+			client: &mockedEventBridgeClient{
+				eventPattern: currentPattern,
+			},
+			expectPutRule:    false,
+			expectPutTargets: true,
+		},
+		"Rule is up to date": {
+			client: &mockedEventBridgeClient{
+				eventPattern: currentPattern,
+				targets:      []*eventbridge.Target{{Id: aws.String(ruleTargetID), Arn: aws.String(tQueueARN)}},
+			},
+			expectPutRule:    false,
+			expectPutTargets: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			src := newSource()
+			ctx := controller.WithEventRecorder(context.Background(), record.NewFakeRecorder(10))
+			ctx = v1alpha1.WithSource(ctx, src)
+
+			err := ensureRule(ctx, tc.client, tQueueARN)
+			require.NoError(t, err)
+
+			assert.True(t, src.Status.GetCondition(v1alpha1.AWSCodeCommitConditionSubscribed).IsTrue())
+
+			if !tc.expectPutRule {
+				assert.Nil(t, tc.client.putRuleInput, "Unexpected PutRule request")
+			} else {
+				require.NotNil(t, tc.client.putRuleInput, "Expected a PutRule request")
+				assert.Equal(t, resourceName(src), *tc.client.putRuleInput.Name)
+
+				var pattern eventPattern
+				require.NoError(t, json.Unmarshal([]byte(*tc.client.putRuleInput.EventPattern), &pattern))
+				assert.Equal(t, []string{src.Spec.ARN.String()}, pattern.Resources)
+				assert.Equal(t, []string{"referenceUpdated", "referenceCreated", "referenceDeleted"},
+					pattern.Detail.Event)
+			}
+
+			if !tc.expectPutTargets {
+				assert.Nil(t, tc.client.putTargetsInput, "Unexpected PutTargets request")
+			} else {
+				require.NotNil(t, tc.client.putTargetsInput, "Expected a PutTargets request")
+				assert.Equal(t, tQueueARN, *tc.client.putTargetsInput.Targets[0].Arn)
+			}
+		})
+	}
+}
+
+// mockedEventBridgeClient is a mocked EventBridge client which records
+// PutRule and PutTargets requests.
+type mockedEventBridgeClient struct {
+	eventbridgeiface.EventBridgeAPI
+
+	describeRuleErr error
+	eventPattern    string
+	targets         []*eventbridge.Target
+
+	putRuleInput    *eventbridge.PutRuleInput
+	putTargetsInput *eventbridge.PutTargetsInput
+}
+
+func (c *mockedEventBridgeClient) DescribeRuleWithContext(_ aws.Context,
+	in *eventbridge.DescribeRuleInput, _ ...request.Option) (*eventbridge.DescribeRuleOutput, error) {
+
+	if c.describeRuleErr != nil {
+		return nil, c.describeRuleErr
+	}
+
+	return &eventbridge.DescribeRuleOutput{
+		Name:         in.Name,
+		EventPattern: &c.eventPattern,
+	}, nil
+}
+
+func (c *mockedEventBridgeClient) PutRuleWithContext(_ aws.Context,
+	in *eventbridge.PutRuleInput, _ ...request.Option) (*eventbridge.PutRuleOutput, error) {
+
+	c.putRuleInput = in
+	return &eventbridge.PutRuleOutput{}, nil
+}
+
+func (c *mockedEventBridgeClient) ListTargetsByRuleWithContext(aws.Context,
+	*eventbridge.ListTargetsByRuleInput, ...request.Option) (*eventbridge.ListTargetsByRuleOutput, error) {
+
+	return &eventbridge.ListTargetsByRuleOutput{
+		Targets: c.targets,
+	}, nil
+}
+
+func (c *mockedEventBridgeClient) PutTargetsWithContext(_ aws.Context,
+	in *eventbridge.PutTargetsInput, _ ...request.Option) (*eventbridge.PutTargetsOutput, error) {
+
+	c.putTargetsInput = in
+	return &eventbridge.PutTargetsOutput{
+		FailedEntryCount: aws.Int64(0),
+	}, nil
+}
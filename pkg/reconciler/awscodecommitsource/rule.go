@@ -0,0 +1,260 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awscodecommitsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/reconciler"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	awseventbridge "github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/aws/eventbridge"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/event"
+)
+
+// ID of the EventBridge target which sends events to the source's SQS queue.
+const ruleTargetID = "sqs-queue"
+
+// ensureRule ensures that an EventBridge rule forwards the events of the
+// CodeCommit repository to the given SQS queue.
+func ensureRule(ctx context.Context, cli eventbridgeiface.EventBridgeAPI, queueARN string) error {
+	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := src.(*v1alpha1.AWSCodeCommitSource)
+
+	status := &typedSrc.Status
+
+	ruleName := resourceName(typedSrc)
+
+	desiredPattern, err := makeEventPattern(typedSrc)
+	if err != nil {
+		return fmt.Errorf("creating event pattern: %w", err)
+	}
+
+	rule, err := cli.DescribeRuleWithContext(ctx, &awseventbridge.DescribeRuleInput{
+		Name: &ruleName,
+	})
+	switch {
+	case common.IsNotFound(err):
+		if err := putRule(ctx, cli, typedSrc, desiredPattern); err != nil {
+			status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonAPIError, "Unable to create EventBridge rule")
+			return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+				"Error creating EventBridge rule: %s", common.ToErrMsg(err)))
+		}
+
+	case common.IsAWSError(err):
+		// All documented API errors require some user intervention and
+		// are not to be retried.
+		// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DescribeRule.html#API_DescribeRule_Errors
+		status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonAPIError, "Request to EventBridge API got rejected")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Failed to synchronize EventBridge rule: %s", common.ToErrMsg(err)))
+
+	case err != nil:
+		status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonAPIError, "Cannot synchronize EventBridge rule")
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Error reading EventBridge rule: %s", common.ToErrMsg(err)))
+
+	case !equalEventPatterns(desiredPattern, aws.StringValue(rule.EventPattern)):
+		if err := putRule(ctx, cli, typedSrc, desiredPattern); err != nil {
+			status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonAPIError, "Cannot update EventBridge rule")
+			return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+				"Error updating event pattern of EventBridge rule: %s", common.ToErrMsg(err)))
+		}
+	}
+
+	if err := eventbridge.EnsureRuleTarget(ctx, cli, ruleName, ruleTargetID, queueARN); err != nil {
+		status.MarkNotSubscribed(v1alpha1.AWSCodeCommitReasonAPIError, "Cannot configure target of EventBridge rule")
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Error configuring target of EventBridge rule: %s", common.ToErrMsg(err)))
+	}
+
+	if !status.GetCondition(v1alpha1.AWSCodeCommitConditionSubscribed).IsTrue() {
+		event.Normal(ctx, ReasonSubscribed, "Configured EventBridge rule %q for CodeCommit repository %q",
+			ruleName, typedSrc.Spec.ARN)
+	}
+	status.MarkSubscribed()
+
+	return nil
+}
+
+// ensureNoRule ensures that the EventBridge rule created for the CodeCommit
+// repository is deleted.
+func ensureNoRule(ctx context.Context, cli eventbridgeiface.EventBridgeAPI) error {
+	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := src.(*v1alpha1.AWSCodeCommitSource)
+
+	ruleName := resourceName(typedSrc)
+
+	owns, err := eventbridge.AssertRuleOwnership(ctx, cli, ruleARN(typedSrc), sourceID(typedSrc))
+	switch {
+	case common.IsNotFound(err):
+		event.Warn(ctx, ReasonUnsubscribed, "Rule not found, skipping deletion")
+		return nil
+	case common.IsDenied(err):
+		// it is unlikely that we recover from auth errors in the
+		// finalizer, so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Authorization error getting EventBridge rule. Ignoring: %s", common.ToErrMsg(err))
+		return nil
+	case err != nil:
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Failed to verify owner of EventBridge rule: %s", common.ToErrMsg(err))
+	}
+
+	if !owns {
+		event.Warn(ctx, ReasonUnsubscribed, "Rule %q is not owned by this source instance, "+
+			"skipping deletion", ruleName)
+		return nil
+	}
+
+	// targets must be removed before a rule can be deleted
+	_, err = cli.RemoveTargetsWithContext(ctx, &awseventbridge.RemoveTargetsInput{
+		Rule: &ruleName,
+		Ids:  aws.StringSlice([]string{ruleTargetID}),
+	})
+	if err != nil && !common.IsNotFound(err) {
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error removing targets of EventBridge rule: %s", common.ToErrMsg(err))
+	}
+
+	_, err = cli.DeleteRuleWithContext(ctx, &awseventbridge.DeleteRuleInput{
+		Name: &ruleName,
+	})
+	switch {
+	case common.IsDenied(err):
+		// it is unlikely that we recover from auth errors in the
+		// finalizer, so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Authorization error deleting EventBridge rule. Ignoring: %s", common.ToErrMsg(err))
+		return nil
+	case err != nil && !common.IsNotFound(err):
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error deleting EventBridge rule: %s", common.ToErrMsg(err))
+	}
+
+	event.Normal(ctx, ReasonUnsubscribed, "Deleted EventBridge rule %q", ruleName)
+
+	return nil
+}
+
+// putRule creates or updates the EventBridge rule of the given source.
+// Tags are only applied when the rule is created.
+func putRule(ctx context.Context, cli eventbridgeiface.EventBridgeAPI,
+	src *v1alpha1.AWSCodeCommitSource, pattern string) error {
+
+	_, err := cli.PutRuleWithContext(ctx, &awseventbridge.PutRuleInput{
+		Name:         aws.String(resourceName(src)),
+		Description:  aws.String("Events from CodeCommit repository " + src.Spec.ARN.String()),
+		EventPattern: &pattern,
+		State:        aws.String(awseventbridge.RuleStateEnabled),
+		Tags:         ruleTags(src),
+	})
+	if err != nil {
+		return fmt.Errorf("putting rule: %w", err)
+	}
+
+	return nil
+}
+
+// eventPattern mirrors the structure of an EventBridge event pattern for easy
+// marshaling and unmarshaling to/from JSON.
+// See https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns.html
+type eventPattern struct {
+	Source    []string            `json:"source"`
+	Resources []string            `json:"resources"`
+	Detail    *eventPatternDetail `json:"detail,omitempty"`
+}
+
+// eventPatternDetail is the "detail" element of an eventPattern.
+type eventPatternDetail struct {
+	Event []string `json:"event"`
+}
+
+// makeEventPattern returns an EventBridge event pattern matching the events
+// selected in the spec of the given source.
+//
+// Branches are not matched by the pattern because EventBridge patterns do not
+// support glob expressions. Events are instead filtered by the adapter.
+func makeEventPattern(src *v1alpha1.AWSCodeCommitSource) (string, error) {
+	pattern := eventPattern{
+		Source:    []string{"aws.codecommit"},
+		Resources: []string{src.Spec.ARN.String()},
+	}
+
+	if events := v1alpha1.AWSCodeCommitEventBridgeEvents(src.Spec.EventTypes); len(events) > 0 {
+		pattern.Detail = &eventPatternDetail{
+			Event: events,
+		}
+	}
+
+	b, err := json.Marshal(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// equalEventPatterns returns whether two serialized event patterns are
+// semantically equal.
+func equalEventPatterns(a, b string) bool {
+	var ap, bp eventPattern
+
+	if err := json.Unmarshal([]byte(a), &ap); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &bp); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(ap, bp)
+}
+
+// ruleTags returns a set of tags containing information from the given source
+// instance to set on an EventBridge rule.
+func ruleTags(src *v1alpha1.AWSCodeCommitSource) []*awseventbridge.Tag {
+	return []*awseventbridge.Tag{
+		{Key: aws.String("repository-arn"), Value: aws.String(src.Spec.ARN.String())},
+		{Key: aws.String(eventbridge.OwnerTagKey), Value: aws.String(sourceID(src))},
+	}
+}
+
+// ruleARN returns the ARN of the EventBridge rule matching the given source
+// instance.
+// The ARN is deterministic, which allows the SQS queue policy to reference the
+// rule before it is created.
+func ruleARN(src *v1alpha1.AWSCodeCommitSource) string {
+	return arn.ARN{
+		Partition: src.Spec.ARN.Partition,
+		Service:   awseventbridge.ServiceName,
+		Region:    src.Spec.ARN.Region,
+		AccountID: src.Spec.ARN.AccountID,
+		Resource:  "rule/" + resourceName(src),
+	}.String()
+}
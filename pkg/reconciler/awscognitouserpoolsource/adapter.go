@@ -18,6 +18,7 @@ package awscognitouserpoolsource
 
 import (
 	"fmt"
+	"strconv"
 
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -25,14 +26,25 @@ import (
 	"knative.dev/eventing/pkg/reconciler/source"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/kmeta"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
 
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
 	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
 	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/resource"
 )
 
+const (
+	envTrackGroupMembership = "TRACK_GROUP_MEMBERSHIP"
+	envTriggersSharedSecret = "COGNITO_TRIGGERS_SHARED_SECRET"
+)
+
 const healthPortName = "health"
 
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "COGNITO_MULTI_TENANT"
+
 // adapterConfig contains properties used to configure the source's adapter.
 // These are automatically populated by envconfig.
 type adapterConfig struct {
@@ -47,13 +59,76 @@ var _ common.AdapterDeploymentBuilder = (*Reconciler)(nil)
 
 // BuildAdapter implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *appsv1.Deployment {
+	if v1alpha1.IsMultiTenant(src) {
+		return r.buildMTAdapter(src)
+	}
+
+	return common.NewAdapterDeployment(src, sinkURI, append(r.adapterOptions(src),
+		resource.Port(healthPortName, 8080),
+		resource.Probe("/health", healthPortName),
+	)...)
+}
+
+// triggersAdapterBuilder builds the adapter of sources which ingest Cognito
+// Lambda trigger payloads. Such adapter needs to be reachable over HTTP,
+// and is therefore backed by a Knative Service instead of a Deployment.
+type triggersAdapterBuilder struct {
+	*Reconciler
+}
+
+// Verify that triggersAdapterBuilder implements common.AdapterServiceBuilder.
+var _ common.AdapterServiceBuilder = (*triggersAdapterBuilder)(nil)
+
+// BuildAdapter implements common.AdapterServiceBuilder.
+func (b *triggersAdapterBuilder) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *servingv1.Service {
+	typedSrc := src.(*v1alpha1.AWSCognitoUserPoolSource)
+
+	return common.NewAdapterKnService(src, sinkURI, append(b.adapterOptions(src),
+		sharedSecretEnvVar(typedSrc.Spec.Triggers.SharedSecret),
+
+		// the adapter keeps polling the user pool between requests,
+		// so it must run exactly one replica: never scaled to zero,
+		// and never scaled out to several concurrent pollers
+		resource.PodAnnotation(autoscaling.MinScaleAnnotationKey, "1"),
+		resource.PodAnnotation(autoscaling.MaxScaleAnnotationKey, "1"),
+		resource.Probe("/health", ""),
+	)...)
+}
+
+// adapterOptions returns the options common to all kinds of adapters of the
+// event source.
+func (r *Reconciler) adapterOptions(src v1alpha1.EventSource) []resource.ObjectOption {
 	typedSrc := src.(*v1alpha1.AWSCognitoUserPoolSource)
 
-	return common.NewAdapterDeployment(src, sinkURI,
+	return []resource.ObjectOption{
 		resource.Image(r.adapterCfg.Image),
 
 		resource.EnvVar(common.EnvARN, typedSrc.Spec.ARN.String()),
+		resource.EnvVar(envTrackGroupMembership, strconv.FormatBool(typedSrc.Spec.TrackGroupMembership)),
 		resource.EnvVars(common.MakeSecurityCredentialsEnvVars(typedSrc.Spec.Credentials)...),
+		resource.EnvVar(common.EnvNamespace, src.GetNamespace()),
+		resource.EnvVar(common.EnvName, src.GetName()),
+		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
+	}
+}
+
+// sharedSecretEnvVar returns an option which sets the secret shared with the
+// relay of Cognito Lambda triggers.
+func sharedSecretEnvVar(secret v1alpha1.ValueFromField) resource.ObjectOption {
+	if vfs := secret.ValueFromSecret; vfs != nil {
+		return resource.EnvVarFromSecret(envTriggersSharedSecret, vfs.Name, vfs.Key)
+	}
+	return resource.EnvVar(envTriggersSharedSecret, secret.Value)
+}
+
+// buildMTAdapter returns the multi-tenant adapter which polls the user pools of
+// all sources that opted into multi-tenancy in the namespace of the given
+// source.
+func (r *Reconciler) buildMTAdapter(src v1alpha1.EventSource) *appsv1.Deployment {
+	return common.NewMTAdapterDeployment(src,
+		resource.Image(r.adapterCfg.Image),
+
+		resource.EnvVar(envMultiTenant, "true"),
 		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
 
 		resource.Port(healthPortName, 8080),
@@ -61,7 +136,8 @@ func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *
 	)
 }
 
-// RBACOwners implements common.AdapterDeploymentBuilder.
+// RBACOwners implements common.AdapterDeploymentBuilder and
+// common.AdapterServiceBuilder.
 func (r *Reconciler) RBACOwners(namespace string) ([]kmeta.OwnerRefable, error) {
 	srcs, err := r.srcLister(namespace).List(labels.Everything())
 	if err != nil {
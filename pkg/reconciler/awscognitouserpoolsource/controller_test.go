@@ -28,11 +28,13 @@ import (
 	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/serviceaccount/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/rbac/v1/rolebinding/fake"
 	_ "knative.dev/pkg/injection/clients/dynamicclient/fake"
+	_ "knative.dev/serving/pkg/client/injection/informers/serving/v1/service/fake"
 )
 
 func TestNewController(t *testing.T) {
 	t.Run("No failure", func(t *testing.T) {
-		TestControllerConstructor(t, NewController)
+		// expected informers: Source, Deployment, Service, ServiceAccount, RoleBinding
+		TestControllerConstructorWithInformers(t, NewController, 5)
 	})
 
 	t.Run("Failure cases", func(t *testing.T) {
@@ -29,8 +29,9 @@ import (
 
 // Reconciler implements controller.Reconciler for the event source type.
 type Reconciler struct {
-	base       common.GenericDeploymentReconciler
-	adapterCfg *adapterConfig
+	base         common.GenericDeploymentReconciler
+	triggersBase common.GenericServiceReconciler
+	adapterCfg   *adapterConfig
 
 	srcLister func(namespace string) listersv1alpha1.AWSCognitoUserPoolSourceNamespaceLister
 }
@@ -43,5 +44,20 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, src *v1alpha1.AWSCognito
 	// inject source into context for usage in reconciliation logic
 	ctx = v1alpha1.WithSource(ctx, src)
 
+	// The adapter needs to be reachable over HTTP only when Cognito Lambda
+	// trigger payloads are ingested. Any adapter of the other kind is a
+	// leftover from a previous version of the source's spec.
+	if src.Spec.Triggers != nil {
+		if err := r.base.DeleteAdapter(ctx); err != nil {
+			return err
+		}
+		return r.triggersBase.ReconcileSource(ctx, &triggersAdapterBuilder{r})
+	}
+
+	if err := r.triggersBase.DeleteAdapter(ctx); err != nil {
+		return err
+	}
+	src.Status.Address = nil
+
 	return r.base.ReconcileSource(ctx, r)
 }
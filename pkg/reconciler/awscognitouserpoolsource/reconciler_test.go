@@ -43,19 +43,46 @@ func TestReconcileSource(t *testing.T) {
 	}
 
 	ctor := reconcilerCtor(adapterCfg)
-	src := newEventSource()
-	ab := adapterBuilder(adapterCfg)
 
-	TestReconcileAdapter(t, ctor, src, ab)
+	t.Run("Polling", func(t *testing.T) {
+		src := newEventSource()
+		ab := adapterBuilder(adapterCfg)
+
+		TestReconcileAdapter(t, ctor, src, ab)
+	})
+
+	t.Run("Polling, multi-tenant", func(t *testing.T) {
+		src := newEventSource()
+		src.Annotations = map[string]string{
+			v1alpha1.MultiTenantAnnotation: "true",
+		}
+		ab := adapterBuilder(adapterCfg)
+
+		TestReconcileAdapter(t, ctor, src, ab)
+	})
+
+	t.Run("Lambda triggers", func(t *testing.T) {
+		src := newEventSource()
+		src.Spec.Triggers = &v1alpha1.AWSCognitoUserPoolTriggers{
+			SharedSecret: v1alpha1.ValueFromField{
+				Value: "s3cr3t",
+			},
+		}
+		Populate(src)
+		ab := &triggersAdapterBuilder{adapterBuilder(adapterCfg).(*Reconciler)}
+
+		TestReconcileAdapter(t, ctor, src, ab)
+	})
 }
 
 // reconcilerCtor returns a Ctor for a AWSCognitoUserPoolSource Reconciler.
 func reconcilerCtor(cfg *adapterConfig) Ctor {
 	return func(t *testing.T, ctx context.Context, _ *rt.TableRow, ls *Listers) controller.Reconciler {
 		r := &Reconciler{
-			base:       NewTestDeploymentReconciler(ctx, ls),
-			adapterCfg: cfg,
-			srcLister:  ls.GetAWSCognitoUserPoolSourceLister().AWSCognitoUserPoolSources,
+			base:         NewTestDeploymentReconciler(ctx, ls),
+			triggersBase: NewTestServiceReconciler(ctx, ls),
+			adapterCfg:   cfg,
+			srcLister:    ls.GetAWSCognitoUserPoolSourceLister().AWSCognitoUserPoolSources,
 		}
 
 		return reconcilerv1alpha1.NewReconciler(ctx, logging.FromContext(ctx),
@@ -47,13 +47,13 @@ var _ common.AdapterDeploymentBuilder = (*Reconciler)(nil)
 func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *appsv1.Deployment {
 	typedSrc := src.(*v1alpha1.AWSDynamoDBSource)
 
-	return common.NewAdapterDeployment(src, sinkURI,
+	return common.NewAdapterDeployment(src, sinkURI, append([]resource.ObjectOption{
 		resource.Image(r.adapterCfg.Image),
 
 		resource.EnvVar(common.EnvARN, typedSrc.Spec.ARN.String()),
 		resource.EnvVars(common.MakeSecurityCredentialsEnvVars(typedSrc.Spec.Credentials)...),
 		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
-	)
+	}, common.ShardedAdapterOptions(src, typedSrc.Spec.Replicas)...)...)
 }
 
 // RBACOwners implements common.AdapterDeploymentBuilder.
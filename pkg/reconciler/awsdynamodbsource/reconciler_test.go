@@ -27,6 +27,7 @@ import (
 	"knative.dev/eventing/pkg/reconciler/source"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
+	"knative.dev/pkg/ptr"
 	rt "knative.dev/pkg/reconciler/testing"
 
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
@@ -43,10 +44,18 @@ func TestReconcileSource(t *testing.T) {
 	}
 
 	ctor := reconcilerCtor(adapterCfg)
-	src := newEventSource()
 	ab := adapterBuilder(adapterCfg)
 
-	TestReconcileAdapter(t, ctor, src, ab)
+	t.Run("Single replica", func(t *testing.T) {
+		TestReconcileAdapter(t, ctor, newEventSource(), ab)
+	})
+
+	t.Run("Multiple replicas", func(t *testing.T) {
+		src := newEventSource()
+		src.Spec.Replicas = ptr.Int32(3)
+
+		TestReconcileAdapter(t, ctor, src, ab)
+	})
 }
 
 // reconcilerCtor returns a Ctor for a AWSDynamoDBSource Reconciler.
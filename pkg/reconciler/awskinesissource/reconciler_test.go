@@ -20,13 +20,17 @@ import (
 	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/aws/aws-sdk-go/service/kinesis"
 
 	corev1 "k8s.io/api/core/v1"
 
 	"knative.dev/eventing/pkg/reconciler/source"
+	"knative.dev/pkg/apis"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
+	"knative.dev/pkg/ptr"
 	rt "knative.dev/pkg/reconciler/testing"
 
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
@@ -43,10 +47,39 @@ func TestReconcileSource(t *testing.T) {
 	}
 
 	ctor := reconcilerCtor(adapterCfg)
-	src := newEventSource()
 	ab := adapterBuilder(adapterCfg)
 
-	TestReconcileAdapter(t, ctor, src, ab)
+	t.Run("Single replica", func(t *testing.T) {
+		TestReconcileAdapter(t, ctor, newEventSource(), ab)
+	})
+
+	t.Run("Multiple replicas", func(t *testing.T) {
+		src := newEventSource()
+		src.Spec.Replicas = ptr.Int32(3)
+
+		TestReconcileAdapter(t, ctor, src, ab)
+	})
+}
+
+func TestShardCoordination(t *testing.T) {
+	ab := adapterBuilder(&adapterConfig{
+		configs: &source.EmptyVarsGenerator{},
+	})
+
+	src := newEventSource()
+	src.Spec.Replicas = ptr.Int32(1)
+
+	adapter := ab.BuildAdapter(src, &apis.URL{Scheme: "http", Host: "sink"})
+
+	var coordinateShards string
+	for _, e := range adapter.Spec.Template.Spec.Containers[0].Env {
+		if e.Name == "COORDINATE_SHARDS" {
+			coordinateShards = e.Value
+		}
+	}
+
+	assert.Equal(t, "true", coordinateShards,
+		"Expected shards to be coordinated even with a single replica")
 }
 
 // reconcilerCtor returns a Ctor for a AWSKinesisSource Reconciler.
@@ -17,7 +17,9 @@ limitations under the License.
 package awsperformanceinsightssource
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -34,9 +36,22 @@ import (
 
 const (
 	envPollingInterval = "POLLING_INTERVAL"
+	envPeriod          = "PI_PERIOD"
 	envMetrics         = "PI_METRICS"
+	envMetricQueries   = "PI_METRIC_QUERIES"
+	envTopDimensions   = "PI_TOP_DIMENSIONS"
+	envInstances       = "PI_INSTANCES"
 )
 
+// Default granularity of Performance Insights datapoints, in seconds.
+const defaultPeriod int64 = 60
+
+// envMultiTenant is the name of the environment variable which enables the
+// multi-tenant mode of the adapter.
+const envMultiTenant = "PI_MULTI_TENANT"
+
+const healthPortName = "health"
+
 // adapterConfig contains properties used to configure the source's adapter.
 // These are automatically populated by envconfig.
 type adapterConfig struct {
@@ -51,20 +66,66 @@ var _ common.AdapterDeploymentBuilder = (*Reconciler)(nil)
 
 // BuildAdapter implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *appsv1.Deployment {
+	if v1alpha1.IsMultiTenant(src) {
+		return r.buildMTAdapter(src)
+	}
+
 	typedSrc := src.(*v1alpha1.AWSPerformanceInsightsSource)
 
+	var metricQueries string
+	if qs := typedSrc.Spec.MetricQueries; len(qs) > 0 {
+		q, _ := json.Marshal(qs)
+		metricQueries = string(q)
+	}
+
+	var topDimensions string
+	if tds := typedSrc.Spec.TopDimensions; len(tds) > 0 {
+		td, _ := json.Marshal(tds)
+		topDimensions = string(td)
+	}
+
+	period := defaultPeriod
+	if p := typedSrc.Spec.Period; p != nil {
+		period = *p
+	}
+
+	var instances string
+	if is := typedSrc.Status.Instances; len(is) > 0 {
+		i, _ := json.Marshal(is)
+		instances = string(i)
+	}
+
 	return common.NewAdapterDeployment(src, sinkURI,
 		resource.Image(r.adapterCfg.Image),
 
 		resource.EnvVar(common.EnvARN, typedSrc.Spec.ARN.String()),
 		resource.EnvVar(envPollingInterval, typedSrc.Spec.PollingInterval.String()),
+		resource.EnvVar(envPeriod, strconv.FormatInt(period, 10)),
 		resource.EnvVar(envMetrics, strings.Join(typedSrc.Spec.Metrics, ",")),
+		resource.EnvVar(envMetricQueries, metricQueries),
+		resource.EnvVar(envTopDimensions, topDimensions),
+		resource.EnvVar(envInstances, instances),
 
 		resource.EnvVars(common.MakeSecurityCredentialsEnvVars(typedSrc.Spec.Credentials)...),
 		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
 	)
 }
 
+// buildMTAdapter returns the multi-tenant adapter which polls the database metrics of
+// all sources that opted into multi-tenancy in the namespace of the given
+// source.
+func (r *Reconciler) buildMTAdapter(src v1alpha1.EventSource) *appsv1.Deployment {
+	return common.NewMTAdapterDeployment(src,
+		resource.Image(r.adapterCfg.Image),
+
+		resource.EnvVar(envMultiTenant, "true"),
+		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
+
+		resource.Port(healthPortName, 8080),
+		resource.Probe("/health", healthPortName),
+	)
+}
+
 // RBACOwners implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) RBACOwners(namespace string) ([]kmeta.OwnerRefable, error) {
 	srcs, err := r.srcLister(namespace).List(labels.Everything())
@@ -18,19 +18,26 @@ package awsperformanceinsightssource
 
 import (
 	"context"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 
 	"knative.dev/eventing/pkg/reconciler/source"
+	k8sclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
 
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
 	informerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/informers/sources/v1alpha1/awsperformanceinsightssource"
 	reconcilerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/reconciler/sources/v1alpha1/awsperformanceinsightssource"
+	rdsclient "github.com/triggermesh/aws-event-sources/pkg/client/rds"
 	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
 )
 
+// the resync period ensures we regularly re-check the state of database instances.
+const informerResyncPeriod = time.Minute * 5
+
 // NewController creates a Reconciler for the event source and returns the result of NewImpl.
 func NewController(
 	ctx context.Context,
@@ -52,6 +59,7 @@ func NewController(
 	r := &Reconciler{
 		adapterCfg: adapterCfg,
 		srcLister:  informer.Lister().AWSPerformanceInsightsSources,
+		rdsCg:      rdsclient.NewClientGetter(k8sclient.Get(ctx).CoreV1().Secrets),
 	}
 	impl := reconcilerv1alpha1.NewImpl(ctx, r)
 
@@ -62,7 +70,13 @@ func NewController(
 		impl.EnqueueControllerOf,
 	)
 
-	informer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
+	// multi-tenant adapters are owned by a ServiceAccount instead of the
+	// source they serve
+	common.WatchMTAdapterDeployment(ctx, typ,
+		common.EnqueueObjectsInNamespaceOf(informer.Informer(), impl.FilteredGlobalResync, logging.FromContext(ctx)),
+	)
+
+	informer.Informer().AddEventHandlerWithResyncPeriod(controller.HandleAll(impl.Enqueue), informerResyncPeriod)
 
 	return impl
 }
@@ -0,0 +1,213 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsperformanceinsightssource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/pi"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/reconciler"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	rdsclient "github.com/triggermesh/aws-event-sources/pkg/client/rds"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/skip"
+)
+
+// Performance Insights service type of Amazon DocumentDB instances, which is
+// not declared in the AWS SDK.
+const serviceTypeDocDB = "DOCDB"
+
+// Database engine of Amazon DocumentDB instances.
+const engineDocDB = "docdb"
+
+// Types of RDS resources, as exposed in ARNs.
+// https://docs.aws.amazon.com/service-authorization/latest/reference/list_amazonrds.html#amazonrds-resources-for-iam-policies
+const (
+	resourceTypeInstance = "db"
+	resourceTypeCluster  = "cluster"
+)
+
+// ensureInstancesResolved resolves the database instances matching the ARN of
+// the source and reports them in its status.
+func (r *Reconciler) ensureInstancesResolved(ctx context.Context) error {
+	if skip.Skip(ctx) {
+		return nil
+	}
+
+	src := v1alpha1.SourceFromContext(ctx).(*v1alpha1.AWSPerformanceInsightsSource)
+	status := &src.Status
+
+	rdsClient, err := r.rdsCg.Get(src)
+	if err != nil {
+		status.MarkInstancesNotResolved(v1alpha1.AWSPerformanceInsightsReasonNoClient, "Cannot obtain RDS client")
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedResolve,
+			"Error creating RDS client: %s", err))
+	}
+
+	instances, err := describeInstances(ctx, rdsClient, src.Spec.ARN.String())
+	switch {
+	case isNotFound(err) || (err == nil && len(instances) == 0):
+		status.MarkInstancesNotResolved(v1alpha1.AWSPerformanceInsightsReasonNotFound,
+			"No database instance exists for the given ARN")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedResolve,
+			"No database instance found for ARN %q", src.Spec.ARN))
+
+	case isDenied(err):
+		status.MarkInstancesNotResolved(v1alpha1.AWSPerformanceInsightsReasonAPIError, "Request to RDS API got rejected")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedResolve,
+			"Authorization error describing database instances: %s", toErrMsg(err)))
+
+	case err != nil:
+		status.MarkInstancesNotResolved(v1alpha1.AWSPerformanceInsightsReasonAPIError, "Cannot describe database instances")
+		// wrap any other error to fail the reconciliation
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedResolve,
+			"Error describing database instances: %s", toErrMsg(err)))
+	}
+
+	var resolved []v1alpha1.AWSPerformanceInsightsInstance
+	var disabled []string
+
+	for _, inst := range instances {
+		if !aws.BoolValue(inst.PerformanceInsightsEnabled) {
+			disabled = append(disabled, aws.StringValue(inst.DBInstanceIdentifier))
+			continue
+		}
+
+		resolved = append(resolved, v1alpha1.AWSPerformanceInsightsInstance{
+			ARN:         aws.StringValue(inst.DBInstanceArn),
+			ResourceID:  aws.StringValue(inst.DbiResourceId),
+			ServiceType: serviceType(inst),
+		})
+	}
+
+	if len(resolved) == 0 {
+		status.MarkInstancesNotResolved(v1alpha1.AWSPerformanceInsightsReasonDisabled,
+			"Performance Insights is disabled on database instances: "+strings.Join(disabled, ", "))
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedResolve,
+			"Performance Insights is disabled on all database instances for ARN %q", src.Spec.ARN))
+	}
+
+	status.MarkInstancesResolved(resolved)
+
+	return nil
+}
+
+// describeInstances returns the database instances matching the given ARN,
+// which may be the ARN of either an instance or a cluster.
+func describeInstances(ctx context.Context, cli rdsclient.Client, arnStr string) ([]*rds.DBInstance, error) {
+	resType, err := resourceType(arnStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var filterName string
+	switch resType {
+	case resourceTypeInstance:
+		filterName = "db-instance-id"
+	case resourceTypeCluster:
+		filterName = "db-cluster-id"
+	default:
+		return nil, fmt.Errorf("unsupported resource type %q", resType)
+	}
+
+	in := &rds.DescribeDBInstancesInput{
+		Filters: []*rds.Filter{{
+			Name:   &filterName,
+			Values: []*string{&arnStr},
+		}},
+	}
+
+	var instances []*rds.DBInstance
+
+	err = cli.DescribeDBInstancesPagesWithContext(ctx, in, func(out *rds.DescribeDBInstancesOutput, lastPage bool) bool {
+		instances = append(instances, out.DBInstances...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+// resourceType returns the type of the RDS resource represented by the given
+// ARN, e.g. "db" for "arn:aws:rds:us-west-2:123456789012:db:mydb".
+func resourceType(arnStr string) (string, error) {
+	parts := strings.SplitN(arnStr, ":", 7)
+	if len(parts) != 7 {
+		return "", fmt.Errorf("invalid RDS resource ARN %q", arnStr)
+	}
+	return parts[5], nil
+}
+
+// serviceType returns the Performance Insights service type of the given
+// database instance.
+func serviceType(inst *rds.DBInstance) string {
+	if aws.StringValue(inst.Engine) == engineDocDB {
+		return serviceTypeDocDB
+	}
+	return pi.ServiceTypeRds
+}
+
+// isNotFound returns whether the given error indicates that some resource
+// was not found.
+func isNotFound(err error) bool {
+	if k8sErr := apierrors.APIStatus(nil); errors.As(err, &k8sErr) {
+		return k8sErr.Status().Reason == metav1.StatusReasonNotFound
+	}
+	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
+		return awsErr.Code() == rds.ErrCodeDBInstanceNotFoundFault ||
+			awsErr.Code() == rds.ErrCodeDBClusterNotFoundFault
+	}
+	return false
+}
+
+// isDenied returns whether the given error indicates that a request to the RDS
+// API could not be authorized.
+func isDenied(err error) bool {
+	if awsReqFail := awserr.RequestFailure(nil); errors.As(err, &awsReqFail) {
+		code := awsReqFail.StatusCode()
+		return code == http.StatusUnauthorized || code == http.StatusForbidden
+	}
+	return false
+}
+
+// toErrMsg attempts to extract the message from the given error if it is an
+// AWS error.
+// Those errors are particularly verbose and include a unique request ID that
+// causes an infinite loop of reconciliations when appended to a status
+// condition. Some AWS errors are not recoverable without manual intervention
+// (e.g. invalid secrets) so there is no point letting that behaviour happen.
+func toErrMsg(err error) string {
+	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
+		return awserr.SprintError(awsErr.Code(), awsErr.Message(), "", awsErr.OrigErr())
+	}
+	return err.Error()
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
 	reconcilerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/reconciler/sources/v1alpha1/awsperformanceinsightssource"
 	listersv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/listers/sources/v1alpha1"
+	rdsclient "github.com/triggermesh/aws-event-sources/pkg/client/rds"
 	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
 )
 
@@ -33,6 +34,9 @@ type Reconciler struct {
 	adapterCfg *adapterConfig
 
 	srcLister func(namespace string) listersv1alpha1.AWSPerformanceInsightsSourceNamespaceLister
+
+	// RDS API client getter
+	rdsCg rdsclient.ClientGetter
 }
 
 // Check that our Reconciler implements Interface
@@ -43,5 +47,10 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, src *v1alpha1.AWSPerform
 	// inject source into context for usage in reconciliation logic
 	ctx = v1alpha1.WithSource(ctx, src)
 
+	// the adapter can only be configured once the database instances are known
+	if err := r.ensureInstancesResolved(ctx); err != nil {
+		return err
+	}
+
 	return r.base.ReconcileSource(ctx, r)
 }
@@ -0,0 +1,319 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awsperformanceinsightssource
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/pi"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"knative.dev/eventing/pkg/reconciler/source"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	rt "knative.dev/pkg/reconciler/testing"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis"
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	fakeinjectionclient "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/client/fake"
+	reconcilerv1alpha1 "github.com/triggermesh/aws-event-sources/pkg/client/generated/injection/reconciler/sources/v1alpha1/awsperformanceinsightssource"
+	rdsclient "github.com/triggermesh/aws-event-sources/pkg/client/rds"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
+	. "github.com/triggermesh/aws-event-sources/pkg/reconciler/testing"
+)
+
+func TestReconcileSource(t *testing.T) {
+	adapterCfg := &adapterConfig{
+		Image:   "registry/image:tag",
+		configs: &source.EmptyVarsGenerator{},
+	}
+
+	ctor := reconcilerCtor(adapterCfg)
+	ab := adapterBuilder(adapterCfg)
+
+	t.Run("Single-tenant", func(t *testing.T) {
+		TestReconcileAdapter(t, ctor, newEventSource(), ab)
+	})
+
+	t.Run("Multi-tenant", func(t *testing.T) {
+		src := newEventSource()
+		src.Annotations = map[string]string{
+			v1alpha1.MultiTenantAnnotation: "true",
+		}
+
+		TestReconcileAdapter(t, ctor, src, ab)
+	})
+}
+
+// reconcilerCtor returns a Ctor for a AWSPerformanceInsightsSource Reconciler.
+func reconcilerCtor(cfg *adapterConfig) Ctor {
+	return func(t *testing.T, ctx context.Context, _ *rt.TableRow, ls *Listers) controller.Reconciler {
+		rdsCli := &mockedRDSClient{
+			pages: [][]*rds.DBInstance{{tInstance}},
+		}
+
+		r := &Reconciler{
+			base:       NewTestDeploymentReconciler(ctx, ls),
+			adapterCfg: cfg,
+			srcLister:  ls.GetAWSPerformanceInsightsSourceLister().AWSPerformanceInsightsSources,
+			rdsCg:      staticClientGetter(rdsCli),
+		}
+
+		return reconcilerv1alpha1.NewReconciler(ctx, logging.FromContext(ctx),
+			fakeinjectionclient.Get(ctx), ls.GetAWSPerformanceInsightsSourceLister(),
+			controller.GetEventRecorder(ctx), r)
+	}
+}
+
+// newEventSource returns a test source object with a minimal set of pre-filled attributes.
+func newEventSource() *v1alpha1.AWSPerformanceInsightsSource {
+	src := &v1alpha1.AWSPerformanceInsightsSource{
+		Spec: v1alpha1.AWSPerformanceInsightsSourceSpec{
+			ARN:             tARN,
+			PollingInterval: apis.Duration(5 * time.Minute),
+			Metrics:         []string{"db.load.avg"},
+			Credentials: v1alpha1.AWSSecurityCredentials{
+				AccessKeyID: v1alpha1.ValueFromField{
+					ValueFromSecret: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "test-secret",
+						},
+						Key: "keyId",
+					},
+				},
+				SecretAccessKey: v1alpha1.ValueFromField{
+					ValueFromSecret: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "test-secret",
+						},
+						Key: "secret",
+					},
+				},
+			},
+		},
+	}
+
+	// assume the instances were already resolved to ensure generic tests
+	// only observe status changes related to the adapter
+	src.Status.MarkInstancesResolved([]v1alpha1.AWSPerformanceInsightsInstance{{
+		ARN:         tARN.String(),
+		ResourceID:  *tInstance.DbiResourceId,
+		ServiceType: pi.ServiceTypeRds,
+	}})
+
+	Populate(src)
+
+	return src
+}
+
+// adapterBuilder returns a slim Reconciler containing only the fields accessed
+// by r.BuildAdapter().
+func adapterBuilder(cfg *adapterConfig) common.AdapterDeploymentBuilder {
+	return &Reconciler{
+		adapterCfg: cfg,
+	}
+}
+
+var (
+	tARN = NewARN(rds.ServiceName, "db:triggermeshtest")
+
+	tInstance = &rds.DBInstance{
+		DBInstanceArn:              aws.String(tARN.String()),
+		DBInstanceIdentifier:       aws.String("triggermeshtest"),
+		DbiResourceId:              aws.String("db-0123456789ABCDEFGHIJKLMNOP"),
+		Engine:                     aws.String("postgres"),
+		PerformanceInsightsEnabled: aws.Bool(true),
+	}
+)
+
+// TestEnsureInstancesResolved contains tests specific to the Performance
+// Insights source.
+func TestEnsureInstancesResolved(t *testing.T) {
+	clusterARN := NewARN(rds.ServiceName, "cluster:triggermeshtest")
+
+	newInstance := func(id, engine string, piEnabled bool) *rds.DBInstance {
+		return &rds.DBInstance{
+			DBInstanceArn:              aws.String(NewARN(rds.ServiceName, "db:"+id).String()),
+			DBInstanceIdentifier:       aws.String(id),
+			DbiResourceId:              aws.String("db-" + id),
+			Engine:                     aws.String(engine),
+			PerformanceInsightsEnabled: aws.Bool(piEnabled),
+		}
+	}
+
+	testCases := map[string]struct {
+		arn   apis.ARN
+		pages [][]*rds.DBInstance
+		err   error
+
+		expectFilter    string
+		expectInstances []v1alpha1.AWSPerformanceInsightsInstance
+		expectReason    string
+		expectErr       bool
+	}{
+		"Single instance": {
+			arn: tARN,
+			pages: [][]*rds.DBInstance{
+				{tInstance},
+			},
+			expectFilter: "db-instance-id",
+			expectInstances: []v1alpha1.AWSPerformanceInsightsInstance{{
+				ARN:         tARN.String(),
+				ResourceID:  *tInstance.DbiResourceId,
+				ServiceType: pi.ServiceTypeRds,
+			}},
+		},
+		"Cluster instances across pages": {
+			arn: clusterARN,
+			pages: [][]*rds.DBInstance{
+				{newInstance("inst1", "docdb", true), newInstance("inst2", "docdb", false)},
+				{newInstance("inst3", "docdb", true)},
+			},
+			expectFilter: "db-cluster-id",
+			expectInstances: []v1alpha1.AWSPerformanceInsightsInstance{{
+				ARN:         NewARN(rds.ServiceName, "db:inst1").String(),
+				ResourceID:  "db-inst1",
+				ServiceType: serviceTypeDocDB,
+			}, {
+				ARN:         NewARN(rds.ServiceName, "db:inst3").String(),
+				ResourceID:  "db-inst3",
+				ServiceType: serviceTypeDocDB,
+			}},
+		},
+		"Instance not found": {
+			arn:          tARN,
+			err:          awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "not found", nil),
+			expectFilter: "db-instance-id",
+			expectReason: v1alpha1.AWSPerformanceInsightsReasonNotFound,
+			expectErr:    true,
+		},
+		"No instance in cluster": {
+			arn:          clusterARN,
+			pages:        [][]*rds.DBInstance{{}},
+			expectFilter: "db-cluster-id",
+			expectReason: v1alpha1.AWSPerformanceInsightsReasonNotFound,
+			expectErr:    true,
+		},
+		"Performance Insights disabled": {
+			arn: tARN,
+			pages: [][]*rds.DBInstance{
+				{newInstance("triggermeshtest", "postgres", false)},
+			},
+			expectFilter: "db-instance-id",
+			expectReason: v1alpha1.AWSPerformanceInsightsReasonDisabled,
+			expectErr:    true,
+		},
+		"Access denied": {
+			arn: tARN,
+			err: awserr.NewRequestFailure(awserr.New("AccessDenied", "denied", nil),
+				http.StatusForbidden, "0123456789"),
+			expectFilter: "db-instance-id",
+			expectReason: v1alpha1.AWSPerformanceInsightsReasonAPIError,
+			expectErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			cli := &mockedRDSClient{
+				pages: tc.pages,
+				err:   tc.err,
+			}
+
+			r := &Reconciler{
+				rdsCg: staticClientGetter(cli),
+			}
+
+			src := newEventSource()
+			src.Spec.ARN = tc.arn
+
+			err := r.ensureInstancesResolved(v1alpha1.WithSource(context.Background(), src))
+
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if assert.NotNil(t, cli.input, "DescribeDBInstances was not called") {
+				filters := cli.input.Filters
+				if assert.Len(t, filters, 1) {
+					assert.Equal(t, tc.expectFilter, *filters[0].Name)
+					assert.Equal(t, []*string{aws.String(tc.arn.String())}, filters[0].Values)
+				}
+			}
+
+			assert.Equal(t, tc.expectInstances, src.Status.Instances)
+
+			cond := src.Status.GetCondition(v1alpha1.AWSPerformanceInsightsConditionInstancesResolved)
+			if tc.expectReason == "" {
+				assert.True(t, cond.IsTrue())
+			} else {
+				assert.True(t, cond.IsFalse())
+				assert.Equal(t, tc.expectReason, cond.Reason)
+			}
+		})
+	}
+}
+
+/* RDS client */
+
+// staticClientGetter transforms the given client interface into a
+// ClientGetter.
+func staticClientGetter(cli rdsclient.Client) rdsclient.ClientGetterFunc {
+	return func(*v1alpha1.AWSPerformanceInsightsSource) (rdsclient.Client, error) {
+		return cli, nil
+	}
+}
+
+type mockedRDSClient struct {
+	rdsclient.Client
+
+	pages [][]*rds.DBInstance
+	err   error
+
+	input *rds.DescribeDBInstancesInput
+}
+
+func (c *mockedRDSClient) DescribeDBInstancesPagesWithContext(_ aws.Context, in *rds.DescribeDBInstancesInput,
+	fn func(*rds.DescribeDBInstancesOutput, bool) bool, _ ...request.Option) error {
+
+	c.input = in
+
+	if c.err != nil {
+		return c.err
+	}
+
+	for i, p := range c.pages {
+		if !fn(&rds.DescribeDBInstancesOutput{DBInstances: p}, i == len(c.pages)-1) {
+			break
+		}
+	}
+
+	return nil
+}
@@ -18,8 +18,11 @@ package awss3source
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	kr "k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/labels"
 
@@ -32,7 +35,13 @@ import (
 	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/resource"
 )
 
-const envMessageProcessor = "SQS_MESSAGE_PROCESSOR"
+const (
+	envMessageProcessor              = "SQS_MESSAGE_PROCESSOR"
+	envS3Enrichment                  = "S3_ENRICHMENT"
+	envS3EnrichmentTags              = "S3_ENRICHMENT_TAGS"
+	envS3EnrichmentInlineMaxSize     = "S3_ENRICHMENT_INLINE_MAX_SIZE"
+	envS3EnrichmentPresignExpiration = "S3_ENRICHMENT_PRESIGN_EXPIRATION"
+)
 
 const healthPortName = "health"
 
@@ -65,6 +74,7 @@ func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *
 		resource.EnvVar(common.EnvNamespace, src.GetNamespace()),
 		resource.EnvVar(common.EnvName, src.GetName()),
 		resource.EnvVar(envMessageProcessor, "s3"),
+		resource.EnvVars(makeEnrichmentEnvVars(typedSrc.Spec.Enrichment)...),
 		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
 
 		resource.Port(healthPortName, 8080),
@@ -84,6 +94,40 @@ func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *
 	)
 }
 
+// makeEnrichmentEnvVars returns the environment variables which enable the
+// enrichment of events by the adapter, if requested in the source's spec.
+func makeEnrichmentEnvVars(e *v1alpha1.AWSS3SourceEnrichment) []corev1.EnvVar {
+	if e == nil {
+		return nil
+	}
+
+	envs := []corev1.EnvVar{{
+		Name:  envS3Enrichment,
+		Value: "true",
+	}}
+
+	if e.Tags != nil && *e.Tags {
+		envs = append(envs, corev1.EnvVar{
+			Name:  envS3EnrichmentTags,
+			Value: "true",
+		})
+	}
+	if s := e.InlineContentMaxSize; s != nil && *s > 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  envS3EnrichmentInlineMaxSize,
+			Value: strconv.FormatInt(*s, 10),
+		})
+	}
+	if exp := e.PresignedURLExpiration; exp != nil && time.Duration(*exp) > 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  envS3EnrichmentPresignExpiration,
+			Value: time.Duration(*exp).String(),
+		})
+	}
+
+	return envs
+}
+
 // RBACOwners implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) RBACOwners(namespace string) ([]kmeta.OwnerRefable, error) {
 	srcs, err := r.srcLister(namespace).List(labels.Everything())
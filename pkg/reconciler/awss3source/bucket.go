@@ -18,25 +18,21 @@ package awss3source
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"net/http"
 	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/reconciler"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
-	"github.com/aws/aws-sdk-go/service/sqs"
 
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
 	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/event"
 )
 
@@ -50,42 +46,94 @@ func (r *Reconciler) ensureNotificationsEnabled(ctx context.Context, cli s3iface
 
 	bucketARN := typedSrc.Spec.ARN
 
-	notifCfg, err := getNotificationsConfig(ctx, cli, bucketARN.Resource)
-	switch {
-	case isNotFound(err):
-		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonNoBucket, "Bucket does not exist")
-		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
-			"The bucket does not exist: %s", toErrMsg(err)))
-	case isAWSError(err):
-		// All documented API errors require some user intervention and
-		// are not to be retried.
-		// https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
-		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Request to S3 API got rejected")
-		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
-			"Failed to synchronize bucket configuration: %s", toErrMsg(err)))
-	case err != nil:
-		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot obtain current bucket configuration")
-		// wrap any other error to fail the finalization
-		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
-			"Error reading current event notifications configuration: %s", toErrMsg(err)))
+	desiredQueueCfg := makeQueueConfiguration(typedSrc, queueARN)
+
+	if err := subscribeBucket(ctx, cli, bucketARN.Resource, desiredQueueCfg, status.MarkNotSubscribed); err != nil {
+		return err
 	}
 
-	desiredQueueCfg := makeQueueConfiguration(typedSrc, queueARN)
+	if !status.GetCondition(v1alpha1.AWSS3ConditionSubscribed).IsTrue() {
+		event.Normal(ctx, ReasonSubscribed, "Configured event notifications for S3 bucket %q", bucketARN)
+	}
+	status.MarkSubscribed()
+
+	return nil
+}
+
+// markNotSubscribedFunc records the reason why a bucket could not be
+// subscribed to.
+type markNotSubscribedFunc func(reason, msg string)
+
+// subscribeBucket ensures that the given QueueConfiguration is set in the event
+// notifications configuration of the given S3 bucket.
+// Failures are recorded using the given markNotSubscribedFunc.
+func subscribeBucket(ctx context.Context, cli s3iface.S3API, bucket string,
+	desiredQueueCfg *s3.QueueConfiguration, markNotSubscribed markNotSubscribedFunc) error {
+
+	notifCfg, err := getNotificationsConfig(ctx, cli, bucket)
+	if err != nil {
+		return notificationsConfigReadError(markNotSubscribed, err)
+	}
+
+	// S3 rejects configurations which would cause an event to be
+	// delivered to multiple destinations, but the error returned by the
+	// API doesn't tell which configuration is conflicting.
+	if id, overlaps := findOverlappingConfiguration(notifCfg, desiredQueueCfg); overlaps {
+		markNotSubscribed(v1alpha1.AWSS3ReasonOverlappingFilter,
+			fmt.Sprintf("Event types and key filter overlap with the event notification %q", id))
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Event notification overlaps with the existing configuration %q of the bucket", id))
+	}
 
 	notifCfg, hasUpdates := setQueueConfiguration(notifCfg, desiredQueueCfg)
 
 	if hasUpdates {
-		if err := configureNotifications(ctx, cli, bucketARN.Resource, notifCfg); err != nil {
-			status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot configure event notifications")
+		if err := configureNotifications(ctx, cli, bucket, notifCfg); err != nil {
+			markNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot configure event notifications")
 			return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
-				"Error configuring event notifications: %s", toErrMsg(err)))
+				"Error configuring event notifications: %s", common.ToErrMsg(err)))
 		}
 	}
 
-	if !status.GetCondition(v1alpha1.AWSS3ConditionSubscribed).IsTrue() {
-		event.Normal(ctx, ReasonSubscribed, "Configured event notifications for S3 bucket %q", bucketARN)
+	return nil
+}
+
+// ensureEventBridgeNotificationsEnabled ensures that the S3 bucket sends its
+// events to Amazon EventBridge.
+// Any event notification previously configured for the source's SQS queue is
+// removed, since events would otherwise be delivered to the queue twice.
+func (r *Reconciler) ensureEventBridgeNotificationsEnabled(ctx context.Context, cli s3iface.S3API) error {
+	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := src.(*v1alpha1.AWSS3Source)
+
+	status := &typedSrc.Status
+
+	bucketARN := typedSrc.Spec.ARN
+
+	notifCfg, err := getNotificationsConfig(ctx, cli, bucketARN.Resource)
+	if err != nil {
+		return notificationsConfigReadError(status.MarkNotSubscribed, err)
+	}
+
+	var hasUpdates bool
+
+	if notifCfg.EventBridgeConfiguration == nil {
+		notifCfg.EventBridgeConfiguration = &s3.EventBridgeConfiguration{}
+		hasUpdates = true
+	}
+
+	if numQueueCfgs := len(notifCfg.QueueConfigurations); numQueueCfgs > 0 {
+		notifCfg = removeQueueConfiguration(notifCfg, sourceID(src))
+		hasUpdates = hasUpdates || len(notifCfg.QueueConfigurations) != numQueueCfgs
+	}
+
+	if hasUpdates {
+		if err := configureNotifications(ctx, cli, bucketARN.Resource, notifCfg); err != nil {
+			status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot enable EventBridge notifications")
+			return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+				"Error enabling EventBridge notifications: %s", common.ToErrMsg(err)))
+		}
 	}
-	status.MarkSubscribed()
 
 	return nil
 }
@@ -98,31 +146,51 @@ func (r *Reconciler) ensureNotificationsDisabled(ctx context.Context, cli s3ifac
 
 	bucketARN := typedSrc.Spec.ARN
 
-	notifCfg, err := getNotificationsConfig(ctx, cli, bucketARN.Resource)
+	unsubscribed, err := unsubscribeBucket(ctx, cli, bucketARN.Resource, sourceID(src))
+	if err != nil || !unsubscribed {
+		return err
+	}
+
+	return reconciler.NewEvent(corev1.EventTypeNormal, ReasonUnsubscribed,
+		"Disabled event notifications for S3 bucket %q", bucketARN)
+}
+
+// unsubscribeBucket ensures that the QueueConfiguration with the given ID is
+// removed from the event notifications configuration of the given S3 bucket.
+// The returned boolean value indicates whether the bucket configuration was
+// effectively updated. Errors which can not be recovered from are recorded as
+// warning events and ignored.
+func unsubscribeBucket(ctx context.Context, cli s3iface.S3API, bucket, id string) (bool, error) {
+	notifCfg, err := getNotificationsConfig(ctx, cli, bucket)
 	switch {
-	case isNotFound(err):
-		return reconciler.NewEvent(corev1.EventTypeNormal, ReasonUnsubscribed,
-			"Bucket not found, skipping finalization")
-	case isDenied(err):
+	case common.IsNotFound(err):
+		event.Normal(ctx, ReasonUnsubscribed, "Bucket %q not found, skipping finalization", bucket)
+		return false, nil
+	case common.IsDenied(err):
 		// it is unlikely that we recover from auth errors in the
 		// finalizer, so we simply record a warning event and return
 		event.Warn(ctx, ReasonFailedUnsubscribe,
-			"Authorization error getting bucket configuration. Ignoring: %s", toErrMsg(err))
-		return nil
+			"Authorization error getting configuration of bucket %q. Ignoring: %s", bucket, common.ToErrMsg(err))
+		return false, nil
 	case err != nil:
-		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
-			"Error reading current event notifications configuration: %s", toErrMsg(err))
+		return false, reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error reading current event notifications configuration: %s", common.ToErrMsg(err))
 	}
 
-	notifCfg = removeQueueConfiguration(notifCfg, sourceID(src))
+	numQueueCfgs := len(notifCfg.QueueConfigurations)
 
-	if err := configureNotifications(ctx, cli, bucketARN.Resource, notifCfg); err != nil {
-		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
-			"Error configuring event notifications: %s", toErrMsg(err)))
+	notifCfg = removeQueueConfiguration(notifCfg, id)
+
+	if len(notifCfg.QueueConfigurations) == numQueueCfgs {
+		return false, nil
 	}
 
-	return reconciler.NewEvent(corev1.EventTypeNormal, ReasonUnsubscribed,
-		"Disabled event notifications for S3 bucket %q", bucketARN)
+	if err := configureNotifications(ctx, cli, bucket, notifCfg); err != nil {
+		return false, fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error configuring event notifications: %s", common.ToErrMsg(err)))
+	}
+
+	return true, nil
 }
 
 // getNotificationsConfig reads the current event notifications configuration
@@ -138,6 +206,30 @@ func getNotificationsConfig(ctx context.Context, cli s3iface.S3API, bucket strin
 	return resp, nil
 }
 
+// notificationsConfigReadError marks the bucket as not subscribed and returns
+// a reconciliation event matching the given error, which was returned while
+// reading the event notifications configuration of the S3 bucket.
+func notificationsConfigReadError(markNotSubscribed markNotSubscribedFunc, err error) error {
+	switch {
+	case common.IsNotFound(err):
+		markNotSubscribed(v1alpha1.AWSS3ReasonNoBucket, "Bucket does not exist")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"The bucket does not exist: %s", common.ToErrMsg(err)))
+	case common.IsAWSError(err):
+		// All documented API errors require some user intervention and
+		// are not to be retried.
+		// https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
+		markNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Request to S3 API got rejected")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Failed to synchronize bucket configuration: %s", common.ToErrMsg(err)))
+	default:
+		markNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot obtain current bucket configuration")
+		// wrap any other error to fail the reconciliation
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Error reading current event notifications configuration: %s", common.ToErrMsg(err)))
+	}
+}
+
 // configureNotifications configures event notifications for the given S3 bucket.
 func configureNotifications(ctx context.Context, cli s3iface.S3API, bucket string, cfg *s3.NotificationConfiguration) error {
 	_, err := cli.PutBucketNotificationConfigurationWithContext(ctx, &s3.PutBucketNotificationConfigurationInput{
@@ -156,10 +248,154 @@ func makeQueueConfiguration(src *v1alpha1.AWSS3Source, queueARN string) *s3.Queu
 	return &s3.QueueConfiguration{
 		Id:       aws.String(sourceID(src)),
 		Events:   aws.StringSlice(src.Spec.EventTypes),
+		Filter:   makeNotificationFilter(src.Spec.Filter),
 		QueueArn: &queueARN,
 	}
 }
 
+// makeNotificationFilter returns a NotificationConfigurationFilter matching
+// the given source filter, or nil if the filter doesn't contain any rule.
+func makeNotificationFilter(f *v1alpha1.AWSS3SourceFilter) *s3.NotificationConfigurationFilter {
+	if f == nil {
+		return nil
+	}
+
+	var rules []*s3.FilterRule
+
+	if prefix := aws.StringValue(f.Prefix); prefix != "" {
+		rules = append(rules, &s3.FilterRule{
+			Name:  aws.String(s3.FilterRuleNamePrefix),
+			Value: &prefix,
+		})
+	}
+	if suffix := aws.StringValue(f.Suffix); suffix != "" {
+		rules = append(rules, &s3.FilterRule{
+			Name:  aws.String(s3.FilterRuleNameSuffix),
+			Value: &suffix,
+		})
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	return &s3.NotificationConfigurationFilter{
+		Key: &s3.KeyFilter{
+			FilterRules: rules,
+		},
+	}
+}
+
+// keyFilterRules returns the prefix and suffix rules of the given
+// NotificationConfigurationFilter. Empty values are returned for rules which
+// are not set.
+func keyFilterRules(f *s3.NotificationConfigurationFilter) (prefix, suffix string) {
+	if f == nil || f.Key == nil {
+		return "", ""
+	}
+
+	// the S3 API returns rule names capitalized ("Prefix", "Suffix")
+	// regardless of the case used while setting them
+	for _, r := range f.Key.FilterRules {
+		switch name := aws.StringValue(r.Name); {
+		case strings.EqualFold(name, s3.FilterRuleNamePrefix):
+			prefix = aws.StringValue(r.Value)
+		case strings.EqualFold(name, s3.FilterRuleNameSuffix):
+			suffix = aws.StringValue(r.Value)
+		}
+	}
+
+	return prefix, suffix
+}
+
+// equalFilters returns whether two NotificationConfigurationFilters are
+// semantically equal.
+func equalFilters(a, b *s3.NotificationConfigurationFilter) bool {
+	aPrefix, aSuffix := keyFilterRules(a)
+	bPrefix, bSuffix := keyFilterRules(b)
+
+	return aPrefix == bPrefix && aSuffix == bSuffix
+}
+
+// findOverlappingConfiguration returns the ID of the first event notification
+// configuration of the bucket, other than the given QueueConfiguration, that
+// would receive some of the same events.
+// Ref. https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-how-to-filtering.html#notification-how-to-filtering-examples-invalid
+func findOverlappingConfiguration(nCfg *s3.NotificationConfiguration, qCfg *s3.QueueConfiguration) (string, bool) {
+	overlapsWith := func(id *string, events []*string, filter *s3.NotificationConfigurationFilter) bool {
+		return aws.StringValue(id) != aws.StringValue(qCfg.Id) &&
+			overlappingEventTypes(qCfg.Events, events) &&
+			overlappingFilters(qCfg.Filter, filter)
+	}
+
+	for _, cfg := range nCfg.QueueConfigurations {
+		if overlapsWith(cfg.Id, cfg.Events, cfg.Filter) {
+			return aws.StringValue(cfg.Id), true
+		}
+	}
+	for _, cfg := range nCfg.TopicConfigurations {
+		if overlapsWith(cfg.Id, cfg.Events, cfg.Filter) {
+			return aws.StringValue(cfg.Id), true
+		}
+	}
+	for _, cfg := range nCfg.LambdaFunctionConfigurations {
+		if overlapsWith(cfg.Id, cfg.Events, cfg.Filter) {
+			return aws.StringValue(cfg.Id), true
+		}
+	}
+
+	return "", false
+}
+
+// overlappingEventTypes returns whether two lists of bucket event types have
+// at least one event type in common, taking wildcards into account.
+func overlappingEventTypes(a, b []*string) bool {
+	for _, aTyp := range a {
+		for _, bTyp := range b {
+			if overlappingEventType(aTyp, bTyp) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// overlappingEventType returns whether two bucket event types match some of
+// the same events.
+// Example: "s3:ObjectCreated:*", "s3:ObjectCreated:Put" -> true
+func overlappingEventType(a, b *string) bool {
+	aTyp, bTyp := aws.StringValue(a), aws.StringValue(b)
+	if aTyp == bTyp {
+		return true
+	}
+
+	aCategory, aName := splitEventType(aTyp)
+	bCategory, bName := splitEventType(bTyp)
+
+	return aCategory == bCategory && (aName == "*" || bName == "*")
+}
+
+// splitEventType splits a bucket event type into a category and a name.
+// Example: "s3:ObjectRemoved:Delete" -> "s3:ObjectRemoved", "Delete"
+func splitEventType(typ string) (category, name string) {
+	if i := strings.LastIndexByte(typ, ':'); i > strings.IndexByte(typ, ':') {
+		return typ[:i], typ[i+1:]
+	}
+	return typ, ""
+}
+
+// overlappingFilters returns whether two NotificationConfigurationFilters can
+// match the same object key. Empty rules match any key.
+func overlappingFilters(a, b *s3.NotificationConfigurationFilter) bool {
+	aPrefix, aSuffix := keyFilterRules(a)
+	bPrefix, bSuffix := keyFilterRules(b)
+
+	overlappingPrefixes := strings.HasPrefix(aPrefix, bPrefix) || strings.HasPrefix(bPrefix, aPrefix)
+	overlappingSuffixes := strings.HasSuffix(aSuffix, bSuffix) || strings.HasSuffix(bSuffix, aSuffix)
+
+	return overlappingPrefixes && overlappingSuffixes
+}
+
 // setQueueConfiguration sets/updates a QueueConfiguration in the given
 // NotificationConfiguration, without touching existing configurations.
 // The returned boolean value indicates whether some updates need to be applied
@@ -172,7 +408,7 @@ func setQueueConfiguration(nCfg *s3.NotificationConfiguration, qCfg *s3.QueueCon
 		if *cfg.Id == *qCfg.Id {
 			isSet = true
 			nCfg.QueueConfigurations[i] = qCfg
-			hasUpdates = !equalEventTypes(qCfg.Events, cfg.Events)
+			hasUpdates = !equalEventTypes(qCfg.Events, cfg.Events) || !equalFilters(qCfg.Filter, cfg.Filter)
 			break
 		}
 	}
@@ -221,48 +457,3 @@ func removeQueueConfiguration(nCfg *s3.NotificationConfiguration, id string) *s3
 
 	return nCfg
 }
-
-// isNotFound returns whether the given error indicates that some resource was
-// not found.
-func isNotFound(err error) bool {
-	if k8sErr := apierrors.APIStatus(nil); errors.As(err, &k8sErr) {
-		return k8sErr.Status().Reason == metav1.StatusReasonNotFound
-	}
-	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
-		errcode := awsErr.Code()
-		return errcode == sqs.ErrCodeQueueDoesNotExist ||
-			errcode == s3.ErrCodeNoSuchBucket
-	}
-	return false
-}
-
-// isDenied returns whether the given error indicates that a request to the AWS
-// API could not be authorized.
-func isDenied(err error) bool {
-	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
-		if awsReqFail := awserr.RequestFailure(nil); errors.As(err, &awsReqFail) {
-			code := awsReqFail.StatusCode()
-			return code == http.StatusUnauthorized || code == http.StatusForbidden
-		}
-	}
-	return false
-}
-
-// isAWSError returns whether the given error is an AWS API error.
-func isAWSError(err error) bool {
-	awsErr := awserr.Error(nil)
-	return errors.As(err, &awsErr)
-}
-
-// toErrMsg attempts to extract the message from the given error if it is an
-// AWS error.
-// Those errors are particularly verbose and include a unique request ID that
-// causes an infinite loop of reconciliations when appended to a status
-// condition. Some AWS errors are not recoverable without manual intervention
-// (e.g. invalid secrets) so there is no point letting that behaviour happen.
-func toErrMsg(err error) string {
-	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
-		return awserr.SprintError(awsErr.Code(), awsErr.Message(), "", awsErr.OrigErr())
-	}
-	return err.Error()
-}
@@ -0,0 +1,179 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+func TestFindOverlappingConfiguration(t *testing.T) {
+	const tID = "io.triggermesh.awss3sources.test.test"
+
+	desired := &s3.QueueConfiguration{
+		Id:     aws.String(tID),
+		Events: aws.StringSlice([]string{"s3:ObjectCreated:Put"}),
+		Filter: makeNotificationFilter(&v1alpha1.AWSS3SourceFilter{
+			Prefix: aws.String("images/"),
+			Suffix: aws.String(".jpg"),
+		}),
+	}
+
+	testCases := map[string]struct {
+		cfg           *s3.NotificationConfiguration
+		expectID      string
+		expectOverlap bool
+	}{
+		"no other configuration": {
+			cfg: &s3.NotificationConfiguration{},
+		},
+		"own configuration is ignored": {
+			cfg: &s3.NotificationConfiguration{
+				QueueConfigurations: []*s3.QueueConfiguration{{
+					Id:     aws.String(tID),
+					Events: aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+				}},
+			},
+		},
+		"wildcard event type without filter": {
+			cfg: &s3.NotificationConfiguration{
+				QueueConfigurations: []*s3.QueueConfiguration{{
+					Id:     aws.String("other"),
+					Events: aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+				}},
+			},
+			expectID:      "other",
+			expectOverlap: true,
+		},
+		"distinct event types": {
+			cfg: &s3.NotificationConfiguration{
+				QueueConfigurations: []*s3.QueueConfiguration{{
+					Id:     aws.String("other"),
+					Events: aws.StringSlice([]string{"s3:ObjectCreated:Copy", "s3:ObjectRemoved:*"}),
+				}},
+			},
+		},
+		"overlapping prefix and suffix": {
+			cfg: &s3.NotificationConfiguration{
+				TopicConfigurations: []*s3.TopicConfiguration{{
+					Id:     aws.String("topic"),
+					Events: aws.StringSlice([]string{"s3:ObjectCreated:Put"}),
+					Filter: &s3.NotificationConfigurationFilter{
+						Key: &s3.KeyFilter{
+							FilterRules: []*s3.FilterRule{{
+								Name:  aws.String("Prefix"),
+								Value: aws.String("images/2021/"),
+							}},
+						},
+					},
+				}},
+			},
+			expectID:      "topic",
+			expectOverlap: true,
+		},
+		"distinct prefixes": {
+			cfg: &s3.NotificationConfiguration{
+				LambdaFunctionConfigurations: []*s3.LambdaFunctionConfiguration{{
+					Id:     aws.String("function"),
+					Events: aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+					Filter: &s3.NotificationConfigurationFilter{
+						Key: &s3.KeyFilter{
+							FilterRules: []*s3.FilterRule{{
+								Name:  aws.String("Prefix"),
+								Value: aws.String("videos/"),
+							}},
+						},
+					},
+				}},
+			},
+		},
+		"distinct suffixes": {
+			cfg: &s3.NotificationConfiguration{
+				QueueConfigurations: []*s3.QueueConfiguration{{
+					Id:     aws.String("other"),
+					Events: aws.StringSlice([]string{"s3:ObjectCreated:Put"}),
+					Filter: &s3.NotificationConfigurationFilter{
+						Key: &s3.KeyFilter{
+							FilterRules: []*s3.FilterRule{{
+								Name:  aws.String("Suffix"),
+								Value: aws.String(".png"),
+							}},
+						},
+					},
+				}},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			id, overlaps := findOverlappingConfiguration(tc.cfg, desired)
+			assert.Equal(t, tc.expectOverlap, overlaps)
+			assert.Equal(t, tc.expectID, id)
+		})
+	}
+}
+
+func TestSetQueueConfigurationFilter(t *testing.T) {
+	const tID = "io.triggermesh.awss3sources.test.test"
+
+	current := &s3.NotificationConfiguration{
+		QueueConfigurations: []*s3.QueueConfiguration{{
+			Id:     aws.String(tID),
+			Events: aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+			Filter: &s3.NotificationConfigurationFilter{
+				Key: &s3.KeyFilter{
+					FilterRules: []*s3.FilterRule{{
+						// the S3 API returns capitalized rule names
+						Name:  aws.String("Prefix"),
+						Value: aws.String("images/"),
+					}},
+				},
+			},
+		}},
+	}
+
+	sameFilter := &s3.QueueConfiguration{
+		Id:     aws.String(tID),
+		Events: aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+		Filter: makeNotificationFilter(&v1alpha1.AWSS3SourceFilter{
+			Prefix: aws.String("images/"),
+		}),
+	}
+
+	_, hasUpdates := setQueueConfiguration(current, sameFilter)
+	assert.False(t, hasUpdates, "Filter rule names should be compared case-insensitively")
+
+	newFilter := &s3.QueueConfiguration{
+		Id:     aws.String(tID),
+		Events: aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+		Filter: makeNotificationFilter(&v1alpha1.AWSS3SourceFilter{
+			Prefix: aws.String("images/"),
+			Suffix: aws.String(".jpg"),
+		}),
+	}
+
+	_, hasUpdates = setQueueConfiguration(current, newFilter)
+	assert.True(t, hasUpdates, "A change of filter should cause an update")
+}
@@ -0,0 +1,222 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/aws/iam"
+)
+
+// Prefix of the Sid of all policy statements managed by AWSS3Sources.
+const policyStatementIDPrefix = "AWSS3Source"
+
+// queuePolicy is the access policy of a SQS queue.
+//
+// Unlike iam.Policy, it keeps statements in their original serialized form, so
+// that statements which were not written by the reconciler are preserved as is
+// when the policy is updated, even if they use elements of the IAM policy
+// grammar which are not modelled by iam.PolicyStatement.
+type queuePolicy struct {
+	Version   string            `json:"Version"`
+	Id        string            `json:"Id,omitempty"`
+	Statement []json.RawMessage `json:"Statement"`
+}
+
+// parseQueuePolicy deserializes the given JSON policy document. An empty
+// document yields a policy without statements.
+func parseQueuePolicy(doc string) (*queuePolicy, error) {
+	pol := &queuePolicy{}
+
+	if doc == "" {
+		return pol, nil
+	}
+
+	// the "Statement" element of a policy can be either a single
+	// statement or an array of statements
+	var rawPol struct {
+		Version   string          `json:"Version"`
+		Id        string          `json:"Id"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(doc), &rawPol); err != nil {
+		return nil, fmt.Errorf("deserializing policy document: %w", err)
+	}
+
+	pol.Version = rawPol.Version
+	pol.Id = rawPol.Id
+
+	switch stmts := bytes.TrimSpace(rawPol.Statement); {
+	case len(stmts) == 0:
+	case stmts[0] == '[':
+		if err := json.Unmarshal(stmts, &pol.Statement); err != nil {
+			return nil, fmt.Errorf("deserializing policy statements: %w", err)
+		}
+	default:
+		pol.Statement = []json.RawMessage{stmts}
+	}
+
+	return pol, nil
+}
+
+// String serializes the policy to a JSON policy document.
+func (p *queuePolicy) String() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+// setStatement adds the given statement to the policy, or replaces the
+// statement which has the same Sid. The returned boolean value indicates
+// whether the policy was modified.
+func (p *queuePolicy) setStatement(stmt iam.PolicyStatement) (bool, error) {
+	rawStmt, err := json.Marshal(stmt)
+	if err != nil {
+		return false, fmt.Errorf("serializing policy statement: %w", err)
+	}
+
+	for i, s := range p.Statement {
+		if statementID(s) != stmt.Sid {
+			continue
+		}
+
+		var current iam.PolicyStatement
+		if err := json.Unmarshal(s, &current); err == nil && equalPolicyStatements(current, stmt) {
+			return false, nil
+		}
+
+		p.Statement[i] = rawStmt
+		return true, nil
+	}
+
+	if p.Version == "" {
+		newPol := iam.NewPolicy()
+		p.Version, p.Id = newPol.Version, newPol.Id
+	}
+
+	p.Statement = append(p.Statement, rawStmt)
+
+	return true, nil
+}
+
+// removeStatement removes the statement with the given Sid from the policy.
+// The returned boolean value indicates whether the policy was modified.
+func (p *queuePolicy) removeStatement(sid string) bool {
+	stmts := p.Statement[:0]
+
+	for _, s := range p.Statement {
+		if statementID(s) != sid {
+			stmts = append(stmts, s)
+		}
+	}
+
+	removed := len(stmts) != len(p.Statement)
+	p.Statement = stmts
+
+	return removed
+}
+
+// removeLegacyStatements removes the statements which are semantically equal
+// to one of the given statements, but have a random UUID as Sid. Such
+// statements were written by earlier versions of the reconciler, which
+// overwrote the entire policy of the queue. The returned boolean value
+// indicates whether the policy was modified.
+func (p *queuePolicy) removeLegacyStatements(legacy ...iam.PolicyStatement) bool {
+	stmts := p.Statement[:0]
+
+	for _, s := range p.Statement {
+		if !isLegacyStatement(s, legacy) {
+			stmts = append(stmts, s)
+		}
+	}
+
+	removed := len(stmts) != len(p.Statement)
+	p.Statement = stmts
+
+	return removed
+}
+
+// isLegacyStatement returns whether the given serialized policy statement has
+// a UUID as Sid and is semantically equal to one of the given statements.
+func isLegacyStatement(stmt json.RawMessage, legacy []iam.PolicyStatement) bool {
+	if _, err := uuid.Parse(statementID(stmt)); err != nil {
+		return false
+	}
+
+	var current iam.PolicyStatement
+	if err := json.Unmarshal(stmt, &current); err != nil {
+		return false
+	}
+
+	for _, l := range legacy {
+		if equalPolicyStatements(current, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSourceStatements returns whether the policy contains statements managed
+// by any AWSS3Source.
+func (p *queuePolicy) hasSourceStatements() bool {
+	for _, s := range p.Statement {
+		if strings.HasPrefix(statementID(s), policyStatementIDPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// statementID returns the Sid of the given serialized policy statement.
+func statementID(stmt json.RawMessage) string {
+	var s struct {
+		Sid string `json:"Sid"`
+	}
+	_ = json.Unmarshal(stmt, &s)
+
+	return s.Sid
+}
+
+// equalPolicyStatements returns whether two policy statements are
+// semantically equal.
+func equalPolicyStatements(a, b iam.PolicyStatement) bool {
+	if a.Effect != b.Effect {
+		return false
+	}
+	if !reflect.DeepEqual(a.Principal, b.Principal) {
+		return false
+	}
+	if !reflect.DeepEqual(a.Condition, b.Condition) {
+		return false
+	}
+	if !reflect.DeepEqual(a.Action, b.Action) {
+		return false
+	}
+	return reflect.DeepEqual(a.Resource, b.Resource)
+}
+
+// policyStatementID returns the Sid of the policy statement managed by the
+// given source instance. Statement IDs only accept alphanumeric characters.
+func policyStatementID(src *v1alpha1.AWSS3Source) string {
+	return policyStatementIDPrefix + sourceIDHash(src)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/aws-event-sources/pkg/aws/iam"
+)
+
+// A policy containing a statement which can not be represented by an
+// iam.PolicyStatement ("AWS" principal, single action string).
+const tForeignPolicy = `{
+	"Version": "2012-10-17",
+	"Id": "my-policy",
+	"Statement": {
+		"Sid": "UserStatement",
+		"Effect": "Allow",
+		"Principal": {"AWS": "arn:aws:iam::123456789012:root"},
+		"Action": "sqs:*",
+		"Resource": "arn:aws:sqs:us-fake-0:123456789012:MyQueue"
+	}
+}`
+
+func TestQueuePolicyStatements(t *testing.T) {
+	pol, err := parseQueuePolicy(tForeignPolicy)
+	require.NoError(t, err)
+	require.Len(t, pol.Statement, 1)
+	assert.False(t, pol.hasSourceStatements())
+
+	stmt := newS3ToSQSPolicyStatement(policyStatementIDPrefix+"0001",
+		"arn:aws:sqs:us-fake-0:123456789012:MyQueue", "arn:aws:s3:::my-bucket", "123456789012")
+
+	hasUpdates, err := pol.setStatement(stmt)
+	require.NoError(t, err)
+	assert.True(t, hasUpdates, "A new statement should be added")
+	assert.Len(t, pol.Statement, 2)
+	assert.True(t, pol.hasSourceStatements())
+
+	hasUpdates, err = pol.setStatement(stmt)
+	require.NoError(t, err)
+	assert.False(t, hasUpdates, "An identical statement should not cause an update")
+
+	updatedStmt := newS3ToSQSPolicyStatement(stmt.Sid,
+		"arn:aws:sqs:us-fake-0:123456789012:MyQueue", "arn:aws:s3:::other-bucket", "123456789012")
+
+	hasUpdates, err = pol.setStatement(updatedStmt)
+	require.NoError(t, err)
+	assert.True(t, hasUpdates, "A modified statement should be replaced")
+	assert.Len(t, pol.Statement, 2)
+
+	// the round trip must preserve the user's statement verbatim
+	pol, err = parseQueuePolicy(pol.String())
+	require.NoError(t, err)
+	assert.Equal(t, "my-policy", pol.Id)
+	assert.JSONEq(t, `{
+		"Sid": "UserStatement",
+		"Effect": "Allow",
+		"Principal": {"AWS": "arn:aws:iam::123456789012:root"},
+		"Action": "sqs:*",
+		"Resource": "arn:aws:sqs:us-fake-0:123456789012:MyQueue"
+	}`, string(pol.Statement[0]))
+
+	assert.True(t, pol.removeStatement(stmt.Sid))
+	assert.False(t, pol.removeStatement(stmt.Sid), "Statement should already be removed")
+	assert.Len(t, pol.Statement, 1)
+	assert.False(t, pol.hasSourceStatements())
+}
+
+func TestQueuePolicyEmpty(t *testing.T) {
+	pol, err := parseQueuePolicy("")
+	require.NoError(t, err)
+	assert.Empty(t, pol.Statement)
+
+	stmt := iam.NewPolicyStatement(iam.EffectAllow, iam.StatementID(policyStatementIDPrefix+"0001"))
+
+	hasUpdates, err := pol.setStatement(stmt)
+	require.NoError(t, err)
+	assert.True(t, hasUpdates)
+	assert.NotEmpty(t, pol.Version, "A policy version should be set")
+
+	_, err = parseQueuePolicy("not a policy")
+	assert.Error(t, err)
+}
+
+func TestQueuePolicyLegacyStatements(t *testing.T) {
+	const queueARN = "arn:aws:sqs:us-fake-0:123456789012:MyQueue"
+
+	legacyStmt := newS3ToSQSPolicyStatement("0b5f2a38-1c6e-4a0e-9d7a-3f2c1d0e4b6a",
+		queueARN, "arn:aws:s3:::my-bucket", "123456789012")
+	otherBucketStmt := newS3ToSQSPolicyStatement("5e0c4a1b-7f3d-4c2e-8b9a-6d1f0e2c3b4a",
+		queueARN, "arn:aws:s3:::other-bucket", "123456789012")
+	userStmt := newS3ToSQSPolicyStatement("UserStatement",
+		queueARN, "arn:aws:s3:::my-bucket", "123456789012")
+
+	pol := &queuePolicy{}
+	for _, stmt := range []iam.PolicyStatement{legacyStmt, otherBucketStmt, userStmt} {
+		_, err := pol.setStatement(stmt)
+		require.NoError(t, err)
+	}
+
+	legacy := newS3ToSQSPolicyStatement("", queueARN, "arn:aws:s3:::my-bucket", "123456789012")
+
+	assert.True(t, pol.removeLegacyStatements(legacy))
+	assert.False(t, pol.removeLegacyStatements(legacy), "Legacy statement should already be removed")
+
+	// only statements with a UUID Sid are considered legacy statements
+	require.Len(t, pol.Statement, 2)
+	assert.Equal(t, otherBucketStmt.Sid, statementID(pol.Statement[0]))
+	assert.Equal(t, userStmt.Sid, statementID(pol.Statement[1]))
+}
@@ -18,15 +18,14 @@ package awss3source
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"reflect"
 
 	corev1 "k8s.io/api/core/v1"
 
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/reconciler"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
 	awssqs "github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
@@ -36,6 +35,7 @@ import (
 	"github.com/triggermesh/aws-event-sources/pkg/aws/iam"
 	"github.com/triggermesh/aws-event-sources/pkg/aws/s3"
 	"github.com/triggermesh/aws-event-sources/pkg/aws/sqs"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
 	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/event"
 )
 
@@ -49,34 +49,41 @@ func ensureQueue(ctx context.Context, cli sqsiface.SQSAPI) (string /*arn*/, erro
 
 	if userProvided := typedSrc.Spec.QueueARN; userProvided != nil {
 		status.QueueARN = userProvided
-		return userProvided.String(), nil
-	}
 
-	queueName := queueName(typedSrc)
+		if !typedSrc.ManagesQueuePolicy() {
+			return userProvided.String(), nil
+		}
+
+		queueURL, err := sqs.QueueURLWithOwner(cli, userProvided.Resource, userProvided.AccountID)
+		if err != nil {
+			return "", queueLookupError(status, err)
+		}
+
+		queueAttrs, err := sqs.QueueAttributes(cli, queueURL, []string{awssqs.QueueAttributeNamePolicy})
+		if err != nil {
+			return "", fmt.Errorf("getting attributes of SQS queue: %w", err)
+		}
+
+		queueARN := userProvided.String()
+
+		err = syncQueuePolicy(cli, status, queueURL, queueAttrs[awssqs.QueueAttributeNamePolicy],
+			makeQueuePolicyStatement(queueARN, typedSrc), legacyQueuePolicyStatements(queueARN, typedSrc))
+		return queueARN, err
+	}
 
-	queueURL, err := sqs.QueueURL(cli, queueName)
+	queueURL, err := sourceQueueURL(cli, typedSrc)
 	switch {
-	case isNotFound(err):
-		queueURL, err = sqs.CreateQueue(cli, queueName, queueTags(typedSrc))
+	case common.IsNotFound(err):
+		queueURL, err = sqs.CreateQueue(cli, queueName(typedSrc), queueTags(typedSrc))
 		if err != nil {
 			status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Unable to create SQS queue")
 			return "", fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedQueue,
-				"Error creating SQS queue for event notifications: %s", toErrMsg(err)))
+				"Error creating SQS queue for event notifications: %s", common.ToErrMsg(err)))
 		}
 		event.Normal(ctx, ReasonQueueCreated, "Created SQS queue %q", queueURL)
 
-	case isAWSError(err):
-		// All documented API errors require some user intervention and
-		// are not to be retried.
-		// https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
-		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Request to SQS API got rejected")
-		return "", controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
-			"Failed to synchronize SQS queue: %s", toErrMsg(err)))
-
 	case err != nil:
-		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot synchronize SQS queue")
-		return "", fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
-			"Failed to determine URL of SQS queue: %s", toErrMsg(err)))
+		return "", queueLookupError(status, err)
 	}
 
 	getAttrs := []string{awssqs.QueueAttributeNameQueueArn, awssqs.QueueAttributeNamePolicy}
@@ -97,68 +104,76 @@ func ensureQueue(ctx context.Context, cli sqsiface.SQSAPI) (string /*arn*/, erro
 	// adapter properly
 	status.QueueARN = queueARNStruct
 
-	currentPol := unmarshalQueuePolicy(queueAttrs[awssqs.QueueAttributeNamePolicy])
-	desiredPol := makeQueuePolicy(queueARN, typedSrc)
-
-	if err := syncQueuePolicy(cli, queueURL, currentPol, desiredPol); err != nil {
-		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot synchronize SQS queue")
-		return queueARN, fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
-			"Error synchronizing policy of SQS queue: %s", toErrMsg(err)))
-	}
-
-	return queueARN, nil
+	err = syncQueuePolicy(cli, status, queueURL, queueAttrs[awssqs.QueueAttributeNamePolicy],
+		makeQueuePolicyStatement(queueARN, typedSrc), legacyQueuePolicyStatements(queueARN, typedSrc))
+	return queueARN, err
 }
 
 // ensureNoQueue ensures that the SQS queue created for sending S3 event
 // notifications is deleted.
+// Queues which were not created by the given source, or which policy still
+// grants permissions to other sources, are not deleted. Only the policy
+// statement of the given source is removed from them.
 func ensureNoQueue(ctx context.Context, cli sqsiface.SQSAPI) error {
 	src := v1alpha1.SourceFromContext(ctx)
 	typedSrc := src.(*v1alpha1.AWSS3Source)
 
 	if userProvided := typedSrc.Spec.QueueARN; userProvided != nil {
-		// do not delete queues managed by the user
-		return nil
+		if !typedSrc.ManagesQueuePolicy() {
+			// do not touch queues managed by the user
+			return nil
+		}
+
+		queueURL, err := sqs.QueueURLWithOwner(cli, userProvided.Resource, userProvided.AccountID)
+		if err := queueFinalizationLookupError(ctx, err); err != nil || queueURL == "" {
+			return err
+		}
+
+		// do not delete queues managed by the user, only revoke the
+		// permissions granted by the source
+		_, err = ensureNoQueuePolicyStatement(ctx, cli, queueURL, typedSrc)
+		return err
 	}
 
-	queueURL, err := sqs.QueueURL(cli, queueName(typedSrc))
-	switch {
-	case isNotFound(err):
-		event.Warn(ctx, ReasonUnsubscribed, "Queue not found, skipping deletion")
-		return nil
-	case isDenied(err):
-		// it is unlikely that we recover from auth errors in the
-		// finalizer, so we simply record a warning event and return
-		event.Warn(ctx, ReasonFailedUnsubscribe,
-			"Authorization error getting SQS queue. Ignoring: %s", toErrMsg(err))
-		return nil
-	case err != nil:
-		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
-			"Failed to determine URL of SQS queue: %s", toErrMsg(err))
+	queueURL, err := sourceQueueURL(cli, typedSrc)
+	if err := queueFinalizationLookupError(ctx, err); err != nil || queueURL == "" {
+		return err
 	}
 
-	owns, err := assertOwnership(cli, queueURL, typedSrc)
+	isOwned, err := sqs.AssertQueueOwnership(cli, queueURL, sourceID(typedSrc))
 	if err != nil {
 		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
-			"Failed to verify owner of SQS queue: %s", toErrMsg(err))
+			"Failed to verify owner of SQS queue: %s", common.ToErrMsg(err))
+	}
+
+	pol, err := ensureNoQueuePolicyStatement(ctx, cli, queueURL, typedSrc)
+	if err != nil || pol == nil {
+		return err
 	}
 
-	if !owns {
+	if !isOwned {
 		event.Warn(ctx, ReasonUnsubscribed, "Queue %q is not owned by this source instance, "+
 			"skipping deletion", queueURL)
 		return nil
 	}
 
+	if pol.hasSourceStatements() {
+		event.Warn(ctx, ReasonUnsubscribed, "Queue %q is still used by other sources, "+
+			"skipping deletion", queueURL)
+		return nil
+	}
+
 	err = sqs.DeleteQueue(cli, queueURL)
 	switch {
-	case isDenied(err):
+	case common.IsDenied(err):
 		// it is unlikely that we recover from auth errors in the
 		// finalizer, so we simply record a warning event and return
 		event.Warn(ctx, ReasonFailedUnsubscribe,
-			"Authorization error deleting SQS queue. Ignoring: %s", toErrMsg(err))
+			"Authorization error deleting SQS queue. Ignoring: %s", common.ToErrMsg(err))
 		return nil
 	case err != nil:
 		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
-			"Error deleting SQS queue: %s", toErrMsg(err))
+			"Error deleting SQS queue: %s", common.ToErrMsg(err))
 	}
 
 	event.Normal(ctx, ReasonQueueDeleted, "Deleted SQS queue %q", queueURL)
@@ -166,55 +181,185 @@ func ensureNoQueue(ctx context.Context, cli sqsiface.SQSAPI) error {
 	return nil
 }
 
-// syncQueuePolicy ensures that a SQS queue has the right permissions to
-// receive messages from the S3 bucket observed by the given source.
-func syncQueuePolicy(cli sqsiface.SQSAPI, queueURL string, current, desired iam.Policy) error {
-	if equalPolicies(desired, current) {
+// queueLookupError marks the source as not subscribed and returns a
+// reconciliation event matching the given error, which was returned while
+// looking up the URL of a SQS queue.
+func queueLookupError(status *v1alpha1.AWSS3SourceStatus, err error) error {
+	switch {
+	case common.IsNotFound(err):
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonNoQueue, "Queue does not exist")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"The SQS queue does not exist: %s", common.ToErrMsg(err)))
+	case common.IsAWSError(err):
+		// All documented API errors require some user intervention and
+		// are not to be retried.
+		// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_GetQueueUrl.html#API_GetQueueUrl_Errors
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Request to SQS API got rejected")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Failed to synchronize SQS queue: %s", common.ToErrMsg(err)))
+	default:
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot synchronize SQS queue")
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Failed to determine URL of SQS queue: %s", common.ToErrMsg(err)))
+	}
+}
+
+// queueFinalizationLookupError returns a reconciliation event matching the
+// given error, which was returned while looking up the URL of a SQS queue
+// during the finalization of a source.
+// Errors which can not be recovered from are recorded as warning events and
+// ignored, in which case nil is returned.
+func queueFinalizationLookupError(ctx context.Context, err error) error {
+	switch {
+	case common.IsNotFound(err):
+		event.Warn(ctx, ReasonUnsubscribed, "Queue not found, skipping finalization")
+		return nil
+	case common.IsDenied(err):
+		// it is unlikely that we recover from auth errors in the
+		// finalizer, so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Authorization error getting SQS queue. Ignoring: %s", common.ToErrMsg(err))
 		return nil
+	case err != nil:
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Failed to determine URL of SQS queue: %s", common.ToErrMsg(err))
 	}
+	return nil
+}
+
+// syncQueuePolicy ensures that the policy of a SQS queue contains the given
+// statement, without altering its other statements. The given legacy
+// statements are removed from the policy.
+func syncQueuePolicy(cli sqsiface.SQSAPI, status *v1alpha1.AWSS3SourceStatus,
+	queueURL, currentPolicy string, stmt iam.PolicyStatement, legacy []iam.PolicyStatement) error {
 
-	if err := sqs.SetQueuePolicy(cli, queueURL, desired); err != nil {
-		return fmt.Errorf("setting policy of SQS queue: %w", err)
+	pol, err := parseQueuePolicy(currentPolicy)
+	if err != nil {
+		// overwriting the policy would discard statements which may
+		// have been written by the owner of the queue
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot parse policy of SQS queue")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Error parsing policy of SQS queue: %s", err))
+	}
+
+	removedLegacy := pol.removeLegacyStatements(legacy...)
+
+	hasUpdates, err := pol.setStatement(stmt)
+	if err != nil {
+		return fmt.Errorf("setting policy statement: %w", err)
+	}
+	if !hasUpdates && !removedLegacy {
+		return nil
+	}
+
+	if err := sqs.SetQueuePolicyDocument(cli, queueURL, pol.String()); err != nil {
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot synchronize SQS queue")
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Error synchronizing policy of SQS queue: %s", common.ToErrMsg(err)))
 	}
 
 	return nil
 }
 
-// equalPolicies returns whether two SQS policies are semantically equal.
-func equalPolicies(a, b iam.Policy) bool {
-	if len(a.Statement) != len(b.Statement) {
-		return false
-	}
+// ensureNoQueuePolicyStatement ensures that the policy of a SQS queue doesn't
+// contain the statement of the given source, nor its legacy statements, and
+// returns the resulting policy.
+// A nil policy is returned alongside a nil error when the policy couldn't be
+// read and the error was ignored.
+func ensureNoQueuePolicyStatement(ctx context.Context, cli sqsiface.SQSAPI, queueURL string,
+	src *v1alpha1.AWSS3Source) (*queuePolicy, error) {
 
-	as, bs := a.Statement[0], b.Statement[0]
+	getAttrs := []string{awssqs.QueueAttributeNameQueueArn, awssqs.QueueAttributeNamePolicy}
+	queueAttrs, err := sqs.QueueAttributes(cli, queueURL, getAttrs)
+	switch {
+	case common.IsNotFound(err):
+		event.Warn(ctx, ReasonUnsubscribed, "Queue not found, skipping finalization")
+		return nil, nil
+	case common.IsDenied(err):
+		// it is unlikely that we recover from auth errors in the
+		// finalizer, so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Authorization error getting SQS queue attributes. Ignoring: %s", common.ToErrMsg(err))
+		return nil, nil
+	case err != nil:
+		return nil, reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error getting attributes of SQS queue: %s", common.ToErrMsg(err))
+	}
 
-	if !reflect.DeepEqual(as.Principal, bs.Principal) {
-		return false
+	pol, err := parseQueuePolicy(queueAttrs[awssqs.QueueAttributeNamePolicy])
+	if err != nil {
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Error parsing policy of SQS queue. Ignoring: %s", err)
+		return nil, nil
 	}
-	if !reflect.DeepEqual(as.Condition, bs.Condition) {
-		return false
+
+	removed := pol.removeStatement(policyStatementID(src))
+	removedLegacy := pol.removeLegacyStatements(
+		legacyQueuePolicyStatements(queueAttrs[awssqs.QueueAttributeNameQueueArn], src)...)
+
+	if !removed && !removedLegacy {
+		return pol, nil
 	}
-	if !reflect.DeepEqual(as.Action, bs.Action) {
-		return false
+
+	err = sqs.SetQueuePolicyDocument(cli, queueURL, pol.String())
+	switch {
+	case common.IsDenied(err):
+		// it is unlikely that we recover from auth errors in the
+		// finalizer, so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Authorization error updating policy of SQS queue. Ignoring: %s", common.ToErrMsg(err))
+		return nil, nil
+	case err != nil:
+		return nil, reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error updating policy of SQS queue: %s", common.ToErrMsg(err))
 	}
-	return reflect.DeepEqual(as.Resource, bs.Resource)
+
+	return pol, nil
 }
 
-// makeQueuePolicy creates an IAM policy for the given SQS queue ARN and source instance.
-func makeQueuePolicy(queueARN string, src *v1alpha1.AWSS3Source) iam.Policy {
+// makeQueuePolicyStatement returns the statement of the SQS queue's policy
+// which allows events to be delivered by the given source instance.
+// Depending on the source's mode, events are sent to the queue either by the
+// S3 bucket itself or by an EventBridge rule.
+func makeQueuePolicyStatement(queueARN string, src *v1alpha1.AWSS3Source) iam.PolicyStatement {
+	sid := policyStatementID(src)
+
+	if src.UsesEventBridge() {
+		return newEventBridgeToSQSPolicyStatement(sid, queueARN, ruleARN(src))
+	}
+
 	bucketARN := s3.RealBucketARN(src.Spec.ARN)
 	accID := src.Spec.ARN.AccountID
 
-	return iam.NewPolicy(
-		newS3ToSQSPolicyStatement(queueARN, bucketARN, accID),
-	)
+	if src.SelectsBuckets() {
+		// tags can not be expressed in the policy, so all buckets
+		// which names match the selector's prefix are allowed
+		bucketsARN := "arn:" + src.Spec.ARN.Partition + ":s3:::" + aws.StringValue(src.Spec.BucketSelector.NamePrefix) + "*"
+		return newS3BucketsToSQSPolicyStatement(sid, queueARN, bucketsARN, accID)
+	}
+
+	return newS3ToSQSPolicyStatement(sid, queueARN, bucketARN, accID)
+}
+
+// legacyQueuePolicyStatements returns the statements which earlier versions of
+// the reconciler may have written to the policy of the SQS queue of the given
+// source instance, in either mode.
+func legacyQueuePolicyStatements(queueARN string, src *v1alpha1.AWSS3Source) []iam.PolicyStatement {
+	// Sids are ignored when legacy statements are compared
+	const anySid = ""
+
+	return []iam.PolicyStatement{
+		newS3ToSQSPolicyStatement(anySid, queueARN, s3.RealBucketARN(src.Spec.ARN), src.Spec.ARN.AccountID),
+		newEventBridgeToSQSPolicyStatement(anySid, queueARN, ruleARN(src)),
+	}
 }
 
 // newS3ToSQSPolicyStatement returns an IAM Policy Statement that allows a S3
 // bucket to publish event notifications to the given SQS queue.
 // Ref. https://docs.aws.amazon.com/AmazonS3/latest/userguide/grant-destinations-permissions-to-s3.html#grant-sns-sqs-permission-for-s3
-func newS3ToSQSPolicyStatement(queueARN, bucketARN, accID string) iam.PolicyStatement {
+func newS3ToSQSPolicyStatement(sid, queueARN, bucketARN, accID string) iam.PolicyStatement {
 	return iam.NewPolicyStatement(iam.EffectAllow,
+		iam.StatementID(sid),
 		iam.PrincipalService("s3.amazonaws.com"),
 		iam.ConditionArnEquals("aws:SourceArn", bucketARN),
 		iam.ConditionStringEquals("aws:SourceAccount", accID),
@@ -223,30 +368,91 @@ func newS3ToSQSPolicyStatement(queueARN, bucketARN, accID string) iam.PolicyStat
 	)
 }
 
-// unmarshalQueuePolicy deserializes an IAM policy string.
-func unmarshalQueuePolicy(polStr string) iam.Policy {
-	var pol iam.Policy
-	_ = json.Unmarshal([]byte(polStr), &pol)
+// newS3BucketsToSQSPolicyStatement returns an IAM Policy Statement that allows
+// all S3 buckets matching the given ARN pattern to publish event notifications
+// to the given SQS queue.
+func newS3BucketsToSQSPolicyStatement(sid, queueARN, bucketsARN, accID string) iam.PolicyStatement {
+	return iam.NewPolicyStatement(iam.EffectAllow,
+		iam.StatementID(sid),
+		iam.PrincipalService("s3.amazonaws.com"),
+		iam.ConditionArnLike("aws:SourceArn", bucketsARN),
+		iam.ConditionStringEquals("aws:SourceAccount", accID),
+		iam.Action("sqs:SendMessage"),
+		iam.Resource(queueARN),
+	)
+}
 
-	// if an error occured, the policy will be empty syncQueuePolicy() will
-	// simply enforce the desired state
-	return pol
+// newEventBridgeToSQSPolicyStatement returns an IAM Policy Statement that
+// allows an EventBridge rule to send events to the given SQS queue.
+// Ref. https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-use-resource-based.html#eb-sqs-permissions
+func newEventBridgeToSQSPolicyStatement(sid, queueARN, ruleARN string) iam.PolicyStatement {
+	return iam.NewPolicyStatement(iam.EffectAllow,
+		iam.StatementID(sid),
+		iam.PrincipalService("events.amazonaws.com"),
+		iam.ConditionArnEquals("aws:SourceArn", ruleARN),
+		iam.Action("sqs:SendMessage"),
+		iam.Resource(queueARN),
+	)
 }
 
+// maxQueueNameLen is the maximum length of a SQS queue name.
+// https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_CreateQueue.html#API_CreateQueue_RequestParameters
+const maxQueueNameLen = 80
+
 // queueName returns a SQS queue name matching the given source instance.
+// Every source instance gets a queue of its own, because each source runs its
+// own SQS adapter which would otherwise compete for the messages of a shared
+// queue.
 func queueName(src *v1alpha1.AWSS3Source) string {
+	const prefix = "s3-events_"
+
+	if src.SelectsBuckets() {
+		return prefix + sourceIDHash(src)
+	}
+
+	hash := sourceIDHash(src)
+
+	bucket := src.Spec.ARN.Resource
+	// SQS queue names are limited to 80 characters
+	if maxLen := maxQueueNameLen - len(prefix) - len(hash) - 1; len(bucket) > maxLen {
+		bucket = bucket[:maxLen]
+	}
+
+	return prefix + bucket + "_" + hash
+}
+
+// legacyQueueName returns the name of the SQS queue which earlier versions of
+// the reconciler created for the given source instance, after the name of its
+// bucket only.
+func legacyQueueName(src *v1alpha1.AWSS3Source) string {
 	return "s3-events_" + src.Spec.ARN.Resource
 }
 
-// assertOwnership returns whether a SQS queue identified by URL is owned by
-// the given source.
-func assertOwnership(cli sqsiface.SQSAPI, queueURL string, src *v1alpha1.AWSS3Source) (bool, error) {
-	tags, err := sqs.QueueTags(cli, queueURL)
-	if err != nil {
-		return false, fmt.Errorf("listing tags of SQS queue: %w", err)
+// sourceQueueURL returns the URL of the SQS queue of the given source instance.
+// A queue which was created with a legacy name is used as long as it is owned
+// by the given source, so that messages which are still in flight don't get
+// lost. Otherwise, the error returned while looking up the queue with the
+// current name is returned.
+func sourceQueueURL(cli sqsiface.SQSAPI, src *v1alpha1.AWSS3Source) (string, error) {
+	queueURL, err := sqs.QueueURL(cli, queueName(src))
+	if !common.IsNotFound(err) || src.SelectsBuckets() {
+		return queueURL, err
+	}
+
+	legacyURL, legacyErr := sqs.QueueURL(cli, legacyQueueName(src))
+	if legacyErr != nil {
+		return "", err
+	}
+
+	isOwned, ownErr := sqs.AssertQueueOwnership(cli, legacyURL, sourceID(src))
+	if ownErr != nil {
+		return "", ownErr
+	}
+	if !isOwned {
+		return "", err
 	}
 
-	return tags["owned-by"] == sourceID(src), nil
+	return legacyURL, nil
 }
 
 // queueTags returns a set of tags containing information from the given source
@@ -255,7 +461,7 @@ func queueTags(src *v1alpha1.AWSS3Source) map[string]string {
 	return map[string]string{
 		"bucket-arn":    s3.RealBucketARN(src.Spec.ARN),
 		"bucket-region": src.Spec.ARN.Region,
-		"owned-by":      sourceID(src),
+		sqs.OwnerTagKey: sourceID(src),
 	}
 }
 
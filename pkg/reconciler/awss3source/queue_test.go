@@ -0,0 +1,251 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+
+	"github.com/triggermesh/aws-event-sources/pkg/aws/iam"
+	"github.com/triggermesh/aws-event-sources/pkg/aws/s3"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
+)
+
+func TestEnsureNoQueue(t *testing.T) {
+	src := newEventSource()
+
+	tQueueURL := "https://sqs.us-test-0.amazonaws.com/123456789012/" + queueName(src)
+
+	bucketARN := s3.RealBucketARN(src.Spec.ARN)
+	accID := src.Spec.ARN.AccountID
+
+	srcStmt := makeQueuePolicyStatement(tQueueARN, src)
+	otherSrcStmt := newS3ToSQSPolicyStatement(policyStatementIDPrefix+"0001", tQueueARN, bucketARN, accID)
+	legacyStmt := newS3ToSQSPolicyStatement("0b5f2a38-1c6e-4a0e-9d7a-3f2c1d0e4b6a", tQueueARN, bucketARN, accID)
+
+	otherSrcTags := map[string]string{
+		"bucket-arn": bucketARN,
+		"owned-by":   sourceIDPrefix + "other-ns.other-name",
+	}
+
+	testCases := map[string]struct {
+		tags  map[string]string
+		stmts []iam.PolicyStatement
+
+		expectStmts  []string
+		expectDelete bool
+	}{
+		"Queue created by the source": {
+			tags:         queueTags(src),
+			stmts:        []iam.PolicyStatement{srcStmt},
+			expectStmts:  []string{},
+			expectDelete: true,
+		},
+		"Queue created by another source": {
+			tags:         otherSrcTags,
+			stmts:        []iam.PolicyStatement{srcStmt},
+			expectStmts:  []string{},
+			expectDelete: false,
+		},
+		"Queue still used by another source": {
+			tags:         queueTags(src),
+			stmts:        []iam.PolicyStatement{srcStmt, otherSrcStmt},
+			expectStmts:  []string{otherSrcStmt.Sid},
+			expectDelete: false,
+		},
+		"Queue with a legacy statement": {
+			tags:         queueTags(src),
+			stmts:        []iam.PolicyStatement{legacyStmt},
+			expectStmts:  []string{},
+			expectDelete: true,
+		},
+		"Queue not created by any source": {
+			tags:         nil,
+			stmts:        []iam.PolicyStatement{srcStmt},
+			expectStmts:  []string{},
+			expectDelete: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			pol := &queuePolicy{}
+			for _, stmt := range tc.stmts {
+				_, err := pol.setStatement(stmt)
+				require.NoError(t, err)
+			}
+
+			cli := &mockedSQSClient{
+				queueURLs: map[string]string{
+					queueName(src): tQueueURL,
+				},
+				tags: tc.tags,
+				attrs: map[string]string{
+					sqs.QueueAttributeNameQueueArn: tQueueARN,
+					sqs.QueueAttributeNamePolicy:   pol.String(),
+				},
+			}
+
+			err := ensureNoQueue(contextWithSource(newEventSource()), cli)
+			require.NoError(t, err)
+
+			require.NotNil(t, cli.setAttrsInput, "Expected policy of the queue to be updated")
+			gotPol, err := parseQueuePolicy(aws.StringValue(cli.setAttrsInput.Attributes[sqs.QueueAttributeNamePolicy]))
+			require.NoError(t, err)
+
+			gotStmts := make([]string, len(gotPol.Statement))
+			for i, s := range gotPol.Statement {
+				gotStmts[i] = statementID(s)
+			}
+			assert.Equal(t, tc.expectStmts, gotStmts)
+
+			assert.Equal(t, tc.expectDelete, cli.deleteQueueInput != nil)
+		})
+	}
+}
+
+func TestQueueName(t *testing.T) {
+	src := newEventSource()
+
+	otherSrc := newEventSource()
+	otherSrc.Name = "other-name"
+
+	assert.NotEqual(t, queueName(src), queueName(otherSrc),
+		"Expected sources of the same bucket to get distinct queues")
+
+	src.Spec.ARN.Resource = strings.Repeat("b", 63)
+	assert.Len(t, queueName(src), maxQueueNameLen)
+}
+
+func TestSourceQueueURL(t *testing.T) {
+	const (
+		tQueueURL       = "https://sqs.us-test-0.amazonaws.com/123456789012/s3-events_my-bucket_0123456789abcdef"
+		tLegacyQueueURL = "https://sqs.us-test-0.amazonaws.com/123456789012/s3-events_my-bucket"
+	)
+
+	src := newEventSource()
+
+	otherSrc := newEventSource()
+	otherSrc.Name = "other-name"
+
+	testCases := map[string]struct {
+		queueURLs map[string]string
+		tags      map[string]string
+
+		expectURL      string
+		expectNotFound bool
+	}{
+		"Queue exists": {
+			queueURLs: map[string]string{
+				queueName(src):       tQueueURL,
+				legacyQueueName(src): tLegacyQueueURL,
+			},
+			tags:      queueTags(src),
+			expectURL: tQueueURL,
+		},
+		"Legacy queue owned by the source": {
+			queueURLs: map[string]string{
+				legacyQueueName(src): tLegacyQueueURL,
+			},
+			tags:      queueTags(src),
+			expectURL: tLegacyQueueURL,
+		},
+		"Legacy queue owned by another source": {
+			queueURLs: map[string]string{
+				legacyQueueName(src): tLegacyQueueURL,
+			},
+			tags:           queueTags(otherSrc),
+			expectNotFound: true,
+		},
+		"No queue": {
+			expectNotFound: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			cli := &mockedSQSClient{
+				queueURLs: tc.queueURLs,
+				tags:      tc.tags,
+			}
+
+			queueURL, err := sourceQueueURL(cli, src)
+
+			if tc.expectNotFound {
+				assert.True(t, common.IsNotFound(err), "Expected a not found error, got %v", err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectURL, queueURL)
+		})
+	}
+}
+
+// mockedSQSClient is a mocked SQS client which serves the tags and attributes
+// of a single queue and records requests which modify that queue.
+type mockedSQSClient struct {
+	sqsiface.SQSAPI
+
+	queueURLs map[string]string // by queue name
+	tags      map[string]string
+	attrs     map[string]string
+
+	setAttrsInput    *sqs.SetQueueAttributesInput
+	deleteQueueInput *sqs.DeleteQueueInput
+}
+
+func (c *mockedSQSClient) GetQueueUrl(in *sqs.GetQueueUrlInput) (*sqs.GetQueueUrlOutput, error) {
+	queueURL, ok := c.queueURLs[aws.StringValue(in.QueueName)]
+	if !ok {
+		return nil, awserr.New(sqs.ErrCodeQueueDoesNotExist, "queue does not exist", nil)
+	}
+	return &sqs.GetQueueUrlOutput{QueueUrl: &queueURL}, nil
+}
+
+func (c *mockedSQSClient) ListQueueTags(*sqs.ListQueueTagsInput) (*sqs.ListQueueTagsOutput, error) {
+	return &sqs.ListQueueTagsOutput{Tags: aws.StringMap(c.tags)}, nil
+}
+
+func (c *mockedSQSClient) GetQueueAttributes(in *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+	attrs := make(map[string]string, len(in.AttributeNames))
+	for _, n := range aws.StringValueSlice(in.AttributeNames) {
+		attrs[n] = c.attrs[n]
+	}
+
+	return &sqs.GetQueueAttributesOutput{Attributes: aws.StringMap(attrs)}, nil
+}
+
+func (c *mockedSQSClient) SetQueueAttributes(in *sqs.SetQueueAttributesInput) (*sqs.SetQueueAttributesOutput, error) {
+	c.setAttrsInput = in
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+func (c *mockedSQSClient) DeleteQueue(in *sqs.DeleteQueueInput) (*sqs.DeleteQueueOutput, error) {
+	c.deleteQueueInput = in
+	return &sqs.DeleteQueueOutput{}, nil
+}
@@ -19,9 +19,12 @@ package awss3source
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 
+	"knative.dev/pkg/controller"
 	"knative.dev/pkg/reconciler"
 
 	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
@@ -34,11 +37,14 @@ import (
 
 // Reconciler implements controller.Reconciler for the event source type.
 type Reconciler struct {
-	// Getter than can obtain clients for interacting with the S3 and SQS APIs
+	// Getter than can obtain clients for interacting with the S3, SQS and EventBridge APIs
 	s3Cg s3client.ClientGetter
 
 	srcLister func(namespace string) listersv1alpha1.AWSS3SourceNamespaceLister
 
+	// Regions of the S3 buckets evaluated against bucket selectors
+	bucketLocations bucketLocationCache
+
 	// SQS adapter
 	base       common.GenericDeploymentReconciler
 	adapterCfg *adapterConfig
@@ -55,7 +61,23 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, src *v1alpha1.AWSS3Sourc
 	// inject source into context for usage in reconciliation logic
 	ctx = v1alpha1.WithSource(ctx, src)
 
-	s3Client, sqsClient, err := r.s3Cg.Get(src)
+	// the bucket name and the bucket selector are mutually dependent,
+	// which can not be expressed in the CRD's schema
+	if err := src.Validate(ctx); err != nil {
+		src.Status.MarkNotSubscribed(v1alpha1.AWSS3ReasonInvalidSpec, err.Error())
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Invalid spec: %s", err))
+	}
+
+	// EventBridge rules match events from explicitly named buckets only
+	if src.SelectsBuckets() && src.UsesEventBridge() {
+		src.Status.MarkNotSubscribed(v1alpha1.AWSS3ReasonUnsupportedSelector,
+			"Bucket selectors are not supported in the eventbridge mode")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Bucket selectors can not be used together with the eventbridge mode"))
+	}
+
+	s3Client, sqsClient, ebClient, err := r.s3Cg.Get(src)
 	if err != nil {
 		src.Status.MarkNotSubscribed(v1alpha1.AWSS3ReasonNoClient, "Cannot obtain AWS API clients")
 		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
@@ -71,6 +93,37 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, src *v1alpha1.AWSS3Sourc
 		return fmt.Errorf("failed to reconcile SQS event source adapter: %w", err)
 	}
 
+	return r.ensureSubscribed(ctx, s3Client, ebClient, queueARN)
+}
+
+// ensureSubscribed ensures that events of the source's bucket(s) are delivered
+// to the given SQS queue, either directly or via EventBridge depending on the
+// source's mode.
+func (r *Reconciler) ensureSubscribed(ctx context.Context, s3Client s3client.Client,
+	ebClient s3client.EventBridgeClient, queueARN string) error {
+
+	src := v1alpha1.SourceFromContext(ctx).(*v1alpha1.AWSS3Source)
+
+	if src.UsesEventBridge() {
+		if err := r.ensureEventBridgeNotificationsEnabled(ctx, s3Client); err != nil {
+			return err
+		}
+		return ensureRule(ctx, ebClient, queueARN)
+	}
+
+	// the source may have been switched from the "eventbridge" mode, in
+	// which case its EventBridge rule is now obsolete
+	if src.Status.RuleARN != nil {
+		if err := ensureNoRule(ctx, ebClient); err != nil {
+			return fmt.Errorf("failed to finalize EventBridge rule: %w", err)
+		}
+		src.Status.RuleARN = nil
+	}
+
+	if src.SelectsBuckets() {
+		return r.ensureSelectedBucketsSubscribed(ctx, s3Client, queueARN)
+	}
+
 	return r.ensureNotificationsEnabled(ctx, s3Client, queueARN)
 }
 
@@ -79,9 +132,9 @@ func (r *Reconciler) FinalizeKind(ctx context.Context, src *v1alpha1.AWSS3Source
 	// inject source into context for usage in finalization logic
 	ctx = v1alpha1.WithSource(ctx, src)
 
-	s3Client, sqsClient, err := r.s3Cg.Get(src)
+	s3Client, sqsClient, ebClient, err := r.s3Cg.Get(src)
 	switch {
-	case isNotFound(err):
+	case common.IsNotFound(err):
 		// the finalizer is unlikely to recover from a missing Secret,
 		// so we simply record a warning event and return
 		event.Warn(ctx, ReasonFailedUnsubscribe,
@@ -92,18 +145,47 @@ func (r *Reconciler) FinalizeKind(ctx context.Context, src *v1alpha1.AWSS3Source
 			"Error creating AWS API clients: %s", err)
 	}
 
+	if src.UsesEventBridge() || src.Status.RuleARN != nil {
+		if err := ensureNoRule(ctx, ebClient); err != nil {
+			return fmt.Errorf("failed to finalize EventBridge rule: %w", err)
+		}
+	}
+
 	if err := ensureNoQueue(ctx, sqsClient); err != nil {
 		return fmt.Errorf("failed to finalize SQS queue: %w", err)
 	}
 
+	// Delivery of events to EventBridge is a bucket-wide setting which
+	// other consumers may depend on, so it is left enabled.
+	if src.UsesEventBridge() {
+		return nil
+	}
+
+	if src.SelectsBuckets() {
+		return r.ensureSelectedBucketsUnsubscribed(ctx, s3Client)
+	}
+
 	// The finalizer blocks the deletion of the source object until
 	// ensureNotificationsDisabled succeeds to ensure that we don't leave
 	// any dangling event notification configurations behind us.
 	return r.ensureNotificationsDisabled(ctx, s3Client)
 }
 
+// Prefix of the IDs of all AWSS3Source instances.
+const sourceIDPrefix = "io.triggermesh.awss3sources."
+
 // sourceID returns an ID that identifies the given source instance in AWS
 // resources or resources tags.
 func sourceID(src v1alpha1.EventSource) string {
-	return "io.triggermesh.awss3sources." + src.GetNamespace() + "." + src.GetName()
+	return sourceIDPrefix + src.GetNamespace() + "." + src.GetName()
+}
+
+// sourceIDHash returns a short hash of the ID of the given source instance, for
+// use in the names of AWS resources which are subject to length or character
+// restrictions.
+func sourceIDHash(src v1alpha1.EventSource) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(sourceID(src)))
+
+	return strconv.FormatUint(h.Sum64(), 16)
 }
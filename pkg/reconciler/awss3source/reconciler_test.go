@@ -0,0 +1,403 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"knative.dev/pkg/controller"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	. "github.com/triggermesh/aws-event-sources/pkg/reconciler/testing"
+)
+
+const tQueueARN = "arn:aws:sqs:us-test-0:123456789012:s3-events_my-bucket"
+
+// newEventSource returns a populated source object.
+func newEventSource() *v1alpha1.AWSS3Source {
+	src := &v1alpha1.AWSS3Source{
+		Spec: v1alpha1.AWSS3SourceSpec{
+			ARN:        NewARN(s3.ServiceName, "my-bucket"),
+			EventTypes: []string{"s3:ObjectCreated:*"},
+			Credentials: v1alpha1.AWSSecurityCredentials{
+				AccessKeyID: v1alpha1.ValueFromField{
+					ValueFromSecret: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "test-secret",
+						},
+						Key: "keyId",
+					},
+				},
+				SecretAccessKey: v1alpha1.ValueFromField{
+					ValueFromSecret: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "test-secret",
+						},
+						Key: "secret",
+					},
+				},
+			},
+		},
+	}
+
+	Populate(src)
+
+	return src
+}
+
+// newEventBridgeSource returns a populated source object in the "eventbridge"
+// mode.
+func newEventBridgeSource() *v1alpha1.AWSS3Source {
+	src := newEventSource()
+	src.Spec.Mode = aws.String(v1alpha1.AWSS3ModeEventBridge)
+	return src
+}
+
+// contextWithSource returns a context which contains the given source and a
+// fake event recorder.
+func contextWithSource(src *v1alpha1.AWSS3Source) context.Context {
+	ctx := controller.WithEventRecorder(context.Background(), record.NewFakeRecorder(10))
+	return v1alpha1.WithSource(ctx, src)
+}
+
+// TestEnsureRule contains tests specific to the "eventbridge" mode.
+func TestEnsureRule(t *testing.T) {
+	currentPattern, err := makeEventPattern(newEventBridgeSource())
+	require.NoError(t, err)
+
+	currentTargets := []*eventbridge.Target{{Id: aws.String(ruleTargetID), Arn: aws.String(tQueueARN)}}
+
+	testCases := map[string]struct {
+		client *mockedEventBridgeClient
+
+		expectPutRule    bool
+		expectPutTargets bool
+	}{
+		"Rule does not exist": {
+			client: &mockedEventBridgeClient{
+				describeRuleErr: awserr.New(eventbridge.ErrCodeResourceNotFoundException, "not found", nil),
+			},
+			expectPutRule:    true,
+			expectPutTargets: true,
+		},
+		"Rule has an outdated event pattern": {
+			client: &mockedEventBridgeClient{
+				eventPattern: `{"source":["aws.s3"],"detail-type":["Object Deleted"],"detail":{"bucket":{"name":["my-bucket"]}}}`,
+				targets:      currentTargets,
+			},
+			expectPutRule:    true,
+			expectPutTargets: false,
+		},
+		"Rule is up to date but has no target": {
+			client: &mockedEventBridgeClient{
+				eventPattern: currentPattern,
+			},
+			expectPutRule:    false,
+			expectPutTargets: true,
+		},
+		"Rule is up to date": {
+			client: &mockedEventBridgeClient{
+				eventPattern: currentPattern,
+				targets:      currentTargets,
+			},
+			expectPutRule:    false,
+			expectPutTargets: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			src := newEventBridgeSource()
+
+			err := ensureRule(contextWithSource(src), tc.client, tQueueARN)
+			require.NoError(t, err)
+
+			assert.True(t, src.Status.GetCondition(v1alpha1.AWSS3ConditionSubscribed).IsTrue())
+			require.NotNil(t, src.Status.RuleARN)
+			assert.Equal(t, ruleARN(src), src.Status.RuleARN.String())
+
+			if !tc.expectPutRule {
+				assert.Nil(t, tc.client.putRuleInput, "Unexpected PutRule request")
+			} else {
+				require.NotNil(t, tc.client.putRuleInput, "Expected a PutRule request")
+				assert.Equal(t, ruleName(src), *tc.client.putRuleInput.Name)
+
+				var pattern eventPattern
+				require.NoError(t, json.Unmarshal([]byte(*tc.client.putRuleInput.EventPattern), &pattern))
+				assert.Equal(t, []string{"my-bucket"}, pattern.Detail.Bucket.Name)
+				assert.Equal(t, []string{"Object Created"}, pattern.DetailType)
+			}
+
+			if !tc.expectPutTargets {
+				assert.Nil(t, tc.client.putTargetsInput, "Unexpected PutTargets request")
+			} else {
+				require.NotNil(t, tc.client.putTargetsInput, "Expected a PutTargets request")
+				assert.Equal(t, tQueueARN, *tc.client.putTargetsInput.Targets[0].Arn)
+			}
+		})
+	}
+
+	t.Run("Event types not delivered by EventBridge", func(t *testing.T) {
+		src := newEventBridgeSource()
+		src.Spec.EventTypes = []string{"s3:ReducedRedundancyLostObject"}
+
+		cli := &mockedEventBridgeClient{}
+
+		err := ensureRule(contextWithSource(src), cli, tQueueARN)
+		assert.True(t, controller.IsPermanentError(err), "Expected a permanent error")
+
+		cond := src.Status.GetCondition(v1alpha1.AWSS3ConditionSubscribed)
+		assert.True(t, cond.IsFalse())
+		assert.Equal(t, v1alpha1.AWSS3ReasonUnsupportedEventTypes, cond.Reason)
+		assert.Nil(t, cli.putRuleInput, "Unexpected PutRule request")
+	})
+}
+
+func TestEnsureNoRule(t *testing.T) {
+	testCases := map[string]struct {
+		client *mockedEventBridgeClient
+
+		expectDelete bool
+	}{
+		"Rule does not exist": {
+			client: &mockedEventBridgeClient{
+				listTagsErr: awserr.New(eventbridge.ErrCodeResourceNotFoundException, "not found", nil),
+			},
+			expectDelete: false,
+		},
+		"Rule is owned by another source": {
+			client: &mockedEventBridgeClient{
+				owner: "io.triggermesh.awss3sources.other-ns.other-name",
+			},
+			expectDelete: false,
+		},
+		"Rule is owned by the source": {
+			client: &mockedEventBridgeClient{
+				owner: sourceID(newEventBridgeSource()),
+			},
+			expectDelete: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			src := newEventBridgeSource()
+
+			err := ensureNoRule(contextWithSource(src), tc.client)
+			require.NoError(t, err)
+
+			if !tc.expectDelete {
+				assert.Nil(t, tc.client.removeTargetsInput, "Unexpected RemoveTargets request")
+				assert.Nil(t, tc.client.deleteRuleInput, "Unexpected DeleteRule request")
+				return
+			}
+
+			require.NotNil(t, tc.client.removeTargetsInput, "Expected a RemoveTargets request")
+			assert.Equal(t, []*string{aws.String(ruleTargetID)}, tc.client.removeTargetsInput.Ids)
+			require.NotNil(t, tc.client.deleteRuleInput, "Expected a DeleteRule request")
+			assert.Equal(t, ruleName(src), *tc.client.deleteRuleInput.Name)
+		})
+	}
+}
+
+func TestEnsureSubscribedModeSwitch(t *testing.T) {
+	r := &Reconciler{}
+
+	t.Run("From notification to eventbridge", func(t *testing.T) {
+		src := newEventBridgeSource()
+
+		s3Cli := &mockedS3Client{
+			notifCfg: &s3.NotificationConfiguration{
+				QueueConfigurations: []*s3.QueueConfiguration{
+					makeQueueConfiguration(newEventSource(), tQueueARN),
+				},
+			},
+		}
+		ebCli := &mockedEventBridgeClient{
+			describeRuleErr: awserr.New(eventbridge.ErrCodeResourceNotFoundException, "not found", nil),
+		}
+
+		err := r.ensureSubscribed(contextWithSource(src), s3Cli, ebCli, tQueueARN)
+		require.NoError(t, err)
+
+		require.NotNil(t, s3Cli.putNotifCfgInput, "Expected bucket configuration to be updated")
+		gotCfg := s3Cli.putNotifCfgInput.NotificationConfiguration
+		assert.NotNil(t, gotCfg.EventBridgeConfiguration, "Expected delivery to EventBridge to be enabled")
+		assert.Empty(t, gotCfg.QueueConfigurations, "Expected event notification of the source to be removed")
+
+		assert.NotNil(t, ebCli.putRuleInput, "Expected a PutRule request")
+		assert.NotNil(t, src.Status.RuleARN)
+		assert.True(t, src.Status.GetCondition(v1alpha1.AWSS3ConditionSubscribed).IsTrue())
+	})
+
+	t.Run("From eventbridge to notification", func(t *testing.T) {
+		src := newEventSource()
+
+		ruleARNStruct, err := arnStrToARN(ruleARN(src))
+		require.NoError(t, err)
+		src.Status.RuleARN = ruleARNStruct
+
+		s3Cli := &mockedS3Client{
+			notifCfg: &s3.NotificationConfiguration{
+				EventBridgeConfiguration: &s3.EventBridgeConfiguration{},
+			},
+		}
+		ebCli := &mockedEventBridgeClient{
+			owner: sourceID(src),
+		}
+
+		err = r.ensureSubscribed(contextWithSource(src), s3Cli, ebCli, tQueueARN)
+		require.NoError(t, err)
+
+		assert.NotNil(t, ebCli.deleteRuleInput, "Expected obsolete rule to be deleted")
+		assert.Nil(t, src.Status.RuleARN)
+
+		require.NotNil(t, s3Cli.putNotifCfgInput, "Expected bucket configuration to be updated")
+		gotCfg := s3Cli.putNotifCfgInput.NotificationConfiguration
+		assert.NotNil(t, gotCfg.EventBridgeConfiguration, "Expected delivery to EventBridge to be left enabled")
+		require.Len(t, gotCfg.QueueConfigurations, 1)
+		assert.Equal(t, sourceID(src), *gotCfg.QueueConfigurations[0].Id)
+		assert.Equal(t, tQueueARN, *gotCfg.QueueConfigurations[0].QueueArn)
+
+		assert.True(t, src.Status.GetCondition(v1alpha1.AWSS3ConditionSubscribed).IsTrue())
+	})
+}
+
+// mockedEventBridgeClient is a mocked EventBridge client which serves a single
+// rule and records requests which modify that rule.
+type mockedEventBridgeClient struct {
+	eventbridgeiface.EventBridgeAPI
+
+	describeRuleErr error
+	eventPattern    string
+	targets         []*eventbridge.Target
+	listTagsErr     error
+	owner           string
+
+	putRuleInput       *eventbridge.PutRuleInput
+	putTargetsInput    *eventbridge.PutTargetsInput
+	removeTargetsInput *eventbridge.RemoveTargetsInput
+	deleteRuleInput    *eventbridge.DeleteRuleInput
+}
+
+func (c *mockedEventBridgeClient) DescribeRuleWithContext(_ aws.Context,
+	in *eventbridge.DescribeRuleInput, _ ...request.Option) (*eventbridge.DescribeRuleOutput, error) {
+
+	if c.describeRuleErr != nil {
+		return nil, c.describeRuleErr
+	}
+
+	return &eventbridge.DescribeRuleOutput{
+		Name:         in.Name,
+		EventPattern: &c.eventPattern,
+	}, nil
+}
+
+func (c *mockedEventBridgeClient) PutRuleWithContext(_ aws.Context,
+	in *eventbridge.PutRuleInput, _ ...request.Option) (*eventbridge.PutRuleOutput, error) {
+
+	c.putRuleInput = in
+	return &eventbridge.PutRuleOutput{}, nil
+}
+
+func (c *mockedEventBridgeClient) ListTargetsByRuleWithContext(aws.Context,
+	*eventbridge.ListTargetsByRuleInput, ...request.Option) (*eventbridge.ListTargetsByRuleOutput, error) {
+
+	return &eventbridge.ListTargetsByRuleOutput{
+		Targets: c.targets,
+	}, nil
+}
+
+func (c *mockedEventBridgeClient) PutTargetsWithContext(_ aws.Context,
+	in *eventbridge.PutTargetsInput, _ ...request.Option) (*eventbridge.PutTargetsOutput, error) {
+
+	c.putTargetsInput = in
+	return &eventbridge.PutTargetsOutput{
+		FailedEntryCount: aws.Int64(0),
+	}, nil
+}
+
+func (c *mockedEventBridgeClient) ListTagsForResourceWithContext(aws.Context,
+	*eventbridge.ListTagsForResourceInput, ...request.Option) (*eventbridge.ListTagsForResourceOutput, error) {
+
+	if c.listTagsErr != nil {
+		return nil, c.listTagsErr
+	}
+
+	out := &eventbridge.ListTagsForResourceOutput{}
+	if c.owner != "" {
+		out.Tags = []*eventbridge.Tag{{Key: aws.String("owned-by"), Value: aws.String(c.owner)}}
+	}
+
+	return out, nil
+}
+
+func (c *mockedEventBridgeClient) RemoveTargetsWithContext(_ aws.Context,
+	in *eventbridge.RemoveTargetsInput, _ ...request.Option) (*eventbridge.RemoveTargetsOutput, error) {
+
+	c.removeTargetsInput = in
+	return &eventbridge.RemoveTargetsOutput{}, nil
+}
+
+func (c *mockedEventBridgeClient) DeleteRuleWithContext(_ aws.Context,
+	in *eventbridge.DeleteRuleInput, _ ...request.Option) (*eventbridge.DeleteRuleOutput, error) {
+
+	c.deleteRuleInput = in
+	return &eventbridge.DeleteRuleOutput{}, nil
+}
+
+// mockedS3Client is a mocked S3 client which serves the event notifications
+// configuration of a single bucket, and records updates to that
+// configuration.
+type mockedS3Client struct {
+	s3iface.S3API
+
+	notifCfg *s3.NotificationConfiguration
+
+	putNotifCfgInput *s3.PutBucketNotificationConfigurationInput
+}
+
+func (c *mockedS3Client) GetBucketNotificationConfigurationWithContext(aws.Context,
+	*s3.GetBucketNotificationConfigurationRequest, ...request.Option) (*s3.NotificationConfiguration, error) {
+
+	return c.notifCfg, nil
+}
+
+func (c *mockedS3Client) PutBucketNotificationConfigurationWithContext(_ aws.Context,
+	in *s3.PutBucketNotificationConfigurationInput, _ ...request.Option) (*s3.PutBucketNotificationConfigurationOutput, error) {
+
+	c.putNotifCfgInput = in
+	return &s3.PutBucketNotificationConfigurationOutput{}, nil
+}
@@ -0,0 +1,338 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/reconciler"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	awseventbridge "github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/aws/eventbridge"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/event"
+)
+
+// ID of the EventBridge target which sends events to the source's SQS queue.
+const ruleTargetID = "sqs-queue"
+
+// errNoDetailTypes is returned when none of the event types selected in the
+// source's spec is delivered by Amazon EventBridge.
+var errNoDetailTypes = errors.New("none of the selected event types is delivered by Amazon EventBridge")
+
+// ensureRule ensures that an EventBridge rule forwards the events of the S3
+// bucket to the given SQS queue.
+func ensureRule(ctx context.Context, cli eventbridgeiface.EventBridgeAPI, queueARN string) error {
+	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := src.(*v1alpha1.AWSS3Source)
+
+	status := &typedSrc.Status
+
+	name := ruleName(typedSrc)
+
+	desiredPattern, err := makeEventPattern(typedSrc)
+	if errors.Is(err, errNoDetailTypes) {
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonUnsupportedEventTypes,
+			"The selected event types are not delivered by EventBridge")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Failed to synchronize EventBridge rule: %s", err))
+	}
+	if err != nil {
+		return fmt.Errorf("creating event pattern: %w", err)
+	}
+
+	rule, err := cli.DescribeRuleWithContext(ctx, &awseventbridge.DescribeRuleInput{
+		Name: &name,
+	})
+	switch {
+	case common.IsNotFound(err):
+		if err := putRule(ctx, cli, typedSrc, desiredPattern); err != nil {
+			status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Unable to create EventBridge rule")
+			return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+				"Error creating EventBridge rule: %s", common.ToErrMsg(err)))
+		}
+
+	case common.IsAWSError(err):
+		// All documented API errors require some user intervention and
+		// are not to be retried.
+		// https://docs.aws.amazon.com/eventbridge/latest/APIReference/API_DescribeRule.html#API_DescribeRule_Errors
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Request to EventBridge API got rejected")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Failed to synchronize EventBridge rule: %s", common.ToErrMsg(err)))
+
+	case err != nil:
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot synchronize EventBridge rule")
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Error reading EventBridge rule: %s", common.ToErrMsg(err)))
+
+	case !equalEventPatterns(desiredPattern, aws.StringValue(rule.EventPattern)):
+		if err := putRule(ctx, cli, typedSrc, desiredPattern); err != nil {
+			status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot update EventBridge rule")
+			return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+				"Error updating event pattern of EventBridge rule: %s", common.ToErrMsg(err)))
+		}
+	}
+
+	if err := eventbridge.EnsureRuleTarget(ctx, cli, name, ruleTargetID, queueARN); err != nil {
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot configure target of EventBridge rule")
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Error configuring target of EventBridge rule: %s", common.ToErrMsg(err)))
+	}
+
+	ruleARNStruct, err := arnStrToARN(ruleARN(typedSrc))
+	if err != nil {
+		return fmt.Errorf("converting ARN string to structured ARN: %w", err)
+	}
+	status.RuleARN = ruleARNStruct
+
+	if !status.GetCondition(v1alpha1.AWSS3ConditionSubscribed).IsTrue() {
+		event.Normal(ctx, ReasonSubscribed, "Configured EventBridge rule %q for S3 bucket %q",
+			name, typedSrc.Spec.ARN)
+	}
+	status.MarkSubscribed()
+
+	return nil
+}
+
+// ensureNoRule ensures that the EventBridge rule created for the S3 bucket is
+// deleted.
+func ensureNoRule(ctx context.Context, cli eventbridgeiface.EventBridgeAPI) error {
+	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := src.(*v1alpha1.AWSS3Source)
+
+	name := ruleName(typedSrc)
+
+	owns, err := eventbridge.AssertRuleOwnership(ctx, cli, ruleARN(typedSrc), sourceID(typedSrc))
+	switch {
+	case common.IsNotFound(err):
+		event.Warn(ctx, ReasonUnsubscribed, "Rule not found, skipping deletion")
+		return nil
+	case common.IsDenied(err):
+		// it is unlikely that we recover from auth errors in the
+		// finalizer, so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Authorization error getting EventBridge rule. Ignoring: %s", common.ToErrMsg(err))
+		return nil
+	case err != nil:
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Failed to verify owner of EventBridge rule: %s", common.ToErrMsg(err))
+	}
+
+	if !owns {
+		event.Warn(ctx, ReasonUnsubscribed, "Rule %q is not owned by this source instance, "+
+			"skipping deletion", name)
+		return nil
+	}
+
+	// targets must be removed before a rule can be deleted
+	_, err = cli.RemoveTargetsWithContext(ctx, &awseventbridge.RemoveTargetsInput{
+		Rule: &name,
+		Ids:  aws.StringSlice([]string{ruleTargetID}),
+	})
+	if err != nil && !common.IsNotFound(err) {
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error removing targets of EventBridge rule: %s", common.ToErrMsg(err))
+	}
+
+	_, err = cli.DeleteRuleWithContext(ctx, &awseventbridge.DeleteRuleInput{
+		Name: &name,
+	})
+	switch {
+	case common.IsDenied(err):
+		// it is unlikely that we recover from auth errors in the
+		// finalizer, so we simply record a warning event and return
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Authorization error deleting EventBridge rule. Ignoring: %s", common.ToErrMsg(err))
+		return nil
+	case err != nil && !common.IsNotFound(err):
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error deleting EventBridge rule: %s", common.ToErrMsg(err))
+	}
+
+	event.Normal(ctx, ReasonUnsubscribed, "Deleted EventBridge rule %q", name)
+
+	return nil
+}
+
+// putRule creates or updates the EventBridge rule of the given source.
+// Tags are only applied when the rule is created.
+func putRule(ctx context.Context, cli eventbridgeiface.EventBridgeAPI,
+	src *v1alpha1.AWSS3Source, pattern string) error {
+
+	_, err := cli.PutRuleWithContext(ctx, &awseventbridge.PutRuleInput{
+		Name:         aws.String(ruleName(src)),
+		Description:  aws.String("Events from S3 bucket " + src.Spec.ARN.String()),
+		EventPattern: &pattern,
+		State:        aws.String(awseventbridge.RuleStateEnabled),
+		Tags:         ruleTags(src),
+	})
+	if err != nil {
+		return fmt.Errorf("putting rule: %w", err)
+	}
+
+	return nil
+}
+
+// eventPattern mirrors the structure of an EventBridge event pattern for easy
+// marshaling and unmarshaling to/from JSON.
+// See https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns.html
+type eventPattern struct {
+	Source     []string           `json:"source"`
+	DetailType []string           `json:"detail-type"`
+	Detail     eventPatternDetail `json:"detail"`
+}
+
+// eventPatternDetail is the "detail" element of an eventPattern.
+type eventPatternDetail struct {
+	Bucket eventPatternBucket  `json:"bucket"`
+	Object *eventPatternObject `json:"object,omitempty"`
+}
+
+// eventPatternBucket is the "bucket" element of an eventPatternDetail.
+type eventPatternBucket struct {
+	Name []string `json:"name"`
+}
+
+// eventPatternObject is the "object" element of an eventPatternDetail.
+type eventPatternObject struct {
+	// content filters, e.g. {"prefix": "images/"}
+	Key []map[string]string `json:"key"`
+}
+
+// makeEventPattern returns an EventBridge event pattern matching the events
+// selected in the spec of the given source.
+func makeEventPattern(src *v1alpha1.AWSS3Source) (string, error) {
+	detailTypes := v1alpha1.AWSS3EventBridgeDetailTypes(src.Spec.EventTypes)
+	if len(detailTypes) == 0 {
+		return "", errNoDetailTypes
+	}
+
+	pattern := eventPattern{
+		Source:     []string{"aws.s3"},
+		DetailType: detailTypes,
+		Detail: eventPatternDetail{
+			Bucket: eventPatternBucket{
+				Name: []string{src.Spec.ARN.Resource},
+			},
+			Object: makeObjectPattern(src.Spec.Filter),
+		},
+	}
+
+	b, err := json.Marshal(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// makeObjectPattern returns an eventPatternObject matching the object keys
+// selected by the given source filter, or nil if the filter doesn't contain any
+// rule.
+// Ref. https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns-content-based-filtering.html
+func makeObjectPattern(f *v1alpha1.AWSS3SourceFilter) *eventPatternObject {
+	if f == nil {
+		return nil
+	}
+
+	prefix, suffix := aws.StringValue(f.Prefix), aws.StringValue(f.Suffix)
+
+	var keyFilter map[string]string
+
+	switch {
+	case prefix != "" && suffix != "":
+		// prefix and suffix matchers can not be combined on a single
+		// field, so both are expressed as a wildcard instead
+		keyFilter = map[string]string{"wildcard": escapeWildcard(prefix) + "*" + escapeWildcard(suffix)}
+	case prefix != "":
+		keyFilter = map[string]string{"prefix": prefix}
+	case suffix != "":
+		keyFilter = map[string]string{"suffix": suffix}
+	default:
+		return nil
+	}
+
+	return &eventPatternObject{
+		Key: []map[string]string{keyFilter},
+	}
+}
+
+// escapeWildcard escapes the characters which have a special meaning in a
+// wildcard pattern of an EventBridge rule.
+func escapeWildcard(s string) string {
+	return wildcardEscaper.Replace(s)
+}
+
+var wildcardEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`)
+
+// equalEventPatterns returns whether two serialized event patterns are
+// semantically equal.
+func equalEventPatterns(a, b string) bool {
+	var ap, bp eventPattern
+
+	if err := json.Unmarshal([]byte(a), &ap); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &bp); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(ap, bp)
+}
+
+// ruleTags returns a set of tags containing information from the given source
+// instance to set on an EventBridge rule.
+func ruleTags(src *v1alpha1.AWSS3Source) []*awseventbridge.Tag {
+	return []*awseventbridge.Tag{
+		{Key: aws.String("bucket-arn"), Value: aws.String(src.Spec.ARN.String())},
+		{Key: aws.String(eventbridge.OwnerTagKey), Value: aws.String(sourceID(src))},
+	}
+}
+
+// ruleName returns a name for the EventBridge rule of the given source
+// instance. Bucket names can be too long to be part of a rule name (max. 64
+// characters), so the name is derived from a hash of the source's ID instead.
+func ruleName(src *v1alpha1.AWSS3Source) string {
+	return "s3-events_" + sourceIDHash(src)
+}
+
+// ruleARN returns the ARN of the EventBridge rule matching the given source
+// instance.
+// The ARN is deterministic, which allows the SQS queue policy to reference the
+// rule before it is created.
+func ruleARN(src *v1alpha1.AWSS3Source) string {
+	return arn.ARN{
+		Partition: src.Spec.ARN.Partition,
+		Service:   awseventbridge.ServiceName,
+		Region:    src.Spec.ARN.Region,
+		AccountID: src.Spec.ARN.AccountID,
+		Resource:  "rule/" + ruleName(src),
+	}.String()
+}
@@ -0,0 +1,308 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/reconciler"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common"
+	"github.com/triggermesh/aws-event-sources/pkg/reconciler/common/event"
+)
+
+// Error code returned by the S3 API when a bucket doesn't have any tag.
+const errCodeNoSuchTagSet = "NoSuchTagSet"
+
+// ensureSelectedBucketsSubscribed ensures that event notifications are enabled
+// in all the S3 buckets selected by the source's bucket selector, and disabled
+// in the buckets which are no longer selected.
+// The subscription state of each bucket is reported in the source's status.
+func (r *Reconciler) ensureSelectedBucketsSubscribed(ctx context.Context, cli s3iface.S3API, queueARN string) error {
+	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := src.(*v1alpha1.AWSS3Source)
+
+	status := &typedSrc.Status
+
+	selected, undetermined, err := selectBuckets(ctx, cli, &r.bucketLocations,
+		typedSrc.Spec.BucketSelector, typedSrc.Spec.ARN.Region)
+	switch {
+	case common.IsAWSError(err):
+		// All documented API errors require some user intervention and
+		// are not to be retried.
+		// https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Request to S3 API got rejected")
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Failed to list buckets: %s", common.ToErrMsg(err)))
+	case err != nil:
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonAPIError, "Cannot list buckets")
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Error listing buckets: %s", common.ToErrMsg(err)))
+	}
+
+	currentStatuses := make(map[string]v1alpha1.AWSS3BucketStatus, len(status.Buckets))
+	for _, bs := range status.Buckets {
+		currentStatuses[bs.Name] = bs
+	}
+
+	bucketStatuses := make([]v1alpha1.AWSS3BucketStatus, 0, len(selected)+len(undetermined))
+
+	var numFailed int
+	var retryableErr error
+
+	desiredQueueCfg := makeQueueConfiguration(typedSrc, queueARN)
+
+	for _, bucket := range selected {
+		bs := v1alpha1.AWSS3BucketStatus{Name: bucket}
+
+		err := subscribeBucket(ctx, cli, bucket, desiredQueueCfg, func(reason, msg string) {
+			markBucketNotSubscribed(&bs, reason, msg)
+		})
+		switch {
+		case err == nil:
+			bs.Subscribed = true
+			if !currentStatuses[bucket].Subscribed {
+				event.Normal(ctx, ReasonSubscribed, "Configured event notifications for S3 bucket %q", bucket)
+			}
+		case controller.IsPermanentError(err):
+			numFailed++
+		default:
+			numFailed++
+			retryableErr = err
+		}
+
+		bucketStatuses = append(bucketStatuses, bs)
+		delete(currentStatuses, bucket)
+	}
+
+	// buckets which membership couldn't be determined are left untouched
+	for bucket, err := range undetermined {
+		bs := currentStatuses[bucket]
+		bs.Name = bucket
+		markBucketNotSubscribed(&bs, v1alpha1.AWSS3ReasonAPIError,
+			"Cannot determine whether the bucket is selected: "+common.ToErrMsg(err))
+
+		numFailed++
+		bucketStatuses = append(bucketStatuses, bs)
+		delete(currentStatuses, bucket)
+	}
+
+	// remaining buckets are no longer selected
+	for bucket, bs := range currentStatuses {
+		unsubscribed, err := unsubscribeBucket(ctx, cli, bucket, sourceID(src))
+		if err != nil {
+			// keep track of the bucket until its configuration can
+			// be cleaned up
+			markBucketNotSubscribed(&bs, v1alpha1.AWSS3ReasonAPIError,
+				"Cannot disable event notifications of the deselected bucket")
+			retryableErr = err
+			bucketStatuses = append(bucketStatuses, bs)
+			continue
+		}
+
+		if unsubscribed {
+			event.Normal(ctx, ReasonUnsubscribed, "Disabled event notifications for deselected S3 bucket %q", bucket)
+		}
+	}
+
+	sort.Slice(bucketStatuses, func(i, j int) bool {
+		return bucketStatuses[i].Name < bucketStatuses[j].Name
+	})
+	status.Buckets = bucketStatuses
+
+	switch {
+	case len(selected) == 0 && len(undetermined) == 0:
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonNoMatchingBucket, "No bucket matches the selector")
+	case numFailed > 0:
+		status.MarkNotSubscribed(v1alpha1.AWSS3ReasonBucketsNotSubscribed,
+			fmt.Sprintf("%d out of %d selected buckets could not be subscribed",
+				numFailed, len(selected)+len(undetermined)))
+	default:
+		status.MarkSubscribed()
+	}
+
+	// errors which require user intervention are reported in the status
+	// only, the selection is re-evaluated at the next resync anyway
+	return retryableErr
+}
+
+// ensureSelectedBucketsUnsubscribed ensures that event notifications are
+// disabled in all the S3 buckets reported in the source's status.
+func (r *Reconciler) ensureSelectedBucketsUnsubscribed(ctx context.Context, cli s3iface.S3API) error {
+	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := src.(*v1alpha1.AWSS3Source)
+
+	var errs []string
+
+	for _, bs := range typedSrc.Status.Buckets {
+		if _, err := unsubscribeBucket(ctx, cli, bs.Name, sourceID(src)); err != nil {
+			errs = append(errs, bs.Name+": "+err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error disabling event notifications of some S3 buckets: %s", strings.Join(errs, "; "))
+	}
+
+	return reconciler.NewEvent(corev1.EventTypeNormal, ReasonUnsubscribed,
+		"Disabled event notifications for %d S3 buckets", len(typedSrc.Status.Buckets))
+}
+
+// selectBuckets returns the names of the S3 buckets located in the given region
+// which match the given selector.
+// Buckets which could not be evaluated against the selector are returned
+// separately, alongside the error which prevented their evaluation.
+func selectBuckets(ctx context.Context, cli s3iface.S3API, locs *bucketLocationCache,
+	sel *v1alpha1.AWSS3BucketSelector, region string) (selected []string, undetermined map[string]error, err error) {
+
+	resp, err := cli.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing buckets: %w", err)
+	}
+
+	undetermined = make(map[string]error)
+
+	for _, b := range resp.Buckets {
+		bucket := aws.StringValue(b.Name)
+
+		if !strings.HasPrefix(bucket, aws.StringValue(sel.NamePrefix)) {
+			continue
+		}
+
+		// S3 buckets can only send event notifications to queues
+		// located in the same region
+		loc, err := locs.get(ctx, cli, b)
+		if err != nil {
+			undetermined[bucket] = fmt.Errorf("getting bucket location: %w", err)
+			continue
+		}
+		if loc != region {
+			continue
+		}
+
+		if len(sel.Tags) > 0 {
+			tagging, err := cli.GetBucketTaggingWithContext(ctx, &s3.GetBucketTaggingInput{
+				Bucket: &bucket,
+			})
+			switch {
+			case isNoTagSet(err):
+				continue
+			case err != nil:
+				undetermined[bucket] = fmt.Errorf("getting bucket tags: %w", err)
+				continue
+			}
+
+			if !matchesTags(tagging.TagSet, sel.Tags) {
+				continue
+			}
+		}
+
+		selected = append(selected, bucket)
+	}
+
+	return selected, undetermined, nil
+}
+
+// bucketLocationCache caches the regions of S3 buckets, which are queried at
+// every resync of sources that use a bucket selector.
+// The region of a bucket can not change during the lifetime of the bucket, so
+// cached entries are keyed by both the name and the creation date of the
+// bucket, in case a bucket gets deleted and re-created in a different region.
+type bucketLocationCache struct {
+	locs sync.Map // bucketLocationKey -> string
+}
+
+// bucketLocationKey uniquely identifies a S3 bucket over time.
+type bucketLocationKey struct {
+	name    string
+	created time.Time
+}
+
+// get returns the region of the given bucket, either from the cache or from
+// the S3 API.
+func (c *bucketLocationCache) get(ctx context.Context, cli s3iface.S3API, b *s3.Bucket) (string, error) {
+	key := bucketLocationKey{
+		name:    aws.StringValue(b.Name),
+		created: aws.TimeValue(b.CreationDate),
+	}
+
+	if loc, ok := c.locs.Load(key); ok {
+		return loc.(string), nil
+	}
+
+	resp, err := cli.GetBucketLocationWithContext(ctx, &s3.GetBucketLocationInput{
+		Bucket: b.Name,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	loc := s3.NormalizeBucketLocation(aws.StringValue(resp.LocationConstraint))
+	c.locs.Store(key, loc)
+
+	return loc, nil
+}
+
+// markBucketNotSubscribed sets the subscription state of the given bucket
+// status to false with the given reason and message.
+func markBucketNotSubscribed(bs *v1alpha1.AWSS3BucketStatus, reason, msg string) {
+	bs.Subscribed = false
+	bs.Reason = reason
+	bs.Message = msg
+}
+
+// matchesTags returns whether the given set of bucket tags contains all the
+// given tags.
+func matchesTags(tagSet []*s3.Tag, tags map[string]string) bool {
+	bucketTags := make(map[string]string, len(tagSet))
+	for _, t := range tagSet {
+		bucketTags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+
+	for k, v := range tags {
+		if val, ok := bucketTags[k]; !ok || val != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isNoTagSet returns whether the given error indicates that a S3 bucket
+// doesn't have any tag.
+func isNoTagSet(err error) bool {
+	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
+		return awsErr.Code() == errCodeNoSuchTagSet
+	}
+	return false
+}
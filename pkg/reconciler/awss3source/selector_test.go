@@ -0,0 +1,223 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awss3source
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
+)
+
+func TestSelectBuckets(t *testing.T) {
+	cli := &mockBucketsS3Client{
+		buckets: map[string]mockBucket{
+			"logs-a": {
+				region: "us-west-2",
+				tags:   map[string]string{"team": "data", "env": "prod"},
+			},
+			"logs-b": {
+				region: "us-west-2",
+				tags:   map[string]string{"team": "data"},
+			},
+			"logs-c": {
+				region: "eu-central-1",
+				tags:   map[string]string{"team": "data", "env": "prod"},
+			},
+			"logs-d": {
+				region: "us-west-2",
+			},
+			"logs-e": {
+				region:    "us-west-2",
+				tagsError: awserr.New("AccessDenied", "Access Denied", nil),
+			},
+			"images": {
+				region: "us-west-2",
+				tags:   map[string]string{"team": "data", "env": "prod"},
+			},
+		},
+	}
+
+	testCases := map[string]struct {
+		sel                *v1alpha1.AWSS3BucketSelector
+		expectSelected     []string
+		expectUndetermined []string
+	}{
+		"name prefix only": {
+			sel:            &v1alpha1.AWSS3BucketSelector{NamePrefix: aws.String("logs-")},
+			expectSelected: []string{"logs-a", "logs-b", "logs-d", "logs-e"},
+		},
+		"tags only": {
+			sel: &v1alpha1.AWSS3BucketSelector{
+				Tags: map[string]string{"team": "data"},
+			},
+			expectSelected:     []string{"images", "logs-a", "logs-b"},
+			expectUndetermined: []string{"logs-e"},
+		},
+		"name prefix and tags": {
+			sel: &v1alpha1.AWSS3BucketSelector{
+				NamePrefix: aws.String("logs-"),
+				Tags:       map[string]string{"team": "data", "env": "prod"},
+			},
+			expectSelected:     []string{"logs-a"},
+			expectUndetermined: []string{"logs-e"},
+		},
+		"no match": {
+			sel: &v1alpha1.AWSS3BucketSelector{NamePrefix: aws.String("backups-")},
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			selected, undetermined, err := selectBuckets(context.Background(), cli, &bucketLocationCache{},
+				tc.sel, "us-west-2")
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expectSelected, selected)
+
+			undeterminedNames := make([]string, 0, len(undetermined))
+			for bucket := range undetermined {
+				undeterminedNames = append(undeterminedNames, bucket)
+			}
+			assert.ElementsMatch(t, tc.expectUndetermined, undeterminedNames)
+		})
+	}
+}
+
+func TestSelectBucketsLocationCache(t *testing.T) {
+	cli := &mockBucketsS3Client{
+		buckets: map[string]mockBucket{
+			"logs-a": {region: "us-west-2"},
+			"logs-b": {region: "eu-central-1"},
+		},
+	}
+
+	sel := &v1alpha1.AWSS3BucketSelector{NamePrefix: aws.String("logs-")}
+	locs := &bucketLocationCache{}
+
+	for i := 0; i < 3; i++ {
+		selected, _, err := selectBuckets(context.Background(), cli, locs, sel, "us-west-2")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"logs-a"}, selected)
+	}
+
+	assert.Equal(t, 2, cli.getLocationCalls, "Expected the location of each bucket to be requested once")
+
+	// a bucket re-created under the same name may be located in a
+	// different region
+	cli.buckets["logs-b"] = mockBucket{region: "us-west-2", created: time.Now()}
+
+	selected, _, err := selectBuckets(context.Background(), cli, locs, sel, "us-west-2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"logs-a", "logs-b"}, selected)
+	assert.Equal(t, 3, cli.getLocationCalls)
+}
+
+func TestMatchesTags(t *testing.T) {
+	tagSet := []*s3.Tag{
+		{Key: aws.String("team"), Value: aws.String("data")},
+		{Key: aws.String("env"), Value: aws.String("prod")},
+	}
+
+	assert.True(t, matchesTags(tagSet, nil), "Empty selector matches any bucket")
+	assert.True(t, matchesTags(tagSet, map[string]string{"env": "prod"}))
+	assert.True(t, matchesTags(tagSet, map[string]string{"env": "prod", "team": "data"}))
+	assert.False(t, matchesTags(tagSet, map[string]string{"env": "dev"}))
+	assert.False(t, matchesTags(tagSet, map[string]string{"env": "prod", "owner": "me"}))
+	assert.False(t, matchesTags(nil, map[string]string{"env": "prod"}))
+}
+
+type mockBucket struct {
+	created   time.Time
+	region    string
+	tags      map[string]string
+	tagsError error
+}
+
+// mockBucketsS3Client is a S3 client which serves a fixed set of buckets.
+type mockBucketsS3Client struct {
+	s3iface.S3API
+
+	buckets map[string]mockBucket
+
+	getLocationCalls int
+}
+
+// ListBucketsWithContext implements s3iface.S3API.
+func (c *mockBucketsS3Client) ListBucketsWithContext(_ aws.Context, _ *s3.ListBucketsInput,
+	_ ...request.Option) (*s3.ListBucketsOutput, error) {
+
+	// S3 returns buckets sorted by name
+	names := make([]string, 0, len(c.buckets))
+	for n := range c.buckets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	out := &s3.ListBucketsOutput{}
+	for _, n := range names {
+		out.Buckets = append(out.Buckets, &s3.Bucket{
+			Name:         aws.String(n),
+			CreationDate: aws.Time(c.buckets[n].created),
+		})
+	}
+
+	return out, nil
+}
+
+// GetBucketLocationWithContext implements s3iface.S3API.
+func (c *mockBucketsS3Client) GetBucketLocationWithContext(_ aws.Context, in *s3.GetBucketLocationInput,
+	_ ...request.Option) (*s3.GetBucketLocationOutput, error) {
+
+	c.getLocationCalls++
+
+	return &s3.GetBucketLocationOutput{
+		LocationConstraint: aws.String(c.buckets[*in.Bucket].region),
+	}, nil
+}
+
+// GetBucketTaggingWithContext implements s3iface.S3API.
+func (c *mockBucketsS3Client) GetBucketTaggingWithContext(_ aws.Context, in *s3.GetBucketTaggingInput,
+	_ ...request.Option) (*s3.GetBucketTaggingOutput, error) {
+
+	b := c.buckets[*in.Bucket]
+
+	if b.tagsError != nil {
+		return nil, b.tagsError
+	}
+	if len(b.tags) == 0 {
+		return nil, awserr.New(errCodeNoSuchTagSet, "The TagSet does not exist", nil)
+	}
+
+	out := &s3.GetBucketTaggingOutput{}
+	for k, v := range b.tags {
+		out.TagSet = append(out.TagSet, &s3.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	return out, nil
+}
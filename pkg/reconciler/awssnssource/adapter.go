@@ -48,6 +48,7 @@ func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, _ *apis.URL) *servin
 	return common.NewMTAdapterKnService(src,
 		resource.Image(r.adapterCfg.Image),
 		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
+		resource.Probe("/health", ""),
 	)
 }
 
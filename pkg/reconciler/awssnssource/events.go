@@ -21,6 +21,8 @@ const (
 	ReasonSubscribed = "Subscribed"
 	// ReasonUnsubscribed indicates the successful deletion of a SNS subscription.
 	ReasonUnsubscribed = "Unsubscribed"
+	// ReasonSubscriptionUpdated indicates the successful update of the attributes of a SNS subscription.
+	ReasonSubscriptionUpdated = "SubscriptionUpdated"
 	// ReasonFailedSubscribe indicates a failure during the subscription to a SNS topic.
 	ReasonFailedSubscribe = "FailedSubscribe"
 	// ReasonFailedUnsubscribe indicates a failure during the deletion of a SNS subscription.
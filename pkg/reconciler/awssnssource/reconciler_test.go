@@ -20,6 +20,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -67,7 +69,8 @@ func TestReconcileSource(t *testing.T) {
 func reconcilerCtor(cfg *adapterConfig) Ctor {
 	return func(t *testing.T, ctx context.Context, tr *rt.TableRow, ls *Listers) controller.Reconciler {
 		snsCli := &mockedSNSClient{
-			subscriptions: getMockSubscriptionsPages(tr),
+			subscriptions:          getMockSubscriptionsPages(tr),
+			subscriptionAttributes: getMockSubscriptionAttributes(tr),
 		}
 
 		// inject client into test data so that table tests can perform
@@ -173,6 +176,51 @@ func TestReconcileSubscription(t *testing.T) {
 			},
 			PostConditions: []func(*testing.T, *rt.TableRow){
 				calledSubscribe(false),
+				setSubscriptionAttributes(nil),
+			},
+		},
+		{
+			Name:          "Legacy subscription without token",
+			Key:           tKey,
+			OtherTestData: withLegacyMockSubscription(makeMockSubscriptionsPages(true)),
+			Objects: []runtime.Object{
+				newReconciledSource(subscribed),
+				newReconciledServiceAccount(),
+				newReconciledRoleBinding(),
+				newReconciledAdapter(),
+			},
+			WantEvents: []string{
+				legacyUnsubscribedEvent(),
+			},
+			PostConditions: []func(*testing.T, *rt.TableRow){
+				calledSubscribe(false),
+				calledUnsubscribe(true),
+			},
+		},
+		{
+			Name: "Subscription attributes drifted",
+			Key:  tKey,
+			OtherTestData: withMockSubscriptionAttributes(makeMockSubscriptionsPages(true), map[string]*string{
+				"DeliveryPolicy":     aws.String(`{"healthyRetryPolicy":{"numRetries":3}}`),
+				"FilterPolicy":       aws.String(`{"color":["blue"]}`),
+				"RawMessageDelivery": aws.String("false"),
+			}),
+			Objects: []runtime.Object{
+				newReconciledSource(subscribed),
+				newReconciledServiceAccount(),
+				newReconciledRoleBinding(),
+				newReconciledAdapter(),
+			},
+			WantEvents: []string{
+				subscriptionUpdatedEvent("DeliveryPolicy"),
+				subscriptionUpdatedEvent("FilterPolicy"),
+			},
+			PostConditions: []func(*testing.T, *rt.TableRow){
+				calledSubscribe(false),
+				setSubscriptionAttributes(map[string]string{
+					"DeliveryPolicy": `{"healthyRetryPolicy":{"numRetries":5}}`,
+					"FilterPolicy":   "{}",
+				}),
 			},
 		},
 
@@ -279,6 +327,8 @@ func newReconciledSource(opts ...sourceOption) *v1alpha1.AWSSNSSource {
 var (
 	tTopicARN = NewARN(sns.ServiceName, "triggermeshtest")
 	tSubARN   = NewARN(sns.ServiceName, "triggermeshtest/0123456789")
+
+	tLegacySubARN = NewARN(sns.ServiceName, "triggermeshtest/9876543210")
 )
 
 // subscribed sets the Subscribed status condition to True and reports the ARN
@@ -338,10 +388,12 @@ type mockSubscriptionsPages map[ /*token*/ *string][]*sns.Subscription
 type mockedSNSClient struct {
 	snsclient.Client
 
-	subscriptions mockSubscriptionsPages
+	subscriptions          mockSubscriptionsPages
+	subscriptionAttributes map[string]*string
 
-	calledSubscribe   bool
-	calledUnsubscribe bool
+	calledSubscribe           bool
+	calledUnsubscribe         bool
+	setSubscriptionAttributes map[string]string
 }
 
 func (c *mockedSNSClient) SubscribeWithContext(aws.Context, *sns.SubscribeInput,
@@ -362,6 +414,25 @@ func (c *mockedSNSClient) UnsubscribeWithContext(aws.Context, *sns.UnsubscribeIn
 	return &sns.UnsubscribeOutput{}, nil
 }
 
+func (c *mockedSNSClient) GetSubscriptionAttributesWithContext(aws.Context, *sns.GetSubscriptionAttributesInput,
+	...request.Option) (*sns.GetSubscriptionAttributesOutput, error) {
+
+	return &sns.GetSubscriptionAttributesOutput{
+		Attributes: c.subscriptionAttributes,
+	}, nil
+}
+
+func (c *mockedSNSClient) SetSubscriptionAttributesWithContext(_ aws.Context, in *sns.SetSubscriptionAttributesInput,
+	_ ...request.Option) (*sns.SetSubscriptionAttributesOutput, error) {
+
+	if c.setSubscriptionAttributes == nil {
+		c.setSubscriptionAttributes = make(map[string]string)
+	}
+	c.setSubscriptionAttributes[*in.AttributeName] = *in.AttributeValue
+
+	return &sns.SetSubscriptionAttributesOutput{}, nil
+}
+
 var page2Token = aws.String("page2token")
 
 func (c *mockedSNSClient) ListSubscriptionsByTopicWithContext(_ aws.Context, in *sns.ListSubscriptionsByTopicInput,
@@ -392,7 +463,7 @@ func makeMockSubscriptionsPages(subExists bool) map[string]interface{} {
 	var wrongSubURL = aws.String("http://not-my-sub.example.com")
 	var wrongSubARN = aws.String("aws:sns:not:my:sub")
 
-	var okSubURL = aws.String(tAdapterURI.String() + tNs + "/" + tName)
+	var okSubURL = aws.String(newReconciledSource().SubscriptionURL().String())
 	var okSubARN = aws.String(tSubARN.String())
 
 	// first page, retrieved without NextToken
@@ -421,6 +492,17 @@ func makeMockSubscriptionsPages(subExists bool) map[string]interface{} {
 	}
 }
 
+// withLegacyMockSubscription adds a subscription of the source's endpoint
+// without subscription token to some TableRow data.
+func withLegacyMockSubscription(data map[string]interface{}) map[string]interface{} {
+	pages := data[mockSubscriptionsPagesDataKey].(mockSubscriptionsPages)
+
+	pages[page2Token][0].Endpoint = aws.String(tAdapterURI.String() + tNs + "/" + tName)
+	pages[page2Token][0].SubscriptionArn = aws.String(tLegacySubARN.String())
+
+	return data
+}
+
 // getMockSubscriptionsPages gets mocked pages of SNS Subscriptions from the
 // TableRow's data.
 func getMockSubscriptionsPages(tr *rt.TableRow) mockSubscriptionsPages {
@@ -431,6 +513,29 @@ func getMockSubscriptionsPages(tr *rt.TableRow) mockSubscriptionsPages {
 	return pages.(mockSubscriptionsPages)
 }
 
+const mockSubscriptionAttributesDataKey = "subattrs"
+
+// withMockSubscriptionAttributes adds the given attributes of the mocked SNS
+// Subscription to some TableRow data.
+func withMockSubscriptionAttributes(data map[string]interface{}, attrs map[string]*string) map[string]interface{} {
+	data[mockSubscriptionAttributesDataKey] = attrs
+	return data
+}
+
+// getMockSubscriptionAttributes gets the attributes of the mocked SNS
+// Subscription from the TableRow's data. Unless specified otherwise, these
+// are the attributes of the source returned by newEventSource, formatted
+// differently than in the source's spec.
+func getMockSubscriptionAttributes(tr *rt.TableRow) map[string]*string {
+	attrs, ok := tr.OtherTestData[mockSubscriptionAttributesDataKey]
+	if !ok {
+		return map[string]*string{
+			"DeliveryPolicy": aws.String(`{ "healthyRetryPolicy": { "numRetries": 5 } }`),
+		}
+	}
+	return attrs.(map[string]*string)
+}
+
 func calledSubscribe(expectCall bool) func(*testing.T, *rt.TableRow) {
 	return func(t *testing.T, tr *rt.TableRow) {
 		cli := tr.OtherTestData[testClientDataKey].(*mockedSNSClient)
@@ -457,6 +562,16 @@ func calledUnsubscribe(expectCall bool) func(*testing.T, *rt.TableRow) {
 	}
 }
 
+func setSubscriptionAttributes(expectAttrs map[string]string) func(*testing.T, *rt.TableRow) {
+	return func(t *testing.T, tr *rt.TableRow) {
+		cli := tr.OtherTestData[testClientDataKey].(*mockedSNSClient)
+
+		if diff := cmp.Diff(expectAttrs, cli.setSubscriptionAttributes); diff != "" {
+			t.Error("Unexpected subscription attributes set (-want, +got):", diff)
+		}
+	}
+}
+
 /* Patches */
 
 func unsetFinalizerPatch() clientgotesting.PatchActionImpl {
@@ -478,6 +593,14 @@ func unsubscribedEvent() string {
 func skippedUnsubscribeEvent() string {
 	return eventtesting.Eventf(corev1.EventTypeNormal, ReasonUnsubscribed, "Subscription already absent, skipping finalization")
 }
+func legacyUnsubscribedEvent() string {
+	return eventtesting.Eventf(corev1.EventTypeNormal, ReasonUnsubscribed, "Removed legacy subscription %q of endpoint %q",
+		tLegacySubARN, tAdapterURI.String()+tNs+"/"+tName)
+}
+func subscriptionUpdatedEvent(attr string) string {
+	return eventtesting.Eventf(corev1.EventTypeNormal, ReasonSubscriptionUpdated,
+		"Updated attribute %s of subscription %q", attr, tSubARN)
+}
 func finalizedEvent() string {
 	return eventtesting.Eventf(corev1.EventTypeNormal, "FinalizerUpdate", "Updated %q finalizers", tName)
 }
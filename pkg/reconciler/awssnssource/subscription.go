@@ -18,9 +18,13 @@ package awssnssource
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -51,8 +55,10 @@ func (r *Reconciler) ensureSubscribed(ctx context.Context) error {
 	src := v1alpha1.SourceFromContext(ctx)
 	status := &src.(*v1alpha1.AWSSNSSource).Status
 
+	typedSrc := src.(*v1alpha1.AWSSNSSource)
+
 	isDeployed := status.GetCondition(v1alpha1.ConditionDeployed).IsTrue()
-	url := status.Address.URL
+	url := typedSrc.SubscriptionURL()
 
 	// skip this cycle if the URL couldn't yet be determined
 	if !isDeployed || url == nil {
@@ -60,7 +66,12 @@ func (r *Reconciler) ensureSubscribed(ctx context.Context) error {
 		return nil
 	}
 
-	typedSrc := src.(*v1alpha1.AWSSNSSource)
+	// invalid subscription attributes would be rejected by the SNS API
+	if err := typedSrc.Validate(ctx); err != nil {
+		status.MarkNotSubscribed(v1alpha1.AWSSNSReasonInvalidSpec, err.Error())
+		return controller.NewPermanentError(reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+			"Invalid spec: %s", err))
+	}
 
 	snsClient, err := r.snsCg.Get(typedSrc)
 	if err != nil {
@@ -70,11 +81,12 @@ func (r *Reconciler) ensureSubscribed(ctx context.Context) error {
 	}
 
 	topicARN := typedSrc.Spec.ARN.String()
+	attrs := subscriptionAttributes(&typedSrc.Spec)
 
 	subsARN, err := findSubscription(ctx, snsClient, topicARN, url.String())
 	switch {
 	case isPending(subsARN), isNotFound(err):
-		subsARN, err = subscribe(ctx, snsClient, topicARN, url, typedSrc.Spec.SubscriptionAttributes)
+		subsARN, err = subscribe(ctx, snsClient, topicARN, url, attrs)
 		switch {
 		case isPending(subsARN):
 			status.MarkNotSubscribed(v1alpha1.AWSSNSReasonPending, "Subscription is pending confirmation")
@@ -110,6 +122,15 @@ func (r *Reconciler) ensureSubscribed(ctx context.Context) error {
 
 	case err != nil:
 		return fmt.Errorf("finding subscription: %w", err)
+
+	default:
+		// the subscription already exists, its attributes might have
+		// drifted from the desired ones
+		if err := syncSubscriptionAttributes(ctx, snsClient, subsARN, attrs); err != nil {
+			status.MarkNotSubscribed(v1alpha1.AWSSNSReasonFailedSync, "Cannot update subscription attributes")
+			return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedSubscribe,
+				"Error updating attributes of subscription %q: %s", subsARN, toErrMsg(err)))
+		}
 	}
 
 	if !status.GetCondition(v1alpha1.AWSSNSConditionSubscribed).IsTrue() {
@@ -117,6 +138,11 @@ func (r *Reconciler) ensureSubscribed(ctx context.Context) error {
 	}
 	status.MarkSubscribed(subsARN)
 
+	if err := unsubscribeLegacyEndpoint(ctx, snsClient, topicARN, typedSrc); err != nil {
+		return fmt.Errorf("%w", reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
+			"Error removing the legacy subscription of the source's endpoint: %s", toErrMsg(err)))
+	}
+
 	return nil
 }
 
@@ -127,16 +153,14 @@ func (r *Reconciler) ensureUnsubscribed(ctx context.Context) error {
 		return nil
 	}
 
-	src := v1alpha1.SourceFromContext(ctx)
+	typedSrc := v1alpha1.SourceFromContext(ctx).(*v1alpha1.AWSSNSSource)
 
-	url := src.GetStatusManager().Address.URL
+	url := typedSrc.SubscriptionURL()
 	if url == nil {
 		event.Warn(ctx, ReasonFailedUnsubscribe, "Missing endpoint URL, skipping finalization")
 		return nil
 	}
 
-	typedSrc := src.(*v1alpha1.AWSSNSSource)
-
 	snsClient, err := r.snsCg.Get(typedSrc)
 	switch {
 	case isNotFound(err):
@@ -152,6 +176,11 @@ func (r *Reconciler) ensureUnsubscribed(ctx context.Context) error {
 
 	topicARN := typedSrc.Spec.ARN.String()
 
+	if err := unsubscribeLegacyEndpoint(ctx, snsClient, topicARN, typedSrc); err != nil {
+		event.Warn(ctx, ReasonFailedUnsubscribe,
+			"Error removing the legacy subscription of the source's endpoint. Ignoring: %s", toErrMsg(err))
+	}
+
 	subsARN, err := findSubscription(ctx, snsClient, topicARN, url.String())
 	switch {
 	case isPending(subsARN):
@@ -189,6 +218,35 @@ func (r *Reconciler) ensureUnsubscribed(ctx context.Context) error {
 		"Unsubscribed from SNS topic %q", topicARN)
 }
 
+// unsubscribeLegacyEndpoint removes the subscription of the source's endpoint
+// URL without subscription token, as subscribed by previous versions of the
+// source, if it exists.
+func unsubscribeLegacyEndpoint(ctx context.Context, cli snsiface.SNSAPI, topicARN string,
+	src *v1alpha1.AWSSNSSource) error {
+
+	url := src.Status.Address.URL
+
+	subsARN, err := findSubscription(ctx, cli, topicARN, url.String())
+	switch {
+	case isNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	case isPending(subsARN):
+		// unconfirmed subscriptions can not be deleted, they expire
+		// after 3 days
+		return nil
+	}
+
+	if err := unsubscribe(ctx, cli, subsARN); err != nil {
+		return err
+	}
+
+	event.Normal(ctx, ReasonUnsubscribed, "Removed legacy subscription %q of endpoint %q", subsARN, url)
+
+	return nil
+}
+
 // findSubscription returns the ARN of the subscription corresponding to the
 // given topic URL if it exists.
 func findSubscription(ctx context.Context, cli snsiface.SNSAPI, topicARN, endpointURL string) (string /*arn*/, error) {
@@ -243,6 +301,40 @@ func subscribe(ctx context.Context, cli snsiface.SNSAPI, topicARN string,
 	return *resp.SubscriptionArn, nil
 }
 
+// syncSubscriptionAttributes applies the given attributes to an existing SNS
+// subscription, for those which differ from the subscription's current
+// attributes.
+func syncSubscriptionAttributes(ctx context.Context, cli snsiface.SNSAPI, subsARN string,
+	attributes map[string]*string) error {
+
+	resp, err := cli.GetSubscriptionAttributesWithContext(ctx, &sns.GetSubscriptionAttributesInput{
+		SubscriptionArn: &subsARN,
+	})
+	if err != nil {
+		return fmt.Errorf("getting subscription attributes: %w", err)
+	}
+
+	for _, name := range attributesToUpdate(resp.Attributes, attributes) {
+		val := attributes[name]
+		if val == nil {
+			val = aws.String(unsetAttributeValues[name])
+		}
+
+		_, err := cli.SetSubscriptionAttributesWithContext(ctx, &sns.SetSubscriptionAttributesInput{
+			SubscriptionArn: &subsARN,
+			AttributeName:   aws.String(name),
+			AttributeValue:  val,
+		})
+		if err != nil {
+			return fmt.Errorf("setting subscription attribute %s: %w", name, err)
+		}
+
+		event.Normal(ctx, ReasonSubscriptionUpdated, "Updated attribute %s of subscription %q", name, subsARN)
+	}
+
+	return nil
+}
+
 // unsubscribe unsubscribes from a SNS topic.
 func unsubscribe(ctx context.Context, cli snsiface.SNSAPI, subsARN string) error {
 	resp, err := cli.UnsubscribeWithContext(ctx, &sns.UnsubscribeInput{
@@ -335,3 +427,97 @@ func unsubscribeErrorEvent(topicARN string, origErr error) reconciler.Event {
 	return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedUnsubscribe,
 		"Error unsubscribing from SNS topic %q: %s", topicARN, toErrMsg(origErr))
 }
+
+// Names of the subscription attributes which can be set via typed fields of
+// the source's spec.
+// https://docs.aws.amazon.com/sns/latest/api/API_SetSubscriptionAttributes.html
+const (
+	subsAttrFilterPolicy       = "FilterPolicy"
+	subsAttrFilterPolicyScope  = "FilterPolicyScope"
+	subsAttrRawMessageDelivery = "RawMessageDelivery"
+	subsAttrDeliveryPolicy     = "DeliveryPolicy"
+)
+
+// unsetAttributeValues contains, for each subscription attribute which is
+// reverted when its typed field gets removed from the source's spec, the
+// value which restores the attribute's default behaviour.
+var unsetAttributeValues = map[string]string{
+	// an empty filter policy disables the filtering of messages
+	subsAttrFilterPolicy:       "{}",
+	subsAttrRawMessageDelivery: "false",
+	// an empty delivery policy restores the delivery policy of the topic
+	subsAttrDeliveryPolicy: "",
+}
+
+// subscriptionAttributes returns the attributes of the SNS subscription
+// described by the given source spec.
+func subscriptionAttributes(spec *v1alpha1.AWSSNSSourceSpec) map[string]*string {
+	attrs := make(map[string]*string, len(spec.SubscriptionAttributes)+4)
+
+	for k, v := range spec.SubscriptionAttributes {
+		attrs[k] = v
+	}
+
+	if spec.FilterPolicy != nil {
+		attrs[subsAttrFilterPolicy] = aws.String(string(spec.FilterPolicy.Raw))
+	}
+	if spec.FilterPolicyScope != nil {
+		attrs[subsAttrFilterPolicyScope] = spec.FilterPolicyScope
+	}
+	if spec.RawMessageDelivery != nil {
+		attrs[subsAttrRawMessageDelivery] = aws.String(strconv.FormatBool(*spec.RawMessageDelivery))
+	}
+	if spec.DeliveryPolicy != nil {
+		attrs[subsAttrDeliveryPolicy] = aws.String(string(spec.DeliveryPolicy.Raw))
+	}
+
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// attributesToUpdate returns the sorted names of the desired subscription
+// attributes which differ from the current ones, as well as the names of
+// revertible attributes which are set on the subscription but not desired
+// anymore.
+func attributesToUpdate(current, desired map[string]*string) []string {
+	var names []string
+
+	for name, val := range desired {
+		if !attributeValuesEqual(current[name], val) {
+			names = append(names, name)
+		}
+	}
+
+	for name, unsetVal := range unsetAttributeValues {
+		if _, isDesired := desired[name]; isDesired {
+			continue
+		}
+		if curr := current[name]; curr != nil && !attributeValuesEqual(curr, &unsetVal) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// attributeValuesEqual returns whether the two given subscription attribute
+// values are equal. Values which are JSON documents, such as policies, are
+// compared semantically since SNS may return them in a different formatting.
+func attributeValuesEqual(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if *a == *b {
+		return true
+	}
+
+	var aJSON, bJSON interface{}
+	if json.Unmarshal([]byte(*a), &aJSON) != nil || json.Unmarshal([]byte(*b), &bJSON) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aJSON, bJSON)
+}
@@ -24,10 +24,14 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/sns"
+
+	"github.com/triggermesh/aws-event-sources/pkg/apis/sources/v1alpha1"
 )
 
 func TestErrors(t *testing.T) {
@@ -76,3 +80,86 @@ func TestErrors(t *testing.T) {
 		assert.Equal(t, genericErr.Error(), toErrMsg(genericErr), "Error was altered")
 	})
 }
+
+func TestSubscriptionAttributes(t *testing.T) {
+	t.Run("typed fields", func(t *testing.T) {
+		spec := &v1alpha1.AWSSNSSourceSpec{
+			SubscriptionAttributes: map[string]*string{
+				"RedrivePolicy": aws.String(`{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:123456789012:dlq"}`),
+			},
+			FilterPolicy:       &runtime.RawExtension{Raw: []byte(`{"color":["blue"]}`)},
+			FilterPolicyScope:  aws.String("MessageBody"),
+			RawMessageDelivery: aws.Bool(true),
+		}
+
+		expectAttrs := map[string]*string{
+			"RedrivePolicy":      aws.String(`{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:123456789012:dlq"}`),
+			"FilterPolicy":       aws.String(`{"color":["blue"]}`),
+			"FilterPolicyScope":  aws.String("MessageBody"),
+			"RawMessageDelivery": aws.String("true"),
+		}
+
+		assert.Equal(t, expectAttrs, subscriptionAttributes(spec))
+	})
+
+	t.Run("no attribute", func(t *testing.T) {
+		assert.Nil(t, subscriptionAttributes(&v1alpha1.AWSSNSSourceSpec{}))
+	})
+}
+
+func TestAttributesToUpdate(t *testing.T) {
+	testCases := map[string]struct {
+		current     map[string]*string
+		desired     map[string]*string
+		expectNames []string
+	}{
+		"in sync, different JSON formatting": {
+			current: map[string]*string{
+				"FilterPolicy":        aws.String(`{ "color": [ "blue" ] }`),
+				"PendingConfirmation": aws.String("false"),
+			},
+			desired: map[string]*string{
+				"FilterPolicy": aws.String(`{"color":["blue"]}`),
+			},
+		},
+		"changed and added attributes": {
+			current: map[string]*string{
+				"FilterPolicy": aws.String(`{"color":["blue"]}`),
+			},
+			desired: map[string]*string{
+				"FilterPolicy":      aws.String(`{"color":["red"]}`),
+				"FilterPolicyScope": aws.String("MessageAttributes"),
+			},
+			expectNames: []string{"FilterPolicy", "FilterPolicyScope"},
+		},
+		"removed revertible attributes": {
+			current: map[string]*string{
+				"FilterPolicy":       aws.String(`{"color":["blue"]}`),
+				"RawMessageDelivery": aws.String("true"),
+				"DeliveryPolicy":     aws.String(`{"healthyRetryPolicy":{"numRetries":5}}`),
+			},
+			expectNames: []string{"DeliveryPolicy", "FilterPolicy", "RawMessageDelivery"},
+		},
+		"removed attributes already reverted": {
+			current: map[string]*string{
+				"FilterPolicy":       aws.String("{}"),
+				"RawMessageDelivery": aws.String("false"),
+			},
+		},
+		"removed attributes set in the deprecated attributes object": {
+			current: map[string]*string{
+				"DeliveryPolicy": aws.String(`{"healthyRetryPolicy":{"numRetries":5}}`),
+			},
+			desired: map[string]*string{
+				"DeliveryPolicy": aws.String(`{"healthyRetryPolicy":{"numRetries":5}}`),
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectNames, attributesToUpdate(tc.current, tc.desired))
+		})
+	}
+}
@@ -46,8 +46,14 @@ type adapterConfig struct {
 // Verify that Reconciler implements common.AdapterDeploymentBuilder.
 var _ common.AdapterDeploymentBuilder = (*Reconciler)(nil)
 
+const envMultiTenant = "SQS_MULTI_TENANT"
+
 // BuildAdapter implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *appsv1.Deployment {
+	if src.(*v1alpha1.AWSSQSSource).IsMultiTenant() {
+		return r.buildMTAdapter(src)
+	}
+
 	typedSrc := src.(*v1alpha1.AWSSQSSource)
 
 	return common.NewAdapterDeployment(src, sinkURI,
@@ -77,6 +83,36 @@ func (r *Reconciler) BuildAdapter(src v1alpha1.EventSource, sinkURI *apis.URL) *
 	)
 }
 
+// buildMTAdapter returns the multi-tenant adapter which serves all sources
+// that opted into multi-tenancy in the namespace of the given source.
+// Credentials and queue properties are read by the adapter from the source
+// objects themselves.
+func (r *Reconciler) buildMTAdapter(src v1alpha1.EventSource) *appsv1.Deployment {
+	return common.NewMTAdapterDeployment(src,
+		resource.Image(r.adapterCfg.Image),
+
+		resource.EnvVar(envMultiTenant, "true"),
+		resource.EnvVars(r.adapterCfg.configs.ToEnvVars()...),
+
+		resource.Port(healthPortName, 8080),
+		resource.Port("metrics", 9090),
+
+		resource.Probe("/health", healthPortName),
+
+		// Each queue is served by a single receiver|processor|deleter,
+		// so the memory usage grows with the number of sources rather
+		// than with the number of available threads.
+		resource.Requests(
+			*kr.NewMilliQuantity(90, kr.DecimalSI),     // 90m
+			*kr.NewQuantity(1024*1024*60, kr.BinarySI), // 60Mi
+		),
+		resource.Limits(
+			*kr.NewMilliQuantity(1000, kr.DecimalSI),    // 1
+			*kr.NewQuantity(1024*1024*200, kr.BinarySI), // 200Mi
+		),
+	)
+}
+
 // RBACOwners implements common.AdapterDeploymentBuilder.
 func (r *Reconciler) RBACOwners(namespace string) ([]kmeta.OwnerRefable, error) {
 	srcs, err := r.srcLister(namespace).List(labels.Everything())
@@ -86,6 +86,25 @@ func NewMTAdapterDeployment(src v1alpha1.EventSource, opts ...resource.ObjectOpt
 	)
 }
 
+// ShardedAdapterOptions returns a set of ObjectOptions which scale the adapter
+// of a source that reads from a sharded data stream to the given number of
+// replicas. Replicas coordinate the ownership of shards regardless of their
+// number, so that checkpoints are preserved when scaling down to a single
+// replica, and during rollouts.
+func ShardedAdapterOptions(src v1alpha1.EventSource, replicas *int32) []resource.ObjectOption {
+	if replicas == nil {
+		return nil
+	}
+
+	return []resource.ObjectOption{
+		resource.Replicas(*replicas),
+		resource.EnvVar(envCoordinateShards, "true"),
+		resource.EnvVarFromFieldRef(envPodName, "metadata.name"),
+		resource.EnvVar(EnvNamespace, src.GetNamespace()),
+		resource.EnvVar(EnvName, src.GetName()),
+	}
+}
+
 // commonAdapterDeploymentOptions returns a set of ObjectOptions common to all
 // adapters backed by a Deployment.
 func commonAdapterDeploymentOptions(src v1alpha1.EventSource) []resource.ObjectOption {
@@ -137,6 +137,27 @@ func NewMTGenericServiceReconciler(ctx context.Context, typ kmeta.OwnerRefable,
 	return newGenericServiceReconciler(ctx, resolverCallback)
 }
 
+// WatchMTAdapterDeployment attaches an event handler to the Deployment informer
+// for changes to the multi-tenant adapter of the given source type. Intended
+// to be used by reconcilers of source types which can be served by either
+// single-tenant or multi-tenant adapters, in combination with
+// NewGenericDeploymentReconciler.
+func WatchMTAdapterDeployment(ctx context.Context, typ kmeta.OwnerRefable,
+	adapterHandlerFn func(obj interface{}),
+) {
+
+	hasLabels := hasAdapterLabelsForType(typ)
+	name := MTAdapterObjectName(typ)
+
+	deploymentinformerv1.Get(ctx).Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			object, ok := obj.(metav1.Object)
+			return ok && object.GetName() == name && hasLabels(obj)
+		},
+		Handler: controller.HandleAll(adapterHandlerFn),
+	})
+}
+
 // NewGenericRBACReconciler creates a new GenericRBACReconciler.
 func NewGenericRBACReconciler(ctx context.Context) *GenericRBACReconciler {
 	return &GenericRBACReconciler{
@@ -24,6 +24,8 @@ const (
 	envSink                  = "K_SINK"
 	envComponent             = "K_COMPONENT"
 	envMetricsPrometheusPort = "METRICS_PROMETHEUS_PORT"
+	envCoordinateShards      = "COORDINATE_SHARDS"
+	envPodName               = "POD_NAME"
 
 	EnvARN             = "ARN"
 	EnvAccessKeyID     = "AWS_ACCESS_KEY_ID"
@@ -0,0 +1,74 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// IsNotFound returns whether the given error indicates that some Kubernetes
+// object or AWS resource was not found.
+func IsNotFound(err error) bool {
+	if k8sErr := apierrors.APIStatus(nil); errors.As(err, &k8sErr) {
+		return k8sErr.Status().Reason == metav1.StatusReasonNotFound
+	}
+	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
+		errcode := awsErr.Code()
+		return errcode == sqs.ErrCodeQueueDoesNotExist ||
+			errcode == s3.ErrCodeNoSuchBucket ||
+			errcode == eventbridge.ErrCodeResourceNotFoundException
+	}
+	return false
+}
+
+// IsDenied returns whether the given error indicates that a request to the AWS
+// API could not be authorized.
+func IsDenied(err error) bool {
+	if awsReqFail := awserr.RequestFailure(nil); errors.As(err, &awsReqFail) {
+		code := awsReqFail.StatusCode()
+		return code == http.StatusUnauthorized || code == http.StatusForbidden
+	}
+	return false
+}
+
+// IsAWSError returns whether the given error is an AWS API error.
+func IsAWSError(err error) bool {
+	awsErr := awserr.Error(nil)
+	return errors.As(err, &awsErr)
+}
+
+// ToErrMsg attempts to extract the message from the given error if it is an
+// AWS error.
+// Those errors are particularly verbose and include a unique request ID that
+// causes an infinite loop of reconciliations when appended to a status
+// condition. Some AWS errors are not recoverable without manual intervention
+// (e.g. invalid secrets) so there is no point letting that behaviour happen.
+func ToErrMsg(err error) string {
+	if awsErr := awserr.Error(nil); errors.As(err, &awsErr) {
+		return awserr.SprintError(awsErr.Code(), awsErr.Message(), "", awsErr.OrigErr())
+	}
+	return err.Error()
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (c) 2021 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestErrorHelpers(t *testing.T) {
+	notFoundAWSErr := awserr.NewRequestFailure(
+		awserr.New(sqs.ErrCodeQueueDoesNotExist, "The queue does not exist", nil),
+		http.StatusBadRequest, "0123-abcd")
+	deniedAWSErr := awserr.NewRequestFailure(
+		awserr.New("AccessDenied", "Access denied", nil),
+		http.StatusForbidden, "0123-abcd")
+	notFoundK8sErr := apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "my-secret")
+	genericErr := errors.New("some error")
+
+	testCases := map[string]struct {
+		err error
+
+		expectNotFound bool
+		expectDenied   bool
+		expectAWSError bool
+		expectMsg      string
+	}{
+		"AWS not found error": {
+			err:            fmt.Errorf("wrapped: %w", notFoundAWSErr),
+			expectNotFound: true,
+			expectAWSError: true,
+			expectMsg:      sqs.ErrCodeQueueDoesNotExist + ": The queue does not exist",
+		},
+		"AWS authorization error": {
+			err:            deniedAWSErr,
+			expectDenied:   true,
+			expectAWSError: true,
+			expectMsg:      "AccessDenied: Access denied",
+		},
+		"Kubernetes not found error": {
+			err:            notFoundK8sErr,
+			expectNotFound: true,
+			expectMsg:      notFoundK8sErr.Error(),
+		},
+		"Generic error": {
+			err:       genericErr,
+			expectMsg: genericErr.Error(),
+		},
+	}
+
+	for name, tc := range testCases {
+		//nolint:scopelint
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectNotFound, IsNotFound(tc.err), "IsNotFound")
+			assert.Equal(t, tc.expectDenied, IsDenied(tc.err), "IsDenied")
+			assert.Equal(t, tc.expectAWSError, IsAWSError(tc.err), "IsAWSError")
+			assert.Equal(t, tc.expectMsg, ToErrMsg(tc.err), "ToErrMsg")
+		})
+	}
+}
@@ -74,6 +74,14 @@ type AdapterServiceBuilder interface {
 func (r *GenericDeploymentReconciler) ReconcileSource(ctx context.Context, ab AdapterDeploymentBuilder) reconciler.Event {
 	src := v1alpha1.SourceFromContext(ctx)
 
+	// A source which opted into multi-tenancy may still have a dedicated
+	// adapter from a previous version of its metadata.
+	if v1alpha1.IsMultiTenant(src) {
+		if err := r.DeleteAdapter(ctx); err != nil {
+			return err
+		}
+	}
+
 	src.GetStatusManager().CloudEventAttributes = CreateCloudEventAttributes(
 		src.AsEventSource(), src.GetEventTypes())
 
@@ -144,6 +152,33 @@ func (r *GenericDeploymentReconciler) reconcileAdapter(ctx context.Context,
 	return nil
 }
 
+// DeleteAdapter deletes the dedicated adapter Deployment of the source from
+// the context, if it exists.
+func (r *GenericDeploymentReconciler) DeleteAdapter(ctx context.Context) error {
+	src := v1alpha1.SourceFromContext(ctx)
+
+	name := kmeta.ChildName(ComponentName(src)+"-", src.GetName())
+
+	depl, err := r.Lister(src.GetNamespace()).Get(name)
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get adapter Deployment from cache: %w", err)
+	case !metav1.IsControlledBy(depl, src):
+		return nil
+	}
+
+	err = r.Client(src.GetNamespace()).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedAdapterDelete,
+			"Failed to delete adapter Deployment %q: %s", name, err)
+	}
+	event.Normal(ctx, ReasonAdapterDelete, "Deleted adapter Deployment %q", name)
+
+	return nil
+}
+
 // getOrCreateAdapter returns the existing adapter Deployment for a given
 // source, or creates it if it is missing.
 func (r *GenericDeploymentReconciler) getOrCreateAdapter(ctx context.Context, desiredAdapter *appsv1.Deployment) (*appsv1.Deployment, error) {
@@ -272,6 +307,33 @@ func (r *GenericServiceReconciler) reconcileAdapter(ctx context.Context,
 	return nil
 }
 
+// DeleteAdapter deletes the dedicated adapter Service of the source from the
+// context, if it exists.
+func (r *GenericServiceReconciler) DeleteAdapter(ctx context.Context) error {
+	src := v1alpha1.SourceFromContext(ctx)
+
+	name := kmeta.ChildName(ComponentName(src)+"-", src.GetName())
+
+	ksvc, err := r.Lister(src.GetNamespace()).Get(name)
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get adapter Service from cache: %w", err)
+	case !metav1.IsControlledBy(ksvc, src):
+		return nil
+	}
+
+	err = r.Client(src.GetNamespace()).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonFailedAdapterDelete,
+			"Failed to delete adapter Service %q: %s", name, err)
+	}
+	event.Normal(ctx, ReasonAdapterDelete, "Deleted adapter Service %q", name)
+
+	return nil
+}
+
 // getOrCreateAdapter returns the existing adapter Service for a given
 // source, or creates it if it is missing.
 func (r *GenericServiceReconciler) getOrCreateAdapter(ctx context.Context, desiredAdapter *servingv1.Service) (*servingv1.Service, error) {
@@ -117,6 +117,20 @@ func EnvVarFromSecret(name, secretName, secretKey string) ObjectOption {
 	}
 }
 
+// EnvVarFromFieldRef sets the value of a Container's environment variable to a
+// reference to a field of the Pod it runs in.
+func EnvVarFromFieldRef(name, fieldPath string) ObjectOption {
+	return func(object interface{}) {
+		valueFrom := &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				FieldPath: fieldPath,
+			},
+		}
+
+		setEnvVar(envVarsFrom(object), name, "", valueFrom)
+	}
+}
+
 func envVarsFrom(object interface{}) (envVars *[]corev1.EnvVar) {
 	switch o := object.(type) {
 	case *corev1.Container:
@@ -60,3 +60,12 @@ func Selector(key, val string) ObjectOption {
 		PodLabel(key, val)(d)
 	}
 }
+
+// Replicas sets the number of replicas of a Deployment.
+func Replicas(n int32) ObjectOption {
+	return func(object interface{}) {
+		d := object.(*appsv1.Deployment)
+
+		d.Spec.Replicas = &n
+	}
+}
@@ -26,6 +26,8 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"knative.dev/pkg/ptr"
 )
 
 func TestNewDeploymentWithDefaultContainer(t *testing.T) {
@@ -43,11 +45,13 @@ func TestNewDeploymentWithDefaultContainer(t *testing.T) {
 		StartupProbe("/initialized", "health"),
 		EnvVars(makeEnvVars(2, "MULTI_ENV", "val")...),
 		EnvVar("TEST_ENV2", "val2"),
+		EnvVarFromFieldRef("TEST_ENV3", "metadata.name"),
 		Label("test.label/2", "val2"),
 		ServiceAccount("god-mode"),
 		Requests(resource.MustParse("250m"), resource.MustParse("100Mi")),
 		Limits(resource.MustParse("250m"), resource.MustParse("100Mi")),
 		TerminationErrorToLogs,
+		Replicas(3),
 	)
 
 	expectDepl := &appsv1.Deployment{
@@ -60,6 +64,7 @@ func TestNewDeploymentWithDefaultContainer(t *testing.T) {
 			},
 		},
 		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.Int32(3),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"test.selector/1": "val1",
@@ -99,6 +104,13 @@ func TestNewDeploymentWithDefaultContainer(t *testing.T) {
 						}, {
 							Name:  "TEST_ENV2",
 							Value: "val2",
+						}, {
+							Name: "TEST_ENV3",
+							ValueFrom: &corev1.EnvVarSource{
+								FieldRef: &corev1.ObjectFieldSelector{
+									FieldPath: "metadata.name",
+								},
+							},
 						}},
 						ReadinessProbe: &corev1.Probe{
 							Handler: corev1.Handler{
@@ -34,6 +34,15 @@ import (
 func TestControllerConstructor(t *testing.T, ctor injection.ControllerConstructor) {
 	t.Helper()
 
+	// expected informers: Source, Deployment, ServiceAccount, RoleBinding
+	TestControllerConstructorWithInformers(t, ctor, 4)
+}
+
+// TestControllerConstructorWithInformers tests that a controller constructor
+// meets our requirements, and that it accesses the given number of informers.
+func TestControllerConstructorWithInformers(t *testing.T, ctor injection.ControllerConstructor, expectInformers int) {
+	t.Helper()
+
 	defer func() {
 		if r := recover(); r != nil {
 			t.Errorf("Unexpected panic: %v", r)
@@ -42,8 +51,7 @@ func TestControllerConstructor(t *testing.T, ctor injection.ControllerConstructo
 
 	ctx, informers := rt.SetupFakeContext(t)
 
-	// expected informers: Source, Deployment, ServiceAccount, RoleBinding
-	if expect, got := 4, len(informers); got != expect {
+	if expect, got := expectInformers, len(informers); got != expect {
 		t.Errorf("Expected %d injected informers, got %d", expect, got)
 	}
 
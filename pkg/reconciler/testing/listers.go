@@ -133,6 +133,11 @@ func (l *Listers) GetAWSKinesisSourceLister() listersv1alpha1.AWSKinesisSourceLi
 	return listersv1alpha1.NewAWSKinesisSourceLister(l.IndexerFor(&v1alpha1.AWSKinesisSource{}))
 }
 
+// GetAWSPerformanceInsightsSourceLister returns a Lister for AWSPerformanceInsightsSource objects.
+func (l *Listers) GetAWSPerformanceInsightsSourceLister() listersv1alpha1.AWSPerformanceInsightsSourceLister {
+	return listersv1alpha1.NewAWSPerformanceInsightsSourceLister(l.IndexerFor(&v1alpha1.AWSPerformanceInsightsSource{}))
+}
+
 // GetAWSSNSSourceLister returns a Lister for AWSSNSSource objects.
 func (l *Listers) GetAWSSNSSourceLister() listersv1alpha1.AWSSNSSourceLister {
 	return listersv1alpha1.NewAWSSNSSourceLister(l.IndexerFor(&v1alpha1.AWSSNSSource{}))